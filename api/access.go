@@ -0,0 +1,281 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	authAPI "moneyplanner/api/auth"
+	categoriesAPI "moneyplanner/api/categories"
+	usersAPI "moneyplanner/api/users"
+	userWalletAPI "moneyplanner/api/userwallet"
+	userWalletGroupAPI "moneyplanner/api/userwalletgroup"
+	walletAPI "moneyplanner/api/wallet"
+	walletGroupAPI "moneyplanner/api/walletgroup"
+	walletGroupWalletAPI "moneyplanner/api/walletgroupwallet"
+	"moneyplanner/models"
+)
+
+// writeForbidden writes a 403 JSON error
+func writeForbidden(w http.ResponseWriter, message string) {
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+// requireAdmin ensures the caller is an admin user
+func requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	user := authAPI.CurrentUser(r)
+	if user == nil || !user.IsAdmin {
+		writeForbidden(w, "This action requires an admin")
+		return false
+	}
+	return true
+}
+
+// requireSelfOrAdmin ensures the caller is the given user or an admin
+func requireSelfOrAdmin(w http.ResponseWriter, r *http.Request, userID uint) bool {
+	user := authAPI.CurrentUser(r)
+	if user == nil || (user.UserID != userID && !user.IsAdmin) {
+		writeForbidden(w, "You can only access your own account")
+		return false
+	}
+	return true
+}
+
+// methodRole returns the wallet role needed for a request method: reads need viewer, writes editor
+func methodRole(r *http.Request) models.WalletRole {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return models.WalletRoleViewer
+	}
+	return models.WalletRoleEditor
+}
+
+// requireWalletRole ensures the caller is a member of the wallet with at least the given role.
+// It writes the error response and returns false when access is denied.
+func requireWalletRole(w http.ResponseWriter, r *http.Request, walletID uint, role models.WalletRole) bool {
+	user := authAPI.CurrentUser(r)
+	if user == nil {
+		writeForbidden(w, "Authentication required")
+		return false
+	}
+
+	current, err := userWalletAPI.GetWalletRole(user.UserID, walletID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return false
+	}
+	if current == "" {
+		writeForbidden(w, "You do not have access to this wallet")
+		return false
+	}
+	if !current.Includes(role) {
+		writeForbidden(w, "This action requires the "+string(role)+" role on the wallet")
+		return false
+	}
+	if !authAPI.AllowsWallet(r, walletID) {
+		writeForbidden(w, "API key scopes do not cover this wallet")
+		return false
+	}
+	return true
+}
+
+// requireWalletsRole ensures the caller holds at least the given role on every listed wallet
+func requireWalletsRole(w http.ResponseWriter, r *http.Request, walletIDs []uint, role models.WalletRole) bool {
+	for _, walletID := range walletIDs {
+		if !requireWalletRole(w, r, walletID, role) {
+			return false
+		}
+	}
+	return true
+}
+
+// requireAnyWalletRole ensures the caller holds at least the given role on one of the listed wallets
+func requireAnyWalletRole(w http.ResponseWriter, r *http.Request, walletIDs []uint, role models.WalletRole) bool {
+	user := authAPI.CurrentUser(r)
+	if user == nil {
+		writeForbidden(w, "Authentication required")
+		return false
+	}
+
+	for _, walletID := range walletIDs {
+		current, err := userWalletAPI.GetWalletRole(user.UserID, walletID)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return false
+		}
+		if current != "" && current.Includes(role) && authAPI.AllowsWallet(r, walletID) {
+			return true
+		}
+	}
+	writeForbidden(w, "You do not have access to these wallets")
+	return false
+}
+
+// walletsWithRole lists the caller's wallets on which they hold at least the given role and
+// that their API key covers
+func walletsWithRole(w http.ResponseWriter, r *http.Request, role models.WalletRole) ([]uint, bool) {
+	user := authAPI.CurrentUser(r)
+	if user == nil {
+		writeForbidden(w, "Authentication required")
+		return nil, false
+	}
+
+	walletIDs, err := userWalletAPI.ListUserWalletIDs(user.UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return nil, false
+	}
+	allowed := []uint{}
+	for _, walletID := range walletIDs {
+		current, err := userWalletAPI.GetWalletRole(user.UserID, walletID)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return nil, false
+		}
+		if current != "" && current.Includes(role) && authAPI.AllowsWallet(r, walletID) {
+			allowed = append(allowed, walletID)
+		}
+	}
+	return allowed, true
+}
+
+// requireWalletGroupAccess ensures the caller is a member of at least one wallet in the group
+func requireWalletGroupAccess(w http.ResponseWriter, r *http.Request, walletGroupID uint) bool {
+	user := authAPI.CurrentUser(r)
+	if user == nil {
+		writeForbidden(w, "Authentication required")
+		return false
+	}
+
+	ok, err := userWalletGroupAPI.IsWalletGroupMember(user.UserID, walletGroupID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return false
+	}
+	if !ok {
+		writeForbidden(w, "You do not have access to this wallet group")
+		return false
+	}
+	return true
+}
+
+// requireWalletGroupEditor ensures the caller may rename or delete a wallet group: an editor of
+// every wallet in it, or its creator while it has none
+func requireWalletGroupEditor(w http.ResponseWriter, r *http.Request, walletGroupID uint) bool {
+	wallets, err := walletGroupWalletAPI.ListWalletsInGroup(walletGroupID)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return false
+	}
+	if len(wallets) == 0 {
+		return requireWalletGroupCreator(w, r, walletGroupID)
+	}
+	walletIDs := make([]uint, 0, len(wallets))
+	for _, wallet := range wallets {
+		walletIDs = append(walletIDs, wallet.WalletID)
+	}
+	return requireWalletsRole(w, r, walletIDs, models.WalletRoleEditor)
+}
+
+// requireWalletGroupCreator ensures the caller created the wallet group or is an admin
+func requireWalletGroupCreator(w http.ResponseWriter, r *http.Request, walletGroupID uint) bool {
+	user := authAPI.CurrentUser(r)
+	if user == nil {
+		writeForbidden(w, "Authentication required")
+		return false
+	}
+
+	group, err := walletGroupAPI.GetWalletGroupByID(walletGroupID)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return false
+	}
+	if !user.IsAdmin && (group.CreatedBy == nil || *group.CreatedBy != user.UserID) {
+		writeForbidden(w, "Only the creator of an empty wallet group can change it")
+		return false
+	}
+	return true
+}
+
+// requireDefaultWallet ensures a user's default wallet is a live wallet they can see. Callers
+// setting their own need viewer on it; an admin setting someone else's needs that user to be a member.
+func requireDefaultWallet(w http.ResponseWriter, r *http.Request, userID, walletID uint) bool {
+	if _, err := walletAPI.GetWalletByID(walletID); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid default_wallet_id: " + err.Error()})
+		return false
+	}
+
+	caller := authAPI.CurrentUser(r)
+	if caller != nil && caller.UserID == userID {
+		return requireWalletRole(w, r, walletID, models.WalletRoleViewer)
+	}
+	role, err := userWalletAPI.GetWalletRole(userID, walletID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return false
+	}
+	if !role.Includes(models.WalletRoleViewer) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid default_wallet_id: the user is not a member of that wallet"})
+		return false
+	}
+	return true
+}
+
+// requireAPIKeyManagement ensures the caller may manage the target user's API keys:
+// either their own, or a bot's when the caller owns a wallet the bot is attached to
+func requireAPIKeyManagement(w http.ResponseWriter, r *http.Request, userID uint) bool {
+	caller := authAPI.CurrentUser(r)
+	if caller == nil || authAPI.IsAPIKeyRequest(r) {
+		writeForbidden(w, "API keys must be managed with a user session")
+		return false
+	}
+	if caller.UserID == userID {
+		return true
+	}
+
+	target, err := usersAPI.GetUserByID(userID)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return false
+	}
+	if target.Type != models.UserTypeBot {
+		writeForbidden(w, "You can only manage your own API keys or those of bot users")
+		return false
+	}
+
+	botWalletIDs, err := userWalletAPI.ListUserWalletIDs(userID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return false
+	}
+	for _, walletID := range botWalletIDs {
+		role, err := userWalletAPI.GetWalletRole(caller.UserID, walletID)
+		if err == nil && role == models.WalletRoleOwner {
+			return true
+		}
+	}
+	writeForbidden(w, "You must own a wallet this bot is attached to")
+	return false
+}
+
+// requireCategoryInWallet ensures the category belongs to the wallet in the URL
+func requireCategoryInWallet(w http.ResponseWriter, walletID, categoryID uint) bool {
+	category, err := categoriesAPI.GetCategoryByID(categoryID)
+	if err != nil || category.WalletID != walletID {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Category not found in this wallet"})
+		return false
+	}
+	return true
+}
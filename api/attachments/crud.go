@@ -0,0 +1,196 @@
+package attachments
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"moneyplanner/database"
+	"moneyplanner/models"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// blobMu keeps a blob from being pruned between being stored and being referred to
+var blobMu sync.Mutex
+
+func thumbnailKey(hash string) string {
+	return hash + "-thumb"
+}
+
+// cleanFileName keeps the base name of an uploaded file without control characters
+func cleanFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, filepath.Base(strings.ReplaceAll(name, "\\", "/")))
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == "/" {
+		return "attachment"
+	}
+	if runes := []rune(name); len(runes) > 255 {
+		name = string(runes[:255])
+	}
+	return name
+}
+
+// ListAttachments lists the attachments of a transaction in the order they were added
+func ListAttachments(transactionID uint) ([]models.Attachment, error) {
+	attachments := []models.Attachment{}
+	if err := database.DB.Where("transaction_id = ?", transactionID).
+		Order("created_at, attachment_id").Find(&attachments).Error; err != nil {
+		return nil, fmt.Errorf("failed to list attachments: %w", err)
+	}
+	return attachments, nil
+}
+
+// GetAttachment retrieves an attachment of a transaction
+func GetAttachment(transactionID, attachmentID uint) (*models.Attachment, error) {
+	var attachment models.Attachment
+	if err := database.DB.Where("transaction_id = ?", transactionID).First(&attachment, attachmentID).Error; err != nil {
+		return nil, fmt.Errorf("attachment not found: %w", err)
+	}
+	return &attachment, nil
+}
+
+// CreateAttachment stores a file and attaches it to a transaction. The type is detected from
+// the contents rather than trusted from the client; images also get a thumbnail.
+func CreateAttachment(transactionID, userID uint, fileName string, r io.Reader) (*models.Attachment, error) {
+	maxSize := MaxSize()
+	data, err := io.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	if int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("%w: files can be at most %d bytes", ErrTooLarge, maxSize)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("file is empty")
+	}
+
+	contentType := http.DetectContentType(data)
+	if !allowedTypes[contentType] {
+		return nil, fmt.Errorf("%w %s: only PDF, JPEG, PNG, GIF and WebP files can be attached", ErrUnsupportedType, contentType)
+	}
+	thumbnail, err := makeThumbnail(contentType, data)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	attachment := &models.Attachment{
+		TransactionID: &transactionID,
+		FileName:      cleanFileName(fileName),
+		ContentType:   contentType,
+		Size:          int64(len(data)),
+		SHA256:        hex.EncodeToString(sum[:]),
+		HasThumbnail:  thumbnail != nil,
+		UserID:        userID,
+	}
+
+	blobMu.Lock()
+	defer blobMu.Unlock()
+	store := Store()
+	if err := store.Put(attachment.SHA256, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	if thumbnail != nil {
+		if err := store.Put(thumbnailKey(attachment.SHA256), bytes.NewReader(thumbnail)); err != nil {
+			return nil, err
+		}
+	}
+	if err := database.DB.Create(attachment).Error; err != nil {
+		return nil, fmt.Errorf("failed to create attachment: %w", err)
+	}
+
+	log.Printf("✓ Attachment '%s' (ID: %d) added to transaction %d", attachment.FileName, attachment.AttachmentID, transactionID)
+	return attachment, nil
+}
+
+// OpenAttachment opens the contents of an attachment for reading
+func OpenAttachment(attachment *models.Attachment) (io.ReadCloser, error) {
+	return Store().Open(attachment.SHA256)
+}
+
+// OpenThumbnail opens the JPEG thumbnail of an image attachment for reading
+func OpenThumbnail(attachment *models.Attachment) (io.ReadCloser, error) {
+	if !attachment.HasThumbnail {
+		return nil, fmt.Errorf("attachment %d has no thumbnail", attachment.AttachmentID)
+	}
+	return Store().Open(thumbnailKey(attachment.SHA256))
+}
+
+// DeleteAttachment removes an attachment from a transaction, and its file from the store
+// unless another attachment has the same contents
+func DeleteAttachment(transactionID, attachmentID uint) error {
+	attachment, err := GetAttachment(transactionID, attachmentID)
+	if err != nil {
+		return err
+	}
+	if err := database.DB.Delete(&models.Attachment{}, attachmentID).Error; err != nil {
+		return fmt.Errorf("failed to delete attachment: %w", err)
+	}
+	PruneBlobs([]string{attachment.SHA256})
+
+	log.Printf("✓ Attachment (ID: %d) removed from transaction %d", attachmentID, transactionID)
+	return nil
+}
+
+// DetachAttachments unlinks the attachments of a transaction that is being deleted for good.
+// They are removed with their files on the next trash purge.
+func DetachAttachments(tx *gorm.DB, transactionID uint) error {
+	if err := tx.Model(&models.Attachment{}).Where("transaction_id = ?", transactionID).
+		Update("transaction_id", nil).Error; err != nil {
+		return fmt.Errorf("failed to detach attachments: %w", err)
+	}
+	return nil
+}
+
+// DeleteOrphans deletes the attachments whose transaction is gone and returns the hashes of
+// their files, to be passed to PruneBlobs once tx has been committed
+func DeleteOrphans(tx *gorm.DB) ([]string, error) {
+	const orphaned = "transaction_id IS NULL OR transaction_id NOT IN (SELECT transaction_id FROM transactions)"
+	hashes := []string{}
+	if err := tx.Model(&models.Attachment{}).Where(orphaned).Distinct().Pluck("sha256", &hashes).Error; err != nil {
+		return nil, fmt.Errorf("failed to find orphaned attachments: %w", err)
+	}
+	if len(hashes) == 0 {
+		return hashes, nil
+	}
+	if err := tx.Where(orphaned).Delete(&models.Attachment{}).Error; err != nil {
+		return nil, fmt.Errorf("failed to purge attachments: %w", err)
+	}
+	return hashes, nil
+}
+
+// PruneBlobs deletes the files and thumbnails of the given hashes from the store when no
+// attachment refers to them any more. Failures are logged; a file left behind does no harm.
+func PruneBlobs(hashes []string) {
+	blobMu.Lock()
+	defer blobMu.Unlock()
+	store := Store()
+	for _, hash := range hashes {
+		var count int64
+		if err := database.DB.Model(&models.Attachment{}).Where("sha256 = ?", hash).Count(&count).Error; err != nil {
+			log.Printf("Warning: Failed to check attachment file %s: %v", hash, err)
+			continue
+		}
+		if count > 0 {
+			continue
+		}
+		for _, key := range []string{hash, thumbnailKey(hash)} {
+			if err := store.Delete(key); err != nil {
+				log.Printf("Warning: Failed to delete attachment file %s: %v", key, err)
+			}
+		}
+	}
+}
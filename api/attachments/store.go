@@ -0,0 +1,124 @@
+package attachments
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ErrBlobNotFound is returned by a BlobStore when there is nothing under a key
+var ErrBlobNotFound = errors.New("file not found in attachment store")
+
+// BlobStore keeps the contents of attachments. Keys are content hashes, so a blob never
+// changes once written and Put of an existing key may keep the old copy.
+type BlobStore interface {
+	Put(key string, r io.Reader) error
+	Open(key string) (io.ReadCloser, error)
+	Delete(key string) error // Deleting a missing key is not an error
+}
+
+// envDir sets the directory the default store keeps attachments in
+const envDir = "MONEYPLANNER_ATTACHMENTS_DIR"
+
+const defaultDir = "attachments"
+
+var (
+	storeMu sync.Mutex
+	store   BlobStore
+)
+
+// SetStore replaces the store attachments are kept in, e.g. with one backed by object storage
+func SetStore(s BlobStore) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	store = s
+}
+
+// Store returns the store attachments are kept in: the one passed to SetStore, or else a
+// DiskStore in MONEYPLANNER_ATTACHMENTS_DIR (default "attachments")
+func Store() BlobStore {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	if store == nil {
+		dir := os.Getenv(envDir)
+		if dir == "" {
+			dir = defaultDir
+		}
+		store = &DiskStore{Dir: dir}
+	}
+	return store
+}
+
+// DiskStore keeps blobs as files on local disk, spread over subdirectories by the first two
+// characters of the key
+type DiskStore struct {
+	Dir string
+}
+
+func (s *DiskStore) path(key string) (string, error) {
+	if len(key) < 3 || key != filepath.Base(key) {
+		return "", fmt.Errorf("invalid attachment key %q", key)
+	}
+	return filepath.Join(s.Dir, key[:2], key), nil
+}
+
+// Put writes the blob to a temporary file first and moves it into place, so a reader never
+// sees a partial file
+func (s *DiskStore) Put(key string, r io.Reader) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create attachment directory: %w", err)
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create attachment file: %w", err)
+	}
+	defer os.Remove(file.Name())
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write attachment file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write attachment file: %w", err)
+	}
+	if err := os.Rename(file.Name(), path); err != nil {
+		return fmt.Errorf("failed to save attachment file: %w", err)
+	}
+	return nil
+}
+
+func (s *DiskStore) Open(key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrBlobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open attachment file: %w", err)
+	}
+	return file, nil
+}
+
+func (s *DiskStore) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete attachment file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package attachments
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+)
+
+const (
+	thumbnailSize    = 320        // Longest side in pixels
+	thumbnailQuality = 80         // JPEG quality
+	maxImagePixels   = 50_000_000 // Larger images are refused rather than decoded
+)
+
+// thumbnailable lists the image types thumbnails can be made of
+var thumbnailable = map[string]func([]byte) (image.Image, error){
+	"image/jpeg": func(data []byte) (image.Image, error) { return jpeg.Decode(bytes.NewReader(data)) },
+	"image/png":  func(data []byte) (image.Image, error) { return png.Decode(bytes.NewReader(data)) },
+	"image/gif":  func(data []byte) (image.Image, error) { return gif.Decode(bytes.NewReader(data)) },
+}
+
+// makeThumbnail scales an image down to fit thumbnailSize, averaging the pixels each thumbnail
+// pixel covers, and encodes it as a JPEG on white. It returns nil for types it cannot read.
+func makeThumbnail(contentType string, data []byte) ([]byte, error) {
+	decode, ok := thumbnailable[contentType]
+	if !ok {
+		return nil, nil
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid image: %w", err)
+	}
+	if config.Width*config.Height > maxImagePixels {
+		return nil, fmt.Errorf("image is %dx%d pixels; at most %d pixels are allowed", config.Width, config.Height, maxImagePixels)
+	}
+	src, err := decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image: %w", err)
+	}
+
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width == 0 || height == 0 {
+		return nil, fmt.Errorf("invalid image: it has no pixels")
+	}
+	if width > thumbnailSize || height > thumbnailSize {
+		if width >= height {
+			width, height = thumbnailSize, max(1, height*thumbnailSize/bounds.Dx())
+		} else {
+			width, height = max(1, width*thumbnailSize/bounds.Dy()), thumbnailSize
+		}
+	}
+
+	// Flatten onto white first so transparent areas do not turn black in the JPEG
+	flat := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(flat, flat.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), src, bounds.Min, draw.Over)
+
+	thumb := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := y*bounds.Dy()/height, max((y+1)*bounds.Dy()/height, y*bounds.Dy()/height+1)
+		for x := 0; x < width; x++ {
+			x0, x1 := x*bounds.Dx()/width, max((x+1)*bounds.Dx()/width, x*bounds.Dx()/width+1)
+			var r, g, b, n uint32
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pixel := flat.RGBAAt(sx, sy)
+					r, g, b, n = r+uint32(pixel.R), g+uint32(pixel.G), b+uint32(pixel.B), n+1
+				}
+			}
+			thumb.SetRGBA(x, y, color.RGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(b / n), A: 0xff})
+		}
+	}
+
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, thumb, &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	return out.Bytes(), nil
+}
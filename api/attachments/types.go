@@ -0,0 +1,41 @@
+package attachments
+
+import (
+	"errors"
+	"log"
+	"os"
+	"strconv"
+)
+
+var (
+	ErrTooLarge        = errors.New("attachment is too large")
+	ErrUnsupportedType = errors.New("unsupported attachment type")
+)
+
+// allowedTypes are the content types that can be attached, as detected from the file itself
+var allowedTypes = map[string]bool{
+	"application/pdf": true,
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"image/webp":      true,
+}
+
+// envMaxSize sets the largest file that can be attached, in bytes
+const envMaxSize = "MONEYPLANNER_ATTACHMENT_MAX_SIZE"
+
+const defaultMaxSize int64 = 10 << 20
+
+// MaxSize returns the largest file that can be attached, in bytes
+func MaxSize() int64 {
+	value := os.Getenv(envMaxSize)
+	if value == "" {
+		return defaultMaxSize
+	}
+	size, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || size <= 0 {
+		log.Printf("Warning: Invalid %s '%s', using %d", envMaxSize, value, defaultMaxSize)
+		return defaultMaxSize
+	}
+	return size
+}
@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"moneyplanner/api/users"
+	"moneyplanner/database"
+	"moneyplanner/models"
+	"strings"
+	"time"
+)
+
+// apiKeyPrefix marks API keys so they can be told apart from session tokens
+const apiKeyPrefix = "mp_"
+
+// isAPIKey reports whether a presented credential is an API key rather than a session token
+func isAPIKey(token string) bool {
+	return strings.HasPrefix(token, apiKeyPrefix)
+}
+
+// toAPIKeyResponse converts a stored key into its API representation
+func toAPIKeyResponse(key *models.APIKey) *APIKeyResponse {
+	return &APIKeyResponse{
+		APIKey: *key,
+		Scopes: strings.Split(key.Scopes, ","),
+	}
+}
+
+// CreateAPIKey issues a new API key for a user. The plaintext key is only returned here.
+func CreateAPIKey(userID uint, req *APIKeyCreationRequest) (*APIKeyResponse, error) {
+	if strings.TrimSpace(req.Name) == "" {
+		return nil, errors.New("name is required")
+	}
+	if len(req.Scopes) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+
+	if _, err := users.GetUserByID(userID); err != nil {
+		return nil, err
+	}
+
+	normalized := make([]string, 0, len(req.Scopes))
+	for _, s := range req.Scopes {
+		scope, err := ParseScope(s)
+		if err != nil {
+			return nil, err
+		}
+		normalized = append(normalized, scope.String())
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+	plaintext := apiKeyPrefix + token
+
+	key := &models.APIKey{
+		UserID:      userID,
+		Name:        req.Name,
+		Prefix:      plaintext[:len(apiKeyPrefix)+6],
+		KeyHash:     hashToken(plaintext),
+		Scopes:      strings.Join(normalized, ","),
+		CreatedTime: time.Now(),
+		ExpiresAt:   req.ExpiresAt,
+	}
+	if err := database.DB.Create(key).Error; err != nil {
+		return nil, fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	log.Printf("✓ API key '%s' created for user %d (ID: %d)", key.Name, userID, key.APIKeyID)
+	resp := toAPIKeyResponse(key)
+	resp.Key = plaintext
+	return resp, nil
+}
+
+// ListAPIKeys lists a user's API keys, including revoked ones
+func ListAPIKeys(userID uint) ([]APIKeyResponse, error) {
+	var keys []models.APIKey
+	if err := database.DB.Where("user_id = ?", userID).Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+
+	result := make([]APIKeyResponse, 0, len(keys))
+	for i := range keys {
+		result = append(result, *toAPIKeyResponse(&keys[i]))
+	}
+	return result, nil
+}
+
+// RevokeAPIKey revokes one of a user's API keys
+func RevokeAPIKey(userID, apiKeyID uint) error {
+	var key models.APIKey
+	if err := database.DB.Where("api_key_id = ? AND user_id = ?", apiKeyID, userID).First(&key).Error; err != nil {
+		return fmt.Errorf("API key not found: %w", err)
+	}
+	if key.RevokedTime != nil {
+		return nil
+	}
+
+	if err := database.DB.Model(&key).Update("revoked_time", time.Now()).Error; err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+
+	log.Printf("✓ API key '%s' (ID: %d) revoked", key.Name, apiKeyID)
+	return nil
+}
+
+// AuthenticateAPIKey resolves an API key to its user and scopes
+func AuthenticateAPIKey(plaintext string) (*models.User, []Scope, error) {
+	var key models.APIKey
+	if err := database.DB.Where("key_hash = ?", hashToken(plaintext)).First(&key).Error; err != nil {
+		return nil, nil, ErrInvalidToken
+	}
+
+	now := time.Now()
+	if key.RevokedTime != nil || (key.ExpiresAt != nil && now.After(*key.ExpiresAt)) {
+		return nil, nil, ErrInvalidToken
+	}
+
+	scopes, err := ParseScopes(key.Scopes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user, err := users.GetUserByID(key.UserID)
+	if err != nil {
+		return nil, nil, ErrInvalidToken
+	}
+
+	database.DB.Model(&models.APIKey{}).Where("api_key_id = ?", key.APIKeyID).Update("last_used_time", now)
+	return user, scopes, nil
+}
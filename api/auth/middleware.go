@@ -0,0 +1,165 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"moneyplanner/models"
+)
+
+type contextKey int
+
+const authContextKey contextKey = iota
+
+// authContext is what the middleware attaches to an authenticated request
+type authContext struct {
+	user *models.User
+
+	// Set only for requests authenticated with an API key
+	viaAPIKey bool
+	scopes    []Scope
+	resource  string
+	action    string
+}
+
+// publicPaths can be reached without a bearer token
+var publicPaths = map[string]bool{
+	"/api/initdone":     true,
+	"/api/auth/login":   true,
+	"/api/auth/refresh": true,
+}
+
+// optionalAuthPaths authenticate a token when one is sent but also accept anonymous
+// requests; the handler decides whether a user is needed
+var optionalAuthPaths = map[string]bool{
+	"/api/init": true,
+}
+
+// BearerToken extracts the token from an "Authorization: Bearer ..." header
+func BearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if len(header) < 7 || !strings.EqualFold(header[:7], "bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(header[7:])
+}
+
+// credential returns the API key or session token presented with the request
+func credential(r *http.Request) string {
+	if key := strings.TrimSpace(r.Header.Get("X-API-Key")); key != "" {
+		return key
+	}
+	return BearerToken(r)
+}
+
+func fromContext(r *http.Request) *authContext {
+	ac, _ := r.Context().Value(authContextKey).(*authContext)
+	return ac
+}
+
+// CurrentUser returns the authenticated user attached to the request, or nil
+func CurrentUser(r *http.Request) *models.User {
+	if ac := fromContext(r); ac != nil {
+		return ac.user
+	}
+	return nil
+}
+
+// IsAPIKeyRequest reports whether the request was authenticated with an API key
+func IsAPIKeyRequest(r *http.Request) bool {
+	ac := fromContext(r)
+	return ac != nil && ac.viaAPIKey
+}
+
+// AllowsWallet reports whether the request's API key scopes cover the wallet.
+// Requests authenticated with a session are never restricted by scopes.
+func AllowsWallet(r *http.Request, walletID uint) bool {
+	ac := fromContext(r)
+	if ac == nil || !ac.viaAPIKey || ac.resource == "" {
+		return true
+	}
+	return scopesAllow(ac.scopes, ac.resource, ac.action, &walletID)
+}
+
+// FilterWalletIDs drops the wallets the request's API key scopes do not cover
+func FilterWalletIDs(r *http.Request, walletIDs []uint) []uint {
+	filtered := []uint{}
+	for _, walletID := range walletIDs {
+		if AllowsWallet(r, walletID) {
+			filtered = append(filtered, walletID)
+		}
+	}
+	return filtered
+}
+
+// WithUser returns a copy of the request carrying the authenticated user
+func WithUser(r *http.Request, user *models.User) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), authContextKey, &authContext{user: user}))
+}
+
+// Middleware authenticates every request outside publicPaths and attaches the user to its context.
+// API keys are additionally checked against the scope the path requires.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if publicPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token := credential(r)
+		if token == "" {
+			if optionalAuthPaths[r.URL.Path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+			writeUnauthorized(w, "Missing bearer token")
+			return
+		}
+
+		if !isAPIKey(token) {
+			user, err := Authenticate(token)
+			if err != nil {
+				writeUnauthorized(w, err.Error())
+				return
+			}
+			next.ServeHTTP(w, WithUser(r, user))
+			return
+		}
+
+		user, scopes, err := AuthenticateAPIKey(token)
+		if err != nil {
+			writeUnauthorized(w, err.Error())
+			return
+		}
+
+		resource, action, ok := requiredScope(r.URL.Path, r.Method)
+		if !ok || (resource != "" && !scopesAllow(scopes, resource, action, nil)) {
+			writeForbidden(w, "API key scopes do not allow this request")
+			return
+		}
+
+		ac := &authContext{
+			user:      user,
+			viaAPIKey: true,
+			scopes:    scopes,
+			resource:  resource,
+			action:    action,
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), authContextKey, ac)))
+	})
+}
+
+func writeUnauthorized(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("WWW-Authenticate", `Bearer realm="moneyplanner"`)
+	w.WriteHeader(http.StatusUnauthorized)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+func writeForbidden(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
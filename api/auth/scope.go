@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	ScopeActionRead  = "read"
+	ScopeActionWrite = "write"
+)
+
+// scopeResources are the resources an API key can be granted access to
+var scopeResources = map[string]bool{
+	"wallets":      true,
+	"categories":   true,
+	"transactions": true,
+	"transfers":    true,
+	"*":            true,
+}
+
+// Scope grants an action on a resource, optionally limited to a single wallet.
+// The string form is "resource:action" or "resource:action@walletID".
+type Scope struct {
+	Resource string
+	Action   string
+	WalletID *uint
+}
+
+// ParseScope parses a scope string such as "transactions:write@3"
+func ParseScope(s string) (Scope, error) {
+	var scope Scope
+	s = strings.TrimSpace(s)
+
+	if at := strings.Index(s, "@"); at >= 0 {
+		walletID, err := strconv.ParseUint(s[at+1:], 10, 32)
+		if err != nil {
+			return scope, fmt.Errorf("invalid wallet in scope '%s'", s)
+		}
+		w := uint(walletID)
+		scope.WalletID = &w
+		s = s[:at]
+	}
+
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 {
+		return scope, fmt.Errorf("invalid scope '%s' (expected resource:action[@walletID])", s)
+	}
+	scope.Resource, scope.Action = parts[0], parts[1]
+
+	if !scopeResources[scope.Resource] {
+		return scope, fmt.Errorf("unknown scope resource '%s'", scope.Resource)
+	}
+	if scope.Action != ScopeActionRead && scope.Action != ScopeActionWrite {
+		return scope, fmt.Errorf("unknown scope action '%s'", scope.Action)
+	}
+	return scope, nil
+}
+
+// ParseScopes parses a comma-separated scope list
+func ParseScopes(s string) ([]Scope, error) {
+	var scopes []Scope
+	for _, part := range strings.Split(s, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		scope, err := ParseScope(part)
+		if err != nil {
+			return nil, err
+		}
+		scopes = append(scopes, scope)
+	}
+	return scopes, nil
+}
+
+// String formats the scope back into its string form
+func (s Scope) String() string {
+	str := s.Resource + ":" + s.Action
+	if s.WalletID != nil {
+		str += "@" + strconv.FormatUint(uint64(*s.WalletID), 10)
+	}
+	return str
+}
+
+// Allows reports whether the scope grants the action on the resource. A nil walletID
+// matches any wallet restriction; listings are filtered per wallet separately.
+func (s Scope) Allows(resource, action string, walletID *uint) bool {
+	if s.Resource != "*" && s.Resource != resource {
+		return false
+	}
+	if action == ScopeActionWrite && s.Action != ScopeActionWrite {
+		return false
+	}
+	if walletID != nil && s.WalletID != nil && *s.WalletID != *walletID {
+		return false
+	}
+	return true
+}
+
+// scopesAllow reports whether any of the scopes grants the action
+func scopesAllow(scopes []Scope, resource, action string, walletID *uint) bool {
+	for _, scope := range scopes {
+		if scope.Allows(resource, action, walletID) {
+			return true
+		}
+	}
+	return false
+}
+
+// requiredScope maps a request path and method to the resource and action an API key needs.
+// An empty resource means any key may call the path. Paths outside the wallet, category
+// and transaction APIs are not reachable with API keys.
+func requiredScope(path, method string) (resource, action string, ok bool) {
+	action = ScopeActionWrite
+	if method == "GET" || method == "HEAD" {
+		action = ScopeActionRead
+	}
+
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) < 2 || parts[0] != "api" {
+		return "", "", false
+	}
+
+	switch parts[1] {
+	case "transactions", "transfers":
+		return parts[1], action, true
+	case "wallets":
+		if len(parts) >= 4 {
+			switch parts[3] {
+			case "transactions", "categories":
+				return parts[3], action, true
+			}
+		}
+		return "wallets", action, true
+	case "auth":
+		if len(parts) == 3 && parts[2] == "me" {
+			return "", "", true
+		}
+	}
+	return "", "", false
+}
@@ -0,0 +1,180 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"moneyplanner/api/users"
+	"moneyplanner/database"
+	"moneyplanner/models"
+	"strings"
+	"time"
+)
+
+const (
+	accessTokenTTL  = 1 * time.Hour
+	refreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// ErrInvalidCredentials is returned for any failed login so callers cannot probe usernames
+var ErrInvalidCredentials = errors.New("invalid username or password")
+
+// ErrPasswordResetRequired is returned on login to an account whose password must be reset
+var ErrPasswordResetRequired = errors.New("this account's password must be reset by an admin before you can log in")
+
+// ErrInvalidToken is returned when a token is unknown or expired
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// generateToken returns a random opaque token
+func generateToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashToken returns the hex SHA-256 of a token, which is what gets stored
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueSession creates a new session for a user and returns the plaintext tokens
+func issueSession(user *models.User) (*TokenResponse, error) {
+	accessToken, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := &models.Session{
+		UserID:           user.UserID,
+		TokenHash:        hashToken(accessToken),
+		RefreshTokenHash: hashToken(refreshToken),
+		ExpiresAt:        now.Add(accessTokenTTL),
+		RefreshExpiresAt: now.Add(refreshTokenTTL),
+		CreatedTime:      now,
+		LastUsedTime:     now,
+	}
+	if err := database.DB.Create(session).Error; err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return &TokenResponse{
+		AccessToken:      accessToken,
+		TokenType:        "Bearer",
+		ExpiresAt:        session.ExpiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: session.RefreshExpiresAt,
+		User:             user,
+	}, nil
+}
+
+// Login verifies credentials and issues a new session
+func Login(req *LoginRequest) (*TokenResponse, error) {
+	username := strings.TrimSpace(req.Username)
+	if username == "" || req.Password == "" {
+		return nil, errors.New("username and password are required")
+	}
+
+	user, err := users.GetUserByUsername(username)
+	if err != nil {
+		user, err = users.GetUserByEmail(username)
+		if err != nil {
+			users.RejectPassword(req.Password)
+			return nil, ErrInvalidCredentials
+		}
+	}
+
+	if !users.VerifyPassword(user, req.Password) {
+		return nil, ErrInvalidCredentials
+	}
+	if user.MustResetPassword {
+		return nil, ErrPasswordResetRequired
+	}
+
+	if err := PurgeExpiredSessions(); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
+	resp, err := issueSession(user)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("✓ User '%s' logged in", user.Username)
+	return resp, nil
+}
+
+// Refresh exchanges a valid refresh token for a new token pair, revoking the old session
+func Refresh(req *RefreshRequest) (*TokenResponse, error) {
+	if req.RefreshToken == "" {
+		return nil, errors.New("refresh_token is required")
+	}
+
+	var session models.Session
+	if err := database.DB.Where("refresh_token_hash = ?", hashToken(req.RefreshToken)).First(&session).Error; err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	// Refresh tokens are single use
+	if err := database.DB.Delete(&models.Session{}, session.SessionID).Error; err != nil {
+		return nil, fmt.Errorf("failed to revoke session: %w", err)
+	}
+	if time.Now().After(session.RefreshExpiresAt) {
+		return nil, ErrInvalidToken
+	}
+
+	user, err := users.GetUserByID(session.UserID)
+	if err != nil || user.MustResetPassword {
+		return nil, ErrInvalidToken
+	}
+
+	return issueSession(user)
+}
+
+// Logout revokes the session identified by an access token
+func Logout(accessToken string) error {
+	if err := database.DB.Where("token_hash = ?", hashToken(accessToken)).Delete(&models.Session{}).Error; err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return nil
+}
+
+// Authenticate resolves an access token to its user
+func Authenticate(accessToken string) (*models.User, error) {
+	var session models.Session
+	if err := database.DB.Where("token_hash = ?", hashToken(accessToken)).First(&session).Error; err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	now := time.Now()
+	if now.After(session.ExpiresAt) {
+		return nil, ErrInvalidToken
+	}
+
+	user, err := users.GetUserByID(session.UserID)
+	if err != nil || user.MustResetPassword {
+		return nil, ErrInvalidToken
+	}
+
+	database.DB.Model(&models.Session{}).Where("session_id = ?", session.SessionID).Update("last_used_time", now)
+	return user, nil
+}
+
+// PurgeExpiredSessions removes sessions whose refresh token has expired
+func PurgeExpiredSessions() error {
+	if err := database.DB.Where("refresh_expires_at < ?", time.Now()).Delete(&models.Session{}).Error; err != nil {
+		return fmt.Errorf("failed to purge sessions: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"time"
+
+	"moneyplanner/models"
+)
+
+// LoginRequest represents a login with username (or email) and password
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// RefreshRequest represents a request to exchange a refresh token for a new token pair
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// TokenResponse contains a freshly issued token pair
+type TokenResponse struct {
+	AccessToken      string       `json:"access_token"`
+	TokenType        string       `json:"token_type"`
+	ExpiresAt        time.Time    `json:"expires_at"`
+	RefreshToken     string       `json:"refresh_token"`
+	RefreshExpiresAt time.Time    `json:"refresh_expires_at"`
+	User             *models.User `json:"user"`
+}
+
+// APIKeyCreationRequest represents a request to issue an API key
+type APIKeyCreationRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"` // e.g. ["transactions:write@3", "categories:read@3"]
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// APIKeyResponse is an API key as returned by the API. Key is only set on creation.
+type APIKeyResponse struct {
+	models.APIKey
+	Scopes []string `json:"scopes"`
+	Key    string   `json:"key,omitempty"`
+}
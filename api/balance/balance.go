@@ -0,0 +1,205 @@
+package balance
+
+import (
+	"fmt"
+	"moneyplanner/database"
+	"moneyplanner/models"
+	"time"
+)
+
+const (
+	dateLayout = "2006-01-02"
+
+	// maxHistoryPoints keeps a single request from walking decades day by day
+	maxHistoryPoints = 3660
+)
+
+// ParseAt parses a point in time as RFC 3339 or as a date, which means the end of that day (UTC)
+func ParseAt(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	date, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time '%s' (expected YYYY-MM-DD or RFC 3339)", s)
+	}
+	return date.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+}
+
+// ParseInterval validates an interval name; empty means daily
+func ParseInterval(s string) (Interval, error) {
+	switch interval := Interval(s); interval {
+	case "":
+		return IntervalDay, nil
+	case IntervalDay, IntervalWeek, IntervalMonth:
+		return interval, nil
+	default:
+		return "", fmt.Errorf("invalid interval '%s' (expected day, week or month)", s)
+	}
+}
+
+// truncateDay returns midnight UTC of t's day
+func truncateDay(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// periodStart returns the start of the period containing day; weeks start on Monday
+func periodStart(day time.Time, interval Interval) time.Time {
+	switch interval {
+	case IntervalWeek:
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	case IntervalMonth:
+		return time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return day
+	}
+}
+
+// nextPeriod returns the start of the period after the one starting at start
+func nextPeriod(start time.Time, interval Interval) time.Time {
+	switch interval {
+	case IntervalWeek:
+		return start.AddDate(0, 0, 7)
+	case IntervalMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// balanceBefore returns the wallet's opening balance plus every transaction dated before t
+func balanceBefore(wallet *models.Wallet, t time.Time) (models.Money, error) {
+	type kindTotal struct {
+		Kind  models.CategoryKind
+		Total models.Money
+	}
+	var totals []kindTotal
+	if err := database.DB.Model(&models.Transaction{}).
+		Select("categories.kind AS kind, SUM(transactions.amount) AS total").
+		Joins("JOIN categories ON categories.category_id = transactions.category_id").
+		Where("transactions.wallet_id = ? AND transactions.transaction_time < ?", wallet.WalletID, t).
+		Group("categories.kind").
+		Scan(&totals).Error; err != nil {
+		return 0, fmt.Errorf("failed to total transactions: %w", err)
+	}
+
+	balance := wallet.OpeningBalance
+	for _, total := range totals {
+		balance += total.Kind.BalanceEffect(total.Total)
+	}
+	return balance, nil
+}
+
+// BalanceAt computes the wallet's balance at the given time. Transactions dated after it,
+// including future-dated ones, are left out.
+func BalanceAt(walletID uint, at time.Time) (*Snapshot, error) {
+	var wallet models.Wallet
+	if err := database.DB.First(&wallet, walletID).Error; err != nil {
+		return nil, fmt.Errorf("wallet not found: %w", err)
+	}
+
+	balance, err := balanceBefore(&wallet, at.Add(time.Nanosecond))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Snapshot{
+		WalletID:       wallet.WalletID,
+		Currency:       wallet.Currency,
+		At:             at,
+		Balance:        balance,
+		CurrentBalance: wallet.Balance,
+	}, nil
+}
+
+// BalanceHistory computes the wallet's closing balance for each period between From and To
+func BalanceHistory(walletID uint, req *HistoryRequest) (*History, error) {
+	interval := req.Interval
+	if interval == "" {
+		interval = IntervalDay
+	}
+
+	to := truncateDay(time.Now())
+	if req.To != nil {
+		to = truncateDay(*req.To)
+	}
+	from := to.AddDate(0, 0, -29)
+	if req.From != nil {
+		from = truncateDay(*req.From)
+	}
+	if from.After(to) {
+		return nil, fmt.Errorf("from must not be after to")
+	}
+	end := to.AddDate(0, 0, 1)
+
+	// Lay out the periods; the first and last are clipped to the range
+	var starts []time.Time
+	for start := from; start.Before(end); start = nextPeriod(periodStart(start, interval), interval) {
+		if len(starts) == maxHistoryPoints {
+			return nil, fmt.Errorf("range too large: at most %d %ss", maxHistoryPoints, interval)
+		}
+		starts = append(starts, start)
+	}
+
+	var wallet models.Wallet
+	if err := database.DB.First(&wallet, walletID).Error; err != nil {
+		return nil, fmt.Errorf("wallet not found: %w", err)
+	}
+
+	opening, err := balanceBefore(&wallet, from)
+	if err != nil {
+		return nil, err
+	}
+
+	type entry struct {
+		TransactionTime time.Time
+		Kind            models.CategoryKind
+		Amount          models.Money
+	}
+	var entries []entry
+	if err := database.DB.Model(&models.Transaction{}).
+		Select("transactions.transaction_time AS transaction_time, categories.kind AS kind, transactions.amount AS amount").
+		Joins("JOIN categories ON categories.category_id = transactions.category_id").
+		Where("transactions.wallet_id = ? AND transactions.transaction_time >= ? AND transactions.transaction_time < ?",
+			wallet.WalletID, from, end).
+		Order("transactions.transaction_time").
+		Scan(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to load transactions: %w", err)
+	}
+
+	history := &History{
+		WalletID:       wallet.WalletID,
+		Currency:       wallet.Currency,
+		Interval:       interval,
+		From:           from.Format(dateLayout),
+		To:             to.Format(dateLayout),
+		OpeningBalance: opening,
+		Points:         make([]HistoryPoint, 0, len(starts)),
+	}
+
+	balance := opening
+	next := 0
+	for i, start := range starts {
+		periodEnd := end
+		if i+1 < len(starts) {
+			periodEnd = starts[i+1]
+		}
+
+		var change models.Money
+		for next < len(entries) && entries[next].TransactionTime.Before(periodEnd) {
+			change += entries[next].Kind.BalanceEffect(entries[next].Amount)
+			next++
+		}
+		balance += change
+
+		history.Points = append(history.Points, HistoryPoint{
+			Date:           start.Format(dateLayout),
+			Change:         change,
+			ClosingBalance: balance,
+		})
+	}
+	history.ClosingBalance = balance
+	return history, nil
+}
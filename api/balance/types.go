@@ -0,0 +1,50 @@
+package balance
+
+import (
+	"moneyplanner/models"
+	"time"
+)
+
+// Interval is the bucket size of a balance history
+type Interval string
+
+const (
+	IntervalDay   Interval = "day"
+	IntervalWeek  Interval = "week"
+	IntervalMonth Interval = "month"
+)
+
+// Snapshot is a wallet's balance at a point in time
+type Snapshot struct {
+	WalletID       uint         `json:"wallet_id"`
+	Currency       string       `json:"currency"`
+	At             time.Time    `json:"at"`
+	Balance        models.Money `json:"balance"`         // OpeningBalance + transactions dated at or before At
+	CurrentBalance models.Money `json:"current_balance"` // Stored balance, including future-dated transactions
+}
+
+// HistoryPoint is the closing balance of one period
+type HistoryPoint struct {
+	Date           string       `json:"date"` // First day of the period (YYYY-MM-DD)
+	Change         models.Money `json:"change"`
+	ClosingBalance models.Money `json:"closing_balance"`
+}
+
+// History is a wallet's closing balance per period over a date range
+type History struct {
+	WalletID       uint           `json:"wallet_id"`
+	Currency       string         `json:"currency"`
+	Interval       Interval       `json:"interval"`
+	From           string         `json:"from"`
+	To             string         `json:"to"`
+	OpeningBalance models.Money   `json:"opening_balance"` // Balance just before From
+	ClosingBalance models.Money   `json:"closing_balance"` // Balance at the end of To
+	Points         []HistoryPoint `json:"points"`
+}
+
+// HistoryRequest selects the range and interval of a balance history
+type HistoryRequest struct {
+	From     *time.Time // Defaults to 29 days before To
+	To       *time.Time // Defaults to today
+	Interval Interval   // Defaults to day
+}
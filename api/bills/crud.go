@@ -0,0 +1,377 @@
+package bills
+
+import (
+	"fmt"
+	"log"
+	"moneyplanner/api/exchangerates"
+	"moneyplanner/api/persons"
+	"moneyplanner/api/transactions"
+	"moneyplanner/database"
+	"moneyplanner/models"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const defaultRemindDaysBefore = 3
+
+// today returns midnight UTC of the day containing t
+func today(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// applyPayments derives what is paid and the status of a bill at now from its live payments,
+// given oldest first
+func applyPayments(bill *models.Bill, payments []models.Transaction, now time.Time) {
+	bill.PaidAmount = 0
+	for _, payment := range payments {
+		bill.PaidAmount += payment.Amount
+	}
+	bill.Remaining = bill.Amount - bill.PaidAmount
+	bill.PaidTime = nil
+	bill.PaidLate = false
+
+	day := today(now)
+	switch {
+	case bill.Remaining <= 0:
+		bill.Status = models.BillStatusPaid
+		if len(payments) > 0 {
+			paidTime := payments[len(payments)-1].TransactionTime
+			bill.PaidTime = &paidTime
+			bill.PaidLate = today(paidTime).After(bill.DueDate)
+		}
+	case day.After(bill.DueDate):
+		bill.Status = models.BillStatusOverdue
+	case !day.Before(bill.DueDate.AddDate(0, 0, -bill.RemindDaysBefore)):
+		bill.Status = models.BillStatusDue
+	default:
+		bill.Status = models.BillStatusUpcoming
+	}
+}
+
+// loadPayments derives the payment fields of the bills on the given handle. With
+// withPayments the payment transactions themselves are attached too.
+func loadPayments(tx *gorm.DB, bills []models.Bill, withPayments bool) error {
+	if len(bills) == 0 {
+		return nil
+	}
+	billIDs := make([]uint, len(bills))
+	for i := range bills {
+		billIDs[i] = bills[i].BillID
+	}
+
+	var payments []models.Transaction
+	query := tx.Where("bill_id IN ?", billIDs).Order("transaction_time, transaction_id")
+	if withPayments {
+		query = query.Preload("Category.Wallet").Preload("Person").Preload("Wallet").Preload("User")
+	}
+	if err := query.Find(&payments).Error; err != nil {
+		return fmt.Errorf("failed to load bill payments: %w", err)
+	}
+	byBill := map[uint][]models.Transaction{}
+	for _, payment := range payments {
+		byBill[*payment.BillID] = append(byBill[*payment.BillID], payment)
+	}
+
+	now := time.Now()
+	for i := range bills {
+		applyPayments(&bills[i], byBill[bills[i].BillID], now)
+		if withPayments {
+			bills[i].Payments = byBill[bills[i].BillID]
+			if bills[i].Payments == nil {
+				bills[i].Payments = []models.Transaction{}
+			}
+		}
+	}
+	return nil
+}
+
+// getBill loads a bill with its payments on the given handle
+func getBill(tx *gorm.DB, billID uint) (*models.Bill, error) {
+	var bill models.Bill
+	if err := tx.First(&bill, billID).Error; err != nil {
+		return nil, fmt.Errorf("bill not found: %w", err)
+	}
+	bills := []models.Bill{bill}
+	if err := loadPayments(tx, bills, true); err != nil {
+		return nil, err
+	}
+	return &bills[0], nil
+}
+
+// GetBillByID retrieves a bill with its payments and status
+func GetBillByID(billID uint) (*models.Bill, error) {
+	return getBill(database.DB, billID)
+}
+
+// ListBills lists bills with their status, by due date
+func ListBills(filter *BillFilter) ([]models.Bill, error) {
+	bills := []models.Bill{}
+	query := database.DB.Order("due_date, bill_id")
+	if filter != nil {
+		if filter.WalletIDs != nil {
+			query = query.Where("wallet_id IN ?", filter.WalletIDs)
+		}
+		if filter.WalletID != nil {
+			query = query.Where("wallet_id = ?", *filter.WalletID)
+		}
+	}
+	if err := query.Find(&bills).Error; err != nil {
+		return nil, fmt.Errorf("failed to list bills: %w", err)
+	}
+	if err := loadPayments(database.DB, bills, false); err != nil {
+		return nil, err
+	}
+
+	// Status is derived, so it is filtered here rather than in SQL
+	if filter != nil && filter.Status != nil {
+		matching := []models.Bill{}
+		for _, bill := range bills {
+			if bill.Status == *filter.Status {
+				matching = append(matching, bill)
+			}
+		}
+		bills = matching
+	}
+	return bills, nil
+}
+
+// ListDueBills lists the unpaid bills due by the end of the day days from now, overdue
+// ones included, by due date
+func ListDueBills(filter *BillFilter, days int) ([]models.Bill, error) {
+	bills, err := ListBills(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	until := today(time.Now()).AddDate(0, 0, days)
+	due := []models.Bill{}
+	for _, bill := range bills {
+		if bill.Status != models.BillStatusPaid && !bill.DueDate.After(until) {
+			due = append(due, bill)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool {
+		return due[i].DueDate.Before(due[j].DueDate)
+	})
+	return due, nil
+}
+
+// validateBill checks a bill's fields and that its category is an expense category of its wallet
+func validateBill(bill *models.Bill) error {
+	if strings.TrimSpace(bill.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if bill.Amount <= 0 {
+		return fmt.Errorf("amount must be positive")
+	}
+	if bill.RemindDaysBefore < 0 {
+		return fmt.Errorf("remind_days_before must not be negative")
+	}
+
+	var category models.Category
+	if err := database.DB.First(&category, bill.CategoryID).Error; err != nil {
+		return fmt.Errorf("category not found: %w", err)
+	}
+	if category.WalletID != bill.WalletID {
+		return fmt.Errorf("category %d does not belong to wallet %d", category.CategoryID, bill.WalletID)
+	}
+	if category.Kind != models.CategoryKindExpense {
+		return fmt.Errorf("bills can only be paid from expense categories")
+	}
+	return nil
+}
+
+// resolvePerson returns the payee named by ID or name, creating it by name if needed
+func resolvePerson(personID *uint, personName *string) (*uint, error) {
+	if personName != nil && strings.TrimSpace(*personName) != "" {
+		person, err := persons.FindOrCreatePersonByName(database.DB, *personName)
+		if err != nil {
+			return nil, err
+		}
+		return &person.PersonID, nil
+	}
+	return personID, nil
+}
+
+// CreateBill creates a bill in a wallet
+func CreateBill(req *BillCreationRequest) (*models.Bill, error) {
+	if req.WalletID == 0 {
+		return nil, fmt.Errorf("wallet_id is required")
+	}
+	if req.UserID == 0 {
+		return nil, fmt.Errorf("user_id is required")
+	}
+	if req.DueDate == "" {
+		return nil, fmt.Errorf("due_date is required")
+	}
+	dueDate, err := exchangerates.ParseDate(req.DueDate)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	bill := &models.Bill{
+		Name:             req.Name,
+		WalletID:         req.WalletID,
+		CategoryID:       req.CategoryID,
+		PersonID:         req.PersonID,
+		Amount:           req.Amount,
+		DueDate:          dueDate,
+		RemindDaysBefore: defaultRemindDaysBefore,
+		Note:             req.Note,
+		UserID:           req.UserID,
+		EntryTime:        now,
+		LastModifiedTime: now,
+	}
+	if req.RemindDaysBefore != nil {
+		bill.RemindDaysBefore = *req.RemindDaysBefore
+	}
+	if err := validateBill(bill); err != nil {
+		return nil, err
+	}
+	bill.PersonID, err = resolvePerson(req.PersonID, req.PersonName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := database.DB.Create(bill).Error; err != nil {
+		return nil, fmt.Errorf("failed to create bill: %w", err)
+	}
+
+	log.Printf("✓ Bill '%s' created (ID: %d)", bill.Name, bill.BillID)
+	return GetBillByID(bill.BillID)
+}
+
+// UpdateBill updates a bill's details; its wallet cannot change and payments stay as they are
+func UpdateBill(billID uint, req *BillUpdateRequest) (*models.Bill, error) {
+	bill, err := GetBillByID(billID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		bill.Name = *req.Name
+	}
+	if req.CategoryID != nil {
+		bill.CategoryID = *req.CategoryID
+	}
+	if req.Amount != nil {
+		bill.Amount = *req.Amount
+	}
+	if req.DueDate != nil {
+		dueDate, err := exchangerates.ParseDate(*req.DueDate)
+		if err != nil {
+			return nil, err
+		}
+		bill.DueDate = dueDate
+	}
+	if req.RemindDaysBefore != nil {
+		bill.RemindDaysBefore = *req.RemindDaysBefore
+	}
+	if req.Note != nil {
+		bill.Note = req.Note
+	}
+	if err := validateBill(bill); err != nil {
+		return nil, err
+	}
+	if req.PersonID != nil || req.PersonName != nil {
+		bill.PersonID, err = resolvePerson(req.PersonID, req.PersonName)
+		if err != nil {
+			return nil, err
+		}
+	}
+	bill.LastModifiedTime = time.Now()
+
+	if err := database.DB.Save(bill).Error; err != nil {
+		return nil, fmt.Errorf("failed to update bill: %w", err)
+	}
+
+	log.Printf("✓ Bill '%s' (ID: %d) updated", bill.Name, billID)
+	return GetBillByID(billID)
+}
+
+// DeleteBill deletes a bill. Its payments stay as ordinary transactions.
+func DeleteBill(billID uint) error {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.Transaction{}).Where("bill_id = ?", billID).
+			Update("bill_id", nil).Error; err != nil {
+			return fmt.Errorf("failed to unlink bill payments: %w", err)
+		}
+		result := tx.Delete(&models.Bill{}, billID)
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete bill: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("bill not found")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("✓ Bill (ID: %d) deleted", billID)
+	return nil
+}
+
+// PayBill records a payment on a bill as an expense transaction in the bill's wallet and
+// category. Payments may be partial but never more than what remains to be paid.
+func PayBill(billID uint, req *BillPaymentRequest) (*BillPayment, error) {
+	if req.UserID == 0 {
+		return nil, fmt.Errorf("user_id is required")
+	}
+
+	payment := &BillPayment{}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		bill, err := getBill(tx, billID)
+		if err != nil {
+			return err
+		}
+		if bill.Status == models.BillStatusPaid {
+			return fmt.Errorf("bill %d is already paid", billID)
+		}
+
+		amount := bill.Remaining
+		if req.Amount != nil {
+			amount = *req.Amount
+		}
+		if amount <= 0 {
+			return fmt.Errorf("amount must be positive")
+		}
+		if amount > bill.Remaining {
+			return fmt.Errorf("amount %s is more than the %s that remains to be paid", amount, bill.Remaining)
+		}
+
+		note := req.Note
+		if note == nil {
+			note = &bill.Name
+		}
+		payment.Transaction, err = transactions.CreateTransactionInTx(tx, &transactions.TransactionCreationRequest{
+			WalletID:        bill.WalletID,
+			CategoryID:      bill.CategoryID,
+			Amount:          amount,
+			PersonID:        bill.PersonID,
+			Note:            note,
+			TransactionTime: req.TransactionTime,
+			UserID:          req.UserID,
+			BillID:          &bill.BillID,
+		})
+		if err != nil {
+			return err
+		}
+
+		payment.Bill, err = getBill(tx, billID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("✓ Bill (ID: %d) paid %s (transaction ID: %d, %s)",
+		billID, payment.Transaction.Amount, payment.Transaction.TransactionID, payment.Bill.Status)
+	return payment, nil
+}
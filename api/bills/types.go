@@ -0,0 +1,50 @@
+package bills
+
+import (
+	"moneyplanner/models"
+	"time"
+)
+
+type BillCreationRequest struct {
+	Name             string       `json:"name"`
+	WalletID         uint         `json:"wallet_id"`
+	CategoryID       uint         `json:"category_id"`
+	PersonID         *uint        `json:"person_id,omitempty"`
+	PersonName       *string      `json:"person_name,omitempty"` // To create person if not exists
+	Amount           models.Money `json:"amount"`
+	DueDate          string       `json:"due_date"`                     // YYYY-MM-DD
+	RemindDaysBefore *int         `json:"remind_days_before,omitempty"` // Defaults to 3
+	Note             *string      `json:"note,omitempty"`
+	UserID           uint         `json:"-"` // Set from the caller
+}
+
+type BillUpdateRequest struct {
+	Name             *string       `json:"name,omitempty"`
+	CategoryID       *uint         `json:"category_id,omitempty"`
+	PersonID         *uint         `json:"person_id,omitempty"`
+	PersonName       *string       `json:"person_name,omitempty"`
+	Amount           *models.Money `json:"amount,omitempty"`
+	DueDate          *string       `json:"due_date,omitempty"`
+	RemindDaysBefore *int          `json:"remind_days_before,omitempty"`
+	Note             *string       `json:"note,omitempty"`
+}
+
+type BillFilter struct {
+	WalletIDs []uint // Restricts results to these wallets when non-nil
+	WalletID  *uint
+	Status    *models.BillStatus
+}
+
+// BillPaymentRequest pays a bill, in full by default
+type BillPaymentRequest struct {
+	Amount          *models.Money `json:"amount,omitempty"`           // Defaults to what remains to be paid
+	TransactionTime *time.Time    `json:"transaction_time,omitempty"` // Defaults to now
+	Note            *string       `json:"note,omitempty"`             // Defaults to the bill's name
+	UserID          uint          `json:"-"`                          // Set from the caller
+}
+
+// BillPayment is a payment made on a bill and the bill after it
+type BillPayment struct {
+	Bill        *models.Bill        `json:"bill"`
+	Transaction *models.Transaction `json:"transaction"`
+}
@@ -0,0 +1,234 @@
+package budgets
+
+import (
+	"fmt"
+	"log"
+	"moneyplanner/api/exchangerates"
+	"moneyplanner/api/walletgroupwallet"
+	"moneyplanner/database"
+	"moneyplanner/models"
+	"strings"
+	"time"
+)
+
+// GetBudgetByID retrieves a budget with its category
+func GetBudgetByID(budgetID uint) (*models.Budget, error) {
+	var budget models.Budget
+	if err := database.DB.Preload("Category").First(&budget, budgetID).Error; err != nil {
+		return nil, fmt.Errorf("budget not found: %w", err)
+	}
+	return &budget, nil
+}
+
+// ListBudgets lists the budgets of the filter's wallets and wallet groups
+func ListBudgets(filter *BudgetFilter) ([]models.Budget, error) {
+	budgets := []models.Budget{}
+	query := database.DB.Preload("Category").Order("budget_id")
+	if filter != nil {
+		query = query.Where("wallet_id IN ? OR wallet_group_id IN ?", filter.WalletIDs, filter.WalletGroupIDs)
+	}
+	if err := query.Find(&budgets).Error; err != nil {
+		return nil, fmt.Errorf("failed to list budgets: %w", err)
+	}
+	return budgets, nil
+}
+
+// loadBudgetCategory loads the category a budget covers; it must be an expense category in
+// the budget's wallet, or in one of the wallets of its group
+func loadBudgetCategory(budget *models.Budget) error {
+	var category models.Category
+	if err := database.DB.First(&category, budget.CategoryID).Error; err != nil {
+		return fmt.Errorf("category not found: %w", err)
+	}
+	if category.Kind != models.CategoryKindExpense {
+		return fmt.Errorf("budgets can only cover expense categories")
+	}
+
+	if budget.WalletID != nil && category.WalletID != *budget.WalletID {
+		return fmt.Errorf("category %d does not belong to wallet %d", category.CategoryID, *budget.WalletID)
+	}
+	if budget.WalletGroupID != nil {
+		groupWallets, err := walletgroupwallet.ListWalletsInGroup(*budget.WalletGroupID)
+		if err != nil {
+			return err
+		}
+		inGroup := false
+		for _, wallet := range groupWallets {
+			inGroup = inGroup || wallet.WalletID == category.WalletID
+		}
+		if !inGroup {
+			return fmt.Errorf("category %d does not belong to a wallet of group %d", category.CategoryID, *budget.WalletGroupID)
+		}
+	}
+
+	budget.Category = category
+	return nil
+}
+
+// parseEndDate parses an optional inclusive end date; "" means none
+func parseEndDate(s string) (*time.Time, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	endDate, err := exchangerates.ParseDate(s)
+	if err != nil {
+		return nil, err
+	}
+	return &endDate, nil
+}
+
+// validateBudget checks the fields that do not depend on other records
+func validateBudget(budget *models.Budget) error {
+	if strings.TrimSpace(budget.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if !budget.Period.IsValid() {
+		return fmt.Errorf("period must be one of weekly, monthly, yearly or custom")
+	}
+	if budget.Amount <= 0 {
+		return fmt.Errorf("amount must be positive")
+	}
+	if budget.Period == models.BudgetPeriodCustom && budget.EndDate == nil {
+		return fmt.Errorf("end_date is required for custom budgets")
+	}
+	if budget.EndDate != nil && budget.EndDate.Before(budget.StartDate) {
+		return fmt.Errorf("end_date must not be before start_date")
+	}
+	return nil
+}
+
+// CreateBudget creates a budget for a category in a wallet or wallet group
+func CreateBudget(req *BudgetCreationRequest) (*models.Budget, error) {
+	if (req.WalletID == nil) == (req.WalletGroupID == nil) {
+		return nil, fmt.Errorf("exactly one of wallet_id and wallet_group_id is required")
+	}
+
+	now := time.Now()
+	budget := &models.Budget{
+		Name:             req.Name,
+		CategoryID:       req.CategoryID,
+		WalletID:         req.WalletID,
+		WalletGroupID:    req.WalletGroupID,
+		Period:           req.Period,
+		Amount:           req.Amount,
+		UserID:           req.UserID,
+		EntryTime:        now,
+		LastModifiedTime: now,
+	}
+
+	if req.StartDate != "" {
+		startDate, err := exchangerates.ParseDate(req.StartDate)
+		if err != nil {
+			return nil, err
+		}
+		budget.StartDate = startDate
+	} else if req.Period == models.BudgetPeriodCustom {
+		return nil, fmt.Errorf("start_date is required for custom budgets")
+	} else if req.Period.IsValid() {
+		budget.StartDate = periodStart(req.Period, now)
+	}
+	if req.EndDate != nil {
+		endDate, err := parseEndDate(*req.EndDate)
+		if err != nil {
+			return nil, err
+		}
+		budget.EndDate = endDate
+	}
+	if err := validateBudget(budget); err != nil {
+		return nil, err
+	}
+	if err := loadBudgetCategory(budget); err != nil {
+		return nil, err
+	}
+
+	if req.Currency != nil {
+		currency, err := models.NormalizeCurrency(*req.Currency)
+		if err != nil {
+			return nil, err
+		}
+		budget.Currency = currency
+	} else {
+		var wallet models.Wallet
+		if err := database.DB.First(&wallet, budget.Category.WalletID).Error; err != nil {
+			return nil, fmt.Errorf("wallet not found: %w", err)
+		}
+		budget.Currency = wallet.Currency
+	}
+
+	if err := database.DB.Omit("Category").Create(budget).Error; err != nil {
+		return nil, fmt.Errorf("failed to create budget: %w", err)
+	}
+
+	log.Printf("✓ Budget '%s' created (ID: %d)", budget.Name, budget.BudgetID)
+	return budget, nil
+}
+
+// UpdateBudget updates a budget's details; its wallet or wallet group cannot change
+func UpdateBudget(budgetID uint, req *BudgetUpdateRequest) (*models.Budget, error) {
+	budget, err := GetBudgetByID(budgetID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		budget.Name = *req.Name
+	}
+	if req.CategoryID != nil {
+		budget.CategoryID = *req.CategoryID
+	}
+	if req.Period != nil {
+		budget.Period = *req.Period
+	}
+	if req.StartDate != nil {
+		startDate, err := exchangerates.ParseDate(*req.StartDate)
+		if err != nil {
+			return nil, err
+		}
+		budget.StartDate = startDate
+	}
+	if req.EndDate != nil {
+		endDate, err := parseEndDate(*req.EndDate)
+		if err != nil {
+			return nil, err
+		}
+		budget.EndDate = endDate
+	}
+	if req.Amount != nil {
+		budget.Amount = *req.Amount
+	}
+	if req.Currency != nil {
+		currency, err := models.NormalizeCurrency(*req.Currency)
+		if err != nil {
+			return nil, err
+		}
+		budget.Currency = currency
+	}
+	if err := validateBudget(budget); err != nil {
+		return nil, err
+	}
+	if err := loadBudgetCategory(budget); err != nil {
+		return nil, err
+	}
+	budget.LastModifiedTime = time.Now()
+
+	if err := database.DB.Omit("Category").Save(budget).Error; err != nil {
+		return nil, fmt.Errorf("failed to update budget: %w", err)
+	}
+
+	log.Printf("✓ Budget '%s' (ID: %d) updated", budget.Name, budgetID)
+	return budget, nil
+}
+
+// DeleteBudget deletes a budget
+func DeleteBudget(budgetID uint) error {
+	result := database.DB.Delete(&models.Budget{}, budgetID)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete budget: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("budget not found")
+	}
+
+	log.Printf("✓ Budget (ID: %d) deleted", budgetID)
+	return nil
+}
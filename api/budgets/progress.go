@@ -0,0 +1,188 @@
+package budgets
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"moneyplanner/api/exchangerates"
+	"moneyplanner/database"
+	"moneyplanner/models"
+	"time"
+)
+
+// periodStart returns the start of the calendar period containing t (UTC); custom budgets
+// have no calendar period and start at t's day
+func periodStart(period models.BudgetPeriod, t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch period {
+	case models.BudgetPeriodWeekly:
+		// Weeks start on Monday
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case models.BudgetPeriodMonthly:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	case models.BudgetPeriodYearly:
+		return time.Date(t.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return day
+}
+
+// nextPeriod returns the start of the calendar period after the one starting at start
+func nextPeriod(period models.BudgetPeriod, start time.Time) time.Time {
+	switch period {
+	case models.BudgetPeriodWeekly:
+		return start.AddDate(0, 0, 7)
+	case models.BudgetPeriodMonthly:
+		return start.AddDate(0, 1, 0)
+	case models.BudgetPeriodYearly:
+		return start.AddDate(1, 0, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// PeriodBounds returns the budget period containing at, clipped to the budget's start and end
+// dates. The end is exclusive.
+func PeriodBounds(budget *models.Budget, at time.Time) (time.Time, time.Time, error) {
+	if at.Before(budget.StartDate) {
+		return time.Time{}, time.Time{}, fmt.Errorf("budget starts on %s", budget.StartDate.Format("2006-01-02"))
+	}
+	var budgetEnd time.Time
+	if budget.EndDate != nil {
+		budgetEnd = budget.EndDate.AddDate(0, 0, 1)
+		if !at.Before(budgetEnd) {
+			return time.Time{}, time.Time{}, fmt.Errorf("budget ended on %s", budget.EndDate.Format("2006-01-02"))
+		}
+	}
+
+	if budget.Period == models.BudgetPeriodCustom {
+		return budget.StartDate, budgetEnd, nil
+	}
+	start := periodStart(budget.Period, at)
+	end := nextPeriod(budget.Period, start)
+	if start.Before(budget.StartDate) {
+		start = budget.StartDate
+	}
+	if budget.EndDate != nil && end.After(budgetEnd) {
+		end = budgetEnd
+	}
+	return start, end, nil
+}
+
+// subtreeCategoryIDs returns the budget's categories in each wallet: its own category in the
+// category's wallet, same-named expense categories elsewhere, and all their descendants
+func subtreeCategoryIDs(budget *models.Budget, walletIDs []uint) (map[uint][]uint, error) {
+	var walletCategories []models.Category
+	if err := database.DB.Where("wallet_id IN ?", walletIDs).Find(&walletCategories).Error; err != nil {
+		return nil, fmt.Errorf("failed to load categories: %w", err)
+	}
+
+	children := map[uint][]uint{}
+	walletOf := map[uint]uint{}
+	var queue []models.Category
+	for _, category := range walletCategories {
+		walletOf[category.CategoryID] = category.WalletID
+		if category.ParentID != nil {
+			children[*category.ParentID] = append(children[*category.ParentID], category.CategoryID)
+		}
+		if category.CategoryID == budget.CategoryID ||
+			(category.WalletID != budget.Category.WalletID && category.Name == budget.Category.Name &&
+				category.Kind == models.CategoryKindExpense) {
+			queue = append(queue, category)
+		}
+	}
+
+	subtrees := map[uint][]uint{}
+	for _, base := range queue {
+		pending := []uint{base.CategoryID}
+		for len(pending) > 0 {
+			categoryID := pending[0]
+			pending = pending[1:]
+			subtrees[walletOf[categoryID]] = append(subtrees[walletOf[categoryID]], categoryID)
+			pending = append(pending, children[categoryID]...)
+		}
+	}
+	return subtrees, nil
+}
+
+// GetProgress reports the spending in the budget period containing at, counted over the
+// given wallets (the budget's wallet, or the group's wallets the caller can see)
+func GetProgress(budget *models.Budget, walletIDs []uint, at time.Time) (*Progress, error) {
+	start, end, err := PeriodBounds(budget, at)
+	if err != nil {
+		return nil, err
+	}
+
+	progress := &Progress{
+		BudgetID:    budget.BudgetID,
+		Currency:    budget.Currency,
+		At:          at,
+		PeriodStart: start,
+		PeriodEnd:   end,
+		Limit:       budget.Amount,
+		Complete:    true,
+		Wallets:     []WalletSpending{},
+	}
+
+	var wallets []models.Wallet
+	if err := database.DB.Where("wallet_id IN ?", walletIDs).Order("wallet_id").Find(&wallets).Error; err != nil {
+		return nil, fmt.Errorf("failed to load wallets: %w", err)
+	}
+	subtrees, err := subtreeCategoryIDs(budget, walletIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, wallet := range wallets {
+		categoryIDs := subtrees[wallet.WalletID]
+		if len(categoryIDs) == 0 {
+			continue
+		}
+
+		// Split transactions count by their lines, each in its own category
+		var spent models.Money
+		if err := database.DB.Model(&models.Transaction{}).
+			Select("COALESCE(SUM(COALESCE(transaction_splits.amount, transactions.amount)), 0)").
+			Joins("LEFT JOIN transaction_splits ON transaction_splits.transaction_id = transactions.transaction_id").
+			Where("transactions.wallet_id = ? AND COALESCE(transaction_splits.category_id, transactions.category_id) IN ?",
+				wallet.WalletID, categoryIDs).
+			Where("transactions.transaction_time >= ? AND transactions.transaction_time < ?", start, end).
+			Scan(&spent).Error; err != nil {
+			return nil, fmt.Errorf("failed to total spending: %w", err)
+		}
+
+		spending := WalletSpending{
+			WalletID: wallet.WalletID,
+			Currency: wallet.Currency,
+			Spent:    spent,
+		}
+		converted, err := exchangerates.Convert(spent, wallet.Currency, budget.Currency, at)
+		if errors.Is(err, exchangerates.ErrNoRate) {
+			progress.Complete = false
+			progress.Warnings = append(progress.Warnings, err.Error())
+		} else if err != nil {
+			return nil, err
+		} else {
+			spending.ConvertedSpent = &converted
+			progress.Spent += converted
+		}
+		progress.Wallets = append(progress.Wallets, spending)
+	}
+
+	progress.Remaining = progress.Limit - progress.Spent
+	progress.PercentUsed = math.Round(float64(progress.Spent)/float64(progress.Limit)*1000) / 10
+	progress.OverBudget = progress.Spent > progress.Limit
+
+	// Assume the rest of the period goes at the pace so far
+	progress.ProjectedSpent = progress.Spent
+	if elapsed := at.Sub(start); elapsed > 0 && at.Before(end) {
+		projected, err := progress.Spent.MulRat(big.NewRat(int64(end.Sub(start)), int64(elapsed)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to project spending: %w", err)
+		}
+		progress.ProjectedSpent = projected
+	}
+	progress.ProjectedOverBudget = progress.ProjectedSpent > progress.Limit
+
+	return progress, nil
+}
@@ -0,0 +1,61 @@
+package budgets
+
+import (
+	"moneyplanner/models"
+	"time"
+)
+
+type BudgetCreationRequest struct {
+	Name          string              `json:"name"`
+	CategoryID    uint                `json:"category_id"`
+	WalletID      *uint               `json:"wallet_id,omitempty"`       // Either this
+	WalletGroupID *uint               `json:"wallet_group_id,omitempty"` // or this
+	Period        models.BudgetPeriod `json:"period"`
+	StartDate     string              `json:"start_date,omitempty"` // YYYY-MM-DD; defaults to the start of the current period
+	EndDate       *string             `json:"end_date,omitempty"`   // YYYY-MM-DD, inclusive; required for custom
+	Amount        models.Money        `json:"amount"`
+	Currency      *string             `json:"currency,omitempty"` // Defaults to the category's wallet currency
+	UserID        uint                `json:"-"`                  // Set from the caller
+}
+
+type BudgetUpdateRequest struct {
+	Name       *string              `json:"name,omitempty"`
+	CategoryID *uint                `json:"category_id,omitempty"`
+	Period     *models.BudgetPeriod `json:"period,omitempty"`
+	StartDate  *string              `json:"start_date,omitempty"`
+	EndDate    *string              `json:"end_date,omitempty"` // "" removes the end date
+	Amount     *models.Money        `json:"amount,omitempty"`
+	Currency   *string              `json:"currency,omitempty"`
+}
+
+type BudgetFilter struct {
+	WalletIDs      []uint // Budgets of any of these wallets or wallet groups
+	WalletGroupIDs []uint
+}
+
+// WalletSpending is what one wallet contributed to a budget period
+type WalletSpending struct {
+	WalletID       uint          `json:"wallet_id"`
+	Currency       string        `json:"currency"`
+	Spent          models.Money  `json:"spent"`
+	ConvertedSpent *models.Money `json:"converted_spent"` // In the budget currency; null when no exchange rate is available
+}
+
+// Progress compares the spending in the budget period containing At with its limit
+type Progress struct {
+	BudgetID            uint             `json:"budget_id"`
+	Currency            string           `json:"currency"`
+	At                  time.Time        `json:"at"`
+	PeriodStart         time.Time        `json:"period_start"`
+	PeriodEnd           time.Time        `json:"period_end"` // Exclusive
+	Limit               models.Money     `json:"limit"`
+	Spent               models.Money     `json:"spent"`
+	Remaining           models.Money     `json:"remaining"`       // Negative when over budget
+	PercentUsed         float64          `json:"percent_used"`    // Spent / Limit * 100
+	ProjectedSpent      models.Money     `json:"projected_spent"` // Spent extrapolated to the whole period
+	OverBudget          bool             `json:"over_budget"`
+	ProjectedOverBudget bool             `json:"projected_over_budget"`
+	Complete            bool             `json:"complete"` // False when some wallets could not be converted
+	Wallets             []WalletSpending `json:"wallets"`
+	Warnings            []string         `json:"warnings,omitempty"`
+}
@@ -3,8 +3,12 @@ package categories
 import (
 	"fmt"
 	"log"
+	"moneyplanner/api/transactions"
 	"moneyplanner/database"
 	"moneyplanner/models"
+	"time"
+
+	"gorm.io/gorm"
 )
 
 // GetCategoryByID retrieves a category by its ID
@@ -110,11 +114,44 @@ func CreateCategory(req *CategoryCreationRequest) (*models.Category, error) {
 		IsGlobal: false, // default
 	}
 
+	// Root categories choose the kind; subcategories inherit it from their parent
+	var parent *models.Category
+	if req.ParentID != nil {
+		var err error
+		parent, err = GetCategoryByID(*req.ParentID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get parent category: %w", err)
+		}
+		if parent.WalletID != req.WalletID {
+			return nil, fmt.Errorf("parent category belongs to another wallet")
+		}
+		if req.Kind != nil && *req.Kind != parent.Kind {
+			return nil, fmt.Errorf("kind must match the parent category's kind (%s)", parent.Kind)
+		}
+		c.Kind = parent.Kind
+	} else {
+		if req.Kind == nil || !req.Kind.IsValid() {
+			return nil, fmt.Errorf("kind is required for root categories (income, expense, transfer, adjustment or debt)")
+		}
+		c.Kind = *req.Kind
+	}
+
 	// Set defaults
 	if req.IsGlobal != nil {
 		c.IsGlobal = *req.IsGlobal
 	}
 
+	// Trashed categories keep their name until purged
+	var trashedCount int64
+	if err := database.DB.Unscoped().Model(&models.Category{}).
+		Where("wallet_id = ? AND name = ? AND deleted_at IS NOT NULL", c.WalletID, c.Name).
+		Count(&trashedCount).Error; err != nil {
+		return nil, fmt.Errorf("failed to check trash: %w", err)
+	}
+	if trashedCount > 0 {
+		return nil, fmt.Errorf("a category named '%s' is in the trash; restore it or choose another name", c.Name)
+	}
+
 	// Create category
 	if err := database.DB.Create(c).Error; err != nil {
 		return nil, fmt.Errorf("failed to create category: %w", err)
@@ -125,11 +162,6 @@ func CreateCategory(req *CategoryCreationRequest) (*models.Category, error) {
 		// This is a root category
 		c.RootID = c.CategoryID
 	} else {
-		// Get parent's RootID
-		parent, err := GetCategoryByID(*req.ParentID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get parent category: %w", err)
-		}
 		c.RootID = parent.RootID
 	}
 
@@ -155,6 +187,9 @@ func UpdateCategory(categoryID uint, req *CategoryUpdateRequest) (*models.Catego
 	if err != nil {
 		return nil, err
 	}
+	if err := models.CheckVersion(category.Version, req.ExpectedVersion); err != nil {
+		return nil, err
+	}
 
 	updates := map[string]interface{}{}
 
@@ -181,6 +216,12 @@ func UpdateCategory(categoryID uint, req *CategoryUpdateRequest) (*models.Catego
 			if err != nil {
 				return nil, fmt.Errorf("failed to get parent category: %w", err)
 			}
+			if parent.WalletID != category.WalletID {
+				return nil, fmt.Errorf("parent category belongs to another wallet")
+			}
+			if parent.Kind != category.Kind {
+				return nil, fmt.Errorf("cannot move a %s category under a %s category", category.Kind, parent.Kind)
+			}
 			updates["root_id"] = parent.RootID
 			category.RootID = parent.RootID
 		}
@@ -191,14 +232,46 @@ func UpdateCategory(categoryID uint, req *CategoryUpdateRequest) (*models.Catego
 		category.IsGlobal = *req.IsGlobal
 	}
 
-	if len(updates) == 0 {
+	var newKind *models.CategoryKind
+	if req.Kind != nil && *req.Kind != category.Kind {
+		if !req.Kind.IsValid() {
+			return nil, fmt.Errorf("invalid kind '%s' (expected income, expense, transfer, adjustment or debt)", *req.Kind)
+		}
+		if category.ParentID != nil && (req.ParentID == nil || *req.ParentID != 0) {
+			return nil, fmt.Errorf("kind can only be changed on root categories")
+		}
+		newKind = req.Kind
+	}
+
+	if len(updates) == 0 && newKind == nil {
 		return category, nil // No updates provided
 	}
 
-	if err := database.DB.Model(&models.Category{}).
-		Where("category_id = ?", categoryID).
-		Updates(updates).Error; err != nil {
-		return nil, fmt.Errorf("failed to update category: %w", err)
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// Guarded by the version read above so a concurrent edit is not overwritten
+		updates["version"] = gorm.Expr("version + 1")
+		result := tx.Model(&models.Category{}).
+			Where("category_id = ? AND version = ?", categoryID, category.Version).
+			Updates(updates)
+		if result.Error != nil {
+			return fmt.Errorf("failed to update category: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return models.ErrVersionConflict
+		}
+		if newKind != nil {
+			return changeTreeKind(tx, categoryID, category.Kind, *newKind)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Reload for the new version (a kind change bumps the whole tree)
+	category, err = GetCategoryByID(categoryID)
+	if err != nil {
+		return nil, err
 	}
 
 	// If set to global, sync to all wallets
@@ -212,13 +285,108 @@ func UpdateCategory(categoryID uint, req *CategoryUpdateRequest) (*models.Catego
 	return category, nil
 }
 
-// DeleteCategory deletes a category by ID
-func DeleteCategory(categoryID uint) error {
+// changeTreeKind switches a root category and all its descendants to a new kind and
+// re-applies their transactions to the wallet balances under the new kind
+func changeTreeKind(tx *gorm.DB, rootID uint, oldKind, newKind models.CategoryKind) error {
+	// The lines of a split transaction must all be of one kind
+	var splitCount int64
+	if err := tx.Model(&models.TransactionSplit{}).
+		Joins("JOIN categories ON categories.category_id = transaction_splits.category_id").
+		Where("categories.root_id = ?", rootID).
+		Count(&splitCount).Error; err != nil {
+		return fmt.Errorf("failed to check split transactions: %w", err)
+	}
+	if splitCount > 0 {
+		return fmt.Errorf("cannot change kind to %s: the category is used by split transactions", newKind)
+	}
+
+	type walletSum struct {
+		WalletID uint
+		Total    models.Money
+		Negative int64
+	}
+	var sums []walletSum
+	if err := tx.Model(&models.Transaction{}).
+		Select("transactions.wallet_id, SUM(transactions.amount) AS total, SUM(CASE WHEN transactions.amount < 0 THEN 1 ELSE 0 END) AS negative").
+		Joins("JOIN categories ON categories.category_id = transactions.category_id").
+		Where("categories.root_id = ?", rootID).
+		Group("transactions.wallet_id").
+		Scan(&sums).Error; err != nil {
+		return fmt.Errorf("failed to total category transactions: %w", err)
+	}
+
+	for _, sum := range sums {
+		if sum.Negative > 0 && !newKind.IsSigned() {
+			return fmt.Errorf("cannot change kind to %s: the category has transactions with negative amounts", newKind)
+		}
+		delta := newKind.BalanceEffect(sum.Total) - oldKind.BalanceEffect(sum.Total)
+		if delta == 0 {
+			continue
+		}
+		if err := tx.Model(&models.Wallet{}).Where("wallet_id = ?", sum.WalletID).
+			Updates(map[string]interface{}{"balance": gorm.Expr("balance + ?", delta), "version": gorm.Expr("version + 1")}).Error; err != nil {
+			return fmt.Errorf("failed to update wallet balance: %w", err)
+		}
+	}
+
+	if err := tx.Model(&models.Category{}).Where("root_id = ?", rootID).
+		Updates(map[string]interface{}{"kind": newKind, "version": gorm.Expr("version + 1")}).Error; err != nil {
+		return fmt.Errorf("failed to update category kind: %w", err)
+	}
+
+	log.Printf("✓ Category tree %d changed from %s to %s", rootID, oldKind, newKind)
+	return nil
+}
+
+// EnsureKindRootCategory returns the wallet's root category of the given kind, creating one
+// with the given name if the wallet has none. Used for system postings such as adjustments.
+func EnsureKindRootCategory(tx *gorm.DB, walletID uint, kind models.CategoryKind, name, icon string) (*models.Category, error) {
+	var category models.Category
+	if err := tx.Unscoped().Where("wallet_id = ? AND parent_id IS NULL AND kind = ?", walletID, kind).
+		Order("deleted_at IS NOT NULL, category_id").Limit(1).Find(&category).Error; err != nil {
+		return nil, fmt.Errorf("failed to look up %s category: %w", kind, err)
+	}
+	if category.CategoryID != 0 && category.DeletedAt.Valid {
+		// Bring a trashed one back rather than clash with its name
+		if err := tx.Unscoped().Model(&category).
+			Updates(map[string]interface{}{"deleted_at": nil, "deleted_by": nil}).Error; err != nil {
+			return nil, fmt.Errorf("failed to restore %s category: %w", kind, err)
+		}
+		log.Printf("✓ Category '%s' restored for wallet %d (ID: %d)", category.Name, walletID, category.CategoryID)
+	}
+	if category.CategoryID != 0 {
+		return &category, nil
+	}
+
+	category = models.Category{
+		Name:     name,
+		Icon:     icon,
+		Kind:     kind,
+		WalletID: walletID,
+	}
+	if err := tx.Create(&category).Error; err != nil {
+		return nil, fmt.Errorf("failed to create %s category: %w", kind, err)
+	}
+	category.RootID = category.CategoryID
+	if err := tx.Model(&category).Update("root_id", category.RootID).Error; err != nil {
+		return nil, fmt.Errorf("failed to update root_id: %w", err)
+	}
+
+	log.Printf("✓ Category '%s' created for wallet %d (ID: %d)", category.Name, walletID, category.CategoryID)
+	return &category, nil
+}
+
+// DeleteCategory moves a category to the trash together with its transactions, reversing
+// their effect on the wallet balance. A non-nil expectedVersion must match the stored one.
+func DeleteCategory(categoryID, deletedBy uint, expectedVersion *uint) error {
 	// Check if category exists
 	category, err := GetCategoryByID(categoryID)
 	if err != nil {
 		return err
 	}
+	if err := models.CheckVersion(category.Version, expectedVersion); err != nil {
+		return err
+	}
 
 	// Check if has children
 	var childrenCount int64
@@ -229,15 +397,109 @@ func DeleteCategory(categoryID uint) error {
 		return fmt.Errorf("cannot delete category with children")
 	}
 
-	// Delete the category
-	if err := database.DB.Delete(&models.Category{}, categoryID).Error; err != nil {
-		return fmt.Errorf("failed to delete category: %w", err)
+	// Transfer legs only go away with their transfer
+	var legCount int64
+	if err := database.DB.Model(&models.Transaction{}).
+		Where("category_id = ? AND transfer_id IS NOT NULL", categoryID).
+		Count(&legCount).Error; err != nil {
+		return fmt.Errorf("failed to check transfers: %w", err)
+	}
+	if legCount > 0 {
+		return fmt.Errorf("cannot delete a category used by %d transfer transaction(s); delete the transfers first", legCount)
 	}
 
-	log.Printf("✓ Category '%s' (ID: %d) deleted", category.Name, categoryID)
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		// Split transactions go with any of their categories
+		var categoryTransactions []models.Transaction
+		if err := tx.Preload("Category").
+			Where("category_id = ? OR transaction_id IN (SELECT transaction_id FROM transaction_splits WHERE category_id = ?)", categoryID, categoryID).
+			Find(&categoryTransactions).Error; err != nil {
+			return fmt.Errorf("failed to load category transactions: %w", err)
+		}
+		for i := range categoryTransactions {
+			if err := transactions.TrashTransaction(tx, &categoryTransactions[i], now, deletedBy); err != nil {
+				return err
+			}
+		}
+
+		result := tx.Model(&models.Category{}).
+			Where("category_id = ? AND version = ?", categoryID, category.Version).
+			Updates(map[string]interface{}{
+				"deleted_at": now,
+				"deleted_by": deletedBy,
+				"version":    gorm.Expr("version + 1"),
+			})
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete category: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return models.ErrVersionConflict
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("✓ Category '%s' (ID: %d) moved to trash", category.Name, categoryID)
 	return nil
 }
 
+// RestoreCategory takes a category of the wallet out of the trash, along with the
+// transactions that were trashed with it
+func RestoreCategory(walletID, categoryID uint) (*models.Category, error) {
+	var trashed models.Category
+	if err := database.DB.Unscoped().Where("deleted_at IS NOT NULL").First(&trashed, categoryID).Error; err != nil ||
+		trashed.WalletID != walletID {
+		return nil, fmt.Errorf("category %d is not in this wallet's trash", categoryID)
+	}
+	if trashed.ParentID != nil {
+		if _, err := GetCategoryByID(*trashed.ParentID); err != nil {
+			return nil, fmt.Errorf("parent category %d is in the trash; restore it first", *trashed.ParentID)
+		}
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// Transactions trashed together with the category share its deletion time
+		var transactionIDs []uint
+		if err := tx.Unscoped().Model(&models.Transaction{}).
+			Where("(category_id = ? OR transaction_id IN (SELECT transaction_id FROM transaction_splits WHERE category_id = ?)) AND deleted_at = ? AND transfer_id IS NULL",
+				categoryID, categoryID, trashed.DeletedAt.Time).
+			Pluck("transaction_id", &transactionIDs).Error; err != nil {
+			return fmt.Errorf("failed to load category transactions: %w", err)
+		}
+
+		result := tx.Unscoped().Model(&models.Category{}).
+			Where("category_id = ? AND deleted_at IS NOT NULL", categoryID).
+			Updates(map[string]interface{}{
+				"deleted_at": nil,
+				"deleted_by": nil,
+				"version":    gorm.Expr("version + 1"),
+			})
+		if result.Error != nil {
+			return fmt.Errorf("failed to restore category: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("category %d is not in the trash", categoryID)
+		}
+
+		for _, transactionID := range transactionIDs {
+			if _, err := transactions.UntrashTransaction(tx, transactionID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("✓ Category '%s' (ID: %d) restored", trashed.Name, categoryID)
+	return GetCategoryByID(categoryID)
+}
+
 // SyncGlobalCategoryToAllWallets syncs a global category to all wallets that don't have it
 func SyncGlobalCategoryToAllWallets(categoryID uint) error {
 	category, err := GetCategoryByID(categoryID)
@@ -266,6 +528,7 @@ func SyncGlobalCategoryToAllWallets(categoryID uint) error {
 				Icon:     category.Icon,
 				WalletID: wallet.WalletID,
 				IsGlobal: true,
+				Kind:     category.Kind,
 				RootID:   0, // Will be set after create
 			}
 			if err := database.DB.Create(newCat).Error; err != nil {
@@ -3,18 +3,22 @@ package categories
 import "moneyplanner/models"
 
 type CategoryCreationRequest struct {
-	Name     string `json:"name"`
-	Icon     string `json:"icon"`
-	ParentID *uint  `json:"parent_id,omitempty"`
-	WalletID uint   `json:"wallet_id"`
-	IsGlobal *bool  `json:"is_global,omitempty"`
+	Name     string               `json:"name"`
+	Icon     string               `json:"icon"`
+	ParentID *uint                `json:"parent_id,omitempty"`
+	WalletID uint                 `json:"wallet_id"`
+	IsGlobal *bool                `json:"is_global,omitempty"`
+	Kind     *models.CategoryKind `json:"kind,omitempty"` // Required for root categories; subcategories inherit it
 }
 
 type CategoryUpdateRequest struct {
-	Name     *string `json:"name,omitempty"`
-	Icon     *string `json:"icon,omitempty"`
-	ParentID *uint   `json:"parent_id,omitempty"`
-	IsGlobal *bool   `json:"is_global,omitempty"`
+	Name     *string              `json:"name,omitempty"`
+	Icon     *string              `json:"icon,omitempty"`
+	ParentID *uint                `json:"parent_id,omitempty"`
+	IsGlobal *bool                `json:"is_global,omitempty"`
+	Kind     *models.CategoryKind `json:"kind,omitempty"` // Root categories only; applies to the whole tree
+
+	ExpectedVersion *uint `json:"-"` // From If-Match
 }
 
 // CategoryWithChildren represents a category with its subcategories
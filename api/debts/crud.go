@@ -0,0 +1,573 @@
+package debts
+
+import (
+	"fmt"
+	"log"
+	"moneyplanner/api/categories"
+	"moneyplanner/api/exchangerates"
+	"moneyplanner/api/persons"
+	"moneyplanner/api/transactions"
+	"moneyplanner/database"
+	"moneyplanner/models"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// liveOpening joins each debt to its opening transaction, leaving out debts whose opening
+// went to the trash with its wallet or category
+const liveOpening = "JOIN transactions ON transactions.transaction_id = debts.transaction_id AND transactions.deleted_at IS NULL"
+
+// DebtCategory returns the wallet's debt category, which all debt postings go to
+func DebtCategory(tx *gorm.DB, walletID uint) (*models.Category, error) {
+	return categories.EnsureKindRootCategory(tx, walletID, models.CategoryKindDebt, "Debts", "🤝")
+}
+
+// loadRepayments derives the repayment fields of the debts and attaches their persons on the
+// given handle. With withRepayments the repayments themselves are attached too.
+func loadRepayments(tx *gorm.DB, debts []models.Debt, withRepayments bool) error {
+	if len(debts) == 0 {
+		return nil
+	}
+	debtIDs := make([]uint, len(debts))
+	personIDs := make([]uint, len(debts))
+	for i := range debts {
+		debtIDs[i] = debts[i].DebtID
+		personIDs[i] = debts[i].PersonID
+	}
+
+	// Repayments in the trash with their transaction do not count
+	var repayments []models.DebtRepayment
+	if err := tx.Joins("JOIN transactions ON transactions.transaction_id = debt_repayments.transaction_id AND transactions.deleted_at IS NULL").
+		Where("debt_repayments.debt_id IN ?", debtIDs).
+		Order("transactions.transaction_time, debt_repayments.debt_repayment_id").
+		Find(&repayments).Error; err != nil {
+		return fmt.Errorf("failed to load debt repayments: %w", err)
+	}
+
+	if withRepayments && len(repayments) > 0 {
+		transactionIDs := make([]uint, len(repayments))
+		for i := range repayments {
+			transactionIDs[i] = repayments[i].TransactionID
+		}
+		var postings []models.Transaction
+		if err := tx.Preload("Category.Wallet").Preload("Person").Preload("Wallet").Preload("User").
+			Where("transaction_id IN ?", transactionIDs).Find(&postings).Error; err != nil {
+			return fmt.Errorf("failed to load repayment transactions: %w", err)
+		}
+		byID := map[uint]*models.Transaction{}
+		for i := range postings {
+			byID[postings[i].TransactionID] = &postings[i]
+		}
+		for i := range repayments {
+			repayments[i].Transaction = byID[repayments[i].TransactionID]
+		}
+	}
+	byDebt := map[uint][]models.DebtRepayment{}
+	for _, repayment := range repayments {
+		byDebt[repayment.DebtID] = append(byDebt[repayment.DebtID], repayment)
+	}
+
+	// Trashed persons still show on their debts
+	var people []models.Person
+	if err := tx.Unscoped().Where("person_id IN ?", personIDs).Find(&people).Error; err != nil {
+		return fmt.Errorf("failed to load debt persons: %w", err)
+	}
+	personByID := map[uint]*models.Person{}
+	for i := range people {
+		personByID[people[i].PersonID] = &people[i]
+	}
+
+	for i := range debts {
+		debt := &debts[i]
+		debt.Repaid = 0
+		for _, repayment := range byDebt[debt.DebtID] {
+			debt.Repaid += repayment.Amount
+		}
+		debt.Outstanding = debt.Amount - debt.Repaid
+		debt.Status = models.DebtStatusOpen
+		if debt.Outstanding <= 0 {
+			debt.Outstanding = 0
+			debt.Status = models.DebtStatusSettled
+		}
+		debt.Person = personByID[debt.PersonID]
+		if withRepayments {
+			debt.Repayments = byDebt[debt.DebtID]
+			if debt.Repayments == nil {
+				debt.Repayments = []models.DebtRepayment{}
+			}
+		}
+	}
+	return nil
+}
+
+// getDebt loads a debt with its repayments on the given handle
+func getDebt(tx *gorm.DB, debtID uint) (*models.Debt, error) {
+	var debt models.Debt
+	if err := tx.Joins(liveOpening).Where("debts.debt_id = ?", debtID).First(&debt).Error; err != nil {
+		return nil, fmt.Errorf("debt not found: %w", err)
+	}
+	debts := []models.Debt{debt}
+	if err := loadRepayments(tx, debts, true); err != nil {
+		return nil, err
+	}
+	return &debts[0], nil
+}
+
+// GetDebtByID retrieves a debt with its repayments and status
+func GetDebtByID(debtID uint) (*models.Debt, error) {
+	return getDebt(database.DB, debtID)
+}
+
+// ListDebts lists debts with their status, newest first
+func ListDebts(filter *DebtFilter) ([]models.Debt, error) {
+	debts := []models.Debt{}
+	query := database.DB.Joins(liveOpening).Order("debts.debt_time DESC, debts.debt_id DESC")
+	if filter != nil {
+		if filter.WalletIDs != nil {
+			query = query.Where("debts.wallet_id IN ?", filter.WalletIDs)
+		}
+		if filter.WalletID != nil {
+			query = query.Where("debts.wallet_id = ?", *filter.WalletID)
+		}
+		if filter.PersonID != nil {
+			query = query.Where("debts.person_id = ?", *filter.PersonID)
+		}
+	}
+	if err := query.Find(&debts).Error; err != nil {
+		return nil, fmt.Errorf("failed to list debts: %w", err)
+	}
+	if err := loadRepayments(database.DB, debts, false); err != nil {
+		return nil, err
+	}
+
+	// Status is derived, so it is filtered here rather than in SQL
+	if filter != nil && filter.Status != nil {
+		matching := []models.Debt{}
+		for _, debt := range debts {
+			if debt.Status == *filter.Status {
+				matching = append(matching, debt)
+			}
+		}
+		debts = matching
+	}
+	return debts, nil
+}
+
+// ListPersonBalances totals the outstanding open debts per person and currency, by person name
+func ListPersonBalances(filter *DebtFilter) ([]PersonBalance, error) {
+	open := models.DebtStatusOpen
+	scoped := DebtFilter{Status: &open}
+	if filter != nil {
+		scoped.WalletIDs, scoped.WalletID, scoped.PersonID = filter.WalletIDs, filter.WalletID, filter.PersonID
+	}
+	debts, err := ListDebts(&scoped)
+	if err != nil {
+		return nil, err
+	}
+
+	type key struct {
+		personID uint
+		currency string
+	}
+	byKey := map[key]*PersonBalance{}
+	balances := []*PersonBalance{}
+	for _, debt := range debts {
+		k := key{debt.PersonID, debt.Currency}
+		balance := byKey[k]
+		if balance == nil {
+			balance = &PersonBalance{PersonID: debt.PersonID, Currency: debt.Currency}
+			if debt.Person != nil {
+				balance.PersonName = debt.Person.PersonName
+			}
+			byKey[k] = balance
+			balances = append(balances, balance)
+		}
+		if debt.Direction == models.DebtDirectionLent {
+			balance.OwedToYou += debt.Outstanding
+		} else {
+			balance.YouOwe += debt.Outstanding
+		}
+		balance.Net = balance.OwedToYou - balance.YouOwe
+		balance.OpenDebts++
+	}
+
+	sort.SliceStable(balances, func(i, j int) bool {
+		if balances[i].PersonName != balances[j].PersonName {
+			return balances[i].PersonName < balances[j].PersonName
+		}
+		return balances[i].Currency < balances[j].Currency
+	})
+	result := make([]PersonBalance, len(balances))
+	for i, balance := range balances {
+		result[i] = *balance
+	}
+	return result, nil
+}
+
+// resolvePerson returns the person named by ID or name, creating it by name if needed
+func resolvePerson(tx *gorm.DB, personID *uint, personName *string) (*models.Person, error) {
+	if personName != nil && strings.TrimSpace(*personName) != "" {
+		return persons.FindOrCreatePersonByName(tx, *personName)
+	}
+	if personID == nil {
+		return nil, fmt.Errorf("person_id or person_name is required")
+	}
+	var person models.Person
+	if err := tx.First(&person, *personID).Error; err != nil {
+		return nil, fmt.Errorf("person not found: %w", err)
+	}
+	return &person, nil
+}
+
+// CreateDebt records money lent to or borrowed from a person, posting it to the wallet's
+// debt category: lending takes the amount out of the wallet and borrowing puts it in
+func CreateDebt(req *DebtCreationRequest) (*models.Debt, error) {
+	if !req.Direction.IsValid() {
+		return nil, fmt.Errorf("direction must be lent or borrowed")
+	}
+	if req.WalletID == 0 {
+		return nil, fmt.Errorf("wallet_id is required")
+	}
+	if req.Amount <= 0 {
+		return nil, fmt.Errorf("amount must be positive")
+	}
+	if req.UserID == 0 {
+		return nil, fmt.Errorf("user_id is required")
+	}
+	var dueDate *time.Time
+	if req.DueDate != nil && *req.DueDate != "" {
+		parsed, err := exchangerates.ParseDate(*req.DueDate)
+		if err != nil {
+			return nil, err
+		}
+		dueDate = &parsed
+	}
+
+	var debt *models.Debt
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		person, err := resolvePerson(tx, req.PersonID, req.PersonName)
+		if err != nil {
+			return err
+		}
+		category, err := DebtCategory(tx, req.WalletID)
+		if err != nil {
+			return err
+		}
+
+		note := req.Note
+		if note == nil {
+			text := "Lent to " + person.PersonName
+			if req.Direction == models.DebtDirectionBorrowed {
+				text = "Borrowed from " + person.PersonName
+			}
+			note = &text
+		}
+		opening, err := transactions.CreateTransactionInTx(tx, &transactions.TransactionCreationRequest{
+			WalletID:        req.WalletID,
+			CategoryID:      category.CategoryID,
+			Amount:          req.Direction.Sign() * req.Amount,
+			PersonID:        &person.PersonID,
+			Note:            note,
+			TransactionTime: req.DebtTime,
+			UserID:          req.UserID,
+		})
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		created := &models.Debt{
+			PersonID:         person.PersonID,
+			WalletID:         req.WalletID,
+			Direction:        req.Direction,
+			Amount:           req.Amount,
+			Currency:         opening.Wallet.Currency,
+			TransactionID:    opening.TransactionID,
+			DueDate:          dueDate,
+			Note:             req.Note,
+			DebtTime:         opening.TransactionTime,
+			UserID:           req.UserID,
+			EntryTime:        now,
+			LastModifiedTime: now,
+		}
+		if err := tx.Create(created).Error; err != nil {
+			return fmt.Errorf("failed to create debt: %w", err)
+		}
+
+		debt, err = getDebt(tx, created.DebtID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("✓ Debt (ID: %d) created: %s %s with person %d", debt.DebtID, debt.Direction, debt.Amount, debt.PersonID)
+	return debt, nil
+}
+
+// UpdateDebt updates a debt's due date and note. The amount, person and wallet are fixed
+// by its opening transaction; delete the debt and record it again to change them.
+func UpdateDebt(debtID uint, req *DebtUpdateRequest) (*models.Debt, error) {
+	debt, err := GetDebtByID(debtID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{"last_modified_time": time.Now()}
+	if req.DueDate != nil {
+		if *req.DueDate == "" {
+			updates["due_date"] = nil
+		} else {
+			dueDate, err := exchangerates.ParseDate(*req.DueDate)
+			if err != nil {
+				return nil, err
+			}
+			updates["due_date"] = dueDate
+		}
+	}
+	if req.Note != nil {
+		updates["note"] = *req.Note
+	}
+
+	if err := database.DB.Model(&models.Debt{}).Where("debt_id = ?", debt.DebtID).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to update debt: %w", err)
+	}
+
+	log.Printf("✓ Debt (ID: %d) updated", debtID)
+	return GetDebtByID(debtID)
+}
+
+// DeleteDebt deletes a debt with its opening transaction and repayments, reversing their
+// effect on the wallet balances. A settle-up transaction that also repaid other debts must
+// be deleted first.
+func DeleteDebt(debtID uint) error {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var debt models.Debt
+		if err := tx.First(&debt, debtID).Error; err != nil {
+			return fmt.Errorf("debt not found: %w", err)
+		}
+
+		var repayments []models.DebtRepayment
+		if err := tx.Where("debt_id = ?", debtID).Find(&repayments).Error; err != nil {
+			return fmt.Errorf("failed to load debt repayments: %w", err)
+		}
+		for _, repayment := range repayments {
+			var shared int64
+			if err := tx.Model(&models.DebtRepayment{}).
+				Joins("JOIN transactions ON transactions.transaction_id = debt_repayments.transaction_id AND transactions.deleted_at IS NULL").
+				Where("debt_repayments.transaction_id = ? AND debt_repayments.debt_id <> ?", repayment.TransactionID, debtID).
+				Count(&shared).Error; err != nil {
+				return fmt.Errorf("failed to look up debt repayments: %w", err)
+			}
+			if shared > 0 {
+				return fmt.Errorf("transaction %d also repays other debts; delete it first", repayment.TransactionID)
+			}
+			if err := transactions.UnpostTransactionByID(tx, repayment.TransactionID); err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("debt_id = ?", debtID).Delete(&models.DebtRepayment{}).Error; err != nil {
+			return fmt.Errorf("failed to delete debt repayments: %w", err)
+		}
+		if err := tx.Delete(&models.Debt{}, debtID).Error; err != nil {
+			return fmt.Errorf("failed to delete debt: %w", err)
+		}
+		return transactions.UnpostTransactionByID(tx, debt.TransactionID)
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("✓ Debt (ID: %d) deleted", debtID)
+	return nil
+}
+
+// Repay records a repayment on a debt as a debt transaction in a wallet of the debt's
+// currency, moving the money the opposite way to the opening. Repayments may be partial
+// but never more than what is outstanding.
+func Repay(debtID uint, req *RepaymentRequest) (*Repayment, error) {
+	if req.UserID == 0 {
+		return nil, fmt.Errorf("user_id is required")
+	}
+
+	repayment := &Repayment{}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		debt, err := getDebt(tx, debtID)
+		if err != nil {
+			return err
+		}
+		if debt.Status == models.DebtStatusSettled {
+			return fmt.Errorf("debt %d is already settled", debtID)
+		}
+
+		amount := debt.Outstanding
+		if req.Amount != nil {
+			amount = *req.Amount
+		}
+		if amount <= 0 {
+			return fmt.Errorf("amount must be positive")
+		}
+		if amount > debt.Outstanding {
+			return fmt.Errorf("amount %s is more than the %s outstanding", amount, debt.Outstanding)
+		}
+
+		walletID := req.WalletID
+		if walletID == 0 {
+			walletID = debt.WalletID
+		}
+		var wallet models.Wallet
+		if err := tx.First(&wallet, walletID).Error; err != nil {
+			return fmt.Errorf("wallet not found: %w", err)
+		}
+		if wallet.Currency != debt.Currency {
+			return fmt.Errorf("debt %d is in %s but wallet %d is in %s", debtID, debt.Currency, walletID, wallet.Currency)
+		}
+		category, err := DebtCategory(tx, walletID)
+		if err != nil {
+			return err
+		}
+
+		note := req.Note
+		if note == nil && debt.Person != nil {
+			text := "Repaid by " + debt.Person.PersonName
+			if debt.Direction == models.DebtDirectionBorrowed {
+				text = "Repaid to " + debt.Person.PersonName
+			}
+			note = &text
+		}
+		repayment.Transaction, err = transactions.CreateTransactionInTx(tx, &transactions.TransactionCreationRequest{
+			WalletID:        walletID,
+			CategoryID:      category.CategoryID,
+			Amount:          -debt.Direction.Sign() * amount,
+			PersonID:        &debt.PersonID,
+			Note:            note,
+			TransactionTime: req.TransactionTime,
+			UserID:          req.UserID,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Create(&models.DebtRepayment{
+			DebtID:        debtID,
+			TransactionID: repayment.Transaction.TransactionID,
+			Amount:        amount,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to record debt repayment: %w", err)
+		}
+
+		repayment.Debt, err = getDebt(tx, debtID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("✓ Debt (ID: %d) repaid %s (transaction ID: %d, %s)",
+		debtID, repayment.Transaction.Amount, repayment.Transaction.TransactionID, repayment.Debt.Status)
+	return repayment, nil
+}
+
+// SettleUp closes every open debt with a person in the wallet's currency with one transaction
+// in that wallet for the net amount: what they owe less what is owed to them. The transaction
+// is recorded even when the debts cancel out, so the settle-up shows in the history.
+func SettleUp(personID uint, req *SettleRequest) (*Settlement, error) {
+	if req.WalletID == 0 {
+		return nil, fmt.Errorf("wallet_id is required")
+	}
+	if req.UserID == 0 {
+		return nil, fmt.Errorf("user_id is required")
+	}
+
+	settlement := &Settlement{PersonID: personID}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var wallet models.Wallet
+		if err := tx.First(&wallet, req.WalletID).Error; err != nil {
+			return fmt.Errorf("wallet not found: %w", err)
+		}
+		var person models.Person
+		if err := tx.First(&person, personID).Error; err != nil {
+			return fmt.Errorf("person not found: %w", err)
+		}
+		settlement.Currency = wallet.Currency
+
+		var candidates []models.Debt
+		query := tx.Joins(liveOpening).
+			Where("debts.person_id = ? AND debts.currency = ?", personID, wallet.Currency).
+			Order("debts.debt_time, debts.debt_id")
+		if req.WalletIDs != nil {
+			query = query.Where("debts.wallet_id IN ?", req.WalletIDs)
+		}
+		if err := query.Find(&candidates).Error; err != nil {
+			return fmt.Errorf("failed to load debts: %w", err)
+		}
+		if err := loadRepayments(tx, candidates, false); err != nil {
+			return err
+		}
+		open := []models.Debt{}
+		for _, debt := range candidates {
+			if debt.Status == models.DebtStatusOpen {
+				open = append(open, debt)
+				settlement.Amount -= debt.Direction.Sign() * debt.Outstanding
+			}
+		}
+		if len(open) == 0 {
+			return fmt.Errorf("no open debts with %s in %s", person.PersonName, wallet.Currency)
+		}
+
+		category, err := DebtCategory(tx, req.WalletID)
+		if err != nil {
+			return err
+		}
+		note := req.Note
+		if note == nil {
+			text := "Settled up with " + person.PersonName
+			note = &text
+		}
+		now := time.Now()
+		transactionTime := now
+		if req.TransactionTime != nil {
+			transactionTime = *req.TransactionTime
+		}
+		// Posted directly rather than through CreateTransactionInTx, which refuses a zero amount
+		settlement.Transaction, err = transactions.PostTransaction(tx, &models.Transaction{
+			WalletID:         req.WalletID,
+			CategoryID:       category.CategoryID,
+			Amount:           settlement.Amount,
+			PersonID:         &personID,
+			Note:             note,
+			TransactionTime:  transactionTime,
+			EntryTime:        now,
+			LastModifiedTime: now,
+			UserID:           req.UserID,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, debt := range open {
+			if err := tx.Create(&models.DebtRepayment{
+				DebtID:        debt.DebtID,
+				TransactionID: settlement.Transaction.TransactionID,
+				Amount:        debt.Outstanding,
+			}).Error; err != nil {
+				return fmt.Errorf("failed to record debt repayment: %w", err)
+			}
+		}
+		if err := loadRepayments(tx, open, false); err != nil {
+			return err
+		}
+		settlement.Debts = open
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("✓ Settled up with person %d: %d debt(s), %s %s (transaction ID: %d)",
+		personID, len(settlement.Debts), settlement.Amount, settlement.Currency, settlement.Transaction.TransactionID)
+	return settlement, nil
+}
@@ -0,0 +1,74 @@
+package debts
+
+import (
+	"moneyplanner/models"
+	"time"
+)
+
+type DebtCreationRequest struct {
+	Direction  models.DebtDirection `json:"direction"` // lent or borrowed
+	PersonID   *uint                `json:"person_id,omitempty"`
+	PersonName *string              `json:"person_name,omitempty"` // To create person if not exists
+	WalletID   uint                 `json:"wallet_id"`
+	Amount     models.Money         `json:"amount"`             // Positive, in the wallet's currency
+	DueDate    *string              `json:"due_date,omitempty"` // YYYY-MM-DD
+	Note       *string              `json:"note,omitempty"`
+	DebtTime   *time.Time           `json:"debt_time,omitempty"` // Defaults to now
+	UserID     uint                 `json:"-"`                   // Set from the caller
+}
+
+type DebtUpdateRequest struct {
+	DueDate *string `json:"due_date,omitempty"` // "" removes the due date
+	Note    *string `json:"note,omitempty"`
+}
+
+type DebtFilter struct {
+	WalletIDs []uint // Restricts results to these wallets when non-nil
+	WalletID  *uint
+	PersonID  *uint
+	Status    *models.DebtStatus
+}
+
+// RepaymentRequest pays off a debt, in full by default
+type RepaymentRequest struct {
+	Amount          *models.Money `json:"amount,omitempty"`           // Defaults to what is outstanding
+	WalletID        uint          `json:"wallet_id"`                  // Defaults to the debt's; must share its currency
+	TransactionTime *time.Time    `json:"transaction_time,omitempty"` // Defaults to now
+	Note            *string       `json:"note,omitempty"`
+	UserID          uint          `json:"-"` // Set from the caller
+}
+
+// Repayment is a repayment made on a debt and the debt after it
+type Repayment struct {
+	Debt        *models.Debt        `json:"debt"`
+	Transaction *models.Transaction `json:"transaction"`
+}
+
+// SettleRequest closes every open debt with a person in a wallet's currency with one transaction
+type SettleRequest struct {
+	WalletID        uint       `json:"wallet_id"`
+	TransactionTime *time.Time `json:"transaction_time,omitempty"` // Defaults to now
+	Note            *string    `json:"note,omitempty"`
+	UserID          uint       `json:"-"` // Set from the caller
+	WalletIDs       []uint     `json:"-"` // Only debts in these wallets are settled when non-nil
+}
+
+// Settlement is the closing transaction of a settle-up and the debts it closed
+type Settlement struct {
+	PersonID    uint                `json:"person_id"`
+	Currency    string              `json:"currency"`
+	Amount      models.Money        `json:"amount"` // Positive when the person paid, negative when they were paid
+	Transaction *models.Transaction `json:"transaction"`
+	Debts       []models.Debt       `json:"debts"`
+}
+
+// PersonBalance is what is outstanding with a person in one currency, over all open debts
+type PersonBalance struct {
+	PersonID   uint         `json:"person_id"`
+	PersonName string       `json:"person_name"`
+	Currency   string       `json:"currency"`
+	OwedToYou  models.Money `json:"owed_to_you"` // Outstanding on money lent to them
+	YouOwe     models.Money `json:"you_owe"`     // Outstanding on money borrowed from them
+	Net        models.Money `json:"net"`         // OwedToYou - YouOwe
+	OpenDebts  int          `json:"open_debts"`
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	categoriesAPI "moneyplanner/api/categories"
+	transactionsAPI "moneyplanner/api/transactions"
+	transfersAPI "moneyplanner/api/transfers"
+	walletAPI "moneyplanner/api/wallet"
+	"moneyplanner/models"
+)
+
+// setETag exposes a resource version as a strong ETag
+func setETag(w http.ResponseWriter, version uint) {
+	w.Header().Set("ETag", fmt.Sprintf("\"%d\"", version))
+}
+
+// parseIfMatch returns the version named by the If-Match header, or nil when the header is
+// absent or "*". It writes a 400 response and returns false when the header is malformed,
+// and a 412 when it is a weak tag, which never matches under If-Match's strong comparison.
+func parseIfMatch(w http.ResponseWriter, r *http.Request) (*uint, bool) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return nil, true
+	}
+
+	if strings.HasPrefix(header, "W/") {
+		w.WriteHeader(http.StatusPreconditionFailed)
+		json.NewEncoder(w).Encode(map[string]string{"error": "If-Match requires a strong ETag such as \"3\"; weak ETags never match"})
+		return nil, false
+	}
+
+	tag := header
+	if len(tag) >= 2 && strings.HasPrefix(tag, "\"") && strings.HasSuffix(tag, "\"") {
+		tag = tag[1 : len(tag)-1]
+	}
+	version64, err := strconv.ParseUint(tag, 10, 32)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid If-Match header: expected an ETag such as \"3\""})
+		return nil, false
+	}
+	version := uint(version64)
+	return &version, true
+}
+
+// writeVersionConflict writes a 412 with the resource's current state, if it still exists
+func writeVersionConflict(w http.ResponseWriter, current interface{}, version uint) {
+	if current != nil {
+		setETag(w, version)
+	}
+	w.WriteHeader(http.StatusPreconditionFailed)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": models.ErrVersionConflict.Error(),
+		"data":  current,
+	})
+}
+
+func writeWalletConflict(w http.ResponseWriter, walletID uint) {
+	if wallet, err := walletAPI.GetWalletByID(walletID); err == nil {
+		writeVersionConflict(w, wallet, wallet.Version)
+		return
+	}
+	writeVersionConflict(w, nil, 0)
+}
+
+func writeCategoryConflict(w http.ResponseWriter, categoryID uint) {
+	if category, err := categoriesAPI.GetCategoryByID(categoryID); err == nil {
+		writeVersionConflict(w, category, category.Version)
+		return
+	}
+	writeVersionConflict(w, nil, 0)
+}
+
+func writeTransactionConflict(w http.ResponseWriter, transactionID uint) {
+	if transaction, err := transactionsAPI.GetTransactionByID(transactionID); err == nil {
+		writeVersionConflict(w, transaction, transaction.Version)
+		return
+	}
+	writeVersionConflict(w, nil, 0)
+}
+
+func writeTransferConflict(w http.ResponseWriter, transferID uint) {
+	if transfer, err := transfersAPI.GetTransferByID(transferID); err == nil {
+		writeVersionConflict(w, transfer, transfer.Version)
+		return
+	}
+	writeVersionConflict(w, nil, 0)
+}
@@ -0,0 +1,236 @@
+package exchangerates
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"moneyplanner/database"
+	"moneyplanner/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const dateLayout = "2006-01-02"
+
+// ErrNoRate is returned when no rate connects two currencies on or before a date
+var ErrNoRate = errors.New("no exchange rate available")
+
+// ParseDate parses a YYYY-MM-DD date as midnight UTC
+func ParseDate(s string) (time.Time, error) {
+	date, err := time.Parse(dateLayout, strings.TrimSpace(s))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date '%s' (expected YYYY-MM-DD)", s)
+	}
+	return date, nil
+}
+
+// parseRate parses a positive exact decimal rate
+func parseRate(s string) (*big.Rat, error) {
+	rate, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok || rate.Sign() <= 0 {
+		return nil, fmt.Errorf("invalid rate '%s' (expected a positive decimal)", s)
+	}
+	return rate, nil
+}
+
+// buildExchangeRate validates a request and turns it into a model
+func buildExchangeRate(req *ExchangeRateRequest, source string) (*models.ExchangeRate, error) {
+	base, err := models.NormalizeCurrency(req.BaseCurrency)
+	if err != nil {
+		return nil, err
+	}
+	quote, err := models.NormalizeCurrency(req.QuoteCurrency)
+	if err != nil {
+		return nil, err
+	}
+	if base == quote {
+		return nil, fmt.Errorf("base_currency and quote_currency must differ")
+	}
+	date, err := ParseDate(req.EffectiveDate)
+	if err != nil {
+		return nil, err
+	}
+	rate, err := parseRate(req.Rate)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ExchangeRate{
+		BaseCurrency:     base,
+		QuoteCurrency:    quote,
+		EffectiveDate:    date,
+		Rate:             rate.FloatString(10),
+		Source:           source,
+		LastModifiedTime: time.Now(),
+	}, nil
+}
+
+// upsertExchangeRate inserts a rate or replaces the one already stored for the same pair and date
+func upsertExchangeRate(tx *gorm.DB, rate *models.ExchangeRate) error {
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "base_currency"}, {Name: "quote_currency"}, {Name: "effective_date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rate", "source", "last_modified_time"}),
+	}).Create(rate).Error
+}
+
+// SetExchangeRate creates or replaces a single rate
+func SetExchangeRate(req *ExchangeRateRequest) (*models.ExchangeRate, error) {
+	rate, err := buildExchangeRate(req, "manual")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := upsertExchangeRate(database.DB, rate); err != nil {
+		return nil, fmt.Errorf("failed to save exchange rate: %w", err)
+	}
+
+	// Reload so the ID is correct when an existing row was replaced
+	if err := database.DB.Where("base_currency = ? AND quote_currency = ? AND effective_date = ?",
+		rate.BaseCurrency, rate.QuoteCurrency, rate.EffectiveDate).First(rate).Error; err != nil {
+		return nil, fmt.Errorf("failed to load exchange rate: %w", err)
+	}
+
+	log.Printf("✓ Exchange rate %s/%s on %s set to %s", rate.BaseCurrency, rate.QuoteCurrency, rate.EffectiveDate.Format(dateLayout), rate.Rate)
+	return rate, nil
+}
+
+// ListExchangeRates lists stored rates, newest first
+func ListExchangeRates(filter *ExchangeRateFilter) ([]models.ExchangeRate, error) {
+	rates := []models.ExchangeRate{}
+	query := database.DB.Order("effective_date DESC, base_currency, quote_currency")
+
+	if filter != nil {
+		if filter.BaseCurrency != nil {
+			query = query.Where("base_currency = ?", strings.ToUpper(*filter.BaseCurrency))
+		}
+		if filter.QuoteCurrency != nil {
+			query = query.Where("quote_currency = ?", strings.ToUpper(*filter.QuoteCurrency))
+		}
+		if filter.StartDate != nil {
+			query = query.Where("effective_date >= ?", *filter.StartDate)
+		}
+		if filter.EndDate != nil {
+			query = query.Where("effective_date <= ?", *filter.EndDate)
+		}
+	}
+
+	if err := query.Find(&rates).Error; err != nil {
+		return nil, fmt.Errorf("failed to list exchange rates: %w", err)
+	}
+	return rates, nil
+}
+
+// DeleteExchangeRate deletes a rate by ID
+func DeleteExchangeRate(exchangeRateID uint) error {
+	var rate models.ExchangeRate
+	if err := database.DB.First(&rate, exchangeRateID).Error; err != nil {
+		return fmt.Errorf("exchange rate not found: %w", err)
+	}
+
+	if err := database.DB.Delete(&models.ExchangeRate{}, exchangeRateID).Error; err != nil {
+		return fmt.Errorf("failed to delete exchange rate: %w", err)
+	}
+
+	log.Printf("✓ Exchange rate %s/%s on %s deleted", rate.BaseCurrency, rate.QuoteCurrency, rate.EffectiveDate.Format(dateLayout))
+	return nil
+}
+
+// lookupRate finds the latest direct or inverse rate from one currency to another on or before a date
+func lookupRate(from, to string, at time.Time) (*big.Rat, error) {
+	var direct, inverse models.ExchangeRate
+	if err := database.DB.
+		Where("base_currency = ? AND quote_currency = ? AND effective_date <= ?", from, to, at).
+		Order("effective_date DESC").Limit(1).Find(&direct).Error; err != nil {
+		return nil, fmt.Errorf("failed to look up exchange rate: %w", err)
+	}
+	if err := database.DB.
+		Where("base_currency = ? AND quote_currency = ? AND effective_date <= ?", to, from, at).
+		Order("effective_date DESC").Limit(1).Find(&inverse).Error; err != nil {
+		return nil, fmt.Errorf("failed to look up exchange rate: %w", err)
+	}
+
+	// Prefer whichever was published most recently; direct wins a tie
+	useDirect := direct.ExchangeRateID != 0 &&
+		(inverse.ExchangeRateID == 0 || !inverse.EffectiveDate.After(direct.EffectiveDate))
+
+	switch {
+	case useDirect:
+		return parseRate(direct.Rate)
+	case inverse.ExchangeRateID != 0:
+		rate, err := parseRate(inverse.Rate)
+		if err != nil {
+			return nil, err
+		}
+		return rate.Inv(rate), nil
+	}
+	return nil, nil
+}
+
+// GetRate returns how many units of `to` one unit of `from` was worth on the given date.
+// Pairs without a stored rate are crossed through a currency both are quoted against
+// (e.g. INR -> USD via EUR when the CSV only has EUR based rates).
+func GetRate(from, to string, at time.Time) (*big.Rat, error) {
+	if from == to {
+		return big.NewRat(1, 1), nil
+	}
+	// Dates are stored as midnight UTC, so compare in UTC
+	at = at.UTC()
+
+	rate, err := lookupRate(from, to, at)
+	if err != nil || rate != nil {
+		return rate, err
+	}
+
+	var pivots []string
+	if err := database.DB.Model(&models.ExchangeRate{}).
+		Where("base_currency = ? AND effective_date <= ?", from, at).
+		Distinct().Pluck("quote_currency", &pivots).Error; err != nil {
+		return nil, fmt.Errorf("failed to look up exchange rate: %w", err)
+	}
+	var inversePivots []string
+	if err := database.DB.Model(&models.ExchangeRate{}).
+		Where("quote_currency = ? AND effective_date <= ?", from, at).
+		Distinct().Pluck("base_currency", &inversePivots).Error; err != nil {
+		return nil, fmt.Errorf("failed to look up exchange rate: %w", err)
+	}
+
+	for _, pivot := range append(pivots, inversePivots...) {
+		if pivot == to {
+			continue
+		}
+		first, err := lookupRate(from, pivot, at)
+		if err != nil {
+			return nil, err
+		}
+		second, err := lookupRate(pivot, to, at)
+		if err != nil {
+			return nil, err
+		}
+		if first != nil && second != nil {
+			return first.Mul(first, second), nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w from %s to %s on or before %s", ErrNoRate, from, to, at.Format(dateLayout))
+}
+
+// Convert converts an amount between currencies using the rate in effect at the given time.
+// The result is rounded half away from zero, like every other Money computation.
+func Convert(amount models.Money, from, to string, at time.Time) (models.Money, error) {
+	if from == to || amount == 0 {
+		return amount, nil
+	}
+	rate, err := GetRate(from, to, at)
+	if err != nil {
+		return 0, err
+	}
+	converted, err := amount.MulRat(rate)
+	if err != nil {
+		return 0, fmt.Errorf("failed to convert %s %s to %s: %w", amount, from, to, err)
+	}
+	return converted, nil
+}
@@ -0,0 +1,96 @@
+package exchangerates
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"moneyplanner/database"
+	"moneyplanner/models"
+	"os"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ImportCSV loads rates from CSV rows of the form
+//
+//	date,base_currency,quote_currency,rate
+//	2024-01-31,EUR,INR,89.9535
+//
+// A header row is optional. Existing rates for the same pair and date are replaced.
+// The import is all-or-nothing: any invalid row aborts it.
+func ImportCSV(r io.Reader) (*ImportResult, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 4
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	var rates []*models.ExchangeRate
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		if line == 1 && strings.EqualFold(strings.TrimSpace(record[0]), "date") {
+			continue
+		}
+
+		rate, err := buildExchangeRate(&ExchangeRateRequest{
+			EffectiveDate: record[0],
+			BaseCurrency:  record[1],
+			QuoteCurrency: record[2],
+			Rate:          record[3],
+		}, "csv")
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rates = append(rates, rate)
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		for _, rate := range rates {
+			if err := upsertExchangeRate(tx, rate); err != nil {
+				return fmt.Errorf("failed to save exchange rate: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("✓ Imported %d exchange rate(s)", len(rates))
+	return &ImportResult{Imported: len(rates)}, nil
+}
+
+// envRatesCSV names a CSV file of rates to load at startup
+const envRatesCSV = "MONEYPLANNER_EXCHANGE_RATES_CSV"
+
+// ImportCSVFromEnv loads the file named by MONEYPLANNER_EXCHANGE_RATES_CSV, if set and the
+// database has been initialized. It returns nil when there is nothing to load.
+func ImportCSVFromEnv() (*ImportResult, error) {
+	path := os.Getenv(envRatesCSV)
+	if path == "" {
+		return nil, nil
+	}
+	if database.DB == nil || !database.DB.Migrator().HasTable(&models.ExchangeRate{}) {
+		log.Printf("Skipping %s: database is not initialized yet", envRatesCSV)
+		return nil, nil
+	}
+	return ImportCSVFile(path)
+}
+
+// ImportCSVFile loads rates from a CSV file on the server's disk
+func ImportCSVFile(path string) (*ImportResult, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open exchange rate file: %w", err)
+	}
+	defer file.Close()
+	return ImportCSV(file)
+}
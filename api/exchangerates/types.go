@@ -0,0 +1,24 @@
+package exchangerates
+
+import "time"
+
+// ExchangeRateRequest creates or replaces the rate for a currency pair on a date
+type ExchangeRateRequest struct {
+	BaseCurrency  string `json:"base_currency"`
+	QuoteCurrency string `json:"quote_currency"`
+	EffectiveDate string `json:"effective_date"` // YYYY-MM-DD
+	Rate          string `json:"rate"`           // 1 base = rate quote, e.g. "89.1234"
+}
+
+// ExchangeRateFilter narrows down ListExchangeRates
+type ExchangeRateFilter struct {
+	BaseCurrency  *string
+	QuoteCurrency *string
+	StartDate     *time.Time
+	EndDate       *time.Time
+}
+
+// ImportResult summarizes a CSV import
+type ImportResult struct {
+	Imported int `json:"imported"`
+}
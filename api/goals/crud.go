@@ -0,0 +1,483 @@
+package goals
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+	"moneyplanner/api/exchangerates"
+	"moneyplanner/database"
+	"moneyplanner/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// holding is what a wallet, or all wallets, hold for a goal
+type holding struct {
+	Amount     models.Money // In the wallet's currency
+	GoalAmount models.Money // In the goal's currency
+}
+
+// loadProgress derives the progress fields of the goals from their contributions
+func loadProgress(tx *gorm.DB, goals []models.Goal) error {
+	if len(goals) == 0 {
+		return nil
+	}
+	goalIDs := make([]uint, len(goals))
+	for i := range goals {
+		goalIDs[i] = goals[i].GoalID
+	}
+
+	var totals []struct {
+		GoalID uint
+		Saved  models.Money
+	}
+	if err := tx.Model(&models.GoalContribution{}).
+		Select("goal_id, COALESCE(SUM(goal_amount), 0) AS saved").
+		Where("goal_id IN ?", goalIDs).Group("goal_id").
+		Scan(&totals).Error; err != nil {
+		return fmt.Errorf("failed to total goal contributions: %w", err)
+	}
+	saved := map[uint]models.Money{}
+	for _, total := range totals {
+		saved[total.GoalID] = total.Saved
+	}
+
+	now := time.Now()
+	for i := range goals {
+		applyProgress(&goals[i], saved[goals[i].GoalID], now)
+	}
+	return nil
+}
+
+// getGoal loads a goal with its progress on the given handle
+func getGoal(tx *gorm.DB, goalID uint) (*models.Goal, error) {
+	var goal models.Goal
+	if err := tx.First(&goal, goalID).Error; err != nil {
+		return nil, fmt.Errorf("goal not found: %w", err)
+	}
+	goals := []models.Goal{goal}
+	if err := loadProgress(tx, goals); err != nil {
+		return nil, err
+	}
+	return &goals[0], nil
+}
+
+// GetGoalByID retrieves a goal with its progress
+func GetGoalByID(goalID uint) (*models.Goal, error) {
+	return getGoal(database.DB, goalID)
+}
+
+// ListGoals lists goals with their progress, soonest deadline first
+func ListGoals(filter *GoalFilter) ([]models.Goal, error) {
+	goals := []models.Goal{}
+	query := database.DB.Order("deadline IS NULL, deadline, goal_id")
+	if filter != nil {
+		if filter.WalletIDs != nil {
+			query = query.Where("wallet_id IN ?", filter.WalletIDs)
+		}
+		if filter.WalletID != nil {
+			query = query.Where("wallet_id = ?", *filter.WalletID)
+		}
+	}
+	if err := query.Find(&goals).Error; err != nil {
+		return nil, fmt.Errorf("failed to list goals: %w", err)
+	}
+	if err := loadProgress(database.DB, goals); err != nil {
+		return nil, err
+	}
+	return goals, nil
+}
+
+// parseDeadline parses an optional deadline; "" means none
+func parseDeadline(s string) (*time.Time, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	deadline, err := exchangerates.ParseDate(s)
+	if err != nil {
+		return nil, err
+	}
+	return &deadline, nil
+}
+
+// validateGoal checks the fields that do not depend on other records
+func validateGoal(goal *models.Goal) error {
+	if strings.TrimSpace(goal.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if goal.TargetAmount <= 0 {
+		return fmt.Errorf("target_amount must be positive")
+	}
+	if goal.Deadline != nil && goal.Deadline.Before(goal.StartDate) {
+		return fmt.Errorf("deadline must not be before start_date")
+	}
+	return nil
+}
+
+// CreateGoal creates a savings goal in a wallet
+func CreateGoal(req *GoalCreationRequest) (*models.Goal, error) {
+	if req.WalletID == 0 {
+		return nil, fmt.Errorf("wallet_id is required")
+	}
+	if req.UserID == 0 {
+		return nil, fmt.Errorf("user_id is required")
+	}
+	var wallet models.Wallet
+	if err := database.DB.First(&wallet, req.WalletID).Error; err != nil {
+		return nil, fmt.Errorf("wallet not found: %w", err)
+	}
+
+	now := time.Now()
+	goal := &models.Goal{
+		Name:             req.Name,
+		Icon:             req.Icon,
+		WalletID:         req.WalletID,
+		TargetAmount:     req.TargetAmount,
+		Currency:         wallet.Currency,
+		StartDate:        today(now),
+		Note:             req.Note,
+		UserID:           req.UserID,
+		EntryTime:        now,
+		LastModifiedTime: now,
+	}
+	if req.Currency != nil {
+		currency, err := models.NormalizeCurrency(*req.Currency)
+		if err != nil {
+			return nil, err
+		}
+		goal.Currency = currency
+	}
+	if req.StartDate != "" {
+		startDate, err := exchangerates.ParseDate(req.StartDate)
+		if err != nil {
+			return nil, err
+		}
+		goal.StartDate = startDate
+	}
+	if req.Deadline != nil {
+		deadline, err := parseDeadline(*req.Deadline)
+		if err != nil {
+			return nil, err
+		}
+		goal.Deadline = deadline
+	}
+	if err := validateGoal(goal); err != nil {
+		return nil, err
+	}
+
+	if err := database.DB.Create(goal).Error; err != nil {
+		return nil, fmt.Errorf("failed to create goal: %w", err)
+	}
+
+	log.Printf("✓ Goal '%s' created (ID: %d)", goal.Name, goal.GoalID)
+	return GetGoalByID(goal.GoalID)
+}
+
+// UpdateGoal updates a goal's details; its wallet and currency cannot change
+func UpdateGoal(goalID uint, req *GoalUpdateRequest) (*models.Goal, error) {
+	goal, err := GetGoalByID(goalID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		goal.Name = *req.Name
+	}
+	if req.Icon != nil {
+		goal.Icon = *req.Icon
+	}
+	if req.TargetAmount != nil {
+		goal.TargetAmount = *req.TargetAmount
+	}
+	if req.StartDate != nil {
+		startDate, err := exchangerates.ParseDate(*req.StartDate)
+		if err != nil {
+			return nil, err
+		}
+		goal.StartDate = startDate
+	}
+	if req.Deadline != nil {
+		deadline, err := parseDeadline(*req.Deadline)
+		if err != nil {
+			return nil, err
+		}
+		goal.Deadline = deadline
+	}
+	if req.Note != nil {
+		goal.Note = req.Note
+	}
+	if err := validateGoal(goal); err != nil {
+		return nil, err
+	}
+	goal.LastModifiedTime = time.Now()
+
+	if err := database.DB.Save(goal).Error; err != nil {
+		return nil, fmt.Errorf("failed to update goal: %w", err)
+	}
+
+	log.Printf("✓ Goal '%s' (ID: %d) updated", goal.Name, goalID)
+	return GetGoalByID(goalID)
+}
+
+// DeleteGoal deletes a goal and its contributions, which releases the money set aside
+func DeleteGoal(goalID uint) error {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("goal_id = ?", goalID).Delete(&models.GoalContribution{}).Error; err != nil {
+			return fmt.Errorf("failed to delete goal contributions: %w", err)
+		}
+		result := tx.Delete(&models.Goal{}, goalID)
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete goal: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("goal not found")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("✓ Goal (ID: %d) deleted", goalID)
+	return nil
+}
+
+// ListContributions lists a goal's contributions and withdrawals, newest first
+func ListContributions(goalID uint) ([]models.GoalContribution, error) {
+	contributions := []models.GoalContribution{}
+	if err := database.DB.Where("goal_id = ?", goalID).
+		Order("contribution_time DESC, goal_contribution_id DESC").
+		Find(&contributions).Error; err != nil {
+		return nil, fmt.Errorf("failed to list goal contributions: %w", err)
+	}
+	return contributions, nil
+}
+
+// walletAllocated returns how much of the wallet's balance is set aside for all goals
+func walletAllocated(tx *gorm.DB, walletID uint) (models.Money, error) {
+	var allocated models.Money
+	if err := tx.Model(&models.GoalContribution{}).Select("COALESCE(SUM(amount), 0)").
+		Where("wallet_id = ?", walletID).Scan(&allocated).Error; err != nil {
+		return 0, fmt.Errorf("failed to total goal contributions: %w", err)
+	}
+	return allocated, nil
+}
+
+// walletHolding returns what the wallet holds for the goal
+func walletHolding(tx *gorm.DB, goalID, walletID uint) (*holding, error) {
+	var held holding
+	if err := tx.Model(&models.GoalContribution{}).
+		Select("COALESCE(SUM(amount), 0) AS amount, COALESCE(SUM(goal_amount), 0) AS goal_amount").
+		Where("goal_id = ? AND wallet_id = ?", goalID, walletID).
+		Scan(&held).Error; err != nil {
+		return nil, fmt.Errorf("failed to total goal contributions: %w", err)
+	}
+	return &held, nil
+}
+
+// Contribute sets money aside for a goal from a wallet. It cannot set aside more than the
+// wallet has available, and is converted into the goal's currency at the contribution time.
+func Contribute(goalID uint, req *ContributionRequest) (*models.GoalContribution, error) {
+	if req.Amount <= 0 {
+		return nil, fmt.Errorf("amount must be positive")
+	}
+	if req.UserID == 0 {
+		return nil, fmt.Errorf("user_id is required")
+	}
+
+	var contribution *models.GoalContribution
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		goal, wallet, err := loadContributionTarget(tx, goalID, req)
+		if err != nil {
+			return err
+		}
+
+		allocated, err := walletAllocated(tx, wallet.WalletID)
+		if err != nil {
+			return err
+		}
+		if available := wallet.Balance - allocated; req.Amount > available {
+			return fmt.Errorf("wallet %d has only %s %s available", wallet.WalletID, available, wallet.Currency)
+		}
+
+		contribution = newContribution(goal, wallet, req)
+		contribution.GoalAmount = req.Amount
+		if wallet.Currency != goal.Currency {
+			contribution.GoalAmount, err = exchangerates.Convert(req.Amount, wallet.Currency, goal.Currency, contribution.ContributionTime)
+			if err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(contribution).Error; err != nil {
+			return fmt.Errorf("failed to record contribution: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("✓ Goal (ID: %d) contribution of %s from wallet %d", goalID, contribution.Amount, contribution.WalletID)
+	return contribution, nil
+}
+
+// Withdraw takes money set aside for a goal back into a wallet. It cannot take back more than
+// the wallet holds for the goal; the goal gives up a proportional share of what it was worth.
+func Withdraw(goalID uint, req *ContributionRequest) (*models.GoalContribution, error) {
+	if req.Amount <= 0 {
+		return nil, fmt.Errorf("amount must be positive")
+	}
+	if req.UserID == 0 {
+		return nil, fmt.Errorf("user_id is required")
+	}
+
+	var contribution *models.GoalContribution
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		goal, wallet, err := loadContributionTarget(tx, goalID, req)
+		if err != nil {
+			return err
+		}
+
+		held, err := walletHolding(tx, goalID, wallet.WalletID)
+		if err != nil {
+			return err
+		}
+		if req.Amount > held.Amount {
+			return fmt.Errorf("wallet %d holds only %s %s for this goal", wallet.WalletID, held.Amount, wallet.Currency)
+		}
+
+		contribution = newContribution(goal, wallet, req)
+		contribution.Amount = -req.Amount
+		goalAmount, err := held.GoalAmount.MulRat(big.NewRat(int64(req.Amount), int64(held.Amount)))
+		if err != nil {
+			return fmt.Errorf("failed to convert withdrawal to the goal's currency: %w", err)
+		}
+		contribution.GoalAmount = -goalAmount
+		if err := tx.Create(contribution).Error; err != nil {
+			return fmt.Errorf("failed to record withdrawal: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("✓ Goal (ID: %d) withdrawal of %s to wallet %d", goalID, req.Amount, contribution.WalletID)
+	return contribution, nil
+}
+
+// loadContributionTarget loads the goal and the wallet a contribution request names,
+// defaulting the wallet to the goal's
+func loadContributionTarget(tx *gorm.DB, goalID uint, req *ContributionRequest) (*models.Goal, *models.Wallet, error) {
+	var goal models.Goal
+	if err := tx.First(&goal, goalID).Error; err != nil {
+		return nil, nil, fmt.Errorf("goal not found: %w", err)
+	}
+	if req.WalletID == 0 {
+		req.WalletID = goal.WalletID
+	}
+	var wallet models.Wallet
+	if err := tx.First(&wallet, req.WalletID).Error; err != nil {
+		return nil, nil, fmt.Errorf("wallet not found: %w", err)
+	}
+	return &goal, &wallet, nil
+}
+
+// newContribution fills in a contribution of the request's amount
+func newContribution(goal *models.Goal, wallet *models.Wallet, req *ContributionRequest) *models.GoalContribution {
+	now := time.Now()
+	contribution := &models.GoalContribution{
+		GoalID:           goal.GoalID,
+		WalletID:         wallet.WalletID,
+		Amount:           req.Amount,
+		Note:             req.Note,
+		ContributionTime: now,
+		UserID:           req.UserID,
+		EntryTime:        now,
+	}
+	if req.ContributionTime != nil {
+		contribution.ContributionTime = *req.ContributionTime
+	}
+	return contribution
+}
+
+// DeleteContribution deletes a contribution or withdrawal made by mistake. It fails when that
+// would leave the wallet holding less than nothing for the goal.
+func DeleteContribution(goalID, contributionID uint) error {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var contribution models.GoalContribution
+		if err := tx.Where("goal_id = ?", goalID).First(&contribution, contributionID).Error; err != nil {
+			return fmt.Errorf("contribution not found: %w", err)
+		}
+		held, err := walletHolding(tx, goalID, contribution.WalletID)
+		if err != nil {
+			return err
+		}
+		if held.Amount-contribution.Amount < 0 {
+			return fmt.Errorf("wallet %d has withdrawn this contribution from the goal since; delete the withdrawal first", contribution.WalletID)
+		}
+		if err := tx.Delete(&contribution).Error; err != nil {
+			return fmt.Errorf("failed to delete contribution: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("✓ Goal (ID: %d) contribution %d deleted", goalID, contributionID)
+	return nil
+}
+
+// GetWalletGoals splits a wallet's balance into what each goal holds and what is left.
+// Goals are listed whatever their home wallet, as long as the wallet holds something for them.
+func GetWalletGoals(walletID uint) (*WalletGoals, error) {
+	var wallet models.Wallet
+	if err := database.DB.First(&wallet, walletID).Error; err != nil {
+		return nil, fmt.Errorf("wallet not found: %w", err)
+	}
+
+	var holdings []struct {
+		GoalID uint
+		Amount models.Money
+	}
+	if err := database.DB.Model(&models.GoalContribution{}).
+		Select("goal_id, COALESCE(SUM(amount), 0) AS amount").
+		Where("wallet_id = ?", walletID).Group("goal_id").Having("SUM(amount) <> 0").
+		Scan(&holdings).Error; err != nil {
+		return nil, fmt.Errorf("failed to total goal contributions: %w", err)
+	}
+	goalIDs := make([]uint, len(holdings))
+	for i, held := range holdings {
+		goalIDs[i] = held.GoalID
+	}
+
+	result := &WalletGoals{
+		WalletID: wallet.WalletID,
+		Currency: wallet.Currency,
+		Balance:  wallet.Balance,
+		Goals:    []GoalAllocation{},
+	}
+	if len(goalIDs) > 0 {
+		goals := []models.Goal{}
+		if err := database.DB.Where("goal_id IN ?", goalIDs).Order("deadline IS NULL, deadline, goal_id").
+			Find(&goals).Error; err != nil {
+			return nil, fmt.Errorf("failed to load goals: %w", err)
+		}
+		if err := loadProgress(database.DB, goals); err != nil {
+			return nil, err
+		}
+		allocated := map[uint]models.Money{}
+		for _, held := range holdings {
+			allocated[held.GoalID] = held.Amount
+		}
+		for _, goal := range goals {
+			result.Goals = append(result.Goals, GoalAllocation{Goal: goal, Allocated: allocated[goal.GoalID]})
+			result.Allocated += allocated[goal.GoalID]
+		}
+	}
+	result.Available = result.Balance - result.Allocated
+	return result, nil
+}
@@ -0,0 +1,90 @@
+package goals
+
+import (
+	"math"
+	"math/big"
+	"moneyplanner/models"
+	"time"
+)
+
+// today returns midnight UTC of the day containing t
+func today(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// monthsUntil counts the months left from day to the deadline, a part month counting as a
+// whole one. It is at least 1 while the deadline has not passed, and 0 after.
+func monthsUntil(day, deadline time.Time) int {
+	if day.After(deadline) {
+		return 0
+	}
+	months := 1
+	for day.AddDate(0, months, 0).Before(deadline) {
+		months++
+	}
+	return months
+}
+
+// applyProgress derives the progress fields of a goal from what is saved for it at now
+func applyProgress(goal *models.Goal, saved models.Money, now time.Time) {
+	goal.Saved = saved
+	goal.Remaining = goal.TargetAmount - saved
+	if goal.Remaining < 0 {
+		goal.Remaining = 0
+	}
+	goal.PercentComplete = 0
+	if goal.TargetAmount > 0 {
+		goal.PercentComplete = math.Round(float64(saved)/float64(goal.TargetAmount)*10000) / 100
+	}
+	goal.MonthsLeft = nil
+	goal.RequiredMonthly = nil
+
+	if goal.Deadline == nil {
+		goal.Status = models.GoalStatusInProgress
+		if goal.Remaining == 0 {
+			goal.Status = models.GoalStatusAchieved
+		}
+		return
+	}
+
+	day := today(now)
+	monthsLeft := monthsUntil(day, *goal.Deadline)
+	goal.MonthsLeft = &monthsLeft
+
+	// Rounded up so paying it every month reaches the target
+	required := goal.Remaining
+	if monthsLeft > 1 {
+		required = (goal.Remaining + models.Money(monthsLeft) - 1) / models.Money(monthsLeft)
+	}
+	goal.RequiredMonthly = &required
+
+	switch {
+	case goal.Remaining == 0:
+		goal.Status = models.GoalStatusAchieved
+	case monthsLeft == 0:
+		goal.Status = models.GoalStatusOverdue
+	case saved >= expectedSaved(goal, day):
+		goal.Status = models.GoalStatusOnTrack
+	default:
+		goal.Status = models.GoalStatusBehind
+	}
+}
+
+// expectedSaved is what a steady pace from the start date reaches by the start of day
+func expectedSaved(goal *models.Goal, day time.Time) models.Money {
+	total := goal.Deadline.AddDate(0, 0, 1).Sub(goal.StartDate)
+	elapsed := day.Sub(goal.StartDate)
+	if elapsed <= 0 || total <= 0 {
+		return 0
+	}
+	if elapsed >= total {
+		return goal.TargetAmount
+	}
+	expected, err := goal.TargetAmount.MulRat(big.NewRat(int64(elapsed), int64(total)))
+	if err != nil {
+		// Cannot happen below the whole target, but never expect more than that
+		return goal.TargetAmount
+	}
+	return expected
+}
@@ -0,0 +1,60 @@
+package goals
+
+import (
+	"testing"
+	"time"
+
+	"moneyplanner/models"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestExpectedSaved(t *testing.T) {
+	deadline := date(2026, 1, 2) // Two days from the start, counting the deadline itself
+	tests := []struct {
+		target models.Money
+		day    time.Time
+		want   models.Money
+	}{
+		{100, date(2026, 1, 1), 0},
+		{100, date(2026, 1, 2), 50},
+		{101, date(2026, 1, 2), 51}, // 50.5 lands on a half cent and rounds up
+		{1, date(2026, 1, 2), 1},
+		{100, date(2026, 1, 3), 100},
+		{100, date(2025, 12, 31), 0}, // Before the start
+	}
+	for _, tt := range tests {
+		goal := &models.Goal{TargetAmount: tt.target, StartDate: date(2026, 1, 1), Deadline: &deadline}
+		if got := expectedSaved(goal, tt.day); got != tt.want {
+			t.Errorf("expectedSaved(%d, %s) = %d, want %d", tt.target, tt.day.Format("2006-01-02"), got, tt.want)
+		}
+	}
+
+	// A third of the way there, exactly, whatever the target
+	thirds := date(2026, 1, 3)
+	goal := &models.Goal{TargetAmount: 300000000000, StartDate: date(2026, 1, 1), Deadline: &thirds}
+	if got := expectedSaved(goal, date(2026, 1, 2)); got != 100000000000 {
+		t.Errorf("expectedSaved a third of the way = %d, want 100000000000", got)
+	}
+}
+
+func TestApplyProgressOnTrackAtHalfCent(t *testing.T) {
+	deadline := date(2026, 1, 2)
+	now := date(2026, 1, 2).Add(12 * time.Hour)
+	tests := []struct {
+		saved models.Money
+		want  models.GoalStatus
+	}{
+		{50, models.GoalStatusBehind}, // Short of the 50.5 expected, rounded to 51
+		{51, models.GoalStatusOnTrack},
+	}
+	for _, tt := range tests {
+		goal := &models.Goal{TargetAmount: 101, StartDate: date(2026, 1, 1), Deadline: &deadline}
+		applyProgress(goal, tt.saved, now)
+		if goal.Status != tt.want {
+			t.Errorf("saved %d: status %s, want %s", tt.saved, goal.Status, tt.want)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package goals
+
+import (
+	"moneyplanner/models"
+	"time"
+)
+
+type GoalCreationRequest struct {
+	Name         string       `json:"name"`
+	Icon         string       `json:"icon"`
+	WalletID     uint         `json:"wallet_id"`
+	TargetAmount models.Money `json:"target_amount"`
+	Currency     *string      `json:"currency,omitempty"`   // Defaults to the wallet's
+	StartDate    string       `json:"start_date,omitempty"` // YYYY-MM-DD; defaults to today
+	Deadline     *string      `json:"deadline,omitempty"`   // YYYY-MM-DD
+	Note         *string      `json:"note,omitempty"`
+	UserID       uint         `json:"-"` // Set from the caller
+}
+
+type GoalUpdateRequest struct {
+	Name         *string       `json:"name,omitempty"`
+	Icon         *string       `json:"icon,omitempty"`
+	TargetAmount *models.Money `json:"target_amount,omitempty"`
+	StartDate    *string       `json:"start_date,omitempty"`
+	Deadline     *string       `json:"deadline,omitempty"` // "" removes the deadline
+	Note         *string       `json:"note,omitempty"`
+}
+
+type GoalFilter struct {
+	WalletIDs []uint // Restricts results to these home wallets when non-nil
+	WalletID  *uint
+}
+
+// ContributionRequest sets money aside for a goal, or takes it back
+type ContributionRequest struct {
+	WalletID         uint         `json:"wallet_id"` // Defaults to the goal's wallet
+	Amount           models.Money `json:"amount"`    // Positive, in the wallet's currency
+	Note             *string      `json:"note,omitempty"`
+	ContributionTime *time.Time   `json:"contribution_time,omitempty"` // Defaults to now
+	UserID           uint         `json:"-"`                           // Set from the caller
+}
+
+// GoalAllocation is what a wallet holds for one goal
+type GoalAllocation struct {
+	Goal      models.Goal  `json:"goal"`
+	Allocated models.Money `json:"allocated"` // In the wallet's currency
+}
+
+// WalletGoals splits a wallet's balance into what is set aside for goals and what is not
+type WalletGoals struct {
+	WalletID  uint             `json:"wallet_id"`
+	Currency  string           `json:"currency"`
+	Balance   models.Money     `json:"balance"`
+	Allocated models.Money     `json:"allocated"`
+	Available models.Money     `json:"available"` // Negative when spending ate into money set aside
+	Goals     []GoalAllocation `json:"goals"`
+}
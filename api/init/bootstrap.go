@@ -0,0 +1,46 @@
+package init
+
+import (
+	"log"
+	"os"
+	"strconv"
+)
+
+// Environment variables read by BootstrapFromEnv
+const (
+	envBootstrap          = "MONEYPLANNER_BOOTSTRAP"
+	envForceMigrate       = "MONEYPLANNER_FORCE_MIGRATE"
+	envAdminUsername      = "MONEYPLANNER_ADMIN_USERNAME"
+	envAdminPassword      = "MONEYPLANNER_ADMIN_PASSWORD"
+	envAdminEmail         = "MONEYPLANNER_ADMIN_EMAIL"
+	envAdminName          = "MONEYPLANNER_ADMIN_NAME"
+	envDefaultWalletName  = "MONEYPLANNER_DEFAULT_WALLET_NAME"
+	envDefaultWalletGroup = "MONEYPLANNER_DEFAULT_WALLET_GROUP"
+)
+
+// envBool parses a boolean environment variable, treating unset or invalid values as false
+func envBool(name string) bool {
+	value, err := strconv.ParseBool(os.Getenv(name))
+	return err == nil && value
+}
+
+// BootstrapFromEnv runs the one-time database initialization at process start when
+// MONEYPLANNER_BOOTSTRAP is true, taking the InitRequest fields from environment variables.
+// It returns nil when bootstrapping is not enabled.
+func BootstrapFromEnv(dbPath string) *InitResponse {
+	if !envBool(envBootstrap) {
+		return nil
+	}
+
+	log.Println("Bootstrapping database from environment...")
+	req := &InitRequest{
+		ForceMigrate:       envBool(envForceMigrate),
+		DefaultWalletName:  os.Getenv(envDefaultWalletName),
+		DefaultWalletGroup: os.Getenv(envDefaultWalletGroup),
+		AdminUsername:      os.Getenv(envAdminUsername),
+		AdminPassword:      os.Getenv(envAdminPassword),
+		AdminEmail:         os.Getenv(envAdminEmail),
+		AdminName:          os.Getenv(envAdminName),
+	}
+	return InitializeDatabase(req, dbPath)
+}
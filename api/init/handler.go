@@ -91,6 +91,26 @@ func InitializeDatabase(req *InitRequest, dbPath string) *InitResponse {
 			log.Println("✓ Migrations completed")
 		}
 
+		if err := users.UpgradePlaintextPasswords(); err != nil {
+			return &InitResponse{
+				Success: false,
+				Message: "Failed to upgrade stored passwords",
+				Error:   err.Error(),
+			}
+		}
+
+		adminUsername := req.AdminUsername
+		if adminUsername == "" {
+			adminUsername = "admin"
+		}
+		if err := users.EnsureAdminExists(adminUsername, req.AdminPassword); err != nil {
+			return &InitResponse{
+				Success: false,
+				Message: "Failed to ensure an admin user exists",
+				Error:   err.Error(),
+			}
+		}
+
 		return &InitResponse{
 			Success: true,
 			Message: "Database schema is in sync",
@@ -98,6 +118,27 @@ func InitializeDatabase(req *InitRequest, dbPath string) *InitResponse {
 		}
 	}
 
+	// Refuse to bootstrap with default or weak admin credentials
+	adminUsername := "admin"
+	if req.AdminUsername != "" {
+		adminUsername = req.AdminUsername
+	}
+
+	if req.AdminPassword == "" {
+		return &InitResponse{
+			Success: false,
+			Message: "Admin password required",
+			Error:   "admin_password is required; default credentials are not allowed",
+		}
+	}
+	if err := users.ValidatePasswordStrength(adminUsername, req.AdminPassword); err != nil {
+		return &InitResponse{
+			Success: false,
+			Message: "Admin password rejected",
+			Error:   err.Error(),
+		}
+	}
+
 	// New database - run migrations and create defaults
 	log.Println("Creating new database...")
 	if err := database.MigrateDB(); err != nil {
@@ -120,16 +161,6 @@ func InitializeDatabase(req *InitRequest, dbPath string) *InitResponse {
 		walletName = req.DefaultWalletName
 	}
 
-	adminUsername := "admin"
-	if req.AdminUsername != "" {
-		adminUsername = req.AdminUsername
-	}
-
-	adminPassword := "admin"
-	if req.AdminPassword != "" {
-		adminPassword = req.AdminPassword
-	}
-
 	adminEmail := "admin@moneyplanner.local"
 	if req.AdminEmail != "" {
 		adminEmail = req.AdminEmail
@@ -145,7 +176,7 @@ func InitializeDatabase(req *InitRequest, dbPath string) *InitResponse {
 		Username:         adminUsername,
 		Name:             adminName,
 		Email:            adminEmail,
-		Password:         adminPassword,
+		Password:         req.AdminPassword,
 		UserType:         models.UserTypeHuman,
 		WalletName:       walletName,
 		WalletGroupName:  walletGroupName,
@@ -160,6 +191,14 @@ func InitializeDatabase(req *InitRequest, dbPath string) *InitResponse {
 		}
 	}
 
+	if err := users.SetUserAdmin(setupResult.User.UserID, true); err != nil {
+		return &InitResponse{
+			Success: false,
+			Error:   "Failed to grant admin rights: " + err.Error(),
+		}
+	}
+	setupResult.User.IsAdmin = true
+
 	initedData.AdminUser = setupResult.User
 	initedData.DefaultWallet = setupResult.Wallet
 	initedData.DefaultWalletGroup = setupResult.WalletGroup
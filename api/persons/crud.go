@@ -5,6 +5,9 @@ import (
 	"log"
 	"moneyplanner/database"
 	"moneyplanner/models"
+	"time"
+
+	"gorm.io/gorm"
 )
 
 // GetPersonByID retrieves a person by its ID
@@ -53,6 +56,27 @@ func CreatePerson(req *PersonCreationRequest) (*models.Person, error) {
 	return p, nil
 }
 
+// FindOrCreatePersonByName returns the person with the given name, creating it if needed.
+// It runs on the given handle so callers can include it in their own DB transaction.
+func FindOrCreatePersonByName(tx *gorm.DB, personName string) (*models.Person, error) {
+	var person models.Person
+	err := tx.Where("person_name = ?", personName).Limit(1).Find(&person).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up person: %w", err)
+	}
+	if person.PersonID != 0 {
+		return &person, nil
+	}
+
+	person = models.Person{PersonName: personName}
+	if err := tx.Create(&person).Error; err != nil {
+		return nil, fmt.Errorf("failed to create person: %w", err)
+	}
+
+	log.Printf("✓ Person '%s' created (ID: %d)", person.PersonName, person.PersonID)
+	return &person, nil
+}
+
 // UpdatePerson updates person details
 func UpdatePerson(personID uint, req *PersonUpdateRequest) (*models.Person, error) {
 	person, err := GetPersonByID(personID)
@@ -86,19 +110,50 @@ func UpdatePerson(personID uint, req *PersonUpdateRequest) (*models.Person, erro
 	return person, nil
 }
 
-// DeletePerson deletes a person by ID
-func DeletePerson(personID uint) error {
+// DeletePerson moves a person to the trash. Their transactions keep referring to them.
+func DeletePerson(personID, deletedBy uint) error {
 	// Check if person exists
 	person, err := GetPersonByID(personID)
 	if err != nil {
 		return err
 	}
 
-	// Delete the person
-	if err := database.DB.Delete(&models.Person{}, personID).Error; err != nil {
+	// Move the person to the trash
+	if err := database.DB.Model(&models.Person{}).
+		Where("person_id = ?", personID).
+		Updates(map[string]interface{}{"deleted_at": time.Now(), "deleted_by": deletedBy}).Error; err != nil {
 		return fmt.Errorf("failed to delete person: %w", err)
 	}
 
-	log.Printf("✓ Person '%s' (ID: %d) deleted", person.PersonName, personID)
+	log.Printf("✓ Person '%s' (ID: %d) moved to trash", person.PersonName, personID)
 	return nil
 }
+
+// ListTrashedPersons retrieves all persons in the trash
+func ListTrashedPersons() ([]models.Person, error) {
+	persons := []models.Person{}
+	if err := database.DB.Unscoped().Where("deleted_at IS NOT NULL").Find(&persons).Error; err != nil {
+		return nil, fmt.Errorf("failed to list trashed persons: %w", err)
+	}
+	return persons, nil
+}
+
+// RestorePerson takes a person out of the trash
+func RestorePerson(personID uint) (*models.Person, error) {
+	result := database.DB.Unscoped().Model(&models.Person{}).
+		Where("person_id = ? AND deleted_at IS NOT NULL", personID).
+		Updates(map[string]interface{}{"deleted_at": nil, "deleted_by": nil})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to restore person: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("person %d is not in the trash", personID)
+	}
+
+	person, err := GetPersonByID(personID)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("✓ Person '%s' (ID: %d) restored", person.PersonName, personID)
+	return person, nil
+}
@@ -0,0 +1,67 @@
+package reconcile
+
+import (
+	"log"
+	"moneyplanner/database"
+	"moneyplanner/models"
+	"os"
+	"time"
+)
+
+// envInterval sets how often the background check runs, as a Go duration ("0" disables it)
+const envInterval = "MONEYPLANNER_RECONCILE_INTERVAL"
+
+const defaultInterval = 24 * time.Hour
+
+// checkAllWallets logs every wallet whose stored balance has drifted from its transactions
+func checkAllWallets() {
+	if database.DB == nil || !database.DB.Migrator().HasTable(&models.Wallet{}) {
+		return
+	}
+
+	reports, err := ReconcileAllWallets()
+	if err != nil {
+		log.Printf("Warning: Balance check failed: %v", err)
+		return
+	}
+
+	drifted := 0
+	for _, report := range reports {
+		if report.InSync {
+			continue
+		}
+		drifted++
+		log.Printf("Warning: Wallet %d balance drift: stored %s, computed %s (discrepancy %s %s)",
+			report.WalletID, report.StoredBalance, report.ComputedBalance, report.Discrepancy, report.Currency)
+	}
+	if drifted == 0 {
+		log.Printf("✓ Balance check: %d wallet(s) in sync", len(reports))
+	}
+}
+
+// StartBackgroundCheck checks all wallet balances now and then every
+// MONEYPLANNER_RECONCILE_INTERVAL (default 24h). It only reports; repairs are explicit.
+func StartBackgroundCheck() {
+	interval := defaultInterval
+	if value := os.Getenv(envInterval); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			log.Printf("Warning: Invalid %s '%s', using %s", envInterval, value, defaultInterval)
+		} else {
+			interval = parsed
+		}
+	}
+	if interval <= 0 {
+		log.Println("Background balance check disabled")
+		return
+	}
+
+	go func() {
+		checkAllWallets()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			checkAllWallets()
+		}
+	}()
+}
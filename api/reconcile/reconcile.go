@@ -0,0 +1,138 @@
+package reconcile
+
+import (
+	"fmt"
+	"log"
+	"moneyplanner/api/categories"
+	"moneyplanner/database"
+	"moneyplanner/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const defaultAdjustmentNote = "Balance reconciliation"
+
+// buildReport recomputes the wallet's balance from its opening balance and transactions
+func buildReport(tx *gorm.DB, walletID uint) (*Report, error) {
+	var wallet models.Wallet
+	if err := tx.First(&wallet, walletID).Error; err != nil {
+		return nil, fmt.Errorf("wallet not found: %w", err)
+	}
+
+	type kindTotal struct {
+		Kind  models.CategoryKind
+		Total models.Money
+		Count int64
+	}
+	var totals []kindTotal
+	if err := tx.Model(&models.Transaction{}).
+		Select("categories.kind AS kind, SUM(transactions.amount) AS total, COUNT(*) AS count").
+		Joins("JOIN categories ON categories.category_id = transactions.category_id").
+		Where("transactions.wallet_id = ?", walletID).
+		Group("categories.kind").
+		Scan(&totals).Error; err != nil {
+		return nil, fmt.Errorf("failed to total transactions: %w", err)
+	}
+
+	report := &Report{
+		WalletID:       wallet.WalletID,
+		Currency:       wallet.Currency,
+		OpeningBalance: wallet.OpeningBalance,
+		StoredBalance:  wallet.Balance,
+		CheckedTime:    time.Now(),
+	}
+	for _, total := range totals {
+		report.TransactionsTotal += total.Kind.BalanceEffect(total.Total)
+		report.TransactionCount += total.Count
+	}
+	report.ComputedBalance = report.OpeningBalance + report.TransactionsTotal
+	report.Discrepancy = report.StoredBalance - report.ComputedBalance
+	report.InSync = report.Discrepancy == 0
+	return report, nil
+}
+
+// ReconcileWallet reports whether the wallet's stored balance matches its transactions
+func ReconcileWallet(walletID uint) (*Report, error) {
+	return buildReport(database.DB, walletID)
+}
+
+// ReconcileAllWallets reports on every wallet
+func ReconcileAllWallets() ([]Report, error) {
+	var walletIDs []uint
+	if err := database.DB.Model(&models.Wallet{}).Order("wallet_id").Pluck("wallet_id", &walletIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to list wallets: %w", err)
+	}
+
+	reports := make([]Report, 0, len(walletIDs))
+	for _, walletID := range walletIDs {
+		report, err := buildReport(database.DB, walletID)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, *report)
+	}
+	return reports, nil
+}
+
+// RepairWallet books the discrepancy as an adjustment transaction so the transactions
+// explain the stored balance. The stored balance itself is left untouched.
+func RepairWallet(walletID, userID uint, req *RepairRequest) (*Report, error) {
+	var report *Report
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		before, err := buildReport(tx, walletID)
+		if err != nil {
+			return err
+		}
+		if before.InSync {
+			report = before
+			return nil
+		}
+
+		category, err := categories.EnsureKindRootCategory(tx, walletID, models.CategoryKindAdjustment, "Adjustment", "⚖️")
+		if err != nil {
+			return err
+		}
+
+		note := defaultAdjustmentNote
+		if req != nil && req.Note != nil && *req.Note != "" {
+			note = *req.Note
+		}
+		now := time.Now()
+		adjustment := &models.Transaction{
+			WalletID:         walletID,
+			CategoryID:       category.CategoryID,
+			Amount:           before.Discrepancy,
+			Note:             &note,
+			TransactionTime:  now,
+			EntryTime:        now,
+			LastModifiedTime: now,
+			UserID:           userID,
+		}
+		// Deliberately not applied to the balance: the stored balance already includes it
+		if err := tx.Create(adjustment).Error; err != nil {
+			return fmt.Errorf("failed to post adjustment: %w", err)
+		}
+		if err := tx.Preload("Category").Preload("Wallet").Preload("User").First(adjustment, adjustment.TransactionID).Error; err != nil {
+			return fmt.Errorf("failed to load adjustment: %w", err)
+		}
+
+		report, err = buildReport(tx, walletID)
+		if err != nil {
+			return err
+		}
+		if !report.InSync {
+			return fmt.Errorf("wallet %d is still out of sync after posting the adjustment", walletID)
+		}
+		report.Adjustment = adjustment
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if report.Adjustment != nil {
+		log.Printf("✓ Wallet %d reconciled with an adjustment of %s", walletID, report.Adjustment.Amount)
+	}
+	return report, nil
+}
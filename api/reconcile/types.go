@@ -0,0 +1,26 @@
+package reconcile
+
+import (
+	"moneyplanner/models"
+	"time"
+)
+
+// Report compares a wallet's stored balance with the one implied by its transactions
+type Report struct {
+	WalletID          uint                `json:"wallet_id"`
+	Currency          string              `json:"currency"`
+	OpeningBalance    models.Money        `json:"opening_balance"`
+	TransactionsTotal models.Money        `json:"transactions_total"` // Net effect of all transactions
+	TransactionCount  int64               `json:"transaction_count"`
+	ComputedBalance   models.Money        `json:"computed_balance"` // OpeningBalance + TransactionsTotal
+	StoredBalance     models.Money        `json:"stored_balance"`
+	Discrepancy       models.Money        `json:"discrepancy"` // StoredBalance - ComputedBalance
+	InSync            bool                `json:"in_sync"`
+	CheckedTime       time.Time           `json:"checked_time"`
+	Adjustment        *models.Transaction `json:"adjustment,omitempty"` // Set when a repair posted one
+}
+
+// RepairRequest asks for the discrepancy to be booked as an adjustment transaction
+type RepairRequest struct {
+	Note *string `json:"note,omitempty"`
+}
@@ -0,0 +1,457 @@
+package recurring
+
+import (
+	"fmt"
+	"log"
+	"moneyplanner/database"
+	"moneyplanner/models"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// maxOccurrences bounds listings that have no explicit limit
+const maxOccurrences = 500
+
+// GetRecurringTransactionByID retrieves a recurring transaction by its ID
+func GetRecurringTransactionByID(recurringID uint) (*models.RecurringTransaction, error) {
+	var recurring models.RecurringTransaction
+	if err := database.DB.First(&recurring, recurringID).Error; err != nil {
+		return nil, fmt.Errorf("recurring transaction not found: %w", err)
+	}
+	return &recurring, nil
+}
+
+// ListRecurringTransactions lists recurring transactions, soonest first
+func ListRecurringTransactions(filter *RecurringTransactionFilter) ([]models.RecurringTransaction, error) {
+	recurrings := []models.RecurringTransaction{}
+	query := database.DB.Order("next_occurrence IS NULL, next_occurrence, recurring_transaction_id")
+	if filter != nil {
+		if filter.WalletIDs != nil {
+			query = query.Where("wallet_id IN ?", filter.WalletIDs)
+		}
+		if filter.WalletID != nil {
+			query = query.Where("wallet_id = ?", *filter.WalletID)
+		}
+	}
+	if err := query.Find(&recurrings).Error; err != nil {
+		return nil, fmt.Errorf("failed to list recurring transactions: %w", err)
+	}
+	return recurrings, nil
+}
+
+// validateRecurring checks a template against its rule and category, normalizing the rule
+// and currency, and returns the parsed rule
+func validateRecurring(recurring *models.RecurringTransaction) (*Rule, error) {
+	rule, err := ParseRule(recurring.RRule)
+	if err != nil {
+		return nil, err
+	}
+	recurring.RRule = rule.String()
+
+	if recurring.Amount == 0 {
+		return nil, fmt.Errorf("amount is required")
+	}
+	var category models.Category
+	if err := database.DB.First(&category, recurring.CategoryID).Error; err != nil {
+		return nil, fmt.Errorf("category not found: %w", err)
+	}
+	if category.WalletID != recurring.WalletID {
+		return nil, fmt.Errorf("category %d does not belong to wallet %d", category.CategoryID, recurring.WalletID)
+	}
+	if !category.Kind.IsSigned() && recurring.Amount < 0 {
+		return nil, fmt.Errorf("amount must be positive for %s categories", category.Kind)
+	}
+
+	if recurring.Currency != nil {
+		if *recurring.Currency == "" {
+			recurring.Currency = nil
+		} else {
+			currency, err := models.NormalizeCurrency(*recurring.Currency)
+			if err != nil {
+				return nil, err
+			}
+			recurring.Currency = &currency
+		}
+	}
+	return rule, nil
+}
+
+// firstOccurrence returns the first occurrence of the series at or after t, or nil when none is left
+func firstOccurrence(rule *Rule, start, t time.Time) *time.Time {
+	if t.Before(start) {
+		t = start
+	}
+	first := rule.Occurrences(start, t, time.Time{}, 1)
+	if len(first) == 0 {
+		return nil
+	}
+	return &first[0]
+}
+
+// isOccurrence reports whether t is an occurrence of the series
+func isOccurrence(rule *Rule, start, t time.Time) bool {
+	return len(rule.Occurrences(start, t, t, 1)) == 1
+}
+
+// CreateRecurringTransaction creates a recurring transaction. Occurrences from its start time
+// on are posted by the scheduler, including ones already in the past.
+func CreateRecurringTransaction(req *RecurringTransactionCreationRequest) (*models.RecurringTransaction, error) {
+	if req.WalletID == 0 {
+		return nil, fmt.Errorf("wallet_id is required")
+	}
+	if req.UserID == 0 {
+		return nil, fmt.Errorf("user_id is required")
+	}
+
+	now := time.Now()
+	recurring := &models.RecurringTransaction{
+		WalletID:         req.WalletID,
+		CategoryID:       req.CategoryID,
+		Amount:           req.Amount,
+		Currency:         req.Currency,
+		PersonID:         req.PersonID,
+		Note:             req.Note,
+		RRule:            req.RRule,
+		StartTime:        now.UTC().Truncate(time.Second),
+		IsEnabled:        true,
+		UserID:           req.UserID,
+		EntryTime:        now,
+		LastModifiedTime: now,
+	}
+	if req.StartTime != nil {
+		recurring.StartTime = req.StartTime.UTC()
+	}
+	if req.IsEnabled != nil {
+		recurring.IsEnabled = *req.IsEnabled
+	}
+
+	rule, err := validateRecurring(recurring)
+	if err != nil {
+		return nil, err
+	}
+	recurring.NextOccurrence = firstOccurrence(rule, recurring.StartTime, recurring.StartTime)
+	if recurring.NextOccurrence == nil {
+		return nil, fmt.Errorf("rrule has no occurrences after start_time")
+	}
+
+	if err := database.DB.Create(recurring).Error; err != nil {
+		return nil, fmt.Errorf("failed to create recurring transaction: %w", err)
+	}
+
+	log.Printf("✓ Recurring transaction created (ID: %d, %s)", recurring.RecurringTransactionID, recurring.RRule)
+	return recurring, nil
+}
+
+// applyUpdate copies the requested changes onto a template
+func applyUpdate(recurring *models.RecurringTransaction, req *RecurringTransactionUpdateRequest) {
+	if req.CategoryID != nil {
+		recurring.CategoryID = *req.CategoryID
+	}
+	if req.Amount != nil {
+		recurring.Amount = *req.Amount
+	}
+	if req.Currency != nil {
+		recurring.Currency = req.Currency
+	}
+	if req.PersonID != nil {
+		recurring.PersonID = req.PersonID
+	}
+	if req.Note != nil {
+		recurring.Note = req.Note
+	}
+	if req.RRule != nil {
+		recurring.RRule = *req.RRule
+	}
+	if req.StartTime != nil {
+		recurring.StartTime = req.StartTime.UTC()
+	}
+	if req.IsEnabled != nil {
+		recurring.IsEnabled = *req.IsEnabled
+	}
+	recurring.LastModifiedTime = time.Now()
+}
+
+// lastPosted returns the latest occurrence posted for the template, or nil
+func lastPosted(tx *gorm.DB, recurringID uint) (*time.Time, error) {
+	var posted []models.Transaction
+	if err := tx.Unscoped().Where("recurring_transaction_id = ?", recurringID).
+		Order("occurrence_time DESC").Limit(1).Find(&posted).Error; err != nil {
+		return nil, fmt.Errorf("failed to load posted occurrences: %w", err)
+	}
+	if len(posted) == 0 {
+		return nil, nil
+	}
+	return posted[0].OccurrenceTime, nil
+}
+
+// UpdateRecurringTransaction changes a recurring transaction. Without ApplyFrom the whole
+// series changes; posted transactions are never touched either way.
+func UpdateRecurringTransaction(recurringID uint, req *RecurringTransactionUpdateRequest) (*models.RecurringTransaction, error) {
+	recurring, err := GetRecurringTransactionByID(recurringID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.ApplyFrom != nil {
+		rule, err := ParseRule(recurring.RRule)
+		if err != nil {
+			return nil, err
+		}
+		from := req.ApplyFrom.UTC()
+		if !isOccurrence(rule, recurring.StartTime, from) {
+			return nil, fmt.Errorf("apply_from %s is not an occurrence of this recurring transaction", from.Format(time.RFC3339))
+		}
+		if recurring.NextOccurrence == nil || from.Before(*recurring.NextOccurrence) {
+			return nil, fmt.Errorf("the occurrence at %s is already posted or skipped; edit the transaction instead", from.Format(time.RFC3339))
+		}
+		if rule.CountBefore(recurring.StartTime, from) > 0 {
+			return splitRecurringTransaction(recurring, rule, from, req)
+		}
+		// Splitting at the first occurrence changes the whole series
+	}
+
+	applyUpdate(recurring, req)
+	rule, err := validateRecurring(recurring)
+	if err != nil {
+		return nil, err
+	}
+
+	// Carry on after the last posted occurrence
+	posted, err := lastPosted(database.DB, recurringID)
+	if err != nil {
+		return nil, err
+	}
+	resume := recurring.StartTime
+	if posted != nil && !posted.Before(resume) {
+		resume = posted.Add(time.Nanosecond)
+	}
+	recurring.NextOccurrence = firstOccurrence(rule, recurring.StartTime, resume)
+
+	if err := database.DB.Save(recurring).Error; err != nil {
+		return nil, fmt.Errorf("failed to update recurring transaction: %w", err)
+	}
+
+	log.Printf("✓ Recurring transaction (ID: %d) updated", recurringID)
+	return recurring, nil
+}
+
+// splitRecurringTransaction ends the series before from and continues it, with the changes,
+// as a new recurring transaction. Skips from then on move to the new one.
+func splitRecurringTransaction(recurring *models.RecurringTransaction, rule *Rule, from time.Time, req *RecurringTransactionUpdateRequest) (*models.RecurringTransaction, error) {
+	before := rule.CountBefore(recurring.StartTime, from)
+
+	ended := *rule
+	if rule.Count > 0 {
+		ended.Count = before
+	} else {
+		until := from.Add(-time.Second)
+		ended.Until = &until
+	}
+
+	next := *recurring
+	next.RecurringTransactionID = 0
+	next.StartTime = from
+	next.EntryTime = time.Now()
+	if rule.Count > 0 {
+		remaining := *rule
+		remaining.Count = rule.Count - before
+		next.RRule = remaining.String()
+	}
+	applyUpdate(&next, req)
+	nextRule, err := validateRecurring(&next)
+	if err != nil {
+		return nil, err
+	}
+	next.NextOccurrence = firstOccurrence(nextRule, next.StartTime, next.StartTime)
+	if next.NextOccurrence == nil {
+		return nil, fmt.Errorf("rrule has no occurrences after %s", next.StartTime.Format(time.RFC3339))
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&next).Error; err != nil {
+			return fmt.Errorf("failed to create recurring transaction: %w", err)
+		}
+
+		if err := tx.Model(&models.RecurringSkip{}).
+			Where("recurring_transaction_id = ? AND occurrence_time >= ?", recurring.RecurringTransactionID, from).
+			Update("recurring_transaction_id", next.RecurringTransactionID).Error; err != nil {
+			return fmt.Errorf("failed to move skipped occurrences: %w", err)
+		}
+
+		result := tx.Model(&models.RecurringTransaction{}).
+			Where("recurring_transaction_id = ? AND next_occurrence = ?", recurring.RecurringTransactionID, *recurring.NextOccurrence).
+			Updates(map[string]interface{}{
+				"rrule":              ended.String(),
+				"next_occurrence":    firstOccurrence(&ended, recurring.StartTime, *recurring.NextOccurrence),
+				"last_modified_time": time.Now(),
+			})
+		if result.Error != nil {
+			return fmt.Errorf("failed to end recurring transaction: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("recurring transaction %d was posted meanwhile; try again", recurring.RecurringTransactionID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("✓ Recurring transaction (ID: %d) split at %s into ID %d",
+		recurring.RecurringTransactionID, from.Format(time.RFC3339), next.RecurringTransactionID)
+	return &next, nil
+}
+
+// DeleteRecurringTransaction deletes a recurring transaction; posted transactions stay
+func DeleteRecurringTransaction(recurringID uint) error {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("recurring_transaction_id = ?", recurringID).Delete(&models.RecurringSkip{}).Error; err != nil {
+			return fmt.Errorf("failed to delete skipped occurrences: %w", err)
+		}
+		result := tx.Delete(&models.RecurringTransaction{}, recurringID)
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete recurring transaction: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("recurring transaction not found")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("✓ Recurring transaction (ID: %d) deleted", recurringID)
+	return nil
+}
+
+// ListOccurrences lists the occurrences in [from, to] (to zero means open-ended), at most limit
+func ListOccurrences(recurring *models.RecurringTransaction, from, to time.Time, limit int) ([]Occurrence, error) {
+	rule, err := ParseRule(recurring.RRule)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > maxOccurrences {
+		limit = maxOccurrences
+	}
+	times := rule.Occurrences(recurring.StartTime, from, to, limit)
+	occurrences := make([]Occurrence, 0, len(times))
+	if len(times) == 0 {
+		return occurrences, nil
+	}
+	first, last := times[0], times[len(times)-1]
+
+	var posted []models.Transaction
+	if err := database.DB.Unscoped().
+		Where("recurring_transaction_id = ? AND occurrence_time >= ? AND occurrence_time <= ?", recurring.RecurringTransactionID, first, last).
+		Find(&posted).Error; err != nil {
+		return nil, fmt.Errorf("failed to load posted occurrences: %w", err)
+	}
+	postedAt := map[int64]uint{}
+	for _, t := range posted {
+		postedAt[t.OccurrenceTime.UnixNano()] = t.TransactionID
+	}
+
+	var skips []models.RecurringSkip
+	if err := database.DB.Where("recurring_transaction_id = ? AND occurrence_time >= ? AND occurrence_time <= ?",
+		recurring.RecurringTransactionID, first, last).Find(&skips).Error; err != nil {
+		return nil, fmt.Errorf("failed to load skipped occurrences: %w", err)
+	}
+	skipped := map[int64]bool{}
+	for _, skip := range skips {
+		skipped[skip.OccurrenceTime.UnixNano()] = true
+	}
+
+	for _, at := range times {
+		occurrence := Occurrence{
+			RecurringTransactionID: recurring.RecurringTransactionID,
+			OccurrenceTime:         at,
+			Status:                 OccurrenceScheduled,
+			WalletID:               recurring.WalletID,
+			CategoryID:             recurring.CategoryID,
+			Amount:                 recurring.Amount,
+			Currency:               recurring.Currency,
+			Note:                   recurring.Note,
+		}
+		if transactionID, ok := postedAt[at.UnixNano()]; ok {
+			occurrence.Status = OccurrencePosted
+			occurrence.TransactionID = &transactionID
+		} else if skipped[at.UnixNano()] {
+			occurrence.Status = OccurrenceSkipped
+		} else if recurring.NextOccurrence == nil || at.Before(*recurring.NextOccurrence) {
+			occurrence.Status = OccurrenceMissed
+		}
+		occurrences = append(occurrences, occurrence)
+	}
+	return occurrences, nil
+}
+
+// ListUpcoming lists the unposted occurrences of the filter's enabled recurring transactions
+// from now until the given time, soonest first
+func ListUpcoming(filter *RecurringTransactionFilter, until time.Time) ([]Occurrence, error) {
+	recurrings, err := ListRecurringTransactions(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	upcoming := []Occurrence{}
+	for i := range recurrings {
+		recurring := &recurrings[i]
+		if !recurring.IsEnabled || recurring.NextOccurrence == nil {
+			continue
+		}
+		occurrences, err := ListOccurrences(recurring, *recurring.NextOccurrence, until, maxOccurrences)
+		if err != nil {
+			return nil, err
+		}
+		for _, occurrence := range occurrences {
+			if occurrence.Status == OccurrenceScheduled || occurrence.Status == OccurrenceSkipped {
+				upcoming = append(upcoming, occurrence)
+			}
+		}
+	}
+	sort.SliceStable(upcoming, func(i, j int) bool {
+		return upcoming[i].OccurrenceTime.Before(upcoming[j].OccurrenceTime)
+	})
+	return upcoming, nil
+}
+
+// SkipOccurrence marks an occurrence that has not been posted yet so it never will be
+func SkipOccurrence(recurringID uint, occurrenceTime time.Time) (*Occurrence, error) {
+	recurring, err := GetRecurringTransactionByID(recurringID)
+	if err != nil {
+		return nil, err
+	}
+	rule, err := ParseRule(recurring.RRule)
+	if err != nil {
+		return nil, err
+	}
+	occurrenceTime = occurrenceTime.UTC()
+	if !isOccurrence(rule, recurring.StartTime, occurrenceTime) {
+		return nil, fmt.Errorf("%s is not an occurrence of this recurring transaction", occurrenceTime.Format(time.RFC3339))
+	}
+
+	var postedCount int64
+	if err := database.DB.Unscoped().Model(&models.Transaction{}).
+		Where("recurring_transaction_id = ? AND occurrence_time = ?", recurringID, occurrenceTime).
+		Count(&postedCount).Error; err != nil {
+		return nil, fmt.Errorf("failed to check posted occurrences: %w", err)
+	}
+	if postedCount > 0 {
+		return nil, fmt.Errorf("the occurrence at %s is already posted; delete the transaction instead", occurrenceTime.Format(time.RFC3339))
+	}
+
+	skip := models.RecurringSkip{RecurringTransactionID: recurringID, OccurrenceTime: occurrenceTime}
+	if err := database.DB.Where(&skip).FirstOrCreate(&skip).Error; err != nil {
+		return nil, fmt.Errorf("failed to skip occurrence: %w", err)
+	}
+
+	log.Printf("✓ Recurring transaction (ID: %d) occurrence %s skipped", recurringID, occurrenceTime.Format(time.RFC3339))
+	occurrences, err := ListOccurrences(recurring, occurrenceTime, occurrenceTime, 1)
+	if err != nil || len(occurrences) == 0 {
+		return nil, err
+	}
+	return &occurrences[0], nil
+}
@@ -0,0 +1,400 @@
+package recurring
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequency is the FREQ part of a recurrence rule
+type Frequency string
+
+const (
+	FrequencyDaily   Frequency = "DAILY"
+	FrequencyWeekly  Frequency = "WEEKLY"
+	FrequencyMonthly Frequency = "MONTHLY"
+	FrequencyYearly  Frequency = "YEARLY"
+)
+
+// maxPeriods bounds how far a rule is expanded, so rules that never match cannot loop forever
+const maxPeriods = 100000
+
+var weekdayCodes = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+// weekdayNum is a BYDAY entry: a weekday, optionally the nth (or nth from last) in the month
+type weekdayNum struct {
+	N       int // 0 means every such weekday
+	Weekday time.Weekday
+}
+
+// Rule is the supported subset of an RFC 5545 RRULE: FREQ, INTERVAL, COUNT, UNTIL, BYDAY,
+// BYMONTHDAY and BYMONTH. Occurrences take their time of day from the series start and are
+// computed in UTC.
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	Count      int        // 0 means unlimited
+	Until      *time.Time // Inclusive
+	ByDay      []weekdayNum
+	ByMonthDay []int
+	ByMonth    []int
+}
+
+// ParseRule parses an RRULE value such as "FREQ=MONTHLY;BYDAY=-1FR" (an "RRULE:" prefix is allowed)
+func ParseRule(s string) (*Rule, error) {
+	s = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(s)), "RRULE:")
+	if s == "" {
+		return nil, fmt.Errorf("rrule is required")
+	}
+
+	rule := &Rule{Interval: 1}
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("invalid rrule part '%s'", part)
+		}
+
+		switch key {
+		case "FREQ":
+			switch freq := Frequency(value); freq {
+			case FrequencyDaily, FrequencyWeekly, FrequencyMonthly, FrequencyYearly:
+				rule.Freq = freq
+			default:
+				return nil, fmt.Errorf("unsupported FREQ '%s' (expected DAILY, WEEKLY, MONTHLY or YEARLY)", value)
+			}
+		case "INTERVAL":
+			interval, err := strconv.Atoi(value)
+			if err != nil || interval < 1 {
+				return nil, fmt.Errorf("INTERVAL must be a positive number")
+			}
+			rule.Interval = interval
+		case "COUNT":
+			count, err := strconv.Atoi(value)
+			if err != nil || count < 1 {
+				return nil, fmt.Errorf("COUNT must be a positive number")
+			}
+			rule.Count = count
+		case "UNTIL":
+			until, err := parseUntil(value)
+			if err != nil {
+				return nil, err
+			}
+			rule.Until = &until
+		case "BYDAY":
+			for _, code := range strings.Split(value, ",") {
+				day, err := parseWeekdayNum(code)
+				if err != nil {
+					return nil, err
+				}
+				rule.ByDay = append(rule.ByDay, day)
+			}
+		case "BYMONTHDAY":
+			days, err := parseInts(value, -31, 31, "BYMONTHDAY")
+			if err != nil {
+				return nil, err
+			}
+			rule.ByMonthDay = days
+		case "BYMONTH":
+			months, err := parseInts(value, 1, 12, "BYMONTH")
+			if err != nil {
+				return nil, err
+			}
+			rule.ByMonth = months
+		default:
+			return nil, fmt.Errorf("unsupported rrule part '%s'", key)
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, fmt.Errorf("FREQ is required")
+	}
+	if rule.Count > 0 && rule.Until != nil {
+		return nil, fmt.Errorf("COUNT and UNTIL cannot be combined")
+	}
+	for _, day := range rule.ByDay {
+		if day.N != 0 && rule.Freq != FrequencyMonthly && rule.Freq != FrequencyYearly {
+			return nil, fmt.Errorf("numbered BYDAY values need FREQ=MONTHLY or YEARLY")
+		}
+		if day.N != 0 && rule.Freq == FrequencyYearly && len(rule.ByMonth) == 0 {
+			return nil, fmt.Errorf("numbered BYDAY values with FREQ=YEARLY need BYMONTH")
+		}
+	}
+	if len(rule.ByMonthDay) > 0 && rule.Freq == FrequencyWeekly {
+		return nil, fmt.Errorf("BYMONTHDAY cannot be used with FREQ=WEEKLY")
+	}
+	return rule, nil
+}
+
+// parseUntil parses an UNTIL value as YYYYMMDDTHHMMSSZ, or YYYYMMDD for the end of that day
+func parseUntil(value string) (time.Time, error) {
+	if until, err := time.Parse("20060102T150405Z", value); err == nil {
+		return until, nil
+	}
+	date, err := time.Parse("20060102", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid UNTIL '%s' (expected YYYYMMDD or YYYYMMDDTHHMMSSZ)", value)
+	}
+	return date.AddDate(0, 0, 1).Add(-time.Second), nil
+}
+
+// parseWeekdayNum parses a BYDAY entry such as "MO", "2TU" or "-1FR"
+func parseWeekdayNum(code string) (weekdayNum, error) {
+	code = strings.TrimSpace(code)
+	if len(code) < 2 {
+		return weekdayNum{}, fmt.Errorf("invalid BYDAY value '%s'", code)
+	}
+	weekday, ok := weekdayCodes[code[len(code)-2:]]
+	if !ok {
+		return weekdayNum{}, fmt.Errorf("invalid BYDAY value '%s'", code)
+	}
+	day := weekdayNum{Weekday: weekday}
+	if prefix := code[:len(code)-2]; prefix != "" {
+		n, err := strconv.Atoi(prefix)
+		if err != nil || n == 0 || n < -5 || n > 5 {
+			return weekdayNum{}, fmt.Errorf("invalid BYDAY value '%s'", code)
+		}
+		day.N = n
+	}
+	return day, nil
+}
+
+// parseInts parses a comma-separated list of non-zero numbers within [min, max]
+func parseInts(value string, min, max int, name string) ([]int, error) {
+	var numbers []int
+	for _, part := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || n == 0 || n < min || n > max {
+			return nil, fmt.Errorf("invalid %s value '%s'", name, part)
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers, nil
+}
+
+// String formats the rule back as an RRULE value
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	if len(r.ByDay) > 0 {
+		codes := make([]string, len(r.ByDay))
+		for i, day := range r.ByDay {
+			code := strings.ToUpper(day.Weekday.String()[:2])
+			if day.N != 0 {
+				code = strconv.Itoa(day.N) + code
+			}
+			codes[i] = code
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinInts(r.ByMonthDay))
+	}
+	if len(r.ByMonth) > 0 {
+		parts = append(parts, "BYMONTH="+joinInts(r.ByMonth))
+	}
+	return strings.Join(parts, ";")
+}
+
+func joinInts(numbers []int) string {
+	parts := make([]string, len(numbers))
+	for i, n := range numbers {
+		parts[i] = strconv.Itoa(n)
+	}
+	return strings.Join(parts, ",")
+}
+
+// truncateDay returns midnight UTC of t's day
+func truncateDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// containsInt reports whether numbers is empty or holds n
+func containsInt(numbers []int, n int) bool {
+	if len(numbers) == 0 {
+		return true
+	}
+	for _, m := range numbers {
+		if m == n {
+			return true
+		}
+	}
+	return false
+}
+
+// monthDates returns the matching days of one month for MONTHLY and YEARLY rules
+func (r *Rule) monthDates(year int, month time.Month, start time.Time) []time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	daysInMonth := first.AddDate(0, 1, -1).Day()
+
+	// Resolve BYMONTHDAY, counting negative days from the end of the month
+	monthDays := map[int]bool{}
+	for _, day := range r.ByMonthDay {
+		if day < 0 {
+			day = daysInMonth + day + 1
+		}
+		if day >= 1 && day <= daysInMonth {
+			monthDays[day] = true
+		}
+	}
+
+	var dates []time.Time
+	switch {
+	case len(r.ByDay) > 0:
+		for day := 1; day <= daysInMonth; day++ {
+			date := first.AddDate(0, 0, day-1)
+			if len(r.ByMonthDay) > 0 && !monthDays[day] {
+				continue
+			}
+			for _, byDay := range r.ByDay {
+				if date.Weekday() != byDay.Weekday {
+					continue
+				}
+				nth := (day-1)/7 + 1                     // Counting from the start of the month
+				nthFromEnd := -((daysInMonth-day)/7 + 1) // Counting from the end
+				if byDay.N == 0 || byDay.N == nth || byDay.N == nthFromEnd {
+					dates = append(dates, date)
+					break
+				}
+			}
+		}
+	case len(r.ByMonthDay) > 0:
+		for day := 1; day <= daysInMonth; day++ {
+			if monthDays[day] {
+				dates = append(dates, first.AddDate(0, 0, day-1))
+			}
+		}
+	default:
+		// Months without the start's day are skipped, as RFC 5545 does
+		if start.Day() <= daysInMonth {
+			dates = append(dates, first.AddDate(0, 0, start.Day()-1))
+		}
+	}
+	return dates
+}
+
+// periodDates returns the matching days of the k-th period after the one containing start
+func (r *Rule) periodDates(start time.Time, k int) []time.Time {
+	startDay := truncateDay(start)
+	var dates []time.Time
+
+	switch r.Freq {
+	case FrequencyDaily:
+		date := startDay.AddDate(0, 0, k*r.Interval)
+		if containsInt(r.ByMonth, int(date.Month())) && containsInt(r.ByMonthDay, date.Day()) && r.matchesWeekday(date) {
+			dates = append(dates, date)
+		}
+	case FrequencyWeekly:
+		// Weeks start on Monday
+		weekStart := startDay.AddDate(0, 0, -((int(startDay.Weekday())+6)%7)+7*k*r.Interval)
+		for i := 0; i < 7; i++ {
+			date := weekStart.AddDate(0, 0, i)
+			if !containsInt(r.ByMonth, int(date.Month())) {
+				continue
+			}
+			if (len(r.ByDay) == 0 && date.Weekday() == start.Weekday()) || (len(r.ByDay) > 0 && r.matchesWeekday(date)) {
+				dates = append(dates, date)
+			}
+		}
+	case FrequencyMonthly:
+		month := time.Date(startDay.Year(), startDay.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, k*r.Interval, 0)
+		if containsInt(r.ByMonth, int(month.Month())) {
+			dates = r.monthDates(month.Year(), month.Month(), start)
+		}
+	case FrequencyYearly:
+		year := startDay.Year() + k*r.Interval
+		months := r.ByMonth
+		if len(months) == 0 {
+			months = []int{int(startDay.Month())}
+		}
+		sorted := append([]int(nil), months...)
+		sort.Ints(sorted)
+		for _, month := range sorted {
+			dates = append(dates, r.monthDates(year, time.Month(month), start)...)
+		}
+	}
+	return dates
+}
+
+// matchesWeekday reports whether BYDAY is empty or lists the date's weekday
+func (r *Rule) matchesWeekday(date time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, day := range r.ByDay {
+		if day.Weekday == date.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// Occurrences expands the rule for a series starting at start and returns the occurrences in
+// [from, to], at most limit of them. A zero to means no upper bound; limit 0 means no limit,
+// in which case to must be set.
+func (r *Rule) Occurrences(start, from, to time.Time, limit int) []time.Time {
+	start = start.UTC()
+	timeOfDay := start.Sub(truncateDay(start))
+
+	occurrences := []time.Time{}
+	seen := 0
+	for k := 0; k < maxPeriods; k++ {
+		dates := r.periodDates(start, k)
+		for _, date := range dates {
+			occurrence := date.Add(timeOfDay)
+			if occurrence.Before(start) {
+				continue
+			}
+			if r.Until != nil && occurrence.After(*r.Until) {
+				return occurrences
+			}
+			if !to.IsZero() && occurrence.After(to) {
+				return occurrences
+			}
+			seen++
+			if r.Count > 0 && seen > r.Count {
+				return occurrences
+			}
+			if !occurrence.Before(from) {
+				occurrences = append(occurrences, occurrence)
+				if limit > 0 && len(occurrences) >= limit {
+					return occurrences
+				}
+			}
+		}
+	}
+	return occurrences
+}
+
+// After returns the first occurrence strictly after t, or nil when the series has ended
+func (r *Rule) After(start, t time.Time) *time.Time {
+	next := r.Occurrences(start, t.Add(time.Nanosecond), time.Time{}, 1)
+	if len(next) == 0 {
+		return nil
+	}
+	return &next[0]
+}
+
+// CountBefore returns how many occurrences of the series fall before t
+func (r *Rule) CountBefore(start, t time.Time) int {
+	if !t.After(start) {
+		return 0
+	}
+	return len(r.Occurrences(start, start, t.Add(-time.Nanosecond), 0))
+}
@@ -0,0 +1,282 @@
+package recurring
+
+import (
+	"testing"
+	"time"
+)
+
+func utc(year int, month time.Month, day, hour, min int) time.Time {
+	return time.Date(year, month, day, hour, min, 0, 0, time.UTC)
+}
+
+func mustParseRule(t *testing.T, s string) *Rule {
+	t.Helper()
+	rule, err := ParseRule(s)
+	if err != nil {
+		t.Fatalf("ParseRule(%q) returned error: %v", s, err)
+	}
+	return rule
+}
+
+func equalTimes(a, b []time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string // As formatted back by String
+	}{
+		{"FREQ=DAILY", "FREQ=DAILY"},
+		{"RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE"},
+		{"  rrule:freq=monthly;byday=-1fr  ", "FREQ=MONTHLY;BYDAY=-1FR"},
+		{"FREQ=MONTHLY;BYDAY=2TU;COUNT=6", "FREQ=MONTHLY;COUNT=6;BYDAY=2TU"},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1,15", "FREQ=MONTHLY;BYMONTHDAY=-1,15"},
+		{"FREQ=YEARLY;BYMONTH=11;BYDAY=4TH", "FREQ=YEARLY;BYDAY=4TH;BYMONTH=11"},
+		{"FREQ=DAILY;UNTIL=20260105T090000Z", "FREQ=DAILY;UNTIL=20260105T090000Z"},
+		{"FREQ=DAILY;UNTIL=20260105", "FREQ=DAILY;UNTIL=20260105T235959Z"}, // A date means the end of that day
+		{"FREQ=DAILY;INTERVAL=1", "FREQ=DAILY"},
+	}
+	for _, tt := range tests {
+		rule, err := ParseRule(tt.in)
+		if err != nil {
+			t.Errorf("ParseRule(%q) returned error: %v", tt.in, err)
+			continue
+		}
+		if got := rule.String(); got != tt.want {
+			t.Errorf("ParseRule(%q).String() = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseRuleInvalid(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"RRULE:",
+		"INTERVAL=2",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;COUNT=-1",
+		"FREQ=DAILY;COUNT=3;UNTIL=20260105",
+		"FREQ=DAILY;UNTIL=2026-01-05",
+		"FREQ=DAILY;BYSETPOS=1",
+		"FREQ=DAILY;COUNT",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=WEEKLY;BYDAY=1MO", // Numbered weekdays only make sense within a month
+		"FREQ=YEARLY;BYDAY=1MO", // or a year with BYMONTH
+		"FREQ=MONTHLY;BYDAY=0MO",
+		"FREQ=MONTHLY;BYDAY=6MO",
+		"FREQ=MONTHLY;BYMONTHDAY=0",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=MONTHLY;BYMONTHDAY=-32",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=YEARLY;BYMONTH=13",
+	} {
+		if rule, err := ParseRule(in); err == nil {
+			t.Errorf("ParseRule(%q) = %s, want an error", in, rule)
+		}
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  string
+		start time.Time
+		from  time.Time // Zero means the start
+		to    time.Time
+		limit int
+		want  []time.Time
+	}{
+		{
+			name:  "last Friday of the month",
+			rule:  "FREQ=MONTHLY;BYDAY=-1FR",
+			start: utc(2026, 1, 1, 9, 0),
+			limit: 4,
+			want:  []time.Time{utc(2026, 1, 30, 9, 0), utc(2026, 2, 27, 9, 0), utc(2026, 3, 27, 9, 0), utc(2026, 4, 24, 9, 0)},
+		},
+		{
+			name:  "second Tuesday of the month",
+			rule:  "FREQ=MONTHLY;BYDAY=2TU",
+			start: utc(2026, 1, 1, 0, 0),
+			limit: 4,
+			want:  []time.Time{utc(2026, 1, 13, 0, 0), utc(2026, 2, 10, 0, 0), utc(2026, 3, 10, 0, 0), utc(2026, 4, 14, 0, 0)},
+		},
+		{
+			name:  "fourth Thursday of November",
+			rule:  "FREQ=YEARLY;BYMONTH=11;BYDAY=4TH",
+			start: utc(2026, 1, 1, 12, 0),
+			limit: 2,
+			want:  []time.Time{utc(2026, 11, 26, 12, 0), utc(2027, 11, 25, 12, 0)},
+		},
+		{
+			name:  "last day of the month in a leap year",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=-1",
+			start: utc(2024, 1, 15, 8, 0),
+			limit: 4,
+			want:  []time.Time{utc(2024, 1, 31, 8, 0), utc(2024, 2, 29, 8, 0), utc(2024, 3, 31, 8, 0), utc(2024, 4, 30, 8, 0)},
+		},
+		{
+			name:  "third to last day of the month",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=-3",
+			start: utc(2025, 1, 1, 0, 0),
+			limit: 3,
+			want:  []time.Time{utc(2025, 1, 29, 0, 0), utc(2025, 2, 26, 0, 0), utc(2025, 3, 29, 0, 0)},
+		},
+		{
+			name:  "31st skips shorter months",
+			rule:  "FREQ=MONTHLY",
+			start: utc(2025, 1, 31, 10, 0),
+			limit: 4,
+			want:  []time.Time{utc(2025, 1, 31, 10, 0), utc(2025, 3, 31, 10, 0), utc(2025, 5, 31, 10, 0), utc(2025, 7, 31, 10, 0)},
+		},
+		{
+			name:  "29 February only in leap years",
+			rule:  "FREQ=YEARLY",
+			start: utc(2024, 2, 29, 0, 0),
+			limit: 3,
+			want:  []time.Time{utc(2024, 2, 29, 0, 0), utc(2028, 2, 29, 0, 0), utc(2032, 2, 29, 0, 0)},
+		},
+		{
+			name:  "every other week on Monday and Wednesday",
+			rule:  "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE",
+			start: utc(2026, 1, 5, 7, 30),
+			limit: 4,
+			want:  []time.Time{utc(2026, 1, 5, 7, 30), utc(2026, 1, 7, 7, 30), utc(2026, 1, 19, 7, 30), utc(2026, 1, 21, 7, 30)},
+		},
+		{
+			name:  "COUNT ends the series",
+			rule:  "FREQ=DAILY;COUNT=3",
+			start: utc(2026, 1, 1, 10, 0),
+			to:    utc(2026, 12, 31, 0, 0),
+			want:  []time.Time{utc(2026, 1, 1, 10, 0), utc(2026, 1, 2, 10, 0), utc(2026, 1, 3, 10, 0)},
+		},
+		{
+			name:  "COUNT counts from the start, not from the window",
+			rule:  "FREQ=DAILY;COUNT=5",
+			start: utc(2026, 1, 1, 10, 0),
+			from:  utc(2026, 1, 4, 0, 0),
+			to:    utc(2026, 12, 31, 0, 0),
+			want:  []time.Time{utc(2026, 1, 4, 10, 0), utc(2026, 1, 5, 10, 0)},
+		},
+		{
+			name:  "UNTIL as a date includes that whole day",
+			rule:  "FREQ=DAILY;UNTIL=20260103",
+			start: utc(2026, 1, 1, 22, 0),
+			to:    utc(2026, 12, 31, 0, 0),
+			want:  []time.Time{utc(2026, 1, 1, 22, 0), utc(2026, 1, 2, 22, 0), utc(2026, 1, 3, 22, 0)},
+		},
+		{
+			name:  "UNTIL as a time is inclusive",
+			rule:  "FREQ=DAILY;UNTIL=20260103T100000Z",
+			start: utc(2026, 1, 1, 10, 0),
+			to:    utc(2026, 12, 31, 0, 0),
+			want:  []time.Time{utc(2026, 1, 1, 10, 0), utc(2026, 1, 2, 10, 0), utc(2026, 1, 3, 10, 0)},
+		},
+		{
+			name:  "UNTIL before the time of day excludes that day",
+			rule:  "FREQ=DAILY;UNTIL=20260103T090000Z",
+			start: utc(2026, 1, 1, 10, 0),
+			to:    utc(2026, 12, 31, 0, 0),
+			want:  []time.Time{utc(2026, 1, 1, 10, 0), utc(2026, 1, 2, 10, 0)},
+		},
+		{
+			name:  "days before the start are skipped",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=1,20",
+			start: utc(2026, 1, 10, 0, 0),
+			limit: 3,
+			want:  []time.Time{utc(2026, 1, 20, 0, 0), utc(2026, 2, 1, 0, 0), utc(2026, 2, 20, 0, 0)},
+		},
+		{
+			name:  "window end",
+			rule:  "FREQ=WEEKLY",
+			start: utc(2026, 1, 5, 9, 0),
+			from:  utc(2026, 1, 10, 0, 0),
+			to:    utc(2026, 1, 26, 9, 0),
+			want:  []time.Time{utc(2026, 1, 12, 9, 0), utc(2026, 1, 19, 9, 0), utc(2026, 1, 26, 9, 0)},
+		},
+	}
+	for _, tt := range tests {
+		from := tt.from
+		if from.IsZero() {
+			from = tt.start
+		}
+		got := mustParseRule(t, tt.rule).Occurrences(tt.start, from, tt.to, tt.limit)
+		if !equalTimes(got, tt.want) {
+			t.Errorf("%s: Occurrences = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestOccurrencesKeepUTCTimeOfDay(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone data not available: %v", err)
+	}
+
+	// 09:00 EST is 14:00 UTC. Clocks go forward on 8 March, after which the series stays at
+	// 14:00 UTC (10:00 local) because occurrences are computed in UTC.
+	start := time.Date(2026, 3, 6, 9, 0, 0, 0, newYork)
+	got := mustParseRule(t, "FREQ=DAILY").Occurrences(start, start, time.Time{}, 4)
+	want := []time.Time{utc(2026, 3, 6, 14, 0), utc(2026, 3, 7, 14, 0), utc(2026, 3, 8, 14, 0), utc(2026, 3, 9, 14, 0)}
+	if !equalTimes(got, want) {
+		t.Fatalf("Occurrences = %v, want %v", got, want)
+	}
+	for _, occurrence := range got {
+		if occurrence.Location() != time.UTC {
+			t.Errorf("occurrence %v is in %v, want UTC", occurrence, occurrence.Location())
+		}
+	}
+
+	// A start late in the local evening falls on the next UTC day, and so do its weekdays
+	start = time.Date(2026, 1, 4, 20, 0, 0, 0, newYork) // Sunday 20:00 EST is Monday 01:00 UTC
+	got = mustParseRule(t, "FREQ=WEEKLY").Occurrences(start, start, time.Time{}, 2)
+	want = []time.Time{utc(2026, 1, 5, 1, 0), utc(2026, 1, 12, 1, 0)}
+	if !equalTimes(got, want) {
+		t.Errorf("Occurrences = %v, want %v", got, want)
+	}
+}
+
+func TestCountBefore(t *testing.T) {
+	start := utc(2026, 1, 1, 10, 0)
+	tests := []struct {
+		rule string
+		t    time.Time
+		want int
+	}{
+		{"FREQ=DAILY", start, 0},
+		{"FREQ=DAILY", start.Add(-time.Hour), 0},
+		{"FREQ=DAILY", start.Add(time.Nanosecond), 1},
+		{"FREQ=DAILY", utc(2026, 1, 4, 10, 0), 3}, // An occurrence exactly at t is not before it
+		{"FREQ=DAILY", utc(2026, 1, 4, 10, 1), 4},
+		{"FREQ=DAILY;COUNT=2", utc(2027, 1, 1, 0, 0), 2},
+		{"FREQ=DAILY;UNTIL=20260102", utc(2027, 1, 1, 0, 0), 2},
+		{"FREQ=MONTHLY;BYDAY=-1FR", utc(2026, 7, 1, 0, 0), 6},
+	}
+	for _, tt := range tests {
+		if got := mustParseRule(t, tt.rule).CountBefore(start, tt.t); got != tt.want {
+			t.Errorf("%s: CountBefore(%v) = %d, want %d", tt.rule, tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestAfter(t *testing.T) {
+	start := utc(2026, 1, 1, 9, 0)
+	rule := mustParseRule(t, "FREQ=MONTHLY;BYDAY=-1FR;COUNT=2")
+
+	next := rule.After(start, utc(2026, 1, 30, 9, 0))
+	if next == nil || !next.Equal(utc(2026, 2, 27, 9, 0)) {
+		t.Errorf("After(30 January) = %v, want 27 February", next)
+	}
+	if next := rule.After(start, utc(2026, 2, 27, 9, 0)); next != nil {
+		t.Errorf("After the last occurrence = %v, want nil", next)
+	}
+}
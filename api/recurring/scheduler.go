@@ -0,0 +1,135 @@
+package recurring
+
+import (
+	"fmt"
+	"log"
+	"moneyplanner/api/transactions"
+	"moneyplanner/database"
+	"moneyplanner/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	schedulerInterval = time.Minute
+	// maxPostsPerRun bounds how much one run catches up; the rest follows on the next run
+	maxPostsPerRun = 1000
+)
+
+// PostDue posts every occurrence due by now of the enabled recurring transactions of live
+// wallets and returns how many transactions were created. Each occurrence is claimed by moving
+// the template's next_occurrence past it in the same DB transaction that posts it, so an
+// occurrence is never posted twice, even across restarts.
+func PostDue(now time.Time) (int, error) {
+	var due []models.RecurringTransaction
+	if err := database.DB.
+		Joins("JOIN wallets ON wallets.wallet_id = recurring_transactions.wallet_id AND wallets.deleted_at IS NULL").
+		Where("recurring_transactions.is_enabled = ? AND recurring_transactions.next_occurrence <= ?", true, now).
+		Order("recurring_transactions.next_occurrence").
+		Find(&due).Error; err != nil {
+		return 0, fmt.Errorf("failed to load due recurring transactions: %w", err)
+	}
+
+	posted := 0
+	for i := range due {
+		recurring := &due[i]
+		rule, err := ParseRule(recurring.RRule)
+		if err != nil {
+			log.Printf("Warning: Recurring transaction %d has an invalid rule: %v", recurring.RecurringTransactionID, err)
+			continue
+		}
+
+		for recurring.NextOccurrence != nil && !recurring.NextOccurrence.After(now) && posted < maxPostsPerRun {
+			created, err := postOccurrence(recurring, rule)
+			if err != nil {
+				log.Printf("Warning: Recurring transaction %d could not post %s: %v",
+					recurring.RecurringTransactionID, recurring.NextOccurrence.Format(time.RFC3339), err)
+				break
+			}
+			if created {
+				posted++
+			}
+		}
+	}
+	return posted, nil
+}
+
+// postOccurrence posts the template's next occurrence unless it is skipped, and advances the
+// template to the occurrence after it. It reports whether a transaction was created.
+func postOccurrence(recurring *models.RecurringTransaction, rule *Rule) (bool, error) {
+	occurrence := *recurring.NextOccurrence
+	next := firstOccurrence(rule, recurring.StartTime, occurrence.Add(time.Nanosecond))
+
+	created := false
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.RecurringTransaction{}).
+			Where("recurring_transaction_id = ? AND next_occurrence = ?", recurring.RecurringTransactionID, occurrence).
+			Update("next_occurrence", next)
+		if result.Error != nil {
+			return fmt.Errorf("failed to advance recurring transaction: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("recurring transaction changed meanwhile")
+		}
+
+		var skipped int64
+		if err := tx.Model(&models.RecurringSkip{}).
+			Where("recurring_transaction_id = ? AND occurrence_time = ?", recurring.RecurringTransactionID, occurrence).
+			Count(&skipped).Error; err != nil {
+			return fmt.Errorf("failed to check skipped occurrences: %w", err)
+		}
+		if skipped > 0 {
+			return nil
+		}
+
+		_, err := transactions.CreateTransactionInTx(tx, &transactions.TransactionCreationRequest{
+			WalletID:               recurring.WalletID,
+			CategoryID:             recurring.CategoryID,
+			Amount:                 recurring.Amount,
+			Currency:               recurring.Currency,
+			PersonID:               recurring.PersonID,
+			Note:                   recurring.Note,
+			TransactionTime:        &occurrence,
+			UserID:                 recurring.UserID,
+			RecurringTransactionID: &recurring.RecurringTransactionID,
+			OccurrenceTime:         &occurrence,
+		})
+		created = err == nil
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+
+	recurring.NextOccurrence = next
+	return created, nil
+}
+
+// runScheduler posts due occurrences, skipping runs until the tables are migrated
+func runScheduler() {
+	if database.DB == nil || !database.DB.Migrator().HasTable(&models.RecurringTransaction{}) {
+		return
+	}
+	posted, err := PostDue(time.Now())
+	if err != nil {
+		log.Printf("Warning: Recurring transaction run failed: %v", err)
+		return
+	}
+	if posted > 0 {
+		log.Printf("✓ Posted %d recurring transaction(s)", posted)
+	}
+}
+
+// StartScheduler posts due recurring transactions now, catching up on any missed while the
+// server was down, and then every minute
+func StartScheduler() {
+	go func() {
+		runScheduler()
+		ticker := time.NewTicker(schedulerInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			runScheduler()
+		}
+	}()
+}
@@ -0,0 +1,66 @@
+package recurring
+
+import (
+	"moneyplanner/models"
+	"time"
+)
+
+type RecurringTransactionCreationRequest struct {
+	WalletID   uint         `json:"wallet_id"`
+	CategoryID uint         `json:"category_id"`
+	Amount     models.Money `json:"amount"`
+	Currency   *string      `json:"currency,omitempty"` // Currency of amount; defaults to the wallet's
+	PersonID   *uint        `json:"person_id,omitempty"`
+	Note       *string      `json:"note,omitempty"`
+	RRule      string       `json:"rrule"`                // e.g. FREQ=MONTHLY;BYMONTHDAY=1
+	StartTime  *time.Time   `json:"start_time,omitempty"` // Defaults to now; past occurrences are caught up
+	IsEnabled  *bool        `json:"is_enabled,omitempty"`
+	UserID     uint         `json:"-"` // Set from the caller
+}
+
+type RecurringTransactionUpdateRequest struct {
+	CategoryID *uint         `json:"category_id,omitempty"`
+	Amount     *models.Money `json:"amount,omitempty"`
+	Currency   *string       `json:"currency,omitempty"` // "" switches back to the wallet's currency
+	PersonID   *uint         `json:"person_id,omitempty"`
+	Note       *string       `json:"note,omitempty"`
+	RRule      *string       `json:"rrule,omitempty"`
+	StartTime  *time.Time    `json:"start_time,omitempty"`
+	IsEnabled  *bool         `json:"is_enabled,omitempty"`
+
+	// Edits this and future occurrences only: the series is split at this occurrence and the
+	// changes go to a new recurring transaction starting there
+	ApplyFrom *time.Time `json:"apply_from,omitempty"`
+}
+
+type RecurringTransactionFilter struct {
+	WalletIDs []uint // Restricts results to these wallets when non-nil
+	WalletID  *uint
+}
+
+type OccurrenceStatus string
+
+const (
+	OccurrenceScheduled OccurrenceStatus = "scheduled"
+	OccurrencePosted    OccurrenceStatus = "posted"
+	OccurrenceSkipped   OccurrenceStatus = "skipped"
+	OccurrenceMissed    OccurrenceStatus = "missed" // Due before the series was last changed; never posted
+)
+
+// Occurrence is one date of a recurring transaction and what became of it
+type Occurrence struct {
+	RecurringTransactionID uint             `json:"recurring_transaction_id"`
+	OccurrenceTime         time.Time        `json:"occurrence_time"`
+	Status                 OccurrenceStatus `json:"status"`
+	TransactionID          *uint            `json:"transaction_id,omitempty"` // Set once posted
+	WalletID               uint             `json:"wallet_id"`
+	CategoryID             uint             `json:"category_id"`
+	Amount                 models.Money     `json:"amount"`
+	Currency               *string          `json:"currency"`
+	Note                   *string          `json:"note"`
+}
+
+// SkipRequest names the occurrence to skip
+type SkipRequest struct {
+	OccurrenceTime time.Time `json:"occurrence_time"`
+}
@@ -2,23 +2,43 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"log"
+	"mime"
+	"mime/multipart"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	attachmentsAPI "moneyplanner/api/attachments"
+	authAPI "moneyplanner/api/auth"
+	balanceAPI "moneyplanner/api/balance"
+	billsAPI "moneyplanner/api/bills"
+	budgetsAPI "moneyplanner/api/budgets"
 	initAPI "moneyplanner/api/init"
 	usersAPI "moneyplanner/api/users"
 	userWalletAPI "moneyplanner/api/userwallet"
 	walletAPI "moneyplanner/api/wallet"
 
 	categoriesAPI "moneyplanner/api/categories"
+	debtsAPI "moneyplanner/api/debts"
+	exchangeRatesAPI "moneyplanner/api/exchangerates"
+	goalsAPI "moneyplanner/api/goals"
 	personsAPI "moneyplanner/api/persons"
+	reconcileAPI "moneyplanner/api/reconcile"
+	recurringAPI "moneyplanner/api/recurring"
+	sharedExpensesAPI "moneyplanner/api/sharedexpenses"
+	summaryAPI "moneyplanner/api/summary"
+	tagsAPI "moneyplanner/api/tags"
 	transactionsAPI "moneyplanner/api/transactions"
+	transfersAPI "moneyplanner/api/transfers"
+	trashAPI "moneyplanner/api/trash"
 	userWalletGroupAPI "moneyplanner/api/userwalletgroup"
 	walletGroupAPI "moneyplanner/api/walletgroup"
 	walletGroupWalletAPI "moneyplanner/api/walletgroupwallet"
+	"moneyplanner/models"
 )
 
 // RegisterRoutes registers all API routes
@@ -31,6 +51,12 @@ func RegisterRoutes(mux *http.ServeMux) {
 	// Init Done API endpoint (GET)
 	mux.HandleFunc("/api/initdone", handleInitDone)
 
+	// Auth API endpoints
+	mux.HandleFunc("/api/auth/login", handleAuthLogin)
+	mux.HandleFunc("/api/auth/refresh", handleAuthRefresh)
+	mux.HandleFunc("/api/auth/logout", handleAuthLogout)
+	mux.HandleFunc("/api/auth/me", handleAuthMe)
+
 	// User CRUD API endpoints
 	mux.HandleFunc("/api/users", handleUsers)
 	mux.HandleFunc("/api/users/", handleUserDetail)
@@ -51,6 +77,42 @@ func RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/transactions", handleTransactions)
 	mux.HandleFunc("/api/transactions/", handleTransactionDetail)
 
+	// Transfer routes
+	mux.HandleFunc("/api/transfers", handleTransfers)
+	mux.HandleFunc("/api/transfers/", handleTransferDetail)
+
+	// Exchange rate routes
+	mux.HandleFunc("/api/exchangerates", handleExchangeRates)
+	mux.HandleFunc("/api/exchangerates/", handleExchangeRateDetail)
+
+	// Budget routes
+	mux.HandleFunc("/api/budgets", handleBudgets)
+	mux.HandleFunc("/api/budgets/", handleBudgetDetail)
+
+	// Recurring transaction routes
+	mux.HandleFunc("/api/recurring", handleRecurringTransactions)
+	mux.HandleFunc("/api/recurring/", handleRecurringTransactionDetail)
+
+	// Bill routes
+	mux.HandleFunc("/api/bills", handleBills)
+	mux.HandleFunc("/api/bills/", handleBillDetail)
+
+	// Savings goal routes
+	mux.HandleFunc("/api/goals", handleGoals)
+	mux.HandleFunc("/api/goals/", handleGoalDetail)
+
+	// Debt routes
+	mux.HandleFunc("/api/debts", handleDebts)
+	mux.HandleFunc("/api/debts/", handleDebtDetail)
+
+	// Shared expense routes
+	mux.HandleFunc("/api/shared-expenses", handleSharedExpenses)
+	mux.HandleFunc("/api/shared-expenses/", handleSharedExpenseDetail)
+
+	// Tag routes
+	mux.HandleFunc("/api/tags", handleTags)
+	mux.HandleFunc("/api/tags/", handleTagDetail)
+
 	log.Println("✓ API routes registered")
 }
 
@@ -63,6 +125,22 @@ func handleInit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Once initialized, init may only be re-run (e.g. for migrations) by an admin
+	if initAPI.CheckInitStatus("moneyplanner.db").InitDone {
+		user := authAPI.CurrentUser(r)
+		if user == nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{
+				"error": "Database is already initialized; admin authentication required",
+			})
+			return
+		}
+		if !user.IsAdmin {
+			writeForbidden(w, "Only admins can re-run initialization")
+			return
+		}
+	}
+
 	var req initAPI.InitRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
@@ -102,10 +180,126 @@ func handleInitDone(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(resp)
 }
 
+// handleAuthLogin handles POST /api/auth/login - Exchange credentials for a token pair
+func handleAuthLogin(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req authAPI.LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error": "Invalid request body: " + err.Error(),
+		})
+		return
+	}
+
+	tokens, err := authAPI.Login(&req)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Logged in successfully",
+		"data":    tokens,
+	})
+}
+
+// handleAuthRefresh handles POST /api/auth/refresh - Rotate a refresh token
+func handleAuthRefresh(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req authAPI.RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error": "Invalid request body: " + err.Error(),
+		})
+		return
+	}
+
+	tokens, err := authAPI.Refresh(&req)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Token refreshed successfully",
+		"data":    tokens,
+	})
+}
+
+// handleAuthLogout handles POST /api/auth/logout - Revoke the current session
+func handleAuthLogout(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := authAPI.Logout(authAPI.BearerToken(r)); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Logged out successfully",
+	})
+}
+
+// handleAuthMe handles GET /api/auth/me - Return the authenticated user
+func handleAuthMe(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Current user retrieved successfully",
+		"data":    authAPI.CurrentUser(r),
+	})
+}
+
 // handleUsers handles user list and creation (POST /api/users)
 func handleUsers(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
+	// Managing accounts is for admins; users manage their own through /api/users/{id}
+	if !requireAdmin(w, r) {
+		return
+	}
+
 	switch r.Method {
 	case http.MethodPost:
 		handleUserCreate(w, r)
@@ -195,6 +389,9 @@ func handleUserDetail(w http.ResponseWriter, r *http.Request) {
 		if len(parts) == 5 || parts[5] == "" {
 			switch r.Method {
 			case http.MethodGet:
+				if !requireSelfOrAdmin(w, r, uint(userID)) {
+					return
+				}
 				handleUserWalletList(w, r, uint(userID))
 			case http.MethodPut:
 				handleUserWalletReplace(w, r, uint(userID))
@@ -216,6 +413,15 @@ func handleUserDetail(w http.ResponseWriter, r *http.Request) {
 		}
 		walletID := uint(walletID64)
 
+		// Managing membership requires ownership; any member may remove themselves
+		requiredRole := models.WalletRoleOwner
+		if r.Method == http.MethodDelete && authAPI.CurrentUser(r).UserID == uint(userID) {
+			requiredRole = models.WalletRoleViewer
+		}
+		if !requireWalletRole(w, r, walletID, requiredRole) {
+			return
+		}
+
 		switch r.Method {
 		case http.MethodPost:
 			handleUserWalletAttach(w, r, uint(userID), walletID)
@@ -227,16 +433,65 @@ func handleUserDetail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Subroute: /api/users/{id}/apikeys...
+	if len(parts) >= 5 && parts[4] == "apikeys" {
+		if !requireAPIKeyManagement(w, r, uint(userID)) {
+			return
+		}
+
+		// /api/users/{id}/apikeys
+		if len(parts) == 5 || parts[5] == "" {
+			switch r.Method {
+			case http.MethodGet:
+				handleUserAPIKeyList(w, r, uint(userID))
+			case http.MethodPost:
+				handleUserAPIKeyCreate(w, r, uint(userID))
+			default:
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
+		// /api/users/{id}/apikeys/{keyId}
+		apiKeyID64, err := strconv.ParseUint(parts[5], 10, 32)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{
+				"error": "Invalid API key ID: " + err.Error(),
+			})
+			return
+		}
+
+		if r.Method != http.MethodDelete {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handleUserAPIKeyRevoke(w, r, uint(userID), uint(apiKeyID64))
+		return
+	}
+
 	// Subroute: /api/users/{id}/walletgroups
 	if len(parts) >= 5 && parts[4] == "walletgroups" {
 		if r.Method != http.MethodGet {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
+		if !requireSelfOrAdmin(w, r, uint(userID)) {
+			return
+		}
 		handleUserWalletGroupList(w, r, uint(userID))
 		return
 	}
 
+	// Users see and edit their own account; only admins delete accounts
+	if r.Method == http.MethodDelete {
+		if !requireAdmin(w, r) {
+			return
+		}
+	} else if !requireSelfOrAdmin(w, r, uint(userID)) {
+		return
+	}
+
 	switch r.Method {
 	case http.MethodGet:
 		handleUserGet(w, r, uint(userID))
@@ -249,6 +504,96 @@ func handleUserDetail(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handleUserAPIKeyList handles GET /api/users/{id}/apikeys - List a user's API keys
+func handleUserAPIKeyList(w http.ResponseWriter, r *http.Request, userID uint) {
+	keys, err := authAPI.ListAPIKeys(userID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "API keys retrieved successfully",
+		"data":    keys,
+	})
+}
+
+// handleUserAPIKeyCreate handles POST /api/users/{id}/apikeys - Issue an API key
+func handleUserAPIKeyCreate(w http.ResponseWriter, r *http.Request, userID uint) {
+	var req authAPI.APIKeyCreationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error": "Invalid request body: " + err.Error(),
+		})
+		return
+	}
+
+	// Keys issued for someone else may only reach wallets the caller owns
+	if authAPI.CurrentUser(r).UserID != userID {
+		var scopedWalletIDs []uint
+		for _, s := range req.Scopes {
+			scope, err := authAPI.ParseScope(s)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{
+					"error": err.Error(),
+				})
+				return
+			}
+			if scope.WalletID == nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{
+					"error": "Scopes for another user's key must name a wallet, e.g. transactions:write@3",
+				})
+				return
+			}
+			scopedWalletIDs = append(scopedWalletIDs, *scope.WalletID)
+		}
+		if !requireWalletsRole(w, r, scopedWalletIDs, models.WalletRoleOwner) {
+			return
+		}
+	}
+
+	key, err := authAPI.CreateAPIKey(userID, &req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "API key created successfully; store the key now, it will not be shown again",
+		"data":    key,
+	})
+}
+
+// handleUserAPIKeyRevoke handles DELETE /api/users/{id}/apikeys/{keyId} - Revoke an API key
+func handleUserAPIKeyRevoke(w http.ResponseWriter, r *http.Request, userID, apiKeyID uint) {
+	if err := authAPI.RevokeAPIKey(userID, apiKeyID); err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "API key revoked successfully",
+	})
+}
+
 // handleUserGet handles GET /api/users/{id} - Get user by ID
 func handleUserGet(w http.ResponseWriter, r *http.Request, userID uint) {
 	user, err := usersAPI.GetUserByID(userID)
@@ -279,6 +624,22 @@ func handleUserUpdate(w http.ResponseWriter, r *http.Request, userID uint) {
 		return
 	}
 
+	caller := authAPI.CurrentUser(r)
+	if req.Type != nil && !caller.IsAdmin {
+		writeForbidden(w, "Only an admin can change a user's type")
+		return
+	}
+	// Changing your own password takes the current one; admins can reset anyone else's
+	if req.Password != nil && caller.UserID == userID {
+		if req.CurrentPassword == nil || !usersAPI.VerifyPassword(caller, *req.CurrentPassword) {
+			writeForbidden(w, "current_password is missing or wrong")
+			return
+		}
+	}
+	if req.DefaultWalletID != nil && !requireDefaultWallet(w, r, userID, *req.DefaultWalletID) {
+		return
+	}
+
 	user, err := usersAPI.UpdateUser(userID, &req)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
@@ -343,6 +704,13 @@ func handleWalletCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The creator becomes a member of the new wallet
+	if err := userWalletAPI.AttachWalletToUser(authAPI.CurrentUser(r).UserID, wallet.WalletID, models.WalletRoleOwner); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
@@ -352,13 +720,21 @@ func handleWalletCreate(w http.ResponseWriter, r *http.Request) {
 }
 
 func handleWalletList(w http.ResponseWriter, r *http.Request) {
-	wallets, err := walletAPI.ListAllWallets()
+	userWallets, err := userWalletAPI.ListUserWallets(authAPI.CurrentUser(r).UserID)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
 	}
 
+	// API keys only see the wallets their scopes cover
+	wallets := make([]models.Wallet, 0, len(userWallets))
+	for _, wallet := range userWallets {
+		if authAPI.AllowsWallet(r, wallet.WalletID) {
+			wallets = append(wallets, wallet)
+		}
+	}
+
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
@@ -377,6 +753,26 @@ func handleWalletDetail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// /api/wallets/summary
+	if len(parts) == 4 && parts[3] == "summary" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handleWalletSummary(w, r)
+		return
+	}
+
+	// /api/wallets/trash
+	if len(parts) == 4 && parts[3] == "trash" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handleTrashedWalletList(w, r)
+		return
+	}
+
 	walletIDStr := parts[3]
 	walletID64, err := strconv.ParseUint(walletIDStr, 10, 32)
 	if err != nil {
@@ -386,21 +782,104 @@ func handleWalletDetail(w http.ResponseWriter, r *http.Request) {
 	}
 	walletID := uint(walletID64)
 
-	// Subroute: /api/wallets/{walletId}/categories...
-	if len(parts) >= 5 && parts[4] == "categories" {
-		// /api/wallets/{walletId}/categories
-		if len(parts) == 5 || parts[5] == "" {
-			switch r.Method {
-			case http.MethodGet:
-				handleWalletCategoryList(w, r, walletID)
-			case http.MethodPost:
-				handleWalletCategoryCreate(w, r, walletID)
-			default:
-				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-			}
+	if !requireWalletRole(w, r, walletID, methodRole(r)) {
+		return
+	}
+
+	// Subroute: /api/wallets/{walletId}/trash
+	if len(parts) == 5 && parts[4] == "trash" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
-
+		handleWalletTrash(w, r, walletID)
+		return
+	}
+
+	// Subroute: /api/wallets/{walletId}/restore
+	if len(parts) == 5 && parts[4] == "restore" {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !requireWalletRole(w, r, walletID, models.WalletRoleOwner) {
+			return
+		}
+		handleWalletRestore(w, r, walletID)
+		return
+	}
+
+	// Subroute: /api/wallets/{walletId}/balance
+	if len(parts) == 5 && parts[4] == "balance" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handleWalletBalanceAt(w, r, walletID)
+		return
+	}
+
+	// Subroute: /api/wallets/{walletId}/balance-history
+	if len(parts) == 5 && parts[4] == "balance-history" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handleWalletBalanceHistory(w, r, walletID)
+		return
+	}
+
+	// Subroute: /api/wallets/{walletId}/reconcile
+	if len(parts) == 5 && parts[4] == "reconcile" {
+		switch r.Method {
+		case http.MethodGet:
+			handleWalletReconcile(w, r, walletID)
+		case http.MethodPost:
+			if !requireWalletRole(w, r, walletID, models.WalletRoleOwner) {
+				return
+			}
+			handleWalletReconcileRepair(w, r, walletID)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	// Subroute: /api/wallets/{walletId}/goals
+	if len(parts) == 5 && parts[4] == "goals" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handleWalletGoals(w, r, walletID)
+		return
+	}
+
+	// Subroute: /api/wallets/{walletId}/members
+	if len(parts) == 5 && parts[4] == "members" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handleWalletMemberList(w, r, walletID)
+		return
+	}
+
+	// Subroute: /api/wallets/{walletId}/categories...
+	if len(parts) >= 5 && parts[4] == "categories" {
+		// /api/wallets/{walletId}/categories
+		if len(parts) == 5 || parts[5] == "" {
+			switch r.Method {
+			case http.MethodGet:
+				handleWalletCategoryList(w, r, walletID)
+			case http.MethodPost:
+				handleWalletCategoryCreate(w, r, walletID)
+			default:
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
 		// /api/wallets/{walletId}/categories/tree
 		if len(parts) == 6 && parts[5] == "tree" && r.Method == http.MethodGet {
 			handleWalletCategoryTree(w, r, walletID)
@@ -418,6 +897,10 @@ func handleWalletDetail(w http.ResponseWriter, r *http.Request) {
 			}
 			categoryID := uint(categoryID64)
 
+			if !requireCategoryInWallet(w, walletID, categoryID) {
+				return
+			}
+
 			switch r.Method {
 			case http.MethodGet:
 				handleWalletCategoryGet(w, r, categoryID)
@@ -431,6 +914,18 @@ func handleWalletDetail(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		// /api/wallets/{walletId}/categories/{categoryId}/restore
+		if len(parts) == 7 && parts[6] == "restore" && r.Method == http.MethodPost {
+			categoryID64, err := strconv.ParseUint(parts[5], 10, 32)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "Invalid category ID: " + err.Error()})
+				return
+			}
+			handleWalletCategoryRestore(w, r, walletID, uint(categoryID64))
+			return
+		}
+
 		// /api/wallets/{walletId}/categories/{categoryId}/sync-global
 		if len(parts) == 7 && parts[6] == "sync-global" && r.Method == http.MethodPost {
 			categoryIDStr := parts[5]
@@ -441,6 +936,9 @@ func handleWalletDetail(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 			categoryID := uint(categoryID64)
+			if !requireCategoryInWallet(w, walletID, categoryID) {
+				return
+			}
 			handleWalletCategorySyncGlobal(w, r, categoryID)
 			return
 		}
@@ -484,6 +982,18 @@ func handleWalletDetail(w http.ResponseWriter, r *http.Request) {
 			}
 			return
 		}
+
+		// /api/wallets/{walletId}/transactions/{transactionId}/restore
+		if len(parts) == 7 && parts[6] == "restore" && r.Method == http.MethodPost {
+			transactionID64, err := strconv.ParseUint(parts[5], 10, 32)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "Invalid transaction ID: " + err.Error()})
+				return
+			}
+			handleWalletTransactionRestore(w, r, walletID, uint(transactionID64))
+			return
+		}
 	}
 
 	switch r.Method {
@@ -506,6 +1016,7 @@ func handleWalletGet(w http.ResponseWriter, r *http.Request, walletID uint) {
 		return
 	}
 
+	setETag(w, wallet.Version)
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
@@ -522,13 +1033,30 @@ func handleWalletUpdate(w http.ResponseWriter, r *http.Request, walletID uint) {
 		return
 	}
 
+	// Only owners may adjust the balance
+	if (req.Balance != nil || req.OpeningBalance != nil) && !requireWalletRole(w, r, walletID, models.WalletRoleOwner) {
+		return
+	}
+	req.UpdatedBy = authAPI.CurrentUser(r).UserID
+
+	expectedVersion, ok := parseIfMatch(w, r)
+	if !ok {
+		return
+	}
+	req.ExpectedVersion = expectedVersion
+
 	wallet, err := walletAPI.UpdateWallet(walletID, &req)
+	if errors.Is(err, models.ErrVersionConflict) {
+		writeWalletConflict(w, walletID)
+		return
+	}
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
 	}
 
+	setETag(w, wallet.Version)
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
@@ -537,9 +1065,22 @@ func handleWalletUpdate(w http.ResponseWriter, r *http.Request, walletID uint) {
 	})
 }
 
-func handleWalletDelete(w http.ResponseWriter, r *http.Request, walletID uint) {
-	if err := walletAPI.DeleteWallet(walletID); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+// handleWalletBalanceAt handles GET /api/wallets/{id}/balance?at=... - Balance at a point in time
+func handleWalletBalanceAt(w http.ResponseWriter, r *http.Request, walletID uint) {
+	at := time.Now()
+	if atStr := r.URL.Query().Get("at"); atStr != "" {
+		parsed, err := balanceAPI.ParseAt(atStr)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		at = parsed
+	}
+
+	snapshot, err := balanceAPI.BalanceAt(walletID, at)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
 	}
@@ -547,26 +1088,45 @@ func handleWalletDelete(w http.ResponseWriter, r *http.Request, walletID uint) {
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
-		"message": "Wallet deleted successfully",
+		"message": "Wallet balance retrieved successfully",
+		"data":    snapshot,
 	})
 }
 
-func handleUserWalletAttach(w http.ResponseWriter, r *http.Request, userID, walletID uint) {
-	if err := userWalletAPI.AttachWalletToUser(userID, walletID); err != nil {
+// handleWalletBalanceHistory handles GET /api/wallets/{id}/balance-history?from=...&to=...&interval=day|week|month
+func handleWalletBalanceHistory(w http.ResponseWriter, r *http.Request, walletID uint) {
+	query := r.URL.Query()
+	req := &balanceAPI.HistoryRequest{}
+
+	interval, err := balanceAPI.ParseInterval(query.Get("interval"))
+	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
 	}
+	req.Interval = interval
 
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"success": true,
-		"message": "Wallet attached to user",
-	})
-}
+	if fromStr := query.Get("from"); fromStr != "" {
+		from, err := balanceAPI.ParseAt(fromStr)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		req.From = &from
+	}
+	if toStr := query.Get("to"); toStr != "" {
+		to, err := balanceAPI.ParseAt(toStr)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		req.To = &to
+	}
 
-func handleUserWalletDetach(w http.ResponseWriter, r *http.Request, userID, walletID uint) {
-	if err := userWalletAPI.DetachWalletFromUser(userID, walletID); err != nil {
+	history, err := balanceAPI.BalanceHistory(walletID, req)
+	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
@@ -575,12 +1135,14 @@ func handleUserWalletDetach(w http.ResponseWriter, r *http.Request, userID, wall
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
-		"message": "Wallet detached from user",
+		"message": "Wallet balance history retrieved successfully",
+		"data":    history,
 	})
 }
 
-func handleUserWalletList(w http.ResponseWriter, r *http.Request, userID uint) {
-	wallets, err := userWalletAPI.ListUserWallets(userID)
+// handleWalletReconcile handles GET /api/wallets/{id}/reconcile - Compare stored and computed balance
+func handleWalletReconcile(w http.ResponseWriter, r *http.Request, walletID uint) {
+	report, err := reconcileAPI.ReconcileWallet(walletID)
 	if err != nil {
 		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
@@ -590,20 +1152,24 @@ func handleUserWalletList(w http.ResponseWriter, r *http.Request, userID uint) {
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
-		"message": "User wallets retrieved successfully",
-		"data":    wallets,
+		"message": "Wallet reconciled successfully",
+		"data":    report,
 	})
 }
 
-func handleUserWalletReplace(w http.ResponseWriter, r *http.Request, userID uint) {
-	var req userWalletAPI.ReplaceWalletsRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body: " + err.Error()})
-		return
+// handleWalletReconcileRepair handles POST /api/wallets/{id}/reconcile - Book the discrepancy as an adjustment
+func handleWalletReconcileRepair(w http.ResponseWriter, r *http.Request, walletID uint) {
+	var req reconcileAPI.RepairRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body: " + err.Error()})
+			return
+		}
 	}
 
-	if err := userWalletAPI.ReplaceUserWallets(userID, req.WalletIDs); err != nil {
+	report, err := reconcileAPI.RepairWallet(walletID, authAPI.CurrentUser(r).UserID, &req)
+	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
@@ -612,48 +1178,86 @@ func handleUserWalletReplace(w http.ResponseWriter, r *http.Request, userID uint
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
-		"message": "User wallets replaced successfully",
+		"message": "Wallet balance repaired successfully",
+		"data":    report,
 	})
 }
 
-func handleWalletGroups(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
+// handleWalletDelete handles DELETE /api/wallets/{id}?mode=refuse|cascade|reassign&target_wallet_id=&dry_run=
+func handleWalletDelete(w http.ResponseWriter, r *http.Request, walletID uint) {
+	if !requireWalletRole(w, r, walletID, models.WalletRoleOwner) {
+		return
+	}
 
-	switch r.Method {
-	case http.MethodPost:
-		handleWalletGroupCreate(w, r)
-	case http.MethodGet:
-		handleWalletGroupList(w, r)
-	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	query := r.URL.Query()
+	req := walletAPI.WalletDeletionRequest{
+		Mode:      walletAPI.DeletionMode(query.Get("mode")),
+		DeletedBy: authAPI.CurrentUser(r).UserID,
 	}
-}
+	if targetStr := query.Get("target_wallet_id"); targetStr != "" {
+		targetID, err := strconv.ParseUint(targetStr, 10, 32)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid target_wallet_id"})
+			return
+		}
+		target := uint(targetID)
+		req.TargetWalletID = &target
 
-func handleWalletGroupCreate(w http.ResponseWriter, r *http.Request) {
-	var req walletGroupAPI.WalletGroupCreationRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body: " + err.Error()})
+		// Moving transactions in writes to the target wallet
+		if !requireWalletRole(w, r, target, models.WalletRoleEditor) {
+			return
+		}
+	}
+	if dryRunStr := query.Get("dry_run"); dryRunStr != "" {
+		dryRun, err := strconv.ParseBool(dryRunStr)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid dry_run"})
+			return
+		}
+		req.DryRun = dryRun
+	}
+
+	expectedVersion, ok := parseIfMatch(w, r)
+	if !ok {
 		return
 	}
+	req.ExpectedVersion = expectedVersion
 
-	wg, err := walletGroupAPI.CreateWalletGroup(&req)
+	result, err := walletAPI.DeleteWallet(walletID, &req)
+	if errors.Is(err, models.ErrVersionConflict) {
+		writeWalletConflict(w, walletID)
+		return
+	}
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
+	message := "Wallet deleted successfully"
+	if req.DryRun {
+		message = "Wallet deletion previewed; nothing was changed"
+	}
+	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
-		"message": "Wallet group created successfully",
-		"data":    wg,
+		"message": message,
+		"data":    result,
 	})
 }
 
-func handleWalletGroupList(w http.ResponseWriter, r *http.Request) {
-	groups, err := walletGroupAPI.ListAllWalletGroups()
+// handleTrashedWalletList handles GET /api/wallets/trash - The caller's deleted wallets
+func handleTrashedWalletList(w http.ResponseWriter, r *http.Request) {
+	walletIDs, err := userWalletAPI.ListUserWalletIDs(authAPI.CurrentUser(r).UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	wallets, err := trashAPI.ListTrashedWallets(authAPI.FilterWalletIDs(r, walletIDs))
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
@@ -663,53 +1267,265 @@ func handleWalletGroupList(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
-		"message": "Wallet groups retrieved successfully",
-		"data":    groups,
+		"message": "Trashed wallets retrieved successfully",
+		"data":    wallets,
 	})
 }
 
-func handleWalletGroupDetail(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-
-	parts := strings.Split(r.URL.Path, "/")
-	if len(parts) < 4 {
-		http.Error(w, "Invalid URL path", http.StatusBadRequest)
+// handleWalletTrash handles GET /api/wallets/{id}/trash - Deleted items of a wallet
+func handleWalletTrash(w http.ResponseWriter, r *http.Request, walletID uint) {
+	trash, err := trashAPI.ListWalletTrash(walletID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
 	}
 
-	groupIDStr := parts[3]
-	groupID64, err := strconv.ParseUint(groupIDStr, 10, 32)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Wallet trash retrieved successfully",
+		"data":    trash,
+	})
+}
+
+// handleWalletRestore handles POST /api/wallets/{id}/restore - Take a wallet out of the trash
+func handleWalletRestore(w http.ResponseWriter, r *http.Request, walletID uint) {
+	wallet, err := walletAPI.RestoreWallet(walletID)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid wallet group ID: " + err.Error()})
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
 	}
-	groupID := uint(groupID64)
 
-	// Subroute: /api/walletgroups/{id}/wallets...
-	if len(parts) >= 5 && parts[4] == "wallets" {
-		// /api/walletgroups/{id}/wallets
-		if len(parts) == 5 || parts[5] == "" {
-			switch r.Method {
-			case http.MethodGet:
-				handleWalletGroupWalletList(w, r, groupID)
-			case http.MethodPut:
-				handleWalletGroupWalletReplace(w, r, groupID)
-			default:
-				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-			}
-			return
-		}
+	setETag(w, wallet.Version)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Wallet restored successfully",
+		"data":    wallet,
+	})
+}
 
-		// /api/walletgroups/{id}/wallets/{walletId}
-		walletIDStr := parts[5]
-		walletID64, err := strconv.ParseUint(walletIDStr, 10, 32)
-		if err != nil {
+func handleWalletMemberList(w http.ResponseWriter, r *http.Request, walletID uint) {
+	members, err := userWalletAPI.ListWalletMembers(walletID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Wallet members retrieved successfully",
+		"data":    members,
+	})
+}
+
+func handleUserWalletAttach(w http.ResponseWriter, r *http.Request, userID, walletID uint) {
+	// The body is optional; without it the user is attached as a viewer
+	var req userWalletAPI.AttachWalletRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid wallet ID: " + err.Error()})
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body: " + err.Error()})
+			return
+		}
+	}
+
+	if err := userWalletAPI.AttachWalletToUser(userID, walletID, req.Role); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Wallet attached to user",
+	})
+}
+
+func handleUserWalletDetach(w http.ResponseWriter, r *http.Request, userID, walletID uint) {
+	if err := userWalletAPI.DetachWalletFromUser(userID, walletID); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Wallet detached from user",
+	})
+}
+
+func handleUserWalletList(w http.ResponseWriter, r *http.Request, userID uint) {
+	wallets, err := userWalletAPI.ListUserWallets(userID)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "User wallets retrieved successfully",
+		"data":    wallets,
+	})
+}
+
+func handleUserWalletReplace(w http.ResponseWriter, r *http.Request, userID uint) {
+	var req userWalletAPI.ReplaceWalletsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	// The caller must own every wallet being added, removed or re-roled
+	currentWalletIDs, err := userWalletAPI.ListUserWalletIDs(userID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	memberships := req.Memberships()
+	affectedWalletIDs := currentWalletIDs
+	for _, m := range memberships {
+		affectedWalletIDs = append(affectedWalletIDs, m.WalletID)
+	}
+	if !requireWalletsRole(w, r, affectedWalletIDs, models.WalletRoleOwner) {
+		return
+	}
+
+	if err := userWalletAPI.ReplaceUserWallets(userID, memberships); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "User wallets replaced successfully",
+	})
+}
+
+func handleWalletGroups(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodPost:
+		handleWalletGroupCreate(w, r)
+	case http.MethodGet:
+		handleWalletGroupList(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func handleWalletGroupCreate(w http.ResponseWriter, r *http.Request) {
+	var req walletGroupAPI.WalletGroupCreationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	wg, err := walletGroupAPI.CreateWalletGroup(&req, authAPI.CurrentUser(r).UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Wallet group created successfully",
+		"data":    wg,
+	})
+}
+
+func handleWalletGroupList(w http.ResponseWriter, r *http.Request) {
+	groups, err := userWalletGroupAPI.ListWalletGroupsForUser(authAPI.CurrentUser(r).UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Wallet groups retrieved successfully",
+		"data":    groups,
+	})
+}
+
+func handleWalletGroupDetail(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 4 {
+		http.Error(w, "Invalid URL path", http.StatusBadRequest)
+		return
+	}
+
+	groupIDStr := parts[3]
+	groupID64, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid wallet group ID: " + err.Error()})
+		return
+	}
+	groupID := uint(groupID64)
+
+	if !requireWalletGroupAccess(w, r, groupID) {
+		return
+	}
+
+	// Subroute: /api/walletgroups/{id}/summary
+	if len(parts) == 5 && parts[4] == "summary" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handleWalletGroupSummary(w, r, groupID)
+		return
+	}
+
+	// Subroute: /api/walletgroups/{id}/wallets...
+	if len(parts) >= 5 && parts[4] == "wallets" {
+		// /api/walletgroups/{id}/wallets
+		if len(parts) == 5 || parts[5] == "" {
+			switch r.Method {
+			case http.MethodGet:
+				handleWalletGroupWalletList(w, r, groupID)
+			case http.MethodPut:
+				handleWalletGroupWalletReplace(w, r, groupID)
+			default:
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
+		// /api/walletgroups/{id}/wallets/{walletId}
+		walletIDStr := parts[5]
+		walletID64, err := strconv.ParseUint(walletIDStr, 10, 32)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid wallet ID: " + err.Error()})
+			return
+		}
+		walletID := uint(walletID64)
+
+		if !requireWalletRole(w, r, walletID, models.WalletRoleEditor) {
 			return
 		}
-		walletID := uint(walletID64)
 
 		switch r.Method {
 		case http.MethodPost:
@@ -735,6 +1551,9 @@ func handleWalletGroupDetail(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "message": "Wallet group retrieved successfully", "data": wg})
 
 	case http.MethodPut:
+		if !requireWalletGroupEditor(w, r, groupID) {
+			return
+		}
 		var req walletGroupAPI.WalletGroupUpdateRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			w.WriteHeader(http.StatusBadRequest)
@@ -751,6 +1570,9 @@ func handleWalletGroupDetail(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "message": "Wallet group updated successfully", "data": wg})
 
 	case http.MethodDelete:
+		if !requireWalletGroupEditor(w, r, groupID) {
+			return
+		}
 		if err := walletGroupAPI.DeleteWalletGroup(groupID); err != nil {
 			w.WriteHeader(http.StatusBadRequest)
 			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
@@ -803,6 +1625,21 @@ func handleWalletGroupWalletReplace(w http.ResponseWriter, r *http.Request, grou
 		return
 	}
 
+	// The caller must be a member of every wallet being added or removed
+	currentWallets, err := walletGroupWalletAPI.ListWalletsInGroup(groupID)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	affectedWalletIDs := append([]uint{}, req.WalletIDs...)
+	for _, wallet := range currentWallets {
+		affectedWalletIDs = append(affectedWalletIDs, wallet.WalletID)
+	}
+	if !requireWalletsRole(w, r, affectedWalletIDs, models.WalletRoleEditor) {
+		return
+	}
+
 	if err := walletGroupWalletAPI.ReplaceWalletsInGroup(groupID, req.WalletIDs); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
@@ -897,6 +1734,7 @@ func handleWalletCategoryGet(w http.ResponseWriter, r *http.Request, categoryID
 		return
 	}
 
+	setETag(w, category.Version)
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
@@ -913,13 +1751,24 @@ func handleWalletCategoryUpdate(w http.ResponseWriter, r *http.Request, category
 		return
 	}
 
+	expectedVersion, ok := parseIfMatch(w, r)
+	if !ok {
+		return
+	}
+	req.ExpectedVersion = expectedVersion
+
 	category, err := categoriesAPI.UpdateCategory(categoryID, &req)
+	if errors.Is(err, models.ErrVersionConflict) {
+		writeCategoryConflict(w, categoryID)
+		return
+	}
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
 	}
 
+	setETag(w, category.Version)
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
@@ -929,7 +1778,17 @@ func handleWalletCategoryUpdate(w http.ResponseWriter, r *http.Request, category
 }
 
 func handleWalletCategoryDelete(w http.ResponseWriter, r *http.Request, categoryID uint) {
-	if err := categoriesAPI.DeleteCategory(categoryID); err != nil {
+	expectedVersion, ok := parseIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	err := categoriesAPI.DeleteCategory(categoryID, authAPI.CurrentUser(r).UserID, expectedVersion)
+	if errors.Is(err, models.ErrVersionConflict) {
+		writeCategoryConflict(w, categoryID)
+		return
+	}
+	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
@@ -942,6 +1801,25 @@ func handleWalletCategoryDelete(w http.ResponseWriter, r *http.Request, category
 	})
 }
 
+// handleWalletCategoryRestore handles POST /api/wallets/{id}/categories/{categoryId}/restore -
+// Take a category and the transactions deleted with it out of the trash
+func handleWalletCategoryRestore(w http.ResponseWriter, r *http.Request, walletID, categoryID uint) {
+	category, err := categoriesAPI.RestoreCategory(walletID, categoryID)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	setETag(w, category.Version)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Category restored successfully",
+		"data":    category,
+	})
+}
+
 func handleWalletCategorySyncGlobal(w http.ResponseWriter, r *http.Request, categoryID uint) {
 	if err := categoriesAPI.SyncGlobalCategoryToAllWallets(categoryID); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
@@ -968,6 +1846,7 @@ func handleWalletTransactionCreate(w http.ResponseWriter, r *http.Request, walle
 
 	// Set wallet ID from path
 	req.WalletID = walletID
+	req.UserID = authAPI.CurrentUser(r).UserID
 
 	transaction, err := transactionsAPI.CreateTransaction(&req)
 	if err != nil {
@@ -1016,6 +1895,7 @@ func handleWalletTransactionGet(w http.ResponseWriter, r *http.Request, walletID
 		return
 	}
 
+	setETag(w, transaction.Version)
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
@@ -1052,13 +1932,24 @@ func handleWalletTransactionUpdate(w http.ResponseWriter, r *http.Request, walle
 		return
 	}
 
+	expectedVersion, ok := parseIfMatch(w, r)
+	if !ok {
+		return
+	}
+	req.ExpectedVersion = expectedVersion
+
 	transaction, err = transactionsAPI.UpdateTransaction(transactionID, &req)
+	if errors.Is(err, models.ErrVersionConflict) {
+		writeTransactionConflict(w, transactionID)
+		return
+	}
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
 	}
 
+	setETag(w, transaction.Version)
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
@@ -1081,7 +1972,16 @@ func handleWalletTransactionDelete(w http.ResponseWriter, r *http.Request, walle
 		return
 	}
 
-	err = transactionsAPI.DeleteTransaction(transactionID)
+	expectedVersion, ok := parseIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	err = transactionsAPI.DeleteTransaction(transactionID, authAPI.CurrentUser(r).UserID, expectedVersion)
+	if errors.Is(err, models.ErrVersionConflict) {
+		writeTransactionConflict(w, transactionID)
+		return
+	}
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
@@ -1095,6 +1995,25 @@ func handleWalletTransactionDelete(w http.ResponseWriter, r *http.Request, walle
 	})
 }
 
+// handleWalletTransactionRestore handles POST /api/wallets/{id}/transactions/{transactionId}/restore -
+// Take a transaction out of the trash and re-apply it to the balance
+func handleWalletTransactionRestore(w http.ResponseWriter, r *http.Request, walletID, transactionID uint) {
+	transaction, err := transactionsAPI.RestoreTransaction(walletID, transactionID)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	setETag(w, transaction.Version)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Transaction restored successfully",
+		"data":    transaction,
+	})
+}
+
 // parseTransactionFilter parses query parameters into TransactionFilter
 func parseTransactionFilter(r *http.Request) *transactionsAPI.TransactionFilter {
 	filter := &transactionsAPI.TransactionFilter{}
@@ -1149,6 +2068,12 @@ func parseTransactionFilter(r *http.Request) *transactionsAPI.TransactionFilter
 		}
 	}
 
+	// Parse kind
+	if kindStr := r.URL.Query().Get("kind"); kindStr != "" {
+		kind := models.CategoryKind(kindStr)
+		filter.Kind = &kind
+	}
+
 	// Parse wallet_id
 	if walletIDStr := r.URL.Query().Get("wallet_id"); walletIDStr != "" {
 		if walletID, err := strconv.ParseUint(walletIDStr, 10, 32); err == nil {
@@ -1165,6 +2090,22 @@ func parseTransactionFilter(r *http.Request) *transactionsAPI.TransactionFilter
 		}
 	}
 
+	// Parse tags and exclude_tags
+	if tagsStr := r.URL.Query().Get("tags"); tagsStr != "" {
+		for _, name := range strings.Split(tagsStr, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				filter.Tags = append(filter.Tags, name)
+			}
+		}
+	}
+	if excludeTagsStr := r.URL.Query().Get("exclude_tags"); excludeTagsStr != "" {
+		for _, name := range strings.Split(excludeTagsStr, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				filter.ExcludeTags = append(filter.ExcludeTags, name)
+			}
+		}
+	}
+
 	// Parse fuzzy_note
 	if fuzzyNote := r.URL.Query().Get("fuzzy_note"); fuzzyNote != "" {
 		filter.FuzzyNote = &fuzzyNote
@@ -1175,7 +2116,7 @@ func parseTransactionFilter(r *http.Request) *transactionsAPI.TransactionFilter
 		filter.AmountOp = &amountOp
 	}
 	if amountValueStr := r.URL.Query().Get("amount_value"); amountValueStr != "" {
-		if amountValue, err := strconv.ParseFloat(amountValueStr, 64); err == nil {
+		if amountValue, err := models.ParseMoney(amountValueStr); err == nil {
 			filter.AmountValue = &amountValue
 		}
 	}
@@ -1254,6 +2195,16 @@ func handlePersonDetail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// /api/persons/trash
+	if len(parts) == 4 && parts[3] == "trash" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handlePersonTrash(w, r)
+		return
+	}
+
 	personIDStr := parts[3]
 	personID, err := strconv.ParseUint(personIDStr, 10, 32)
 	if err != nil {
@@ -1264,9 +2215,29 @@ func handlePersonDetail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	switch r.Method {
-	case http.MethodGet:
-		handlePersonGet(w, r, uint(personID))
+	// /api/persons/{id}/restore
+	if len(parts) == 5 && parts[4] == "restore" {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handlePersonRestore(w, r, uint(personID))
+		return
+	}
+
+	// /api/persons/{id}/settle
+	if len(parts) == 5 && parts[4] == "settle" {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handlePersonSettle(w, r, uint(personID))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		handlePersonGet(w, r, uint(personID))
 	case http.MethodPut:
 		handlePersonUpdate(w, r, uint(personID))
 	case http.MethodDelete:
@@ -1325,7 +2296,7 @@ func handlePersonUpdate(w http.ResponseWriter, r *http.Request, personID uint) {
 
 // handlePersonDelete handles DELETE /api/persons/{id} - Delete person
 func handlePersonDelete(w http.ResponseWriter, r *http.Request, personID uint) {
-	err := personsAPI.DeletePerson(personID)
+	err := personsAPI.DeletePerson(personID, authAPI.CurrentUser(r).UserID)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{
@@ -1341,6 +2312,40 @@ func handlePersonDelete(w http.ResponseWriter, r *http.Request, personID uint) {
 	})
 }
 
+// handlePersonTrash handles GET /api/persons/trash - List deleted persons
+func handlePersonTrash(w http.ResponseWriter, r *http.Request) {
+	persons, err := personsAPI.ListTrashedPersons()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Trashed persons retrieved successfully",
+		"data":    persons,
+	})
+}
+
+// handlePersonRestore handles POST /api/persons/{id}/restore - Take a person out of the trash
+func handlePersonRestore(w http.ResponseWriter, r *http.Request, personID uint) {
+	person, err := personsAPI.RestorePerson(personID)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Person restored successfully",
+		"data":    person,
+	})
+}
+
 // handleTransactions handles transaction list and creation (POST /api/transactions)
 func handleTransactions(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -1366,6 +2371,11 @@ func handleTransactionCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !requireWalletRole(w, r, req.WalletID, models.WalletRoleEditor) {
+		return
+	}
+	req.UserID = authAPI.CurrentUser(r).UserID
+
 	transaction, err := transactionsAPI.CreateTransaction(&req)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
@@ -1383,9 +2393,36 @@ func handleTransactionCreate(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// scopedTransactionFilter parses the transaction filter and restricts it to wallets the caller
+// is a member of. It writes the error response and returns false when the request is not allowed.
+func scopedTransactionFilter(w http.ResponseWriter, r *http.Request) (*transactionsAPI.TransactionFilter, bool) {
+	filter := parseTransactionFilter(r)
+
+	if filter.WalletID != nil {
+		if !requireWalletRole(w, r, *filter.WalletID, models.WalletRoleViewer) {
+			return nil, false
+		}
+	} else {
+		walletIDs, err := userWalletAPI.ListUserWalletIDs(authAPI.CurrentUser(r).UserID)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{
+				"error": err.Error(),
+			})
+			return nil, false
+		}
+		filter.WalletIDs = authAPI.FilterWalletIDs(r, walletIDs)
+	}
+	return filter, true
+}
+
 // handleTransactionList handles GET /api/transactions - List all transactions
 func handleTransactionList(w http.ResponseWriter, r *http.Request) {
-	filter := parseTransactionFilter(r)
+	// Restrict the listing to wallets the caller is a member of
+	filter, ok := scopedTransactionFilter(w, r)
+	if !ok {
+		return
+	}
 	transactions, err := transactionsAPI.ListAllTransactions(filter)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -1403,7 +2440,47 @@ func handleTransactionList(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// handleTransactionDetail handles transaction detail operations (GET, PUT, DELETE /api/transactions/{id})
+// handleTransactionTotals handles GET /api/transactions/totals?group_by= - Totals per category
+// (the default) or per tag of the transactions matching the same filters as the listing, split
+// lines counted in their own categories
+func handleTransactionTotals(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	filter, ok := scopedTransactionFilter(w, r)
+	if !ok {
+		return
+	}
+	var totals interface{}
+	var err error
+	switch r.URL.Query().Get("group_by") {
+	case "", "category":
+		totals, err = transactionsAPI.TotalsByCategory(filter)
+	case "tag":
+		totals, err = transactionsAPI.TotalsByTag(filter)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "group_by must be category or tag"})
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Transaction totals retrieved successfully",
+		"data":    totals,
+	})
+}
+
+// handleTransactionDetail handles transaction detail operations (GET, PUT, DELETE /api/transactions/{id}),
+// PUT /api/transactions/{id}/tags and GET /api/transactions/totals
 func handleTransactionDetail(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
@@ -1413,6 +2490,12 @@ func handleTransactionDetail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// /api/transactions/totals
+	if len(parts) == 4 && parts[3] == "totals" {
+		handleTransactionTotals(w, r)
+		return
+	}
+
 	transactionIDStr := parts[3]
 	transactionID, err := strconv.ParseUint(transactionIDStr, 10, 32)
 	if err != nil {
@@ -1423,6 +2506,31 @@ func handleTransactionDetail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The caller must be a member of the transaction's wallet
+	transaction, err := transactionsAPI.GetTransactionByID(uint(transactionID))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+	if !requireWalletRole(w, r, transaction.WalletID, methodRole(r)) {
+		return
+	}
+
+	if len(parts) >= 5 {
+		switch {
+		case len(parts) == 5 && parts[4] == "tags":
+			handleTransactionTags(w, r, uint(transactionID))
+		case parts[4] == "attachments":
+			handleTransactionAttachments(w, r, uint(transactionID), parts[5:])
+		default:
+			http.Error(w, "Not found", http.StatusNotFound)
+		}
+		return
+	}
+
 	switch r.Method {
 	case http.MethodGet:
 		handleTransactionGet(w, r, uint(transactionID))
@@ -1446,6 +2554,7 @@ func handleTransactionGet(w http.ResponseWriter, r *http.Request, transactionID
 		return
 	}
 
+	setETag(w, transaction.Version)
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
@@ -1465,7 +2574,22 @@ func handleTransactionUpdate(w http.ResponseWriter, r *http.Request, transaction
 		return
 	}
 
+	// Moving a transaction requires access to the target wallet too
+	if req.WalletID != nil && !requireWalletRole(w, r, *req.WalletID, models.WalletRoleEditor) {
+		return
+	}
+
+	expectedVersion, ok := parseIfMatch(w, r)
+	if !ok {
+		return
+	}
+	req.ExpectedVersion = expectedVersion
+
 	transaction, err := transactionsAPI.UpdateTransaction(transactionID, &req)
+	if errors.Is(err, models.ErrVersionConflict) {
+		writeTransactionConflict(w, transactionID)
+		return
+	}
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{
@@ -1474,6 +2598,7 @@ func handleTransactionUpdate(w http.ResponseWriter, r *http.Request, transaction
 		return
 	}
 
+	setETag(w, transaction.Version)
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
@@ -1482,9 +2607,218 @@ func handleTransactionUpdate(w http.ResponseWriter, r *http.Request, transaction
 	})
 }
 
+// handleTransactionTags handles PUT /api/transactions/{id}/tags - Replace the tags of any
+// transaction, including transfer legs and debt postings: {"tags": ["vacation-2026"]}
+func handleTransactionTags(w http.ResponseWriter, r *http.Request, transactionID uint) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Tags []string `json:"tags"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	expectedVersion, ok := parseIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	transaction, err := transactionsAPI.SetTransactionTags(transactionID, req.Tags, expectedVersion)
+	if errors.Is(err, models.ErrVersionConflict) {
+		writeTransactionConflict(w, transactionID)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	setETag(w, transaction.Version)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Transaction tags updated successfully",
+		"data":    transaction,
+	})
+}
+
+// handleTransactionAttachments handles /api/transactions/{id}/attachments[/{attachment_id}[/thumbnail]]
+func handleTransactionAttachments(w http.ResponseWriter, r *http.Request, transactionID uint, rest []string) {
+	if len(rest) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			handleAttachmentList(w, r, transactionID)
+		case http.MethodPost:
+			handleAttachmentUpload(w, r, transactionID)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	attachmentID, err := strconv.ParseUint(rest[0], 10, 32)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid attachment ID: " + err.Error()})
+		return
+	}
+	attachment, err := attachmentsAPI.GetAttachment(transactionID, uint(attachmentID))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	switch {
+	case len(rest) == 1 && r.Method == http.MethodGet:
+		handleAttachmentDownload(w, attachment, false)
+	case len(rest) == 1 && r.Method == http.MethodDelete:
+		handleAttachmentDelete(w, attachment)
+	case len(rest) == 2 && rest[1] == "thumbnail" && r.Method == http.MethodGet:
+		handleAttachmentDownload(w, attachment, true)
+	case len(rest) <= 2:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// handleAttachmentList handles GET /api/transactions/{id}/attachments
+func handleAttachmentList(w http.ResponseWriter, r *http.Request, transactionID uint) {
+	attachments, err := attachmentsAPI.ListAttachments(transactionID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Attachments retrieved successfully",
+		"data":    attachments,
+	})
+}
+
+// handleAttachmentUpload handles POST /api/transactions/{id}/attachments - multipart/form-data
+// with the document in a part named "file"
+func handleAttachmentUpload(w http.ResponseWriter, r *http.Request, transactionID uint) {
+	// Room for the file plus the multipart framing around it
+	r.Body = http.MaxBytesReader(w, r.Body, attachmentsAPI.MaxSize()+1<<20)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Expected a multipart/form-data upload: " + err.Error()})
+		return
+	}
+
+	var part *multipart.Part
+	for {
+		part, err = reader.NextPart()
+		if err != nil || part.FormName() == "file" {
+			break
+		}
+		part.Close()
+	}
+	if err != nil {
+		status, message := http.StatusBadRequest, "Invalid upload: "+err.Error()
+		if errors.Is(err, io.EOF) {
+			message = `The upload has no part named "file"`
+		}
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": message})
+		return
+	}
+	defer part.Close()
+
+	attachment, err := attachmentsAPI.CreateAttachment(transactionID, authAPI.CurrentUser(r).UserID, part.FileName(), part)
+	if err != nil {
+		status := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.Is(err, attachmentsAPI.ErrTooLarge), errors.As(err, &tooLarge):
+			status = http.StatusRequestEntityTooLarge
+		case errors.Is(err, attachmentsAPI.ErrUnsupportedType):
+			status = http.StatusUnsupportedMediaType
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Attachment uploaded successfully",
+		"data":    attachment,
+	})
+}
+
+// handleAttachmentDownload handles GET /api/transactions/{id}/attachments/{attachment_id} and
+// .../thumbnail - the file itself, or a JPEG thumbnail of an image
+func handleAttachmentDownload(w http.ResponseWriter, attachment *models.Attachment, thumbnail bool) {
+	open, contentType := attachmentsAPI.OpenAttachment, attachment.ContentType
+	if thumbnail {
+		open, contentType = attachmentsAPI.OpenThumbnail, "image/jpeg"
+	}
+	file, err := open(attachment)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": attachment.FileName}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	if !thumbnail {
+		w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, file); err != nil {
+		log.Printf("Warning: Failed to send attachment %d: %v", attachment.AttachmentID, err)
+	}
+}
+
+// handleAttachmentDelete handles DELETE /api/transactions/{id}/attachments/{attachment_id}
+func handleAttachmentDelete(w http.ResponseWriter, attachment *models.Attachment) {
+	if err := attachmentsAPI.DeleteAttachment(*attachment.TransactionID, attachment.AttachmentID); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Attachment deleted successfully",
+	})
+}
+
 // handleTransactionDelete handles DELETE /api/transactions/{id} - Delete transaction
 func handleTransactionDelete(w http.ResponseWriter, r *http.Request, transactionID uint) {
-	err := transactionsAPI.DeleteTransaction(transactionID)
+	expectedVersion, ok := parseIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	err := transactionsAPI.DeleteTransaction(transactionID, authAPI.CurrentUser(r).UserID, expectedVersion)
+	if errors.Is(err, models.ErrVersionConflict) {
+		writeTransactionConflict(w, transactionID)
+		return
+	}
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{
@@ -1499,3 +2833,2408 @@ func handleTransactionDelete(w http.ResponseWriter, r *http.Request, transaction
 		"message": "Transaction deleted successfully",
 	})
 }
+
+// ==================== Exchange Rate Handlers ====================
+
+func handleExchangeRates(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		handleExchangeRateList(w, r)
+	case http.MethodPost:
+		if !requireAdmin(w, r) {
+			return
+		}
+		handleExchangeRateSet(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleExchangeRateList handles GET /api/exchangerates?base=EUR&quote=INR&start_date=...&end_date=...
+func handleExchangeRateList(w http.ResponseWriter, r *http.Request) {
+	filter := &exchangeRatesAPI.ExchangeRateFilter{}
+	if base := r.URL.Query().Get("base"); base != "" {
+		filter.BaseCurrency = &base
+	}
+	if quote := r.URL.Query().Get("quote"); quote != "" {
+		filter.QuoteCurrency = &quote
+	}
+	if startDateStr := r.URL.Query().Get("start_date"); startDateStr != "" {
+		if startDate, err := exchangeRatesAPI.ParseDate(startDateStr); err == nil {
+			filter.StartDate = &startDate
+		}
+	}
+	if endDateStr := r.URL.Query().Get("end_date"); endDateStr != "" {
+		if endDate, err := exchangeRatesAPI.ParseDate(endDateStr); err == nil {
+			filter.EndDate = &endDate
+		}
+	}
+
+	rates, err := exchangeRatesAPI.ListExchangeRates(filter)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Exchange rates retrieved successfully",
+		"data":    rates,
+	})
+}
+
+// handleExchangeRateSet handles POST /api/exchangerates - Create or replace a rate
+func handleExchangeRateSet(w http.ResponseWriter, r *http.Request) {
+	var req exchangeRatesAPI.ExchangeRateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	rate, err := exchangeRatesAPI.SetExchangeRate(&req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Exchange rate saved successfully",
+		"data":    rate,
+	})
+}
+
+// handleExchangeRateDetail handles POST /api/exchangerates/import and DELETE /api/exchangerates/{id}
+func handleExchangeRateDetail(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 4 {
+		http.Error(w, "Invalid URL path", http.StatusBadRequest)
+		return
+	}
+
+	if !requireAdmin(w, r) {
+		return
+	}
+
+	// /api/exchangerates/import - body is the CSV file
+	if parts[3] == "import" {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		result, err := exchangeRatesAPI.ImportCSV(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Exchange rates imported successfully",
+			"data":    result,
+		})
+		return
+	}
+
+	rateID64, err := strconv.ParseUint(parts[3], 10, 32)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid exchange rate ID: " + err.Error()})
+		return
+	}
+
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := exchangeRatesAPI.DeleteExchangeRate(uint(rateID64)); err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Exchange rate deleted successfully",
+	})
+}
+
+// ==================== Summary Handlers ====================
+
+// writeWalletSummary totals the wallets in the currency from ?currency= (or the caller's
+// reporting currency) at the rates in effect on ?rate_date= (or today)
+func writeWalletSummary(w http.ResponseWriter, r *http.Request, wallets []models.Wallet) {
+	currency := summaryAPI.ReportingCurrency(authAPI.CurrentUser(r))
+	if currencyStr := r.URL.Query().Get("currency"); currencyStr != "" {
+		normalized, err := models.NormalizeCurrency(currencyStr)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		currency = normalized
+	}
+
+	rateDate := time.Now()
+	if rateDateStr := r.URL.Query().Get("rate_date"); rateDateStr != "" {
+		parsed, err := exchangeRatesAPI.ParseDate(rateDateStr)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		rateDate = parsed
+	}
+
+	summary, err := summaryAPI.SummarizeWallets(wallets, currency, rateDate)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Summary retrieved successfully",
+		"data":    summary,
+	})
+}
+
+// handleWalletSummary handles GET /api/wallets/summary - Totals across the caller's wallets
+func handleWalletSummary(w http.ResponseWriter, r *http.Request) {
+	userWallets, err := userWalletAPI.ListUserWallets(authAPI.CurrentUser(r).UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	wallets := make([]models.Wallet, 0, len(userWallets))
+	for _, wallet := range userWallets {
+		if authAPI.AllowsWallet(r, wallet.WalletID) {
+			wallets = append(wallets, wallet)
+		}
+	}
+	writeWalletSummary(w, r, wallets)
+}
+
+// handleWalletGroupSummary handles GET /api/walletgroups/{id}/summary - Totals across the
+// group's wallets that the caller is a member of
+func handleWalletGroupSummary(w http.ResponseWriter, r *http.Request, groupID uint) {
+	groupWallets, err := walletGroupWalletAPI.ListWalletsInGroup(groupID)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	memberWalletIDs, err := userWalletAPI.ListUserWalletIDs(authAPI.CurrentUser(r).UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	isMember := make(map[uint]bool, len(memberWalletIDs))
+	for _, walletID := range memberWalletIDs {
+		isMember[walletID] = true
+	}
+
+	wallets := make([]models.Wallet, 0, len(groupWallets))
+	for _, wallet := range groupWallets {
+		if isMember[wallet.WalletID] {
+			wallets = append(wallets, wallet)
+		}
+	}
+	writeWalletSummary(w, r, wallets)
+}
+
+// ==================== Transfer Handlers ====================
+
+// handleTransfers handles transfer list and creation (GET, POST /api/transfers)
+func handleTransfers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodPost:
+		handleTransferCreate(w, r)
+	case http.MethodGet:
+		handleTransferList(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleTransferCreate handles POST /api/transfers - Move money between two wallets
+func handleTransferCreate(w http.ResponseWriter, r *http.Request) {
+	var req transfersAPI.TransferCreationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	if !requireWalletsRole(w, r, []uint{req.FromWalletID, req.ToWalletID}, models.WalletRoleEditor) {
+		return
+	}
+	req.UserID = authAPI.CurrentUser(r).UserID
+
+	transfer, err := transfersAPI.CreateTransfer(&req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Transfer created successfully",
+		"data":    transfer,
+	})
+}
+
+// handleTransferList handles GET /api/transfers?wallet_id=... - Transfers touching the caller's wallets
+func handleTransferList(w http.ResponseWriter, r *http.Request) {
+	filter := &transfersAPI.TransferFilter{}
+	if walletIDStr := r.URL.Query().Get("wallet_id"); walletIDStr != "" {
+		walletID64, err := strconv.ParseUint(walletIDStr, 10, 32)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid wallet ID: " + err.Error()})
+			return
+		}
+		walletID := uint(walletID64)
+		if !requireWalletRole(w, r, walletID, models.WalletRoleViewer) {
+			return
+		}
+		filter.WalletID = &walletID
+	} else {
+		walletIDs, err := userWalletAPI.ListUserWalletIDs(authAPI.CurrentUser(r).UserID)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		filter.WalletIDs = authAPI.FilterWalletIDs(r, walletIDs)
+	}
+
+	transfers, err := transfersAPI.ListTransfers(filter)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Transfers retrieved successfully",
+		"data":    transfers,
+	})
+}
+
+// handleTransferRestore handles POST /api/transfers/{id}/restore - Take a transfer and its legs out of the trash
+func handleTransferRestore(w http.ResponseWriter, r *http.Request, transferID uint) {
+	trashed, err := transfersAPI.GetTrashedTransfer(transferID)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if !requireWalletsRole(w, r, []uint{trashed.FromWalletID, trashed.ToWalletID}, models.WalletRoleEditor) {
+		return
+	}
+
+	transfer, err := transfersAPI.RestoreTransfer(transferID)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	setETag(w, transfer.Version)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Transfer restored successfully",
+		"data":    transfer,
+	})
+}
+
+// handleTransferDetail handles transfer detail operations (GET, PUT, DELETE /api/transfers/{id})
+func handleTransferDetail(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 4 {
+		http.Error(w, "Invalid URL path", http.StatusBadRequest)
+		return
+	}
+
+	transferID64, err := strconv.ParseUint(parts[3], 10, 32)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid transfer ID: " + err.Error()})
+		return
+	}
+	transferID := uint(transferID64)
+
+	// /api/transfers/{id}/restore
+	if len(parts) == 5 && parts[4] == "restore" {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handleTransferRestore(w, r, transferID)
+		return
+	}
+
+	transfer, err := transfersAPI.GetTransferByID(transferID)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	walletIDs := []uint{transfer.FromWalletID, transfer.ToWalletID}
+
+	switch r.Method {
+	case http.MethodGet:
+		// Members of either side may see the transfer
+		if !requireAnyWalletRole(w, r, walletIDs, models.WalletRoleViewer) {
+			return
+		}
+		setETag(w, transfer.Version)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Transfer retrieved successfully",
+			"data":    transfer,
+		})
+
+	case http.MethodPut:
+		var req transfersAPI.TransferUpdateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body: " + err.Error()})
+			return
+		}
+
+		// Both the current and any new wallets must be editable
+		if req.FromWalletID != nil {
+			walletIDs = append(walletIDs, *req.FromWalletID)
+		}
+		if req.ToWalletID != nil {
+			walletIDs = append(walletIDs, *req.ToWalletID)
+		}
+		if !requireWalletsRole(w, r, walletIDs, models.WalletRoleEditor) {
+			return
+		}
+
+		expectedVersion, ok := parseIfMatch(w, r)
+		if !ok {
+			return
+		}
+		req.ExpectedVersion = expectedVersion
+
+		updated, err := transfersAPI.UpdateTransfer(transferID, &req)
+		if errors.Is(err, models.ErrVersionConflict) {
+			writeTransferConflict(w, transferID)
+			return
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		setETag(w, updated.Version)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Transfer updated successfully",
+			"data":    updated,
+		})
+
+	case http.MethodDelete:
+		if !requireWalletsRole(w, r, walletIDs, models.WalletRoleEditor) {
+			return
+		}
+
+		expectedVersion, ok := parseIfMatch(w, r)
+		if !ok {
+			return
+		}
+
+		err := transfersAPI.DeleteTransfer(transferID, authAPI.CurrentUser(r).UserID, expectedVersion)
+		if errors.Is(err, models.ErrVersionConflict) {
+			writeTransferConflict(w, transferID)
+			return
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Transfer deleted successfully",
+		})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// ==================== Budget Handlers ====================
+
+// requireBudgetRole ensures the caller may use a budget: the role on its wallet, or access to
+// its wallet group plus, for changes, the role on the category's wallet
+func requireBudgetRole(w http.ResponseWriter, r *http.Request, budget *models.Budget, role models.WalletRole) bool {
+	if budget.WalletID != nil {
+		return requireWalletRole(w, r, *budget.WalletID, role)
+	}
+	if !requireWalletGroupAccess(w, r, *budget.WalletGroupID) {
+		return false
+	}
+	if role == models.WalletRoleViewer {
+		return true
+	}
+	category, err := categoriesAPI.GetCategoryByID(budget.CategoryID)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return false
+	}
+	return requireWalletRole(w, r, category.WalletID, role)
+}
+
+// budgetWalletIDs returns the wallets whose spending counts for the caller: the budget's
+// wallet, or the wallets of its group the caller is a member of
+func budgetWalletIDs(r *http.Request, budget *models.Budget) ([]uint, error) {
+	if budget.WalletID != nil {
+		return []uint{*budget.WalletID}, nil
+	}
+
+	groupWallets, err := walletGroupWalletAPI.ListWalletsInGroup(*budget.WalletGroupID)
+	if err != nil {
+		return nil, err
+	}
+	memberWalletIDs, err := userWalletAPI.ListUserWalletIDs(authAPI.CurrentUser(r).UserID)
+	if err != nil {
+		return nil, err
+	}
+	isMember := make(map[uint]bool, len(memberWalletIDs))
+	for _, walletID := range authAPI.FilterWalletIDs(r, memberWalletIDs) {
+		isMember[walletID] = true
+	}
+
+	walletIDs := []uint{}
+	for _, wallet := range groupWallets {
+		if isMember[wallet.WalletID] {
+			walletIDs = append(walletIDs, wallet.WalletID)
+		}
+	}
+	return walletIDs, nil
+}
+
+// handleBudgets handles budget list and creation (GET, POST /api/budgets)
+func handleBudgets(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodPost:
+		handleBudgetCreate(w, r)
+	case http.MethodGet:
+		handleBudgetList(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleBudgetCreate handles POST /api/budgets - Create a budget for a wallet or wallet group
+func handleBudgetCreate(w http.ResponseWriter, r *http.Request) {
+	var req budgetsAPI.BudgetCreationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	scope := &models.Budget{
+		CategoryID:    req.CategoryID,
+		WalletID:      req.WalletID,
+		WalletGroupID: req.WalletGroupID,
+	}
+	if (req.WalletID == nil) == (req.WalletGroupID == nil) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "exactly one of wallet_id and wallet_group_id is required"})
+		return
+	}
+	if !requireBudgetRole(w, r, scope, models.WalletRoleEditor) {
+		return
+	}
+	req.UserID = authAPI.CurrentUser(r).UserID
+
+	budget, err := budgetsAPI.CreateBudget(&req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Budget created successfully",
+		"data":    budget,
+	})
+}
+
+// handleBudgetList handles GET /api/budgets - Budgets of the caller's wallets and wallet groups,
+// or of one wallet (?wallet_id=) or wallet group (?wallet_group_id=)
+func handleBudgetList(w http.ResponseWriter, r *http.Request) {
+	filter := &budgetsAPI.BudgetFilter{WalletIDs: []uint{}, WalletGroupIDs: []uint{}}
+	walletIDStr := r.URL.Query().Get("wallet_id")
+	groupIDStr := r.URL.Query().Get("wallet_group_id")
+
+	if walletIDStr != "" {
+		walletID64, err := strconv.ParseUint(walletIDStr, 10, 32)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid wallet ID: " + err.Error()})
+			return
+		}
+		if !requireWalletRole(w, r, uint(walletID64), models.WalletRoleViewer) {
+			return
+		}
+		filter.WalletIDs = append(filter.WalletIDs, uint(walletID64))
+	}
+	if groupIDStr != "" {
+		groupID64, err := strconv.ParseUint(groupIDStr, 10, 32)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid wallet group ID: " + err.Error()})
+			return
+		}
+		if !requireWalletGroupAccess(w, r, uint(groupID64)) {
+			return
+		}
+		filter.WalletGroupIDs = append(filter.WalletGroupIDs, uint(groupID64))
+	}
+
+	if walletIDStr == "" && groupIDStr == "" {
+		userID := authAPI.CurrentUser(r).UserID
+		walletIDs, err := userWalletAPI.ListUserWalletIDs(userID)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		filter.WalletIDs = authAPI.FilterWalletIDs(r, walletIDs)
+
+		groups, err := userWalletGroupAPI.ListWalletGroupsForUser(userID)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		for _, group := range groups {
+			filter.WalletGroupIDs = append(filter.WalletGroupIDs, group.WalletGroupID)
+		}
+	}
+
+	budgets, err := budgetsAPI.ListBudgets(filter)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Budgets retrieved successfully",
+		"data":    budgets,
+	})
+}
+
+// handleBudgetDetail handles budget operations (GET, PUT, DELETE /api/budgets/{id}) and
+// GET /api/budgets/{id}/progress
+func handleBudgetDetail(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 4 {
+		http.Error(w, "Invalid URL path", http.StatusBadRequest)
+		return
+	}
+
+	budgetID64, err := strconv.ParseUint(parts[3], 10, 32)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid budget ID: " + err.Error()})
+		return
+	}
+	budgetID := uint(budgetID64)
+
+	budget, err := budgetsAPI.GetBudgetByID(budgetID)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if !requireBudgetRole(w, r, budget, methodRole(r)) {
+		return
+	}
+
+	// /api/budgets/{id}/progress
+	if len(parts) == 5 && parts[4] == "progress" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handleBudgetProgress(w, r, budget)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Budget retrieved successfully",
+			"data":    budget,
+		})
+
+	case http.MethodPut:
+		var req budgetsAPI.BudgetUpdateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body: " + err.Error()})
+			return
+		}
+
+		// A new category must be editable as well
+		if req.CategoryID != nil {
+			moved := *budget
+			moved.CategoryID = *req.CategoryID
+			if !requireBudgetRole(w, r, &moved, models.WalletRoleEditor) {
+				return
+			}
+		}
+
+		updated, err := budgetsAPI.UpdateBudget(budgetID, &req)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Budget updated successfully",
+			"data":    updated,
+		})
+
+	case http.MethodDelete:
+		if err := budgetsAPI.DeleteBudget(budgetID); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Budget deleted successfully",
+		})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleBudgetProgress handles GET /api/budgets/{id}/progress?at= - Spending against the limit
+// in the period containing at (default now)
+func handleBudgetProgress(w http.ResponseWriter, r *http.Request, budget *models.Budget) {
+	at := time.Now()
+	if atStr := r.URL.Query().Get("at"); atStr != "" {
+		parsed, err := balanceAPI.ParseAt(atStr)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		at = parsed
+	}
+
+	walletIDs, err := budgetWalletIDs(r, budget)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	progress, err := budgetsAPI.GetProgress(budget, walletIDs, at)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Budget progress retrieved successfully",
+		"data":    progress,
+	})
+}
+
+// ==================== Recurring Transaction Handlers ====================
+
+// parseRangeStart parses an RFC 3339 time, or a YYYY-MM-DD date meaning the start of that day
+func parseRangeStart(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return exchangeRatesAPI.ParseDate(value)
+}
+
+// handleRecurringTransactions handles recurring transaction list and creation
+// (GET, POST /api/recurring)
+func handleRecurringTransactions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodPost:
+		handleRecurringTransactionCreate(w, r)
+	case http.MethodGet:
+		filter, ok := recurringFilter(w, r)
+		if !ok {
+			return
+		}
+		recurrings, err := recurringAPI.ListRecurringTransactions(filter)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Recurring transactions retrieved successfully",
+			"data":    recurrings,
+		})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// walletScope reads ?wallet_id= as the one wallet to list, or returns the caller's wallets
+// without it. It writes the error response and returns false when the request is not allowed.
+func walletScope(w http.ResponseWriter, r *http.Request) (walletID *uint, walletIDs []uint, ok bool) {
+	if walletIDStr := r.URL.Query().Get("wallet_id"); walletIDStr != "" {
+		walletID64, err := strconv.ParseUint(walletIDStr, 10, 32)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid wallet ID: " + err.Error()})
+			return nil, nil, false
+		}
+		if !requireWalletRole(w, r, uint(walletID64), models.WalletRoleViewer) {
+			return nil, nil, false
+		}
+		id := uint(walletID64)
+		return &id, nil, true
+	}
+
+	walletIDs, err := userWalletAPI.ListUserWalletIDs(authAPI.CurrentUser(r).UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return nil, nil, false
+	}
+	walletIDs = authAPI.FilterWalletIDs(r, walletIDs)
+	if walletIDs == nil {
+		walletIDs = []uint{}
+	}
+	return nil, walletIDs, true
+}
+
+// recurringFilter builds the filter for ?wallet_id=, or the caller's wallets without it.
+// It writes the error response and returns false when the request is not allowed.
+func recurringFilter(w http.ResponseWriter, r *http.Request) (*recurringAPI.RecurringTransactionFilter, bool) {
+	walletID, walletIDs, ok := walletScope(w, r)
+	if !ok {
+		return nil, false
+	}
+	return &recurringAPI.RecurringTransactionFilter{WalletID: walletID, WalletIDs: walletIDs}, true
+}
+
+// handleRecurringTransactionCreate handles POST /api/recurring - Create a recurring transaction
+func handleRecurringTransactionCreate(w http.ResponseWriter, r *http.Request) {
+	var req recurringAPI.RecurringTransactionCreationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if req.WalletID == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "wallet_id is required"})
+		return
+	}
+	if !requireWalletRole(w, r, req.WalletID, models.WalletRoleEditor) {
+		return
+	}
+	req.UserID = authAPI.CurrentUser(r).UserID
+
+	recurring, err := recurringAPI.CreateRecurringTransaction(&req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Recurring transaction created successfully",
+		"data":    recurring,
+	})
+}
+
+// handleRecurringUpcoming handles GET /api/recurring/upcoming?until=&days=&wallet_id= -
+// Occurrences still to be posted, soonest first (default: the next 30 days)
+func handleRecurringUpcoming(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	until := time.Now().AddDate(0, 0, 30)
+	if untilStr := r.URL.Query().Get("until"); untilStr != "" {
+		parsed, err := balanceAPI.ParseAt(untilStr)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		until = parsed
+	} else if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+		days, err := strconv.Atoi(daysStr)
+		if err != nil || days < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "days must be a non-negative integer"})
+			return
+		}
+		until = time.Now().AddDate(0, 0, days)
+	}
+
+	filter, ok := recurringFilter(w, r)
+	if !ok {
+		return
+	}
+	upcoming, err := recurringAPI.ListUpcoming(filter, until)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Upcoming occurrences retrieved successfully",
+		"data":    upcoming,
+	})
+}
+
+// handleRecurringTransactionDetail handles recurring transaction operations
+// (GET, PUT, DELETE /api/recurring/{id}), GET /api/recurring/{id}/occurrences,
+// POST /api/recurring/{id}/skip and GET /api/recurring/upcoming
+func handleRecurringTransactionDetail(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 4 {
+		http.Error(w, "Invalid URL path", http.StatusBadRequest)
+		return
+	}
+
+	// /api/recurring/upcoming
+	if len(parts) == 4 && parts[3] == "upcoming" {
+		handleRecurringUpcoming(w, r)
+		return
+	}
+
+	recurringID64, err := strconv.ParseUint(parts[3], 10, 32)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid recurring transaction ID: " + err.Error()})
+		return
+	}
+	recurringID := uint(recurringID64)
+
+	recurring, err := recurringAPI.GetRecurringTransactionByID(recurringID)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if !requireWalletRole(w, r, recurring.WalletID, methodRole(r)) {
+		return
+	}
+
+	if len(parts) == 5 {
+		switch parts[4] {
+		case "occurrences":
+			handleRecurringOccurrences(w, r, recurring)
+		case "skip":
+			handleRecurringSkip(w, r, recurring)
+		default:
+			http.Error(w, "Not found", http.StatusNotFound)
+		}
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Recurring transaction retrieved successfully",
+			"data":    recurring,
+		})
+
+	case http.MethodPut:
+		var req recurringAPI.RecurringTransactionUpdateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body: " + err.Error()})
+			return
+		}
+
+		updated, err := recurringAPI.UpdateRecurringTransaction(recurringID, &req)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Recurring transaction updated successfully",
+			"data":    updated,
+		})
+
+	case http.MethodDelete:
+		if err := recurringAPI.DeleteRecurringTransaction(recurringID); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Recurring transaction deleted successfully",
+		})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleRecurringOccurrences handles GET /api/recurring/{id}/occurrences?from=&to=&limit= -
+// Occurrences with their status (default: from the start time, at most 100)
+func handleRecurringOccurrences(w http.ResponseWriter, r *http.Request, recurring *models.RecurringTransaction) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	from := recurring.StartTime
+	var to time.Time
+	limit := 100
+	query := r.URL.Query()
+	if fromStr := query.Get("from"); fromStr != "" {
+		parsed, err := parseRangeStart(fromStr)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		from = parsed
+	}
+	if toStr := query.Get("to"); toStr != "" {
+		parsed, err := balanceAPI.ParseAt(toStr)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		to = parsed
+	}
+	if limitStr := query.Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "limit must be a positive integer"})
+			return
+		}
+		limit = parsed
+	}
+
+	occurrences, err := recurringAPI.ListOccurrences(recurring, from, to, limit)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Occurrences retrieved successfully",
+		"data":    occurrences,
+	})
+}
+
+// handleRecurringSkip handles POST /api/recurring/{id}/skip - Skip one occurrence that has
+// not been posted yet
+func handleRecurringSkip(w http.ResponseWriter, r *http.Request, recurring *models.RecurringTransaction) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req recurringAPI.SkipRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if req.OccurrenceTime.IsZero() {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "occurrence_time is required"})
+		return
+	}
+
+	occurrence, err := recurringAPI.SkipOccurrence(recurring.RecurringTransactionID, req.OccurrenceTime)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Occurrence skipped successfully",
+		"data":    occurrence,
+	})
+}
+
+// ==================== Bill Handlers ====================
+
+// handleBills handles bill list and creation (GET, POST /api/bills)
+func handleBills(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodPost:
+		handleBillCreate(w, r)
+	case http.MethodGet:
+		filter, ok := billFilter(w, r)
+		if !ok {
+			return
+		}
+		bills, err := billsAPI.ListBills(filter)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Bills retrieved successfully",
+			"data":    bills,
+		})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// billFilter builds the filter for ?wallet_id=&status=, or the caller's wallets without a
+// wallet_id. It writes the error response and returns false when the request is not allowed.
+func billFilter(w http.ResponseWriter, r *http.Request) (*billsAPI.BillFilter, bool) {
+	filter := &billsAPI.BillFilter{}
+	if statusStr := r.URL.Query().Get("status"); statusStr != "" {
+		status := models.BillStatus(statusStr)
+		if !status.IsValid() {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "status must be one of upcoming, due, overdue or paid"})
+			return nil, false
+		}
+		filter.Status = &status
+	}
+
+	walletID, walletIDs, ok := walletScope(w, r)
+	if !ok {
+		return nil, false
+	}
+	filter.WalletID = walletID
+	filter.WalletIDs = walletIDs
+	return filter, true
+}
+
+// handleBillCreate handles POST /api/bills - Create a bill
+func handleBillCreate(w http.ResponseWriter, r *http.Request) {
+	var req billsAPI.BillCreationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if req.WalletID == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "wallet_id is required"})
+		return
+	}
+	if !requireWalletRole(w, r, req.WalletID, models.WalletRoleEditor) {
+		return
+	}
+	req.UserID = authAPI.CurrentUser(r).UserID
+
+	bill, err := billsAPI.CreateBill(&req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Bill created successfully",
+		"data":    bill,
+	})
+}
+
+// handleBillsDue handles GET /api/bills/due?days=&wallet_id= - Unpaid bills due within the
+// next days (default 7), overdue ones included
+func handleBillsDue(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	days := 7
+	if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+		parsed, err := strconv.Atoi(daysStr)
+		if err != nil || parsed < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "days must be a non-negative integer"})
+			return
+		}
+		days = parsed
+	}
+
+	walletID, walletIDs, ok := walletScope(w, r)
+	if !ok {
+		return
+	}
+	bills, err := billsAPI.ListDueBills(&billsAPI.BillFilter{WalletID: walletID, WalletIDs: walletIDs}, days)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Due bills retrieved successfully",
+		"data":    bills,
+	})
+}
+
+// handleBillDetail handles bill operations (GET, PUT, DELETE /api/bills/{id}),
+// POST /api/bills/{id}/pay and GET /api/bills/due
+func handleBillDetail(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 4 {
+		http.Error(w, "Invalid URL path", http.StatusBadRequest)
+		return
+	}
+
+	// /api/bills/due
+	if len(parts) == 4 && parts[3] == "due" {
+		handleBillsDue(w, r)
+		return
+	}
+
+	billID64, err := strconv.ParseUint(parts[3], 10, 32)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid bill ID: " + err.Error()})
+		return
+	}
+	billID := uint(billID64)
+
+	bill, err := billsAPI.GetBillByID(billID)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if !requireWalletRole(w, r, bill.WalletID, methodRole(r)) {
+		return
+	}
+
+	if len(parts) == 5 {
+		switch parts[4] {
+		case "pay":
+			handleBillPay(w, r, bill)
+		default:
+			http.Error(w, "Not found", http.StatusNotFound)
+		}
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Bill retrieved successfully",
+			"data":    bill,
+		})
+
+	case http.MethodPut:
+		var req billsAPI.BillUpdateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body: " + err.Error()})
+			return
+		}
+
+		updated, err := billsAPI.UpdateBill(billID, &req)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Bill updated successfully",
+			"data":    updated,
+		})
+
+	case http.MethodDelete:
+		if err := billsAPI.DeleteBill(billID); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Bill deleted successfully",
+		})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleBillPay handles POST /api/bills/{id}/pay - Record a payment, in full by default
+func handleBillPay(w http.ResponseWriter, r *http.Request, bill *models.Bill) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req billsAPI.BillPaymentRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body: " + err.Error()})
+			return
+		}
+	}
+	req.UserID = authAPI.CurrentUser(r).UserID
+
+	payment, err := billsAPI.PayBill(bill.BillID, &req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Bill payment recorded successfully",
+		"data":    payment,
+	})
+}
+
+// ==================== Savings Goal Handlers ====================
+
+// handleGoals handles goal list and creation (GET, POST /api/goals)
+func handleGoals(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodPost:
+		handleGoalCreate(w, r)
+	case http.MethodGet:
+		walletID, walletIDs, ok := walletScope(w, r)
+		if !ok {
+			return
+		}
+		goals, err := goalsAPI.ListGoals(&goalsAPI.GoalFilter{WalletID: walletID, WalletIDs: walletIDs})
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Goals retrieved successfully",
+			"data":    goals,
+		})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleGoalCreate handles POST /api/goals - Create a savings goal
+func handleGoalCreate(w http.ResponseWriter, r *http.Request) {
+	var req goalsAPI.GoalCreationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if req.WalletID == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "wallet_id is required"})
+		return
+	}
+	if !requireWalletRole(w, r, req.WalletID, models.WalletRoleEditor) {
+		return
+	}
+	req.UserID = authAPI.CurrentUser(r).UserID
+
+	goal, err := goalsAPI.CreateGoal(&req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Goal created successfully",
+		"data":    goal,
+	})
+}
+
+// handleGoalDetail handles goal operations (GET, PUT, DELETE /api/goals/{id}),
+// GET, POST /api/goals/{id}/contributions, DELETE /api/goals/{id}/contributions/{contributionId}
+// and POST /api/goals/{id}/withdrawals
+func handleGoalDetail(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 4 {
+		http.Error(w, "Invalid URL path", http.StatusBadRequest)
+		return
+	}
+
+	goalID64, err := strconv.ParseUint(parts[3], 10, 32)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid goal ID: " + err.Error()})
+		return
+	}
+	goalID := uint(goalID64)
+
+	goal, err := goalsAPI.GetGoalByID(goalID)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if !requireWalletRole(w, r, goal.WalletID, methodRole(r)) {
+		return
+	}
+
+	if len(parts) >= 5 {
+		switch {
+		case parts[4] == "contributions" && len(parts) == 5:
+			switch r.Method {
+			case http.MethodGet:
+				handleGoalContributionList(w, r, goal)
+			case http.MethodPost:
+				handleGoalContribute(w, r, goal, goalsAPI.Contribute)
+			default:
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+		case parts[4] == "contributions" && len(parts) == 6:
+			if r.Method != http.MethodDelete {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			contributionID64, err := strconv.ParseUint(parts[5], 10, 32)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "Invalid contribution ID: " + err.Error()})
+				return
+			}
+			if err := goalsAPI.DeleteContribution(goalID, uint(contributionID64)); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": true,
+				"message": "Contribution deleted successfully",
+			})
+		case parts[4] == "withdrawals" && len(parts) == 5:
+			if r.Method != http.MethodPost {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			handleGoalContribute(w, r, goal, goalsAPI.Withdraw)
+		default:
+			http.Error(w, "Not found", http.StatusNotFound)
+		}
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Goal retrieved successfully",
+			"data":    goal,
+		})
+
+	case http.MethodPut:
+		var req goalsAPI.GoalUpdateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body: " + err.Error()})
+			return
+		}
+
+		updated, err := goalsAPI.UpdateGoal(goalID, &req)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Goal updated successfully",
+			"data":    updated,
+		})
+
+	case http.MethodDelete:
+		if err := goalsAPI.DeleteGoal(goalID); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Goal deleted successfully",
+		})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleGoalContributionList handles GET /api/goals/{id}/contributions - Contributions and
+// withdrawals, newest first
+func handleGoalContributionList(w http.ResponseWriter, r *http.Request, goal *models.Goal) {
+	contributions, err := goalsAPI.ListContributions(goal.GoalID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Contributions retrieved successfully",
+		"data":    contributions,
+	})
+}
+
+// handleGoalContribute handles POST /api/goals/{id}/contributions and /withdrawals - Set money
+// aside from a wallet, or take it back. The caller must be an editor of that wallet too.
+func handleGoalContribute(w http.ResponseWriter, r *http.Request, goal *models.Goal,
+	record func(uint, *goalsAPI.ContributionRequest) (*models.GoalContribution, error)) {
+	var req goalsAPI.ContributionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if req.WalletID == 0 {
+		req.WalletID = goal.WalletID
+	}
+	if !requireWalletRole(w, r, req.WalletID, models.WalletRoleEditor) {
+		return
+	}
+	req.UserID = authAPI.CurrentUser(r).UserID
+
+	contribution, err := record(goal.GoalID, &req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	updated, err := goalsAPI.GetGoalByID(goal.GoalID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Contribution recorded successfully",
+		"data": map[string]interface{}{
+			"contribution": contribution,
+			"goal":         updated,
+		},
+	})
+}
+
+// handleWalletGoals handles GET /api/wallets/{walletId}/goals - The wallet's balance split
+// into what is set aside for each goal and what is available
+func handleWalletGoals(w http.ResponseWriter, r *http.Request, walletID uint) {
+	walletGoals, err := goalsAPI.GetWalletGoals(walletID)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Wallet goals retrieved successfully",
+		"data":    walletGoals,
+	})
+}
+
+// ==================== Debt Handlers ====================
+
+// handleDebts handles debt list and creation (GET, POST /api/debts)
+func handleDebts(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodPost:
+		handleDebtCreate(w, r)
+	case http.MethodGet:
+		filter, ok := debtFilter(w, r)
+		if !ok {
+			return
+		}
+		debts, err := debtsAPI.ListDebts(filter)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Debts retrieved successfully",
+			"data":    debts,
+		})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// debtFilter builds the filter for ?wallet_id=&person_id=&status=, or the caller's wallets
+// without a wallet_id. It writes the error response and returns false when the request is not allowed.
+func debtFilter(w http.ResponseWriter, r *http.Request) (*debtsAPI.DebtFilter, bool) {
+	filter := &debtsAPI.DebtFilter{}
+	if statusStr := r.URL.Query().Get("status"); statusStr != "" {
+		status := models.DebtStatus(statusStr)
+		if !status.IsValid() {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "status must be open or settled"})
+			return nil, false
+		}
+		filter.Status = &status
+	}
+	if personIDStr := r.URL.Query().Get("person_id"); personIDStr != "" {
+		personID, err := strconv.ParseUint(personIDStr, 10, 32)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid person ID: " + err.Error()})
+			return nil, false
+		}
+		id := uint(personID)
+		filter.PersonID = &id
+	}
+
+	walletID, walletIDs, ok := walletScope(w, r)
+	if !ok {
+		return nil, false
+	}
+	filter.WalletID = walletID
+	filter.WalletIDs = walletIDs
+	return filter, true
+}
+
+// handleDebtCreate handles POST /api/debts - Record money lent or borrowed
+func handleDebtCreate(w http.ResponseWriter, r *http.Request) {
+	var req debtsAPI.DebtCreationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if req.WalletID == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "wallet_id is required"})
+		return
+	}
+	if !requireWalletRole(w, r, req.WalletID, models.WalletRoleEditor) {
+		return
+	}
+	req.UserID = authAPI.CurrentUser(r).UserID
+
+	debt, err := debtsAPI.CreateDebt(&req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Debt created successfully",
+		"data":    debt,
+	})
+}
+
+// handleDebtBalances handles GET /api/debts/balances?wallet_id=&person_id= - What is
+// outstanding with each person over all open debts
+func handleDebtBalances(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	filter, ok := debtFilter(w, r)
+	if !ok {
+		return
+	}
+	balances, err := debtsAPI.ListPersonBalances(filter)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Debt balances retrieved successfully",
+		"data":    balances,
+	})
+}
+
+// handleDebtDetail handles debt operations (GET, PUT, DELETE /api/debts/{id}),
+// POST /api/debts/{id}/repayments and GET /api/debts/balances
+func handleDebtDetail(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 4 {
+		http.Error(w, "Invalid URL path", http.StatusBadRequest)
+		return
+	}
+
+	// /api/debts/balances
+	if len(parts) == 4 && parts[3] == "balances" {
+		handleDebtBalances(w, r)
+		return
+	}
+
+	debtID64, err := strconv.ParseUint(parts[3], 10, 32)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid debt ID: " + err.Error()})
+		return
+	}
+	debtID := uint(debtID64)
+
+	debt, err := debtsAPI.GetDebtByID(debtID)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if !requireWalletRole(w, r, debt.WalletID, methodRole(r)) {
+		return
+	}
+
+	if len(parts) == 5 {
+		switch parts[4] {
+		case "repayments":
+			handleDebtRepay(w, r, debt)
+		default:
+			http.Error(w, "Not found", http.StatusNotFound)
+		}
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Debt retrieved successfully",
+			"data":    debt,
+		})
+
+	case http.MethodPut:
+		var req debtsAPI.DebtUpdateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body: " + err.Error()})
+			return
+		}
+
+		updated, err := debtsAPI.UpdateDebt(debtID, &req)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Debt updated successfully",
+			"data":    updated,
+		})
+
+	case http.MethodDelete:
+		if err := debtsAPI.DeleteDebt(debtID); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Debt deleted successfully",
+		})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleDebtRepay handles POST /api/debts/{id}/repayments - Record a repayment, in full by default
+func handleDebtRepay(w http.ResponseWriter, r *http.Request, debt *models.Debt) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req debtsAPI.RepaymentRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body: " + err.Error()})
+			return
+		}
+	}
+	if req.WalletID != 0 && req.WalletID != debt.WalletID &&
+		!requireWalletRole(w, r, req.WalletID, models.WalletRoleEditor) {
+		return
+	}
+	req.UserID = authAPI.CurrentUser(r).UserID
+
+	repayment, err := debtsAPI.Repay(debt.DebtID, &req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Debt repayment recorded successfully",
+		"data":    repayment,
+	})
+}
+
+// handlePersonSettle handles POST /api/persons/{id}/settle - Close every open debt with the
+// person in the wallet's currency, over the wallets the caller can edit, with one transaction
+func handlePersonSettle(w http.ResponseWriter, r *http.Request, personID uint) {
+	var req debtsAPI.SettleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if req.WalletID == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "wallet_id is required"})
+		return
+	}
+	if !requireWalletRole(w, r, req.WalletID, models.WalletRoleEditor) {
+		return
+	}
+	walletIDs, ok := walletsWithRole(w, r, models.WalletRoleEditor)
+	if !ok {
+		return
+	}
+	req.WalletIDs = walletIDs
+	req.UserID = authAPI.CurrentUser(r).UserID
+
+	settlement, err := debtsAPI.SettleUp(personID, &req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Settled up successfully",
+		"data":    settlement,
+	})
+}
+
+// ==================== Shared Expense Handlers ====================
+
+// handleSharedExpenses handles shared expense list and creation (GET, POST /api/shared-expenses)
+func handleSharedExpenses(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodPost:
+		var req sharedExpensesAPI.SharedExpenseRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body: " + err.Error()})
+			return
+		}
+		if req.WalletID == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "wallet_id is required"})
+			return
+		}
+		if !requireWalletRole(w, r, req.WalletID, models.WalletRoleEditor) {
+			return
+		}
+		req.UserID = authAPI.CurrentUser(r).UserID
+
+		expense, err := sharedExpensesAPI.CreateSharedExpense(&req)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Shared expense created successfully",
+			"data":    expense,
+		})
+
+	case http.MethodGet:
+		filter, ok := sharedExpenseFilter(w, r)
+		if !ok {
+			return
+		}
+		expenses, err := sharedExpensesAPI.ListSharedExpenses(filter)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Shared expenses retrieved successfully",
+			"data":    expenses,
+		})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// sharedExpenseFilter builds the filter for ?wallet_id=&person_id=, or the caller's wallets
+// without a wallet_id. It writes the error response and returns false when the request is not allowed.
+func sharedExpenseFilter(w http.ResponseWriter, r *http.Request) (*sharedExpensesAPI.SharedExpenseFilter, bool) {
+	filter := &sharedExpensesAPI.SharedExpenseFilter{}
+	if personIDStr := r.URL.Query().Get("person_id"); personIDStr != "" {
+		personID, err := strconv.ParseUint(personIDStr, 10, 32)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid person ID: " + err.Error()})
+			return nil, false
+		}
+		id := uint(personID)
+		filter.PersonID = &id
+	}
+
+	walletID, walletIDs, ok := walletScope(w, r)
+	if !ok {
+		return nil, false
+	}
+	filter.WalletID = walletID
+	filter.WalletIDs = walletIDs
+	return filter, true
+}
+
+// handleSharedExpenseDetail handles shared expense operations (GET, PUT, DELETE
+// /api/shared-expenses/{id}), GET /api/shared-expenses/balances, POST
+// /api/shared-expenses/settle-up and the /api/shared-expenses/settlements routes
+func handleSharedExpenseDetail(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 4 {
+		http.Error(w, "Invalid URL path", http.StatusBadRequest)
+		return
+	}
+
+	switch parts[3] {
+	case "balances":
+		if len(parts) == 4 {
+			handleSharedBalances(w, r)
+			return
+		}
+	case "settle-up":
+		if len(parts) == 4 {
+			handleSharedSettleUp(w, r)
+			return
+		}
+	case "settlements":
+		if len(parts) == 4 {
+			handleSharedSettlements(w, r)
+			return
+		}
+		if len(parts) == 5 {
+			handleSharedSettlementDetail(w, r, parts[4])
+			return
+		}
+	}
+	if len(parts) != 4 {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	expenseID64, err := strconv.ParseUint(parts[3], 10, 32)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid shared expense ID: " + err.Error()})
+		return
+	}
+	expenseID := uint(expenseID64)
+
+	expense, err := sharedExpensesAPI.GetSharedExpenseByID(expenseID)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if !requireWalletRole(w, r, expense.WalletID, methodRole(r)) {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Shared expense retrieved successfully",
+			"data":    expense,
+		})
+
+	case http.MethodPut:
+		var req sharedExpensesAPI.SharedExpenseRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body: " + err.Error()})
+			return
+		}
+		req.UserID = authAPI.CurrentUser(r).UserID
+
+		updated, err := sharedExpensesAPI.UpdateSharedExpense(expenseID, &req)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Shared expense updated successfully",
+			"data":    updated,
+		})
+
+	case http.MethodDelete:
+		if err := sharedExpensesAPI.DeleteSharedExpense(expenseID); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Shared expense deleted successfully",
+		})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleSharedBalances handles GET /api/shared-expenses/balances?wallet_id= - Where everyone
+// stands in the wallet's shared ledger, who owes whom and the payments that settle everyone
+func handleSharedBalances(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	walletID, _, ok := walletScope(w, r)
+	if !ok {
+		return
+	}
+	if walletID == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "wallet_id is required"})
+		return
+	}
+
+	balances, err := sharedExpensesAPI.GetBalances(*walletID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Shared balances retrieved successfully",
+		"data":    balances,
+	})
+}
+
+// handleSharedSettleUp handles POST /api/shared-expenses/settle-up - Record the suggested
+// payments of the wallet's shared ledger as settlements
+func handleSharedSettleUp(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req sharedExpensesAPI.SettleUpRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if req.WalletID == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "wallet_id is required"})
+		return
+	}
+	if !requireWalletRole(w, r, req.WalletID, models.WalletRoleEditor) {
+		return
+	}
+	req.UserID = authAPI.CurrentUser(r).UserID
+
+	settlements, err := sharedExpensesAPI.SettleUp(&req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Settled up successfully",
+		"data":    settlements,
+	})
+}
+
+// handleSharedSettlements handles settlement list and recording (GET, POST
+// /api/shared-expenses/settlements)
+func handleSharedSettlements(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		var req sharedExpensesAPI.SettlementRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body: " + err.Error()})
+			return
+		}
+		if req.WalletID == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "wallet_id is required"})
+			return
+		}
+		if !requireWalletRole(w, r, req.WalletID, models.WalletRoleEditor) {
+			return
+		}
+		req.UserID = authAPI.CurrentUser(r).UserID
+
+		settlement, err := sharedExpensesAPI.CreateSettlement(&req)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Shared settlement recorded successfully",
+			"data":    settlement,
+		})
+
+	case http.MethodGet:
+		filter, ok := sharedExpenseFilter(w, r)
+		if !ok {
+			return
+		}
+		settlements, err := sharedExpensesAPI.ListSettlements(filter)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Shared settlements retrieved successfully",
+			"data":    settlements,
+		})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleSharedSettlementDetail handles settlement operations (GET, DELETE
+// /api/shared-expenses/settlements/{id})
+func handleSharedSettlementDetail(w http.ResponseWriter, r *http.Request, idStr string) {
+	settlementID64, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid settlement ID: " + err.Error()})
+		return
+	}
+	settlementID := uint(settlementID64)
+
+	settlement, err := sharedExpensesAPI.GetSettlementByID(settlementID)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if !requireWalletRole(w, r, settlement.WalletID, methodRole(r)) {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Shared settlement retrieved successfully",
+			"data":    settlement,
+		})
+
+	case http.MethodDelete:
+		if err := sharedExpensesAPI.DeleteSettlement(settlementID); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Shared settlement deleted successfully",
+		})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// ==================== Tag Handlers ====================
+
+// handleTags handles tag list and creation (GET, POST /api/tags)
+func handleTags(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodPost:
+		var req tagsAPI.TagCreationRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body: " + err.Error()})
+			return
+		}
+
+		tag, err := tagsAPI.CreateTag(&req)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Tag created successfully",
+			"data":    tag,
+		})
+
+	case http.MethodGet:
+		// Transactions are counted in one wallet with ?wallet_id=, otherwise in the caller's wallets
+		walletID, walletIDs, ok := walletScope(w, r)
+		if !ok {
+			return
+		}
+		if walletID != nil {
+			walletIDs = []uint{*walletID}
+		}
+		tags, err := tagsAPI.ListTags(walletIDs)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Tags retrieved successfully",
+			"data":    tags,
+		})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleTagDetail handles tag operations (GET, PUT, DELETE /api/tags/{id})
+func handleTagDetail(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) != 4 {
+		http.Error(w, "Invalid URL path", http.StatusBadRequest)
+		return
+	}
+
+	tagID64, err := strconv.ParseUint(parts[3], 10, 32)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid tag ID: " + err.Error()})
+		return
+	}
+	tagID := uint(tagID64)
+
+	switch r.Method {
+	case http.MethodGet:
+		tag, err := tagsAPI.GetTagByID(tagID)
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Tag retrieved successfully",
+			"data":    tag,
+		})
+
+	case http.MethodPut:
+		var req tagsAPI.TagUpdateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body: " + err.Error()})
+			return
+		}
+
+		tag, err := tagsAPI.UpdateTag(tagID, &req)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Tag updated successfully",
+			"data":    tag,
+		})
+
+	case http.MethodDelete:
+		if err := tagsAPI.DeleteTag(tagID); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Tag deleted successfully",
+		})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,516 @@
+package sharedexpenses
+
+import (
+	"fmt"
+	"log"
+	"moneyplanner/api/debts"
+	"moneyplanner/api/transactions"
+	"moneyplanner/database"
+	"moneyplanner/models"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ledger is everything recorded in a wallet's shared ledger with the names of its participants
+type ledger struct {
+	wallet      models.Wallet
+	expenses    []models.SharedExpense
+	settlements []models.SharedSettlement
+	names       map[uint]string // By personKey
+}
+
+// loadLedger loads a wallet's shared ledger on the given handle
+func loadLedger(tx *gorm.DB, walletID uint) (*ledger, error) {
+	l := &ledger{names: map[uint]string{0: youName}}
+	if err := tx.First(&l.wallet, walletID).Error; err != nil {
+		return nil, fmt.Errorf("wallet not found: %w", err)
+	}
+	if err := tx.Where("wallet_id = ?", walletID).Order("expense_time, shared_expense_id").Find(&l.expenses).Error; err != nil {
+		return nil, fmt.Errorf("failed to load shared expenses: %w", err)
+	}
+	if err := loadShares(tx, l.expenses); err != nil {
+		return nil, err
+	}
+	if err := tx.Where("wallet_id = ?", walletID).Order("settlement_time, shared_settlement_id").Find(&l.settlements).Error; err != nil {
+		return nil, fmt.Errorf("failed to load shared settlements: %w", err)
+	}
+
+	// Trashed persons still show in the balances
+	personIDs := []uint{}
+	for _, expense := range l.expenses {
+		if expense.PaidByPersonID != nil {
+			personIDs = append(personIDs, *expense.PaidByPersonID)
+		}
+		for _, share := range expense.Shares {
+			if share.PersonID != nil {
+				personIDs = append(personIDs, *share.PersonID)
+			}
+		}
+	}
+	for _, settlement := range l.settlements {
+		for _, personID := range []*uint{settlement.FromPersonID, settlement.ToPersonID} {
+			if personID != nil {
+				personIDs = append(personIDs, *personID)
+			}
+		}
+	}
+	if len(personIDs) > 0 {
+		var people []models.Person
+		if err := tx.Unscoped().Where("person_id IN ?", personIDs).Find(&people).Error; err != nil {
+			return nil, fmt.Errorf("failed to load persons: %w", err)
+		}
+		for _, person := range people {
+			l.names[person.PersonID] = person.PersonName
+		}
+	}
+	return l, nil
+}
+
+// payment builds a payment between two participants, named from the ledger
+func (l *ledger) payment(from, to uint, amount models.Money) Payment {
+	return Payment{
+		FromPersonID: personRef(from),
+		FromName:     l.names[from],
+		ToPersonID:   personRef(to),
+		ToName:       l.names[to],
+		Amount:       amount,
+	}
+}
+
+// members works out where each participant stands, by name with you first
+func (l *ledger) members() []MemberBalance {
+	byKey := map[uint]*MemberBalance{}
+	member := func(key uint) *MemberBalance {
+		if byKey[key] == nil {
+			byKey[key] = &MemberBalance{PersonID: personRef(key), PersonName: l.names[key]}
+		}
+		return byKey[key]
+	}
+	for _, expense := range l.expenses {
+		member(personKey(expense.PaidByPersonID)).Paid += expense.Amount
+		for _, share := range expense.Shares {
+			member(personKey(share.PersonID)).Share += share.Amount
+		}
+	}
+	for _, settlement := range l.settlements {
+		member(personKey(settlement.FromPersonID)).Sent += settlement.Amount
+		member(personKey(settlement.ToPersonID)).Received += settlement.Amount
+	}
+
+	members := make([]MemberBalance, 0, len(byKey))
+	for _, m := range byKey {
+		m.Net = m.Paid - m.Share + m.Sent - m.Received
+		members = append(members, *m)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if (members[i].PersonID == nil) != (members[j].PersonID == nil) {
+			return members[i].PersonID == nil
+		}
+		if members[i].PersonName != members[j].PersonName {
+			return members[i].PersonName < members[j].PersonName
+		}
+		return personKey(members[i].PersonID) < personKey(members[j].PersonID)
+	})
+	return members
+}
+
+// pair is a debtor and creditor, by personKey
+type pair struct{ from, to uint }
+
+// owes nets what each pair of participants owe each other: every share is owed to the one who
+// paid the expense, and a settlement pays off what its payer owes the other
+func (l *ledger) owes() []Payment {
+	owed := map[pair]models.Money{}
+	for _, expense := range l.expenses {
+		payer := personKey(expense.PaidByPersonID)
+		for _, share := range expense.Shares {
+			if debtor := personKey(share.PersonID); debtor != payer {
+				owed[pair{debtor, payer}] += share.Amount
+			}
+		}
+	}
+	for _, settlement := range l.settlements {
+		owed[pair{personKey(settlement.FromPersonID), personKey(settlement.ToPersonID)}] -= settlement.Amount
+	}
+
+	netted := map[pair]models.Money{}
+	for p, amount := range owed {
+		if p.from > p.to {
+			p, amount = pair{p.to, p.from}, -amount
+		}
+		netted[p] += amount
+	}
+	debts := map[pair]models.Money{}
+	for p, amount := range netted {
+		switch {
+		case amount > 0:
+			debts[p] = amount
+		case amount < 0:
+			debts[pair{p.to, p.from}] = -amount
+		}
+	}
+	cancelCycles(debts)
+
+	payments := []Payment{}
+	for p, amount := range debts {
+		payments = append(payments, l.payment(p.from, p.to, amount))
+	}
+	sortPayments(payments)
+	return payments
+}
+
+// cancelCycles takes out debts that go round in a circle, such as A owing B, B owing C and C
+// owing A, by the smallest amount in the circle. Everyone's net stays the same, and once all
+// nets are zero nothing is left owed; that happens when settlements followed the suggested
+// payments rather than the pairs.
+func cancelCycles(debts map[pair]models.Money) {
+	for {
+		cycle := findCycle(debts)
+		if cycle == nil {
+			return
+		}
+		smallest := debts[cycle[0]]
+		for _, p := range cycle {
+			smallest = min(smallest, debts[p])
+		}
+		for _, p := range cycle {
+			if debts[p] -= smallest; debts[p] == 0 {
+				delete(debts, p)
+			}
+		}
+	}
+}
+
+// findCycle returns the debts along one circle, or nil when there is none
+func findCycle(debts map[pair]models.Money) []pair {
+	creditors := map[uint][]uint{}
+	for p := range debts {
+		creditors[p.from] = append(creditors[p.from], p.to)
+	}
+	for from := range creditors {
+		sort.Slice(creditors[from], func(i, j int) bool { return creditors[from][i] < creditors[from][j] })
+	}
+
+	const (
+		unvisited = iota
+		onPath
+		done
+	)
+	state := map[uint]int{}
+	var path []uint
+	var visit func(key uint) []pair
+	visit = func(key uint) []pair {
+		state[key] = onPath
+		path = append(path, key)
+		for _, next := range creditors[key] {
+			switch state[next] {
+			case onPath:
+				var cycle []pair
+				for i := len(path) - 1; path[i] != next; i-- {
+					cycle = append(cycle, pair{path[i-1], path[i]})
+				}
+				return append(cycle, pair{key, next})
+			case unvisited:
+				if cycle := visit(next); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		state[key] = done
+		path = path[:len(path)-1]
+		return nil
+	}
+
+	starts := make([]uint, 0, len(creditors))
+	for from := range creditors {
+		starts = append(starts, from)
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+	for _, start := range starts {
+		if state[start] == unvisited {
+			if cycle := visit(start); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// sortPayments orders payments by payer and then payee, with you first
+func sortPayments(payments []Payment) {
+	sort.Slice(payments, func(i, j int) bool {
+		a, b := payments[i], payments[j]
+		if personKey(a.FromPersonID) != personKey(b.FromPersonID) {
+			return personKey(a.FromPersonID) < personKey(b.FromPersonID)
+		}
+		return personKey(a.ToPersonID) < personKey(b.ToPersonID)
+	})
+}
+
+// suggest finds a small set of payments that brings every participant's net to zero. Debtors
+// and creditors whose amounts match pay each other directly; the rest are settled by having the
+// largest debtor pay the largest creditor until everyone is even, which takes at most one
+// payment fewer than there are participants left.
+func (l *ledger) suggest(members []MemberBalance) []Payment {
+	type party struct {
+		key    uint
+		amount models.Money
+	}
+	var debtors, creditors []*party
+	for _, m := range members {
+		switch {
+		case m.Net < 0:
+			debtors = append(debtors, &party{personKey(m.PersonID), -m.Net})
+		case m.Net > 0:
+			creditors = append(creditors, &party{personKey(m.PersonID), m.Net})
+		}
+	}
+
+	payments := []Payment{}
+	for _, debtor := range debtors {
+		for _, creditor := range creditors {
+			if creditor.amount > 0 && creditor.amount == debtor.amount {
+				payments = append(payments, l.payment(debtor.key, creditor.key, debtor.amount))
+				debtor.amount, creditor.amount = 0, 0
+				break
+			}
+		}
+	}
+
+	largest := func(parties []*party) *party {
+		var found *party
+		for _, p := range parties {
+			if p.amount > 0 && (found == nil || p.amount > found.amount) {
+				found = p
+			}
+		}
+		return found
+	}
+	for {
+		debtor, creditor := largest(debtors), largest(creditors)
+		if debtor == nil || creditor == nil {
+			break
+		}
+		amount := min(debtor.amount, creditor.amount)
+		payments = append(payments, l.payment(debtor.key, creditor.key, amount))
+		debtor.amount -= amount
+		creditor.amount -= amount
+	}
+	sortPayments(payments)
+	return payments
+}
+
+// GetBalances works out the running balances of a wallet's shared ledger: where each
+// participant stands, who owes whom, and the payments that would settle everyone
+func GetBalances(walletID uint) (*SharedBalances, error) {
+	l, err := loadLedger(database.DB, walletID)
+	if err != nil {
+		return nil, err
+	}
+	members := l.members()
+	return &SharedBalances{
+		WalletID:  walletID,
+		Currency:  l.wallet.Currency,
+		Members:   members,
+		Owes:      l.owes(),
+		Suggested: l.suggest(members),
+	}, nil
+}
+
+// ListSettlements lists the settlements of the wallets, newest first
+func ListSettlements(filter *SharedExpenseFilter) ([]models.SharedSettlement, error) {
+	settlements := []models.SharedSettlement{}
+	query := database.DB.Order("settlement_time DESC, shared_settlement_id DESC")
+	if filter != nil {
+		if filter.WalletIDs != nil {
+			query = query.Where("wallet_id IN ?", filter.WalletIDs)
+		}
+		if filter.WalletID != nil {
+			query = query.Where("wallet_id = ?", *filter.WalletID)
+		}
+		if filter.PersonID != nil {
+			query = query.Where("(from_person_id = ? OR to_person_id = ?)", *filter.PersonID, *filter.PersonID)
+		}
+	}
+	if err := query.Find(&settlements).Error; err != nil {
+		return nil, fmt.Errorf("failed to list shared settlements: %w", err)
+	}
+	return settlements, nil
+}
+
+// recordSettlement stores a settlement on the given handle. When you are one of the two it
+// is posted to the wallet's debt category: paying takes the amount out of the wallet and
+// being paid puts it in.
+func recordSettlement(tx *gorm.DB, settlement *models.SharedSettlement) error {
+	from, to := personKey(settlement.FromPersonID), personKey(settlement.ToPersonID)
+	if from == to {
+		return fmt.Errorf("a settlement needs two different participants")
+	}
+	if settlement.Amount <= 0 {
+		return fmt.Errorf("amount must be positive")
+	}
+
+	if from == 0 || to == 0 {
+		category, err := debts.DebtCategory(tx, settlement.WalletID)
+		if err != nil {
+			return err
+		}
+		amount, other := -settlement.Amount, settlement.ToPersonID
+		if to == 0 {
+			amount, other = settlement.Amount, settlement.FromPersonID
+		}
+		note := settlement.Note
+		if note == nil {
+			var person models.Person
+			if err := tx.Unscoped().First(&person, *other).Error; err != nil {
+				return fmt.Errorf("person %d not found: %w", *other, err)
+			}
+			text := "Settled up with " + person.PersonName
+			note = &text
+		}
+		posting, err := transactions.CreateTransactionInTx(tx, &transactions.TransactionCreationRequest{
+			WalletID:        settlement.WalletID,
+			CategoryID:      category.CategoryID,
+			Amount:          amount,
+			PersonID:        other,
+			Note:            note,
+			TransactionTime: &settlement.SettlementTime,
+			UserID:          settlement.UserID,
+		})
+		if err != nil {
+			return err
+		}
+		settlement.TransactionID = &posting.TransactionID
+	}
+
+	if err := tx.Create(settlement).Error; err != nil {
+		return fmt.Errorf("failed to create shared settlement: %w", err)
+	}
+	return nil
+}
+
+// CreateSettlement records a payment between two participants of a wallet's shared ledger
+func CreateSettlement(req *SettlementRequest) (*models.SharedSettlement, error) {
+	if req.WalletID == 0 {
+		return nil, fmt.Errorf("wallet_id is required")
+	}
+	if req.UserID == 0 {
+		return nil, fmt.Errorf("user_id is required")
+	}
+
+	now := time.Now()
+	settlement := &models.SharedSettlement{
+		WalletID:       req.WalletID,
+		Amount:         req.Amount,
+		Note:           req.Note,
+		SettlementTime: now,
+		UserID:         req.UserID,
+		EntryTime:      now,
+	}
+	if req.SettlementTime != nil {
+		settlement.SettlementTime = *req.SettlementTime
+	}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if settlement.FromPersonID, err = resolveParticipant(tx, req.FromPersonID, nil); err != nil {
+			return err
+		}
+		if settlement.ToPersonID, err = resolveParticipant(tx, req.ToPersonID, nil); err != nil {
+			return err
+		}
+		if err := tx.First(&models.Wallet{}, req.WalletID).Error; err != nil {
+			return fmt.Errorf("wallet not found: %w", err)
+		}
+		return recordSettlement(tx, settlement)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("✓ Shared settlement (ID: %d) recorded: %s in wallet %d", settlement.SharedSettlementID, settlement.Amount, settlement.WalletID)
+	return settlement, nil
+}
+
+// SettleUp records every suggested payment of a wallet's shared ledger as a settlement
+func SettleUp(req *SettleUpRequest) ([]models.SharedSettlement, error) {
+	if req.WalletID == 0 {
+		return nil, fmt.Errorf("wallet_id is required")
+	}
+	if req.UserID == 0 {
+		return nil, fmt.Errorf("user_id is required")
+	}
+
+	now := time.Now()
+	settlementTime := now
+	if req.SettlementTime != nil {
+		settlementTime = *req.SettlementTime
+	}
+	settlements := []models.SharedSettlement{}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		l, err := loadLedger(tx, req.WalletID)
+		if err != nil {
+			return err
+		}
+		suggested := l.suggest(l.members())
+		if len(suggested) == 0 {
+			return fmt.Errorf("everyone in wallet %d is already settled up", req.WalletID)
+		}
+		for _, payment := range suggested {
+			settlement := models.SharedSettlement{
+				WalletID:       req.WalletID,
+				FromPersonID:   payment.FromPersonID,
+				ToPersonID:     payment.ToPersonID,
+				Amount:         payment.Amount,
+				SettlementTime: settlementTime,
+				UserID:         req.UserID,
+				EntryTime:      now,
+			}
+			if err := recordSettlement(tx, &settlement); err != nil {
+				return err
+			}
+			settlements = append(settlements, settlement)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("✓ Settled up wallet %d: %d payment(s)", req.WalletID, len(settlements))
+	return settlements, nil
+}
+
+// DeleteSettlement deletes a settlement, reversing its posting
+func DeleteSettlement(settlementID uint) error {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var settlement models.SharedSettlement
+		if err := tx.First(&settlement, settlementID).Error; err != nil {
+			return fmt.Errorf("shared settlement not found: %w", err)
+		}
+		// Deleted first so the posting is no longer guarded as a settlement
+		if err := tx.Delete(&models.SharedSettlement{}, settlementID).Error; err != nil {
+			return fmt.Errorf("failed to delete shared settlement: %w", err)
+		}
+		if settlement.TransactionID == nil {
+			return nil
+		}
+		return transactions.UnpostTransactionByID(tx, *settlement.TransactionID)
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("✓ Shared settlement (ID: %d) deleted", settlementID)
+	return nil
+}
+
+// GetSettlementByID retrieves a settlement
+func GetSettlementByID(settlementID uint) (*models.SharedSettlement, error) {
+	var settlement models.SharedSettlement
+	if err := database.DB.First(&settlement, settlementID).Error; err != nil {
+		return nil, fmt.Errorf("shared settlement not found: %w", err)
+	}
+	return &settlement, nil
+}
@@ -0,0 +1,239 @@
+package sharedexpenses
+
+import (
+	"math/rand"
+	"testing"
+
+	"moneyplanner/models"
+)
+
+// testNames are the participants of the test ledgers by personKey; 0 is you
+var testNames = map[uint]string{0: youName, 1: "Alice", 2: "Bob", 3: "Carol", 4: "Dave"}
+
+func newTestLedger() *ledger {
+	names := map[uint]string{}
+	for key, name := range testNames {
+		names[key] = name
+	}
+	return &ledger{names: names}
+}
+
+// addExpense records an expense paid by payer and split by the method between the participants
+func (l *ledger) addExpense(t *testing.T, payer uint, amount models.Money, method models.SplitMethod, participants []uint, values []models.Money) {
+	t.Helper()
+	shares := make([]models.SharedExpenseShare, len(participants))
+	for i, key := range participants {
+		shares[i].PersonID = personRef(key)
+		if values != nil {
+			shares[i].Value = values[i]
+		}
+	}
+	if err := computeShares(method, amount, shares); err != nil {
+		t.Fatalf("computeShares returned error: %v", err)
+	}
+	l.expenses = append(l.expenses, models.SharedExpense{
+		Amount:         amount,
+		PaidByPersonID: personRef(payer),
+		SplitMethod:    method,
+		Shares:         shares,
+	})
+}
+
+func (l *ledger) addSettlement(from, to uint, amount models.Money) {
+	l.settlements = append(l.settlements, models.SharedSettlement{
+		FromPersonID: personRef(from),
+		ToPersonID:   personRef(to),
+		Amount:       amount,
+	})
+}
+
+// netsAfter returns each participant's net once the payments are made
+func netsAfter(members []MemberBalance, payments []Payment) map[uint]models.Money {
+	nets := map[uint]models.Money{}
+	for _, m := range members {
+		nets[personKey(m.PersonID)] = m.Net
+	}
+	for _, p := range payments {
+		nets[personKey(p.FromPersonID)] += p.Amount
+		nets[personKey(p.ToPersonID)] -= p.Amount
+	}
+	return nets
+}
+
+// checkLedger checks that the nets add up to zero and that both the suggested payments and
+// paying off what is owed leave everyone even
+func checkLedger(t *testing.T, l *ledger) {
+	t.Helper()
+	members := l.members()
+
+	var total models.Money
+	nonZero := 0
+	for _, m := range members {
+		if m.Net != m.Paid-m.Share+m.Sent-m.Received {
+			t.Errorf("%s: net %d, want paid - share + sent - received", m.PersonName, m.Net)
+		}
+		total += m.Net
+		if m.Net != 0 {
+			nonZero++
+		}
+	}
+	if total != 0 {
+		t.Fatalf("nets add up to %d, want 0: %+v", total, members)
+	}
+
+	suggested := l.suggest(members)
+	if nonZero > 0 && len(suggested) > nonZero-1 {
+		t.Errorf("%d suggested payments for %d participants who are not even, want at most %d",
+			len(suggested), nonZero, nonZero-1)
+	}
+	owed := l.owes()
+	for name, payments := range map[string][]Payment{"suggested": suggested, "owed": owed} {
+		for _, p := range payments {
+			if p.Amount <= 0 {
+				t.Errorf("%s payment from %s to %s of %d, want a positive amount", name, p.FromName, p.ToName, p.Amount)
+			}
+			if personKey(p.FromPersonID) == personKey(p.ToPersonID) {
+				t.Errorf("%s payment from %s to themselves", name, p.FromName)
+			}
+		}
+		for key, net := range netsAfter(members, payments) {
+			if net != 0 {
+				t.Errorf("after the %s payments %s is at %d, want 0", name, testNames[key], net)
+			}
+		}
+	}
+
+	// Any two participants owe each other in one direction only
+	seen := map[pair]bool{}
+	for _, p := range owed {
+		from, to := personKey(p.FromPersonID), personKey(p.ToPersonID)
+		if seen[pair{to, from}] {
+			t.Errorf("%s and %s owe each other both ways", p.FromName, p.ToName)
+		}
+		seen[pair{from, to}] = true
+	}
+}
+
+func TestLedgerBalances(t *testing.T) {
+	l := newTestLedger()
+	l.addExpense(t, 0, 9000, models.SplitMethodEqual, []uint{0, 1, 2}, nil)
+	l.addExpense(t, 1, 5000, models.SplitMethodExact, []uint{0, 3}, []models.Money{2500, 2500})
+	l.addExpense(t, 2, 1001, models.SplitMethodEqual, []uint{1, 2, 3}, nil)
+	l.addSettlement(3, 0, 1000)
+
+	want := map[uint]models.Money{
+		0: 9000 - 3000 - 2500 - 1000, // Paid for dinner; owes Alice for tickets; paid back by Carol
+		1: 5000 - 3000 - 334,
+		2: 1001 - 3000 - 334,
+		3: -2500 - 333 + 1000,
+	}
+	for _, m := range l.members() {
+		if m.Net != want[personKey(m.PersonID)] {
+			t.Errorf("%s: net %d, want %d", m.PersonName, m.Net, want[personKey(m.PersonID)])
+		}
+	}
+	checkLedger(t, l)
+}
+
+func TestLedgerMembersOrder(t *testing.T) {
+	l := newTestLedger()
+	l.addExpense(t, 3, 300, models.SplitMethodEqual, []uint{2, 3, 0, 1}, nil)
+
+	var got []string
+	for _, m := range l.members() {
+		got = append(got, m.PersonName)
+	}
+	want := []string{youName, "Alice", "Bob", "Carol"}
+	if len(got) != len(want) {
+		t.Fatalf("members = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("members = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestLedgerCycleCancels(t *testing.T) {
+	// Alice paid for Bob, Bob for Carol and Carol for Alice: nobody owes anything in the end
+	l := newTestLedger()
+	l.addExpense(t, 1, 1000, models.SplitMethodExact, []uint{2}, []models.Money{1000})
+	l.addExpense(t, 2, 1000, models.SplitMethodExact, []uint{3}, []models.Money{1000})
+	l.addExpense(t, 3, 1000, models.SplitMethodExact, []uint{1}, []models.Money{1000})
+
+	if owed := l.owes(); len(owed) != 0 {
+		t.Errorf("owes() = %+v, want nothing", owed)
+	}
+	if suggested := l.suggest(l.members()); len(suggested) != 0 {
+		t.Errorf("suggest() = %+v, want nothing", suggested)
+	}
+	checkLedger(t, l)
+}
+
+func TestLedgerSettledBySuggestions(t *testing.T) {
+	l := newTestLedger()
+	l.addExpense(t, 0, 12000, models.SplitMethodWeighted, []uint{0, 1, 2, 3}, []models.Money{100, 200, 100, 100})
+	l.addExpense(t, 4, 777, models.SplitMethodPercentage, []uint{4, 2}, []models.Money{3000, 7000})
+
+	// Settling as suggested, rather than pair by pair, leaves nothing owed
+	for _, p := range l.suggest(l.members()) {
+		l.addSettlement(personKey(p.FromPersonID), personKey(p.ToPersonID), p.Amount)
+	}
+	for _, m := range l.members() {
+		if m.Net != 0 {
+			t.Errorf("%s: net %d after settling up, want 0", m.PersonName, m.Net)
+		}
+	}
+	if owed := l.owes(); len(owed) != 0 {
+		t.Errorf("owes() after settling up = %+v, want nothing", owed)
+	}
+	checkLedger(t, l)
+}
+
+func TestLedgerAlwaysNetsToZero(t *testing.T) {
+	methods := []models.SplitMethod{
+		models.SplitMethodEqual, models.SplitMethodExact, models.SplitMethodPercentage, models.SplitMethodWeighted,
+	}
+	random := rand.New(rand.NewSource(1))
+	for round := 0; round < 200; round++ {
+		l := newTestLedger()
+		for i := 0; i < 1+random.Intn(8); i++ {
+			amount := models.Money(1 + random.Intn(100000))
+			participants := random.Perm(len(testNames))[:1+random.Intn(len(testNames))]
+			keys := make([]uint, len(participants))
+			for j, p := range participants {
+				keys[j] = uint(p)
+			}
+
+			method := methods[random.Intn(len(methods))]
+			var values []models.Money
+			switch method {
+			case models.SplitMethodExact:
+				values = allocate(amount, randomWeights(random, len(keys)))
+			case models.SplitMethodPercentage:
+				values = allocate(hundredPercent, randomWeights(random, len(keys)))
+			case models.SplitMethodWeighted:
+				values = randomWeights(random, len(keys))
+			}
+			l.addExpense(t, uint(random.Intn(len(testNames))), amount, method, keys, values)
+		}
+		for i := 0; i < random.Intn(4); i++ {
+			from, to := uint(random.Intn(len(testNames))), uint(random.Intn(len(testNames)))
+			if from != to {
+				l.addSettlement(from, to, models.Money(1+random.Intn(5000)))
+			}
+		}
+		checkLedger(t, l)
+		if t.Failed() {
+			t.Fatalf("round %d failed", round)
+		}
+	}
+}
+
+func randomWeights(random *rand.Rand, n int) []models.Money {
+	weights := make([]models.Money, n)
+	for i := range weights {
+		weights[i] = models.Money(1 + random.Intn(500))
+	}
+	return weights
+}
@@ -0,0 +1,403 @@
+package sharedexpenses
+
+import (
+	"fmt"
+	"log"
+	"moneyplanner/api/debts"
+	"moneyplanner/api/persons"
+	"moneyplanner/api/transactions"
+	"moneyplanner/database"
+	"moneyplanner/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// youName is how the wallet itself shows among the participants
+const youName = "You"
+
+// personKey maps a participant to a map key; 0 is you, as person IDs start at 1
+func personKey(personID *uint) uint {
+	if personID == nil {
+		return 0
+	}
+	return *personID
+}
+
+// personRef is the reverse of personKey
+func personRef(key uint) *uint {
+	if key == 0 {
+		return nil
+	}
+	return &key
+}
+
+// loadShares attaches the shares to the expenses on the given handle
+func loadShares(tx *gorm.DB, expenses []models.SharedExpense) error {
+	if len(expenses) == 0 {
+		return nil
+	}
+	expenseIDs := make([]uint, len(expenses))
+	for i := range expenses {
+		expenseIDs[i] = expenses[i].SharedExpenseID
+	}
+
+	var shares []models.SharedExpenseShare
+	if err := tx.Where("shared_expense_id IN ?", expenseIDs).Order("shared_expense_share_id").Find(&shares).Error; err != nil {
+		return fmt.Errorf("failed to load shares: %w", err)
+	}
+	byExpense := map[uint][]models.SharedExpenseShare{}
+	for _, share := range shares {
+		byExpense[share.SharedExpenseID] = append(byExpense[share.SharedExpenseID], share)
+	}
+	for i := range expenses {
+		expenses[i].Shares = byExpense[expenses[i].SharedExpenseID]
+	}
+	return nil
+}
+
+// getSharedExpense loads a shared expense with its shares on the given handle
+func getSharedExpense(tx *gorm.DB, expenseID uint) (*models.SharedExpense, error) {
+	var expense models.SharedExpense
+	if err := tx.First(&expense, expenseID).Error; err != nil {
+		return nil, fmt.Errorf("shared expense not found: %w", err)
+	}
+	expenses := []models.SharedExpense{expense}
+	if err := loadShares(tx, expenses); err != nil {
+		return nil, err
+	}
+	return &expenses[0], nil
+}
+
+// GetSharedExpenseByID retrieves a shared expense with its shares
+func GetSharedExpenseByID(expenseID uint) (*models.SharedExpense, error) {
+	return getSharedExpense(database.DB, expenseID)
+}
+
+// ListSharedExpenses lists shared expenses with their shares, newest first
+func ListSharedExpenses(filter *SharedExpenseFilter) ([]models.SharedExpense, error) {
+	expenses := []models.SharedExpense{}
+	query := database.DB.Order("expense_time DESC, shared_expense_id DESC")
+	if filter != nil {
+		if filter.WalletIDs != nil {
+			query = query.Where("wallet_id IN ?", filter.WalletIDs)
+		}
+		if filter.WalletID != nil {
+			query = query.Where("wallet_id = ?", *filter.WalletID)
+		}
+		if filter.PersonID != nil {
+			query = query.Where("(paid_by_person_id = ? OR shared_expense_id IN (?))", *filter.PersonID,
+				database.DB.Model(&models.SharedExpenseShare{}).Select("shared_expense_id").Where("person_id = ?", *filter.PersonID))
+		}
+	}
+	if err := query.Find(&expenses).Error; err != nil {
+		return nil, fmt.Errorf("failed to list shared expenses: %w", err)
+	}
+	if err := loadShares(database.DB, expenses); err != nil {
+		return nil, err
+	}
+	return expenses, nil
+}
+
+// resolveParticipant returns the person named by ID or name, creating it by name if needed.
+// Naming nobody means you, which comes back as nil.
+func resolveParticipant(tx *gorm.DB, personID *uint, personName *string) (*uint, error) {
+	if personName != nil && strings.TrimSpace(*personName) != "" {
+		person, err := persons.FindOrCreatePersonByName(tx, *personName)
+		if err != nil {
+			return nil, err
+		}
+		return &person.PersonID, nil
+	}
+	if personID == nil {
+		return nil, nil
+	}
+	var person models.Person
+	if err := tx.First(&person, *personID).Error; err != nil {
+		return nil, fmt.Errorf("person %d not found: %w", *personID, err)
+	}
+	return &person.PersonID, nil
+}
+
+// buildSharedExpense checks a request and works out its shares on the given handle
+func buildSharedExpense(tx *gorm.DB, req *SharedExpenseRequest) (*models.SharedExpense, error) {
+	if req.WalletID == 0 {
+		return nil, fmt.Errorf("wallet_id is required")
+	}
+	if strings.TrimSpace(req.Description) == "" {
+		return nil, fmt.Errorf("description is required")
+	}
+	if req.Amount <= 0 {
+		return nil, fmt.Errorf("amount must be positive")
+	}
+	if req.UserID == 0 {
+		return nil, fmt.Errorf("user_id is required")
+	}
+	method := req.SplitMethod
+	if method == "" {
+		method = models.SplitMethodEqual
+	}
+	if !method.IsValid() {
+		return nil, fmt.Errorf("split_method must be equal, exact, percentage or weighted")
+	}
+	if len(req.Shares) < 2 {
+		return nil, fmt.Errorf("a shared expense needs at least two shares")
+	}
+	if err := tx.First(&models.Wallet{}, req.WalletID).Error; err != nil {
+		return nil, fmt.Errorf("wallet not found: %w", err)
+	}
+
+	paidBy, err := resolveParticipant(tx, req.PaidByPersonID, req.PaidByPersonName)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[uint]bool{}
+	shares := make([]models.SharedExpenseShare, len(req.Shares))
+	for i, share := range req.Shares {
+		personID, err := resolveParticipant(tx, share.PersonID, share.PersonName)
+		if err != nil {
+			return nil, err
+		}
+		if seen[personKey(personID)] {
+			return nil, fmt.Errorf("a participant appears in more than one share")
+		}
+		seen[personKey(personID)] = true
+		shares[i] = models.SharedExpenseShare{PersonID: personID, Value: share.Value}
+	}
+	if err := computeShares(method, req.Amount, shares); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	expenseTime := now
+	if req.ExpenseTime != nil {
+		expenseTime = *req.ExpenseTime
+	}
+	expense := &models.SharedExpense{
+		WalletID:         req.WalletID,
+		Description:      strings.TrimSpace(req.Description),
+		Amount:           req.Amount,
+		PaidByPersonID:   paidBy,
+		SplitMethod:      method,
+		ExpenseTime:      expenseTime,
+		UserID:           req.UserID,
+		EntryTime:        now,
+		LastModifiedTime: now,
+		Shares:           shares,
+	}
+
+	// The category only matters for your own share
+	if yourShare(expense) > 0 {
+		if req.CategoryID == nil {
+			return nil, fmt.Errorf("category_id is required when you have a share")
+		}
+		var category models.Category
+		if err := tx.First(&category, *req.CategoryID).Error; err != nil {
+			return nil, fmt.Errorf("category not found: %w", err)
+		}
+		if category.WalletID != req.WalletID {
+			return nil, fmt.Errorf("category %d does not belong to wallet %d", category.CategoryID, req.WalletID)
+		}
+		if category.Kind != models.CategoryKindExpense {
+			return nil, fmt.Errorf("your share can only be posted to an expense category")
+		}
+		expense.CategoryID = &category.CategoryID
+	}
+	return expense, nil
+}
+
+// yourShare returns your part of an expense, 0 when you have none
+func yourShare(expense *models.SharedExpense) models.Money {
+	for _, share := range expense.Shares {
+		if share.PersonID == nil {
+			return share.Amount
+		}
+	}
+	return 0
+}
+
+// postSharedExpense posts the expense's effect on the wallet: your share as an expense, and
+// the rest to the debt category, so that the balance moves only by what actually left it.
+// When you paid, what the others owe goes out as lent; when someone else paid, your share
+// comes in as borrowed from them.
+func postSharedExpense(tx *gorm.DB, expense *models.SharedExpense) error {
+	share := yourShare(expense)
+	paid := models.Money(0)
+	if expense.PaidByPersonID == nil {
+		paid = expense.Amount
+	}
+
+	description := expense.Description
+	if share > 0 {
+		if _, err := transactions.CreateTransactionInTx(tx, &transactions.TransactionCreationRequest{
+			WalletID:        expense.WalletID,
+			CategoryID:      *expense.CategoryID,
+			Amount:          share,
+			PersonID:        expense.PaidByPersonID,
+			Note:            &description,
+			TransactionTime: &expense.ExpenseTime,
+			UserID:          expense.UserID,
+			SharedExpenseID: &expense.SharedExpenseID,
+		}); err != nil {
+			return err
+		}
+	}
+
+	if share == paid {
+		return nil
+	}
+	category, err := debts.DebtCategory(tx, expense.WalletID)
+	if err != nil {
+		return err
+	}
+	_, err = transactions.CreateTransactionInTx(tx, &transactions.TransactionCreationRequest{
+		WalletID:        expense.WalletID,
+		CategoryID:      category.CategoryID,
+		Amount:          share - paid,
+		PersonID:        expense.PaidByPersonID,
+		Note:            &description,
+		TransactionTime: &expense.ExpenseTime,
+		UserID:          expense.UserID,
+		SharedExpenseID: &expense.SharedExpenseID,
+	})
+	return err
+}
+
+// unpostSharedExpense removes the expense's live postings together with their effect on
+// the wallet balance. Postings in the trash stay there as ordinary transactions.
+func unpostSharedExpense(tx *gorm.DB, expenseID uint) error {
+	var postingIDs []uint
+	if err := tx.Model(&models.Transaction{}).Where("shared_expense_id = ?", expenseID).
+		Pluck("transaction_id", &postingIDs).Error; err != nil {
+		return fmt.Errorf("failed to load shared expense postings: %w", err)
+	}
+	for _, postingID := range postingIDs {
+		if err := transactions.UnpostTransactionByID(tx, postingID); err != nil {
+			return err
+		}
+	}
+	if err := tx.Unscoped().Model(&models.Transaction{}).Where("shared_expense_id = ?", expenseID).
+		Update("shared_expense_id", nil).Error; err != nil {
+		return fmt.Errorf("failed to unlink shared expense postings: %w", err)
+	}
+	return nil
+}
+
+// saveShares replaces the shares of an expense
+func saveShares(tx *gorm.DB, expense *models.SharedExpense) error {
+	if err := tx.Where("shared_expense_id = ?", expense.SharedExpenseID).Delete(&models.SharedExpenseShare{}).Error; err != nil {
+		return fmt.Errorf("failed to delete shares: %w", err)
+	}
+	for i := range expense.Shares {
+		expense.Shares[i].SharedExpenseShareID = 0
+		expense.Shares[i].SharedExpenseID = expense.SharedExpenseID
+		if err := tx.Create(&expense.Shares[i]).Error; err != nil {
+			return fmt.Errorf("failed to create share: %w", err)
+		}
+	}
+	return nil
+}
+
+// CreateSharedExpense records an expense shared among persons and posts your side of it
+func CreateSharedExpense(req *SharedExpenseRequest) (*models.SharedExpense, error) {
+	var expense *models.SharedExpense
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		created, err := buildSharedExpense(tx, req)
+		if err != nil {
+			return err
+		}
+		if err := tx.Create(created).Error; err != nil {
+			return fmt.Errorf("failed to create shared expense: %w", err)
+		}
+		if err := saveShares(tx, created); err != nil {
+			return err
+		}
+		if err := postSharedExpense(tx, created); err != nil {
+			return err
+		}
+
+		expense, err = getSharedExpense(tx, created.SharedExpenseID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("✓ Shared expense (ID: %d) created: %s split %s among %d", expense.SharedExpenseID, expense.Amount, expense.SplitMethod, len(expense.Shares))
+	return expense, nil
+}
+
+// UpdateSharedExpense replaces a shared expense as a whole, reposting your side of it.
+// It stays in its wallet.
+func UpdateSharedExpense(expenseID uint, req *SharedExpenseRequest) (*models.SharedExpense, error) {
+	var expense *models.SharedExpense
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		existing, err := getSharedExpense(tx, expenseID)
+		if err != nil {
+			return err
+		}
+		if req.WalletID == 0 {
+			req.WalletID = existing.WalletID
+		}
+		if req.WalletID != existing.WalletID {
+			return fmt.Errorf("a shared expense cannot move to another wallet")
+		}
+		if req.ExpenseTime == nil {
+			req.ExpenseTime = &existing.ExpenseTime
+		}
+
+		updated, err := buildSharedExpense(tx, req)
+		if err != nil {
+			return err
+		}
+		updated.SharedExpenseID = expenseID
+		updated.EntryTime = existing.EntryTime
+		if err := tx.Save(updated).Error; err != nil {
+			return fmt.Errorf("failed to update shared expense: %w", err)
+		}
+		if err := saveShares(tx, updated); err != nil {
+			return err
+		}
+		if err := unpostSharedExpense(tx, expenseID); err != nil {
+			return err
+		}
+		if err := postSharedExpense(tx, updated); err != nil {
+			return err
+		}
+
+		expense, err = getSharedExpense(tx, expenseID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("✓ Shared expense (ID: %d) updated", expenseID)
+	return expense, nil
+}
+
+// DeleteSharedExpense deletes a shared expense with its shares, reversing its postings
+func DeleteSharedExpense(expenseID uint) error {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&models.SharedExpense{}, expenseID).Error; err != nil {
+			return fmt.Errorf("shared expense not found: %w", err)
+		}
+		if err := unpostSharedExpense(tx, expenseID); err != nil {
+			return err
+		}
+		if err := tx.Where("shared_expense_id = ?", expenseID).Delete(&models.SharedExpenseShare{}).Error; err != nil {
+			return fmt.Errorf("failed to delete shares: %w", err)
+		}
+		if err := tx.Delete(&models.SharedExpense{}, expenseID).Error; err != nil {
+			return fmt.Errorf("failed to delete shared expense: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("✓ Shared expense (ID: %d) deleted", expenseID)
+	return nil
+}
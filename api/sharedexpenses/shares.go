@@ -0,0 +1,84 @@
+package sharedexpenses
+
+import (
+	"fmt"
+	"math/big"
+	"moneyplanner/models"
+	"sort"
+)
+
+// hundredPercent is 100 as a Money value, which percentages are entered in
+const hundredPercent models.Money = 100_00
+
+// allocate divides amount in proportion to the weights by the largest remainder method:
+// everyone gets the rounded-down part and the cents left over go one by one to the largest
+// fractions, earlier entries first on a tie, so the parts add up to amount exactly
+func allocate(amount models.Money, weights []models.Money) []models.Money {
+	var sum int64
+	for _, weight := range weights {
+		sum += int64(weight)
+	}
+	total := big.NewInt(sum)
+
+	parts := make([]models.Money, len(weights))
+	remainders := make([]*big.Int, len(weights))
+	var allocated models.Money
+	for i, weight := range weights {
+		quotient, remainder := new(big.Int).QuoRem(
+			new(big.Int).Mul(big.NewInt(int64(amount)), big.NewInt(int64(weight))), total, new(big.Int))
+		parts[i] = models.Money(quotient.Int64())
+		remainders[i] = remainder
+		allocated += parts[i]
+	}
+
+	order := make([]int, len(weights))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return remainders[order[i]].Cmp(remainders[order[j]]) > 0
+	})
+	for i := 0; allocated < amount; i++ {
+		parts[order[i%len(order)]]++
+		allocated++
+	}
+	return parts
+}
+
+// computeShares works out each share's amount from its entered value by the split method
+func computeShares(method models.SplitMethod, amount models.Money, shares []models.SharedExpenseShare) error {
+	values := make([]models.Money, len(shares))
+	var sum models.Money
+	for i := range shares {
+		if method == models.SplitMethodEqual {
+			shares[i].Value = 0
+			values[i] = 1
+			continue
+		}
+		if shares[i].Value < 0 || (shares[i].Value == 0 && method == models.SplitMethodWeighted) {
+			return fmt.Errorf("share values must be positive for %s splits", method)
+		}
+		values[i] = shares[i].Value
+		sum += shares[i].Value
+	}
+
+	switch method {
+	case models.SplitMethodExact:
+		if sum != amount {
+			return fmt.Errorf("shares add up to %s but the amount is %s", sum, amount)
+		}
+		for i := range shares {
+			shares[i].Amount = shares[i].Value
+		}
+		return nil
+	case models.SplitMethodPercentage:
+		if sum != hundredPercent {
+			return fmt.Errorf("percentages add up to %s but must add up to 100", sum)
+		}
+	}
+
+	for i, part := range allocate(amount, values) {
+		shares[i].Amount = part
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package sharedexpenses
+
+import (
+	"testing"
+
+	"moneyplanner/models"
+)
+
+func sumMoney(amounts []models.Money) models.Money {
+	var sum models.Money
+	for _, amount := range amounts {
+		sum += amount
+	}
+	return sum
+}
+
+func equalMoney(a, b []models.Money) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		amount  models.Money
+		weights []models.Money
+		want    []models.Money
+	}{
+		{1000, []models.Money{1, 1}, []models.Money{500, 500}},
+		{100, []models.Money{1, 1, 1}, []models.Money{34, 33, 33}}, // Equal remainders: earlier entries first
+		{200, []models.Money{1, 1, 1}, []models.Money{67, 67, 66}},
+		{1, []models.Money{1, 1, 1}, []models.Money{1, 0, 0}},
+		{1000, []models.Money{1, 2}, []models.Money{333, 667}}, // The larger remainder gets the cent
+		{2000, []models.Money{2, 1, 1}, []models.Money{1000, 500, 500}},
+		{999, []models.Money{5000, 2500, 2500}, []models.Money{499, 250, 250}},
+		{0, []models.Money{1, 1}, []models.Money{0, 0}},
+	}
+	for _, tt := range tests {
+		if got := allocate(tt.amount, tt.weights); !equalMoney(got, tt.want) {
+			t.Errorf("allocate(%d, %v) = %v, want %v", tt.amount, tt.weights, got, tt.want)
+		}
+	}
+}
+
+// The parts always add up to the amount, and each is its exact proportion rounded down or up
+func TestAllocateAlwaysAddsUp(t *testing.T) {
+	weightSets := [][]models.Money{
+		{1, 1},
+		{1, 1, 1},
+		{1, 2, 3, 4, 5, 6, 7},
+		{3333, 3333, 3334},
+		{1, 99999},
+		{7, 7, 7, 7, 7, 7},
+	}
+	for _, weights := range weightSets {
+		total := sumMoney(weights)
+		for amount := models.Money(0); amount <= 3000; amount++ {
+			parts := allocate(amount, weights)
+			if sum := sumMoney(parts); sum != amount {
+				t.Fatalf("allocate(%d, %v) adds up to %d", amount, weights, sum)
+			}
+			for i, part := range parts {
+				floor := amount * weights[i] / total
+				if part != floor && part != floor+1 {
+					t.Fatalf("allocate(%d, %v) gives part %d = %d, want %d or %d", amount, weights, i, part, floor, floor+1)
+				}
+			}
+		}
+	}
+}
+
+func TestComputeShares(t *testing.T) {
+	tests := []struct {
+		method models.SplitMethod
+		amount models.Money
+		values []models.Money
+		want   []models.Money // nil when the shares are rejected
+	}{
+		{models.SplitMethodEqual, 1000, []models.Money{0, 0, 0}, []models.Money{334, 333, 333}},
+		{models.SplitMethodEqual, 1000, []models.Money{7, 1, 0}, []models.Money{334, 333, 333}}, // Values are ignored
+		{models.SplitMethodExact, 1000, []models.Money{600, 400}, []models.Money{600, 400}},
+		{models.SplitMethodExact, 1000, []models.Money{600, 0, 400}, []models.Money{600, 0, 400}},
+		{models.SplitMethodExact, 1000, []models.Money{600, 300}, nil},
+		{models.SplitMethodExact, 1000, []models.Money{1200, -200}, nil},
+		{models.SplitMethodPercentage, 999, []models.Money{5000, 2500, 2500}, []models.Money{499, 250, 250}},
+		{models.SplitMethodPercentage, 1000, []models.Money{3333, 3333, 3334}, []models.Money{333, 333, 334}},
+		{models.SplitMethodPercentage, 1000, []models.Money{5000, 4000}, nil},
+		{models.SplitMethodWeighted, 1000, []models.Money{200, 100}, []models.Money{667, 333}},
+		{models.SplitMethodWeighted, 1000, []models.Money{100, 0}, nil},
+	}
+	for _, tt := range tests {
+		shares := make([]models.SharedExpenseShare, len(tt.values))
+		for i, value := range tt.values {
+			shares[i].Value = value
+		}
+		err := computeShares(tt.method, tt.amount, shares)
+		if tt.want == nil {
+			if err == nil {
+				t.Errorf("computeShares(%s, %d, %v) returned no error", tt.method, tt.amount, tt.values)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("computeShares(%s, %d, %v) returned error: %v", tt.method, tt.amount, tt.values, err)
+			continue
+		}
+		got := make([]models.Money, len(shares))
+		for i, share := range shares {
+			got[i] = share.Amount
+		}
+		if !equalMoney(got, tt.want) {
+			t.Errorf("computeShares(%s, %d, %v) = %v, want %v", tt.method, tt.amount, tt.values, got, tt.want)
+		}
+		if sumMoney(got) != tt.amount {
+			t.Errorf("computeShares(%s, %d, %v) adds up to %d", tt.method, tt.amount, tt.values, sumMoney(got))
+		}
+		if tt.method == models.SplitMethodEqual {
+			for _, share := range shares {
+				if share.Value != 0 {
+					t.Errorf("equal split kept value %d, want 0", share.Value)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package sharedexpenses
+
+import (
+	"moneyplanner/models"
+	"time"
+)
+
+// ShareRequest names one participant of a shared expense; leave out both person fields for you
+type ShareRequest struct {
+	PersonID   *uint        `json:"person_id,omitempty"`
+	PersonName *string      `json:"person_name,omitempty"` // To create person if not exists
+	Value      models.Money `json:"value"`                 // The amount, percentage or weight; ignored for equal splits
+}
+
+// SharedExpenseRequest records a shared expense; on update it replaces the expense as a whole
+type SharedExpenseRequest struct {
+	WalletID         uint               `json:"wallet_id"`
+	Description      string             `json:"description"`
+	Amount           models.Money       `json:"amount"`                        // Positive, in the wallet's currency
+	PaidByPersonID   *uint              `json:"paid_by_person_id,omitempty"`   // Leave out both payer fields when you paid
+	PaidByPersonName *string            `json:"paid_by_person_name,omitempty"` // To create person if not exists
+	SplitMethod      models.SplitMethod `json:"split_method"`                  // Defaults to equal
+	Shares           []ShareRequest     `json:"shares"`
+	CategoryID       *uint              `json:"category_id,omitempty"`  // Expense category for your share; required when you have one
+	ExpenseTime      *time.Time         `json:"expense_time,omitempty"` // Defaults to now
+	UserID           uint               `json:"-"`                      // Set from the caller
+}
+
+type SharedExpenseFilter struct {
+	WalletIDs []uint // Restricts results to these wallets when non-nil
+	WalletID  *uint
+	PersonID  *uint // Expenses the person paid or has a share in
+}
+
+// SettlementRequest records a payment from one participant to another; leave out a person for you
+type SettlementRequest struct {
+	WalletID       uint         `json:"wallet_id"`
+	FromPersonID   *uint        `json:"from_person_id,omitempty"`
+	ToPersonID     *uint        `json:"to_person_id,omitempty"`
+	Amount         models.Money `json:"amount"` // Positive, in the wallet's currency
+	Note           *string      `json:"note,omitempty"`
+	SettlementTime *time.Time   `json:"settlement_time,omitempty"` // Defaults to now
+	UserID         uint         `json:"-"`                         // Set from the caller
+}
+
+// SettleUpRequest records every suggested payment of a wallet's shared ledger as a settlement
+type SettleUpRequest struct {
+	WalletID       uint       `json:"wallet_id"`
+	SettlementTime *time.Time `json:"settlement_time,omitempty"` // Defaults to now
+	UserID         uint       `json:"-"`                         // Set from the caller
+}
+
+// Payment is an amount one participant owes or should pay another; a nil person is you
+type Payment struct {
+	FromPersonID *uint        `json:"from_person_id"`
+	FromName     string       `json:"from_name"`
+	ToPersonID   *uint        `json:"to_person_id"`
+	ToName       string       `json:"to_name"`
+	Amount       models.Money `json:"amount"`
+}
+
+// MemberBalance is where one participant stands in a wallet's shared ledger; a nil person is you
+type MemberBalance struct {
+	PersonID   *uint        `json:"person_id"`
+	PersonName string       `json:"person_name"`
+	Paid       models.Money `json:"paid"`     // Shared expenses they paid
+	Share      models.Money `json:"share"`    // Their shares of shared expenses
+	Sent       models.Money `json:"sent"`     // Settlements they paid
+	Received   models.Money `json:"received"` // Settlements they were paid
+	Net        models.Money `json:"net"`      // Paid - Share + Sent - Received; positive when they are owed
+}
+
+// SharedBalances are the running balances of a wallet's shared ledger
+type SharedBalances struct {
+	WalletID  uint            `json:"wallet_id"`
+	Currency  string          `json:"currency"`
+	Members   []MemberBalance `json:"members"`
+	Owes      []Payment       `json:"owes"`      // Who owes whom, pair by pair
+	Suggested []Payment       `json:"suggested"` // A minimal set of payments that settles everyone
+}
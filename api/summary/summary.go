@@ -0,0 +1,62 @@
+package summary
+
+import (
+	"errors"
+	"moneyplanner/api/exchangerates"
+	"moneyplanner/database"
+	"moneyplanner/models"
+	"time"
+)
+
+// ReportingCurrency returns the currency the user wants totals in: their reporting
+// currency if set, else their default wallet's currency, else models.DefaultCurrency
+func ReportingCurrency(user *models.User) string {
+	if user.ReportingCurrency != "" {
+		return user.ReportingCurrency
+	}
+	if user.DefaultWalletID != nil {
+		var wallet models.Wallet
+		if err := database.DB.First(&wallet, *user.DefaultWalletID).Error; err == nil && wallet.Currency != "" {
+			return wallet.Currency
+		}
+	}
+	return models.DefaultCurrency
+}
+
+// SummarizeWallets totals the wallets' balances in the given currency using the rates in
+// effect at rateDate. Wallets without a usable rate are listed but left out of the total.
+func SummarizeWallets(wallets []models.Wallet, currency string, rateDate time.Time) (*WalletSummary, error) {
+	summary := &WalletSummary{
+		Currency:   currency,
+		RateDate:   rateDate,
+		Complete:   true,
+		ByCurrency: map[string]models.Money{},
+		Wallets:    make([]WalletTotal, 0, len(wallets)),
+	}
+
+	for _, wallet := range wallets {
+		summary.ByCurrency[wallet.Currency] += wallet.Balance
+
+		total := WalletTotal{
+			WalletID: wallet.WalletID,
+			Name:     wallet.Name,
+			Currency: wallet.Currency,
+			Balance:  wallet.Balance,
+		}
+
+		converted, err := exchangerates.Convert(wallet.Balance, wallet.Currency, currency, rateDate)
+		if errors.Is(err, exchangerates.ErrNoRate) {
+			summary.Complete = false
+			summary.Warnings = append(summary.Warnings, err.Error())
+		} else if err != nil {
+			return nil, err
+		} else {
+			total.ConvertedBalance = &converted
+			summary.Total += converted
+		}
+
+		summary.Wallets = append(summary.Wallets, total)
+	}
+
+	return summary, nil
+}
@@ -0,0 +1,26 @@
+package summary
+
+import (
+	"moneyplanner/models"
+	"time"
+)
+
+// WalletTotal is one wallet's balance in its own and in the reporting currency
+type WalletTotal struct {
+	WalletID         uint          `json:"wallet_id"`
+	Name             string        `json:"name"`
+	Currency         string        `json:"currency"`
+	Balance          models.Money  `json:"balance"`
+	ConvertedBalance *models.Money `json:"converted_balance"` // Null when no exchange rate is available
+}
+
+// WalletSummary totals a set of wallets in a single reporting currency
+type WalletSummary struct {
+	Currency   string                  `json:"currency"`
+	RateDate   time.Time               `json:"rate_date"`
+	Total      models.Money            `json:"total"`
+	Complete   bool                    `json:"complete"` // False when some wallets could not be converted
+	ByCurrency map[string]models.Money `json:"by_currency"`
+	Wallets    []WalletTotal           `json:"wallets"`
+	Warnings   []string                `json:"warnings,omitempty"`
+}
@@ -0,0 +1,197 @@
+package tags
+
+import (
+	"fmt"
+	"log"
+	"moneyplanner/database"
+	"moneyplanner/models"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// normalizeName trims a tag name and checks that it can be used in a comma-separated filter
+func normalizeName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("tag name is required")
+	}
+	if strings.Contains(name, ",") {
+		return "", fmt.Errorf("tag name %q must not contain a comma", name)
+	}
+	return name, nil
+}
+
+// findByName looks up a tag by name regardless of case on the given handle; the ID is 0 when
+// there is none
+func findByName(tx *gorm.DB, name string) (*models.Tag, error) {
+	var tag models.Tag
+	if err := tx.Where("LOWER(name) = LOWER(?)", name).Limit(1).Find(&tag).Error; err != nil {
+		return nil, fmt.Errorf("failed to look up tag: %w", err)
+	}
+	return &tag, nil
+}
+
+// GetTagByID retrieves a tag by its ID
+func GetTagByID(tagID uint) (*models.Tag, error) {
+	var tag models.Tag
+	if err := database.DB.First(&tag, tagID).Error; err != nil {
+		return nil, fmt.Errorf("tag not found: %w", err)
+	}
+	return &tag, nil
+}
+
+// ListTags lists all tags by name, each with the number of live transactions carrying it.
+// Only transactions in walletIDs are counted when it is non-nil.
+func ListTags(walletIDs []uint) ([]models.Tag, error) {
+	tags := []models.Tag{}
+	if err := database.DB.Order("LOWER(name)").Find(&tags).Error; err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+
+	var counts []struct {
+		TagID uint
+		Count int64
+	}
+	query := database.DB.Table("transaction_tags").
+		Select("transaction_tags.tag_tag_id AS tag_id, COUNT(*) AS count").
+		Joins("JOIN transactions ON transactions.transaction_id = transaction_tags.transaction_transaction_id AND transactions.deleted_at IS NULL").
+		Group("transaction_tags.tag_tag_id")
+	if walletIDs != nil {
+		query = query.Where("transactions.wallet_id IN ?", walletIDs)
+	}
+	if err := query.Scan(&counts).Error; err != nil {
+		return nil, fmt.Errorf("failed to count tagged transactions: %w", err)
+	}
+	byTag := map[uint]int64{}
+	for _, count := range counts {
+		byTag[count.TagID] = count.Count
+	}
+	for i := range tags {
+		count := byTag[tags[i].TagID]
+		tags[i].TransactionCount = &count
+	}
+	return tags, nil
+}
+
+// CreateTag creates a new tag
+func CreateTag(req *TagCreationRequest) (*models.Tag, error) {
+	name, err := normalizeName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := findByName(database.DB, name)
+	if err != nil {
+		return nil, err
+	}
+	if existing.TagID != 0 {
+		return nil, fmt.Errorf("tag %q already exists", existing.Name)
+	}
+
+	tag := &models.Tag{Name: name, Color: req.Color}
+	if err := database.DB.Create(tag).Error; err != nil {
+		return nil, fmt.Errorf("failed to create tag: %w", err)
+	}
+
+	log.Printf("✓ Tag '%s' created (ID: %d)", tag.Name, tag.TagID)
+	return tag, nil
+}
+
+// FindOrCreateTagsByName returns the tags with the given names, creating the missing ones.
+// Names differing only in case are the same tag. It runs on the given handle so callers can
+// include it in their own DB transaction.
+func FindOrCreateTagsByName(tx *gorm.DB, names []string) ([]models.Tag, error) {
+	tags := []models.Tag{}
+	seen := map[uint]bool{}
+	for _, name := range names {
+		name, err := normalizeName(name)
+		if err != nil {
+			return nil, err
+		}
+		tag, err := findByName(tx, name)
+		if err != nil {
+			return nil, err
+		}
+		if tag.TagID == 0 {
+			tag = &models.Tag{Name: name}
+			if err := tx.Create(tag).Error; err != nil {
+				return nil, fmt.Errorf("failed to create tag: %w", err)
+			}
+			log.Printf("✓ Tag '%s' created (ID: %d)", tag.Name, tag.TagID)
+		}
+		if !seen[tag.TagID] {
+			seen[tag.TagID] = true
+			tags = append(tags, *tag)
+		}
+	}
+	return tags, nil
+}
+
+// UpdateTag renames a tag or changes its color
+func UpdateTag(tagID uint, req *TagUpdateRequest) (*models.Tag, error) {
+	tag, err := GetTagByID(tagID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{}
+	if req.Name != nil {
+		name, err := normalizeName(*req.Name)
+		if err != nil {
+			return nil, err
+		}
+		existing, err := findByName(database.DB, name)
+		if err != nil {
+			return nil, err
+		}
+		if existing.TagID != 0 && existing.TagID != tagID {
+			return nil, fmt.Errorf("tag %q already exists", existing.Name)
+		}
+		updates["name"] = name
+		tag.Name = name
+	}
+	if req.Color != nil {
+		if *req.Color == "" {
+			updates["color"] = nil
+			tag.Color = nil
+		} else {
+			updates["color"] = *req.Color
+			tag.Color = req.Color
+		}
+	}
+
+	if len(updates) == 0 {
+		return tag, nil // No updates provided
+	}
+
+	if err := database.DB.Model(&models.Tag{}).Where("tag_id = ?", tagID).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to update tag: %w", err)
+	}
+
+	log.Printf("✓ Tag '%s' (ID: %d) updated", tag.Name, tagID)
+	return tag, nil
+}
+
+// DeleteTag deletes a tag for good, taking it off every transaction
+func DeleteTag(tagID uint) error {
+	tag, err := GetTagByID(tagID)
+	if err != nil {
+		return err
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM transaction_tags WHERE tag_tag_id = ?", tagID).Error; err != nil {
+			return fmt.Errorf("failed to untag transactions: %w", err)
+		}
+		if err := tx.Delete(&models.Tag{}, tagID).Error; err != nil {
+			return fmt.Errorf("failed to delete tag: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("✓ Tag '%s' (ID: %d) deleted", tag.Name, tagID)
+	return nil
+}
@@ -0,0 +1,11 @@
+package tags
+
+type TagCreationRequest struct {
+	Name  string  `json:"name"`
+	Color *string `json:"color,omitempty"`
+}
+
+type TagUpdateRequest struct {
+	Name  *string `json:"name,omitempty"`
+	Color *string `json:"color,omitempty"` // "" removes the color
+}
@@ -3,6 +3,8 @@ package transactions
 import (
 	"fmt"
 	"log"
+	"moneyplanner/api/attachments"
+	"moneyplanner/api/exchangerates"
 	"moneyplanner/api/persons"
 	"moneyplanner/database"
 	"moneyplanner/models"
@@ -12,36 +14,258 @@ import (
 	"gorm.io/gorm"
 )
 
-// adjustWalletBalance adjusts the wallet balance based on category root ID
-func adjustWalletBalance(walletID uint, rootID uint, amount float64) error {
-	var adjustment float64
-	if rootID == 1 {
-		adjustment = amount
-	} else if rootID == 2 {
-		adjustment = -amount
-	} else {
-		return nil // No adjustment for other root categories
+// balanceEffect returns how much a loaded transaction moves its wallet's balance
+func balanceEffect(t *models.Transaction) models.Money {
+	return t.Category.Kind.BalanceEffect(t.Amount)
+}
+
+// validateTransaction checks a loaded transaction against its category's kind and wallet
+func validateTransaction(t *models.Transaction) error {
+	if t.Category.WalletID != t.WalletID {
+		return fmt.Errorf("category %d does not belong to wallet %d", t.CategoryID, t.WalletID)
+	}
+	if !t.Category.Kind.IsSigned() && t.Amount < 0 {
+		return fmt.Errorf("amount must be positive for %s categories", t.Category.Kind)
+	}
+	return nil
+}
+
+// adjustWalletBalance adds delta to the wallet's stored balance and bumps its version, so a
+// client holding the old balance gets a 412 on its next conditional write
+func adjustWalletBalance(tx *gorm.DB, walletID uint, delta models.Money) error {
+	if delta == 0 {
+		return nil
 	}
 
-	if err := database.DB.Model(&models.Wallet{}).Where("wallet_id = ?", walletID).Update("balance", gorm.Expr("balance + ?", adjustment)).Error; err != nil {
-		return fmt.Errorf("failed to update wallet balance: %w", err)
+	result := tx.Model(&models.Wallet{}).Where("wallet_id = ?", walletID).
+		Updates(map[string]interface{}{"balance": gorm.Expr("balance + ?", delta), "version": gorm.Expr("version + 1")})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update wallet balance: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("failed to update wallet balance: wallet %d not found", walletID)
 	}
 	return nil
 }
 
-// GetTransactionByID retrieves a transaction by its ID
-func GetTransactionByID(transactionID uint) (*models.Transaction, error) {
+// convertToWalletCurrency converts an amount entered in the given currency (nil means the
+// wallet's own) into the wallet's currency at the transaction time. When a conversion
+// happened the entered amount and currency are returned as well so they can be stored.
+func convertToWalletCurrency(tx *gorm.DB, walletID uint, amount models.Money, currency *string, at time.Time) (models.Money, *models.Money, *string, error) {
+	var wallet models.Wallet
+	if err := tx.First(&wallet, walletID).Error; err != nil {
+		return 0, nil, nil, fmt.Errorf("wallet not found: %w", err)
+	}
+	if currency == nil {
+		return amount, nil, nil, nil
+	}
+
+	from, err := models.NormalizeCurrency(*currency)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	if from == wallet.Currency {
+		return amount, nil, nil, nil
+	}
+
+	converted, err := exchangerates.Convert(amount, from, wallet.Currency, at)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	return converted, &amount, &from, nil
+}
+
+// loadTransaction loads a transaction with its relationships on the given handle
+func loadTransaction(tx *gorm.DB, transactionID uint) (*models.Transaction, error) {
 	var transaction models.Transaction
-	if err := database.DB.Preload("Category.Wallet").Preload("Person").Preload("Wallet").Preload("User").First(&transaction, transactionID).Error; err != nil {
+	if err := tx.Preload("Category.Wallet").Preload("Person").Preload("Wallet").Preload("User").Preload("Tags").First(&transaction, transactionID).Error; err != nil {
 		return nil, fmt.Errorf("transaction not found: %w", err)
 	}
-	return &transaction, nil
+	loaded := []models.Transaction{transaction}
+	if err := attachSplits(tx, loaded); err != nil {
+		return nil, err
+	}
+	return &loaded[0], nil
+}
+
+// ensureNotTransferLeg rejects direct changes to a leg of a transfer, which must change as a unit
+func ensureNotTransferLeg(t *models.Transaction) error {
+	if t.TransferID != nil {
+		return fmt.Errorf("transaction %d is part of transfer %d; edit or delete the transfer instead", t.TransactionID, *t.TransferID)
+	}
+	return nil
+}
+
+// ensureNotDebtPosting rejects direct changes to the transaction that opens a debt, which must
+// change through the debt. Repayments may be deleted but not edited, as the part of them that
+// repays each debt is recorded separately.
+func ensureNotDebtPosting(tx *gorm.DB, t *models.Transaction, deleting bool) error {
+	var debt models.Debt
+	if err := tx.Where("transaction_id = ?", t.TransactionID).Limit(1).Find(&debt).Error; err != nil {
+		return fmt.Errorf("failed to look up debts: %w", err)
+	}
+	if debt.DebtID != 0 {
+		return fmt.Errorf("transaction %d opens debt %d; edit or delete the debt instead", t.TransactionID, debt.DebtID)
+	}
+	if deleting {
+		return nil
+	}
+
+	var repayments int64
+	if err := tx.Model(&models.DebtRepayment{}).Where("transaction_id = ?", t.TransactionID).Count(&repayments).Error; err != nil {
+		return fmt.Errorf("failed to look up debt repayments: %w", err)
+	}
+	if repayments > 0 {
+		return fmt.Errorf("transaction %d repays a debt; delete it and record the repayment again instead", t.TransactionID)
+	}
+	return nil
+}
+
+// ensureNotSharedPosting rejects direct changes to the postings of a shared expense or of a
+// settlement between its participants, whose balances would no longer match the wallet
+func ensureNotSharedPosting(tx *gorm.DB, t *models.Transaction) error {
+	if t.SharedExpenseID != nil {
+		return fmt.Errorf("transaction %d is part of shared expense %d; edit or delete the shared expense instead", t.TransactionID, *t.SharedExpenseID)
+	}
+
+	var settlement models.SharedSettlement
+	if err := tx.Where("transaction_id = ?", t.TransactionID).Limit(1).Find(&settlement).Error; err != nil {
+		return fmt.Errorf("failed to look up shared settlements: %w", err)
+	}
+	if settlement.SharedSettlementID != 0 {
+		return fmt.Errorf("transaction %d records shared settlement %d; delete the settlement instead", t.TransactionID, settlement.SharedSettlementID)
+	}
+	return nil
+}
+
+// PostTransaction inserts a transaction, validates it against its category and applies it
+// to the wallet balance on the given handle. It returns the transaction with relationships loaded.
+func PostTransaction(tx *gorm.DB, t *models.Transaction) (*models.Transaction, error) {
+	if err := tx.Create(t).Error; err != nil {
+		return nil, fmt.Errorf("failed to create transaction: %w", err)
+	}
+
+	// Load relationships
+	loaded, err := loadTransaction(tx, t.TransactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateTransaction(loaded); err != nil {
+		return nil, err
+	}
+	if err := adjustWalletBalance(tx, loaded.WalletID, balanceEffect(loaded)); err != nil {
+		return nil, err
+	}
+	return loaded, nil
+}
+
+// UnpostTransaction reverses a loaded transaction's effect on the wallet balance and deletes
+// it for good, bypassing the trash
+func UnpostTransaction(tx *gorm.DB, t *models.Transaction) error {
+	// Guarded by version so a concurrent change or delete is not reversed twice
+	result := tx.Unscoped().Where("version = ?", t.Version).Delete(&models.Transaction{}, t.TransactionID)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete transaction: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return models.ErrVersionConflict
+	}
+	if err := saveSplits(tx, t.TransactionID, nil); err != nil {
+		return err
+	}
+	if err := saveTags(tx, t.TransactionID, nil); err != nil {
+		return err
+	}
+	if err := attachments.DetachAttachments(tx, t.TransactionID); err != nil {
+		return err
+	}
+	return adjustWalletBalance(tx, t.WalletID, -balanceEffect(t))
+}
+
+// UnpostTransactionByID unposts a live transaction; one in the trash stays there as an
+// ordinary transaction
+func UnpostTransactionByID(tx *gorm.DB, transactionID uint) error {
+	var posting models.Transaction
+	if err := tx.Preload("Category").Where("transaction_id = ?", transactionID).Limit(1).Find(&posting).Error; err != nil {
+		return fmt.Errorf("failed to load transaction %d: %w", transactionID, err)
+	}
+	if posting.TransactionID == 0 {
+		return nil
+	}
+	return UnpostTransaction(tx, &posting)
+}
+
+// TrashTransaction reverses a loaded transaction's effect on the wallet balance and moves it
+// to the trash. Items trashed together share deletedAt so they can be restored together.
+func TrashTransaction(tx *gorm.DB, t *models.Transaction, deletedAt time.Time, deletedBy uint) error {
+	result := tx.Model(&models.Transaction{}).
+		Where("transaction_id = ? AND version = ?", t.TransactionID, t.Version).
+		Updates(map[string]interface{}{
+			"deleted_at": deletedAt,
+			"deleted_by": deletedBy,
+			"version":    gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete transaction: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return models.ErrVersionConflict
+	}
+	return adjustWalletBalance(tx, t.WalletID, -balanceEffect(t))
+}
+
+// UntrashTransaction takes a transaction out of the trash and re-applies it to the wallet balance
+func UntrashTransaction(tx *gorm.DB, transactionID uint) (*models.Transaction, error) {
+	result := tx.Unscoped().Model(&models.Transaction{}).
+		Where("transaction_id = ? AND deleted_at IS NOT NULL", transactionID).
+		Updates(map[string]interface{}{
+			"deleted_at": nil,
+			"deleted_by": nil,
+			"version":    gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to restore transaction: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("transaction %d is not in the trash", transactionID)
+	}
+
+	loaded, err := loadTransaction(tx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	// Trashed relationships do not load
+	if loaded.Wallet.WalletID == 0 {
+		return nil, fmt.Errorf("wallet %d is in the trash; restore it first", loaded.WalletID)
+	}
+	if loaded.Category.CategoryID == 0 {
+		return nil, fmt.Errorf("category %d is in the trash; restore it first", loaded.CategoryID)
+	}
+	for _, split := range loaded.Splits {
+		if split.Category.CategoryID == 0 {
+			return nil, fmt.Errorf("category %d is in the trash; restore it first", split.CategoryID)
+		}
+	}
+
+	if err := validateTransaction(loaded); err != nil {
+		return nil, err
+	}
+	if err := adjustWalletBalance(tx, loaded.WalletID, balanceEffect(loaded)); err != nil {
+		return nil, err
+	}
+	return loaded, nil
+}
+
+// GetTransactionByID retrieves a transaction by its ID
+func GetTransactionByID(transactionID uint) (*models.Transaction, error) {
+	return loadTransaction(database.DB, transactionID)
 }
 
 // ListAllTransactions retrieves all transactions with optional filters
 func ListAllTransactions(filter *TransactionFilter) ([]models.Transaction, error) {
 	var transactions []models.Transaction
-	query := database.DB.Preload("Category.Wallet").Preload("Person").Preload("Wallet").Preload("User")
+	query := database.DB.Preload("Category.Wallet").Preload("Person").Preload("Wallet").Preload("User").Preload("Tags")
 
 	// Apply filters
 	if filter != nil {
@@ -74,13 +298,21 @@ func ListAllTransactions(filter *TransactionFilter) ([]models.Transaction, error
 		}
 
 		if len(filter.CategoryIDs) > 0 {
-			query = query.Where("category_id IN ?", filter.CategoryIDs)
+			query = splitCategoryFilter(query, filter.CategoryIDs)
+		}
+
+		if filter.Kind != nil {
+			query = query.Where("category_id IN (?)", database.DB.Model(&models.Category{}).Select("category_id").Where("kind = ?", *filter.Kind))
 		}
 
 		if filter.WalletID != nil {
 			query = query.Where("wallet_id = ?", *filter.WalletID)
 		}
 
+		if filter.WalletIDs != nil {
+			query = query.Where("wallet_id IN ?", filter.WalletIDs)
+		}
+
 		if filter.PersonID != nil {
 			query = query.Where("person_id = ?", *filter.PersonID)
 		}
@@ -89,6 +321,10 @@ func ListAllTransactions(filter *TransactionFilter) ([]models.Transaction, error
 			query = query.Where("note LIKE ?", "%"+*filter.FuzzyNote+"%")
 		}
 
+		if len(filter.Tags) > 0 || len(filter.ExcludeTags) > 0 {
+			query = tagFilter(query, filter.Tags, filter.ExcludeTags)
+		}
+
 		if filter.AmountOp != nil && filter.AmountValue != nil {
 			switch *filter.AmountOp {
 			case "eq":
@@ -108,22 +344,28 @@ func ListAllTransactions(filter *TransactionFilter) ([]models.Transaction, error
 	if err := query.Find(&transactions).Error; err != nil {
 		return nil, fmt.Errorf("failed to list transactions: %w", err)
 	}
+	if err := attachSplits(database.DB, transactions); err != nil {
+		return nil, err
+	}
 	return transactions, nil
 }
 
 // ListTransactionsByUser retrieves transactions for a user
 func ListTransactionsByUser(userID uint) ([]models.Transaction, error) {
 	var transactions []models.Transaction
-	if err := database.DB.Preload("Category.Wallet").Preload("Person").Preload("Wallet").Preload("User").Where("user_id = ?", userID).Find(&transactions).Error; err != nil {
+	if err := database.DB.Preload("Category.Wallet").Preload("Person").Preload("Wallet").Preload("User").Preload("Tags").Where("user_id = ?", userID).Find(&transactions).Error; err != nil {
 		return nil, fmt.Errorf("failed to list transactions for user: %w", err)
 	}
+	if err := attachSplits(database.DB, transactions); err != nil {
+		return nil, err
+	}
 	return transactions, nil
 }
 
 // ListTransactionsByWallet retrieves transactions for a wallet with optional additional filters
 func ListTransactionsByWallet(walletID uint, filter *TransactionFilter) ([]models.Transaction, error) {
 	var transactions []models.Transaction
-	query := database.DB.Preload("Category.Wallet").Preload("Person").Preload("Wallet").Preload("User").Where("wallet_id = ?", walletID)
+	query := database.DB.Preload("Category.Wallet").Preload("Person").Preload("Wallet").Preload("User").Preload("Tags").Where("wallet_id = ?", walletID)
 
 	// Apply additional filters
 	if filter != nil {
@@ -156,7 +398,11 @@ func ListTransactionsByWallet(walletID uint, filter *TransactionFilter) ([]model
 		}
 
 		if len(filter.CategoryIDs) > 0 {
-			query = query.Where("category_id IN ?", filter.CategoryIDs)
+			query = splitCategoryFilter(query, filter.CategoryIDs)
+		}
+
+		if filter.Kind != nil {
+			query = query.Where("category_id IN (?)", database.DB.Model(&models.Category{}).Select("category_id").Where("kind = ?", *filter.Kind))
 		}
 
 		if filter.PersonID != nil {
@@ -167,6 +413,10 @@ func ListTransactionsByWallet(walletID uint, filter *TransactionFilter) ([]model
 			query = query.Where("note LIKE ?", "%"+*filter.FuzzyNote+"%")
 		}
 
+		if len(filter.Tags) > 0 || len(filter.ExcludeTags) > 0 {
+			query = tagFilter(query, filter.Tags, filter.ExcludeTags)
+		}
+
 		if filter.AmountOp != nil && filter.AmountValue != nil {
 			switch *filter.AmountOp {
 			case "eq":
@@ -186,15 +436,35 @@ func ListTransactionsByWallet(walletID uint, filter *TransactionFilter) ([]model
 	if err := query.Find(&transactions).Error; err != nil {
 		return nil, fmt.Errorf("failed to list transactions for wallet: %w", err)
 	}
+	if err := attachSplits(database.DB, transactions); err != nil {
+		return nil, err
+	}
 	return transactions, nil
 }
 
-// CreateTransaction creates a new transaction
+// CreateTransaction creates a new transaction and applies it to the wallet balance.
+// Everything, including auto-creating the person, happens in one DB transaction.
 func CreateTransaction(req *TransactionCreationRequest) (*models.Transaction, error) {
+	var t *models.Transaction
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		t, err = CreateTransactionInTx(tx, req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("✓ Transaction created (ID: %d)", t.TransactionID)
+	return t, nil
+}
+
+// CreateTransactionInTx does the work of CreateTransaction on the given handle
+func CreateTransactionInTx(tx *gorm.DB, req *TransactionCreationRequest) (*models.Transaction, error) {
 	if req.WalletID == 0 {
 		return nil, fmt.Errorf("wallet_id is required")
 	}
-	if req.CategoryID == 0 {
+	if req.CategoryID == 0 && len(req.Splits) == 0 {
 		return nil, fmt.Errorf("category_id is required")
 	}
 	if req.Amount == 0 {
@@ -204,27 +474,18 @@ func CreateTransaction(req *TransactionCreationRequest) (*models.Transaction, er
 		return nil, fmt.Errorf("user_id is required")
 	}
 
-	// Handle person
-	var personID *uint
-	if req.PersonName != nil && strings.TrimSpace(*req.PersonName) != "" {
-		// Try to find existing person
-		p, err := persons.GetPersonByName(*req.PersonName)
+	categoryID := req.CategoryID
+	var splits []models.TransactionSplit
+	if len(req.Splits) > 0 {
+		var err error
+		splits, err = buildSplits(req.Splits, req.Amount)
 		if err != nil {
-			// Create new person
-			newPersonReq := &persons.PersonCreationRequest{
-				PersonName: *req.PersonName,
-				Alias:      "", // No alias provided
-			}
-			newPerson, err := persons.CreatePerson(newPersonReq)
-			if err != nil {
-				return nil, fmt.Errorf("failed to create person: %w", err)
-			}
-			personID = &newPerson.PersonID
-		} else {
-			personID = &p.PersonID
+			return nil, err
 		}
-	} else if req.PersonID != nil {
-		personID = req.PersonID
+		if categoryID != 0 && categoryID != splits[0].CategoryID {
+			return nil, fmt.Errorf("category_id must be left out or match the first split")
+		}
+		categoryID = splits[0].CategoryID
 	}
 
 	now := time.Now()
@@ -233,165 +494,297 @@ func CreateTransaction(req *TransactionCreationRequest) (*models.Transaction, er
 		transactionTime = *req.TransactionTime
 	}
 
-	t := &models.Transaction{
-		WalletID:         req.WalletID,
-		CategoryID:       req.CategoryID,
-		Amount:           req.Amount,
-		PersonID:         personID,
-		Note:             req.Note,
-		TransactionTime:  transactionTime,
-		EntryTime:        now,
-		LastModifiedTime: now,
-		UserID:           req.UserID,
+	// Handle person
+	var personID *uint
+	if req.PersonName != nil && strings.TrimSpace(*req.PersonName) != "" {
+		p, err := persons.FindOrCreatePersonByName(tx, *req.PersonName)
+		if err != nil {
+			return nil, err
+		}
+		personID = &p.PersonID
+	} else if req.PersonID != nil {
+		personID = req.PersonID
 	}
 
-	if err := database.DB.Create(t).Error; err != nil {
-		return nil, fmt.Errorf("failed to create transaction: %w", err)
+	amount, originalAmount, originalCurrency, err := convertToWalletCurrency(tx, req.WalletID, req.Amount, req.Currency, transactionTime)
+	if err != nil {
+		return nil, err
 	}
 
-	// Load relationships
-	if err := database.DB.Preload("Category.Wallet").Preload("Person").Preload("Wallet").Preload("User").First(t, t.TransactionID).Error; err != nil {
-		log.Printf("Warning: Failed to preload transaction relationships: %v", err)
+	if splits != nil {
+		if splits, err = scaleSplits(splits, amount); err != nil {
+			return nil, err
+		}
 	}
 
-	// Adjust wallet balance
-	if err := adjustWalletBalance(t.WalletID, t.Category.RootID, t.Amount); err != nil {
-		log.Printf("Warning: Failed to adjust wallet balance: %v", err)
+	created := &models.Transaction{
+		WalletID:               req.WalletID,
+		CategoryID:             categoryID,
+		Amount:                 amount,
+		OriginalAmount:         originalAmount,
+		OriginalCurrency:       originalCurrency,
+		PersonID:               personID,
+		Note:                   req.Note,
+		TransactionTime:        transactionTime,
+		EntryTime:              now,
+		LastModifiedTime:       now,
+		UserID:                 req.UserID,
+		RecurringTransactionID: req.RecurringTransactionID,
+		OccurrenceTime:         req.OccurrenceTime,
+		BillID:                 req.BillID,
+		SharedExpenseID:        req.SharedExpenseID,
+	}
+	posted, err := PostTransaction(tx, created)
+	if err != nil || (splits == nil && len(req.Tags) == 0) {
+		return posted, err
+	}
+
+	if splits != nil {
+		if err := validateSplits(tx, posted, splits); err != nil {
+			return nil, err
+		}
+		if err := saveSplits(tx, posted.TransactionID, splits); err != nil {
+			return nil, err
+		}
 	}
-
-	log.Printf("✓ Transaction created (ID: %d)", t.TransactionID)
-	return t, nil
+	if err := saveTags(tx, posted.TransactionID, req.Tags); err != nil {
+		return nil, err
+	}
+	return loadTransaction(tx, posted.TransactionID)
 }
 
-// UpdateTransaction updates transaction details
+// UpdateTransaction updates transaction details, moving its effect on the wallet balance
+// from the old values to the new ones in the same DB transaction
 func UpdateTransaction(transactionID uint, req *TransactionUpdateRequest) (*models.Transaction, error) {
-	transaction, err := GetTransactionByID(transactionID)
-	if err != nil {
-		return nil, err
-	}
+	var transaction *models.Transaction
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		transaction, err = loadTransaction(tx, transactionID)
+		if err != nil {
+			return err
+		}
 
-	// Save old values for balance adjustment
-	oldRootID := transaction.Category.RootID
-	oldAmount := transaction.Amount
-	oldWalletID := transaction.WalletID
+		if err := ensureNotTransferLeg(transaction); err != nil {
+			return err
+		}
+		if err := ensureNotDebtPosting(tx, transaction, false); err != nil {
+			return err
+		}
+		if err := ensureNotSharedPosting(tx, transaction); err != nil {
+			return err
+		}
+		if err := models.CheckVersion(transaction.Version, req.ExpectedVersion); err != nil {
+			return err
+		}
 
-	updates := map[string]interface{}{}
+		// Save old values for balance adjustment
+		oldWalletID := transaction.WalletID
+		oldEffect := balanceEffect(transaction)
 
-	if req.WalletID != nil {
-		updates["wallet_id"] = *req.WalletID
-		transaction.WalletID = *req.WalletID
-	}
+		// The amount as entered, in the currency it was entered in (nil means the wallet's)
+		sourceAmount := transaction.Amount
+		sourceCurrency := &transaction.Wallet.Currency
+		if transaction.OriginalAmount != nil && transaction.OriginalCurrency != nil {
+			sourceAmount = *transaction.OriginalAmount
+			sourceCurrency = transaction.OriginalCurrency
+		}
 
-	if req.CategoryID != nil {
-		updates["category_id"] = *req.CategoryID
-		transaction.CategoryID = *req.CategoryID
-	}
+		updates := map[string]interface{}{}
 
-	if req.Amount != nil {
-		updates["amount"] = *req.Amount
-		transaction.Amount = *req.Amount
-	}
+		if req.WalletID != nil {
+			updates["wallet_id"] = *req.WalletID
+			transaction.WalletID = *req.WalletID
+		}
 
-	if req.Note != nil {
-		updates["note"] = *req.Note
-		transaction.Note = req.Note
-	}
+		if req.CategoryID != nil {
+			updates["category_id"] = *req.CategoryID
+			transaction.CategoryID = *req.CategoryID
+		}
 
-	if req.TransactionTime != nil {
-		updates["transaction_time"] = *req.TransactionTime
-		transaction.TransactionTime = *req.TransactionTime
-	}
+		if req.Amount != nil {
+			sourceAmount = *req.Amount
+			sourceCurrency = req.Currency
+		} else if req.Currency != nil {
+			sourceCurrency = req.Currency
+		}
 
-	// Handle person update
-	if req.PersonName != nil && strings.TrimSpace(*req.PersonName) != "" {
-		p, err := persons.GetPersonByName(*req.PersonName)
-		if err != nil {
-			newPersonReq := &persons.PersonCreationRequest{
-				PersonName: *req.PersonName,
-				Alias:      "",
+		if req.Note != nil {
+			updates["note"] = *req.Note
+			transaction.Note = req.Note
+		}
+
+		if req.TransactionTime != nil {
+			updates["transaction_time"] = *req.TransactionTime
+			transaction.TransactionTime = *req.TransactionTime
+		}
+
+		// The lines of a split transaction follow its amount unless new ones are sent
+		splits := transaction.Splits
+		splitsChanged := req.Splits != nil
+		if req.Splits != nil {
+			splits = nil
+			if len(*req.Splits) > 0 {
+				if splits, err = buildSplits(*req.Splits, sourceAmount); err != nil {
+					return err
+				}
+				if req.CategoryID != nil && *req.CategoryID != splits[0].CategoryID {
+					return fmt.Errorf("category_id must be left out or match the first split")
+				}
+				updates["category_id"] = splits[0].CategoryID
+				transaction.CategoryID = splits[0].CategoryID
 			}
-			newPerson, err := persons.CreatePerson(newPersonReq)
-			if err != nil {
-				return nil, fmt.Errorf("failed to create person: %w", err)
+		} else if len(splits) > 0 {
+			if req.CategoryID != nil && *req.CategoryID != splits[0].CategoryID {
+				return fmt.Errorf("transaction %d is split; send splits to change its categories", transactionID)
+			}
+			if req.WalletID != nil && *req.WalletID != oldWalletID {
+				return fmt.Errorf("transaction %d is split; send splits in the new wallet's categories to move it", transactionID)
 			}
-			updates["person_id"] = newPerson.PersonID
-			transaction.PersonID = &newPerson.PersonID
-		} else {
-			updates["person_id"] = p.PersonID
-			transaction.PersonID = &p.PersonID
 		}
-	} else if req.PersonID != nil {
-		updates["person_id"] = *req.PersonID
-		transaction.PersonID = req.PersonID
-	}
 
-	updates["last_modified_time"] = time.Now()
+		// Re-derive the stored amount whenever the amount, its currency, the wallet or the
+		// date (and so the exchange rate) changes
+		if req.Amount != nil || req.Currency != nil || req.WalletID != nil || req.TransactionTime != nil {
+			amount, originalAmount, originalCurrency, err := convertToWalletCurrency(
+				tx, transaction.WalletID, sourceAmount, sourceCurrency, transaction.TransactionTime)
+			if err != nil {
+				return err
+			}
+			updates["amount"] = amount
+			updates["original_amount"] = originalAmount
+			updates["original_currency"] = originalCurrency
+			transaction.Amount = amount
+		}
+		if len(splits) > 0 && splitsTotal(splits) != transaction.Amount {
+			if splits, err = scaleSplits(splits, transaction.Amount); err != nil {
+				return err
+			}
+			splitsChanged = true
+		}
 
-	if len(updates) == 0 {
-		return transaction, nil // No updates provided
-	}
+		// Handle person update
+		if req.PersonName != nil && strings.TrimSpace(*req.PersonName) != "" {
+			p, err := persons.FindOrCreatePersonByName(tx, *req.PersonName)
+			if err != nil {
+				return err
+			}
+			updates["person_id"] = p.PersonID
+		} else if req.PersonID != nil {
+			updates["person_id"] = *req.PersonID
+		}
 
-	if err := database.DB.Model(&models.Transaction{}).
-		Where("transaction_id = ?", transactionID).
-		Updates(updates).Error; err != nil {
-		return nil, fmt.Errorf("failed to update transaction: %w", err)
-	}
+		updates["last_modified_time"] = time.Now()
+		updates["version"] = gorm.Expr("version + 1")
 
-	// Reverse old effect on balance
-	if oldRootID == 1 {
-		if err := adjustWalletBalance(oldWalletID, oldRootID, -oldAmount); err != nil {
-			log.Printf("Warning: Failed to reverse wallet balance: %v", err)
+		// Guarded by the version read above so a concurrent edit is not overwritten
+		result := tx.Model(&models.Transaction{}).
+			Where("transaction_id = ? AND version = ?", transactionID, transaction.Version).
+			Updates(updates)
+		if result.Error != nil {
+			return fmt.Errorf("failed to update transaction: %w", result.Error)
 		}
-	} else if oldRootID == 2 {
-		if err := adjustWalletBalance(oldWalletID, oldRootID, oldAmount); err != nil {
-			log.Printf("Warning: Failed to reverse wallet balance: %v", err)
+		if result.RowsAffected == 0 {
+			return models.ErrVersionConflict
 		}
-	}
 
-	// Reload relationships
-	if err := database.DB.Preload("Category.Wallet").Preload("Person").Preload("Wallet").Preload("User").First(transaction, transactionID).Error; err != nil {
-		log.Printf("Warning: Failed to preload transaction relationships: %v", err)
-	}
+		// Reload relationships
+		transaction, err = loadTransaction(tx, transactionID)
+		if err != nil {
+			return err
+		}
 
-	// Apply new effect on balance
-	if transaction.Category.RootID == 1 {
-		if err := adjustWalletBalance(transaction.WalletID, transaction.Category.RootID, transaction.Amount); err != nil {
-			log.Printf("Warning: Failed to adjust new wallet balance: %v", err)
+		if err := validateTransaction(transaction); err != nil {
+			return err
+		}
+		if splitsChanged {
+			if len(splits) > 0 {
+				if err := validateSplits(tx, transaction, splits); err != nil {
+					return err
+				}
+			}
+			if err := saveSplits(tx, transactionID, splits); err != nil {
+				return err
+			}
+			if transaction, err = loadTransaction(tx, transactionID); err != nil {
+				return err
+			}
+		}
+		if req.Tags != nil {
+			if err := saveTags(tx, transactionID, *req.Tags); err != nil {
+				return err
+			}
+			if transaction, err = loadTransaction(tx, transactionID); err != nil {
+				return err
+			}
 		}
-	} else if transaction.Category.RootID == 2 {
-		if err := adjustWalletBalance(transaction.WalletID, transaction.Category.RootID, -transaction.Amount); err != nil {
-			log.Printf("Warning: Failed to adjust new wallet balance: %v", err)
+
+		// Reverse the old effect on balance, then apply the new one
+		if err := adjustWalletBalance(tx, oldWalletID, -oldEffect); err != nil {
+			return err
 		}
+		return adjustWalletBalance(tx, transaction.WalletID, balanceEffect(transaction))
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	log.Printf("✓ Transaction (ID: %d) updated", transactionID)
 	return transaction, nil
 }
 
-// DeleteTransaction deletes a transaction by ID
-func DeleteTransaction(transactionID uint) error {
-	// Check if transaction exists
-	transaction, err := GetTransactionByID(transactionID)
+// DeleteTransaction moves a transaction to the trash and reverses its effect on the wallet
+// balance. A non-nil expectedVersion must match the stored one.
+func DeleteTransaction(transactionID, deletedBy uint, expectedVersion *uint) error {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// Check if transaction exists
+		transaction, err := loadTransaction(tx, transactionID)
+		if err != nil {
+			return err
+		}
+		if err := ensureNotTransferLeg(transaction); err != nil {
+			return err
+		}
+		if err := ensureNotDebtPosting(tx, transaction, true); err != nil {
+			return err
+		}
+		if err := ensureNotSharedPosting(tx, transaction); err != nil {
+			return err
+		}
+		if err := models.CheckVersion(transaction.Version, expectedVersion); err != nil {
+			return err
+		}
+		return TrashTransaction(tx, transaction, time.Now(), deletedBy)
+	})
 	if err != nil {
 		return err
 	}
 
-	// Reverse the effect on balance
-	if transaction.Category.RootID == 1 {
-		if err := adjustWalletBalance(transaction.WalletID, transaction.Category.RootID, -transaction.Amount); err != nil {
-			log.Printf("Warning: Failed to reverse wallet balance on delete: %v", err)
+	log.Printf("✓ Transaction (ID: %d) moved to trash", transactionID)
+	return nil
+}
+
+// RestoreTransaction takes a transaction of the wallet out of the trash and re-applies it
+// to the wallet balance
+func RestoreTransaction(walletID, transactionID uint) (*models.Transaction, error) {
+	var transaction *models.Transaction
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var trashed models.Transaction
+		if err := tx.Unscoped().Where("deleted_at IS NOT NULL").First(&trashed, transactionID).Error; err != nil ||
+			trashed.WalletID != walletID {
+			return fmt.Errorf("transaction %d is not in this wallet's trash", transactionID)
 		}
-	} else if transaction.Category.RootID == 2 {
-		if err := adjustWalletBalance(transaction.WalletID, transaction.Category.RootID, transaction.Amount); err != nil {
-			log.Printf("Warning: Failed to reverse wallet balance on delete: %v", err)
+		if trashed.TransferID != nil {
+			return fmt.Errorf("transaction %d is part of transfer %d; restore the transfer instead", transactionID, *trashed.TransferID)
 		}
-	}
 
-	// Delete the transaction
-	if err := database.DB.Delete(&models.Transaction{}, transactionID).Error; err != nil {
-		return fmt.Errorf("failed to delete transaction: %w", err)
+		var err error
+		transaction, err = UntrashTransaction(tx, transactionID)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	log.Printf("✓ Transaction (ID: %d) deleted", transactionID)
-	return nil
+	log.Printf("✓ Transaction (ID: %d) restored", transactionID)
+	return transaction, nil
 }
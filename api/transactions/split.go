@@ -0,0 +1,238 @@
+package transactions
+
+import (
+	"fmt"
+	"math/big"
+	"moneyplanner/database"
+	"moneyplanner/models"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+// buildSplits checks the requested lines of a split transaction against the amount as entered
+func buildSplits(lines []SplitLine, entered models.Money) ([]models.TransactionSplit, error) {
+	if len(lines) < 2 {
+		return nil, fmt.Errorf("a split transaction needs at least two splits")
+	}
+
+	var sum models.Money
+	seen := map[uint]bool{}
+	splits := make([]models.TransactionSplit, len(lines))
+	for i, line := range lines {
+		if line.CategoryID == 0 {
+			return nil, fmt.Errorf("category_id is required on every split")
+		}
+		if seen[line.CategoryID] {
+			return nil, fmt.Errorf("category %d appears in more than one split", line.CategoryID)
+		}
+		seen[line.CategoryID] = true
+		if line.Amount <= 0 {
+			return nil, fmt.Errorf("split amounts must be positive")
+		}
+		sum += line.Amount
+		splits[i] = models.TransactionSplit{CategoryID: line.CategoryID, Amount: line.Amount, Note: line.Note}
+	}
+	if sum != entered {
+		return nil, fmt.Errorf("splits add up to %s but the amount is %s", sum, entered)
+	}
+	return splits, nil
+}
+
+// splitsTotal adds up the lines of a split transaction
+func splitsTotal(splits []models.TransactionSplit) models.Money {
+	var sum models.Money
+	for _, split := range splits {
+		sum += split.Amount
+	}
+	return sum
+}
+
+// scaleSplits re-balances the lines in proportion to a new total. Rounding differences go
+// to the largest line so the lines still add up exactly.
+func scaleSplits(splits []models.TransactionSplit, total models.Money) ([]models.TransactionSplit, error) {
+	sum := splitsTotal(splits)
+	if sum == total {
+		return splits, nil
+	}
+	largest := 0
+	for i, split := range splits {
+		if split.Amount > splits[largest].Amount {
+			largest = i
+		}
+	}
+
+	scaled := make([]models.TransactionSplit, len(splits))
+	var scaledSum models.Money
+	for i, split := range splits {
+		scaled[i] = split
+		amount, err := split.Amount.MulRat(big.NewRat(int64(total), int64(sum)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to scale splits to %s: %w", total, err)
+		}
+		scaled[i].Amount = amount
+		scaledSum += scaled[i].Amount
+	}
+	scaled[largest].Amount += total - scaledSum
+
+	for _, split := range scaled {
+		if split.Amount <= 0 {
+			return nil, fmt.Errorf("amount %s is too small to split across %d categories", total, len(scaled))
+		}
+	}
+	return scaled, nil
+}
+
+// validateSplits checks the lines of a loaded transaction: every category must be live, in
+// the transaction's wallet and of its category's kind, which must be income or expense
+func validateSplits(tx *gorm.DB, t *models.Transaction, splits []models.TransactionSplit) error {
+	if t.Category.Kind.IsSigned() {
+		return fmt.Errorf("%s transactions cannot be split", t.Category.Kind)
+	}
+	for _, split := range splits {
+		var category models.Category
+		if err := tx.First(&category, split.CategoryID).Error; err != nil {
+			return fmt.Errorf("split category %d not found: %w", split.CategoryID, err)
+		}
+		if category.WalletID != t.WalletID {
+			return fmt.Errorf("category %d does not belong to wallet %d", split.CategoryID, t.WalletID)
+		}
+		if category.Kind != t.Category.Kind {
+			return fmt.Errorf("category %d is %s but the transaction is %s; all splits must be of one kind",
+				split.CategoryID, category.Kind, t.Category.Kind)
+		}
+	}
+	return nil
+}
+
+// saveSplits replaces the lines of a transaction; nil makes it an unsplit transaction
+func saveSplits(tx *gorm.DB, transactionID uint, splits []models.TransactionSplit) error {
+	if err := tx.Where("transaction_id = ?", transactionID).Delete(&models.TransactionSplit{}).Error; err != nil {
+		return fmt.Errorf("failed to delete splits: %w", err)
+	}
+	for _, split := range splits {
+		split.TransactionSplitID = 0
+		split.TransactionID = transactionID
+		split.Category = models.Category{}
+		if err := tx.Omit("Category").Create(&split).Error; err != nil {
+			return fmt.Errorf("failed to create split: %w", err)
+		}
+	}
+	return nil
+}
+
+// attachSplits loads the lines of the split transactions among the given ones
+func attachSplits(tx *gorm.DB, transactions []models.Transaction) error {
+	if len(transactions) == 0 {
+		return nil
+	}
+	transactionIDs := make([]uint, len(transactions))
+	for i := range transactions {
+		transactionIDs[i] = transactions[i].TransactionID
+	}
+
+	var splits []models.TransactionSplit
+	if err := tx.Preload("Category").Where("transaction_id IN ?", transactionIDs).
+		Order("transaction_split_id").Find(&splits).Error; err != nil {
+		return fmt.Errorf("failed to load splits: %w", err)
+	}
+	byTransaction := map[uint][]models.TransactionSplit{}
+	for _, split := range splits {
+		byTransaction[split.TransactionID] = append(byTransaction[split.TransactionID], split)
+	}
+	for i := range transactions {
+		transactions[i].Splits = byTransaction[transactions[i].TransactionID]
+	}
+	return nil
+}
+
+// line is the part of a matching transaction that counts in one category: the whole
+// transaction, or one line of a split transaction
+type line struct {
+	transaction *models.Transaction
+	category    *models.Category
+	amount      models.Money
+}
+
+// matchingLines lists the lines of the transactions matching the filter, each line of a split
+// transaction in its own category. With CategoryIDs only lines in those categories are listed.
+func matchingLines(filter *TransactionFilter) ([]line, error) {
+	matching, err := ListAllTransactions(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var wanted map[uint]bool
+	if filter != nil && len(filter.CategoryIDs) > 0 {
+		wanted = map[uint]bool{}
+		for _, categoryID := range filter.CategoryIDs {
+			wanted[categoryID] = true
+		}
+	}
+
+	lines := []line{}
+	add := func(t *models.Transaction, category *models.Category, amount models.Money) {
+		if wanted == nil || wanted[category.CategoryID] {
+			lines = append(lines, line{t, category, amount})
+		}
+	}
+	for i := range matching {
+		t := &matching[i]
+		if len(t.Splits) == 0 {
+			add(t, &t.Category, t.Amount)
+			continue
+		}
+		for j := range t.Splits {
+			add(t, &t.Splits[j].Category, t.Splits[j].Amount)
+		}
+	}
+	return lines, nil
+}
+
+// TotalsByCategory totals the transactions matching the filter per category. Each line of a
+// split transaction counts in its own category; with CategoryIDs only lines in those count.
+func TotalsByCategory(filter *TransactionFilter) ([]CategoryTotal, error) {
+	lines, err := matchingLines(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	byCategory := map[uint]*CategoryTotal{}
+	for _, line := range lines {
+		total := byCategory[line.category.CategoryID]
+		if total == nil {
+			total = &CategoryTotal{
+				CategoryID:   line.category.CategoryID,
+				CategoryName: line.category.Name,
+				Kind:         line.category.Kind,
+				WalletID:     line.transaction.WalletID,
+				Currency:     line.transaction.Wallet.Currency,
+			}
+			byCategory[line.category.CategoryID] = total
+		}
+		total.Total += line.amount
+		total.Count++
+	}
+
+	totals := make([]CategoryTotal, 0, len(byCategory))
+	for _, total := range byCategory {
+		totals = append(totals, *total)
+	}
+	sort.Slice(totals, func(i, j int) bool {
+		if totals[i].WalletID != totals[j].WalletID {
+			return totals[i].WalletID < totals[j].WalletID
+		}
+		if totals[i].Kind != totals[j].Kind {
+			return totals[i].Kind < totals[j].Kind
+		}
+		return totals[i].CategoryName < totals[j].CategoryName
+	})
+	return totals, nil
+}
+
+// splitCategoryFilter matches transactions in the categories, by their own category or by
+// one of their split lines
+func splitCategoryFilter(query *gorm.DB, categoryIDs []uint) *gorm.DB {
+	return query.Where("(category_id IN ? OR transaction_id IN (?))", categoryIDs,
+		database.DB.Model(&models.TransactionSplit{}).Select("transaction_id").Where("category_id IN ?", categoryIDs))
+}
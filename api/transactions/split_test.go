@@ -0,0 +1,197 @@
+package transactions
+
+import (
+	"path/filepath"
+	"testing"
+
+	"moneyplanner/models"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func splitsOf(amounts ...models.Money) []models.TransactionSplit {
+	splits := make([]models.TransactionSplit, len(amounts))
+	for i, amount := range amounts {
+		splits[i] = models.TransactionSplit{CategoryID: uint(i + 1), Amount: amount}
+	}
+	return splits
+}
+
+func splitAmounts(splits []models.TransactionSplit) []models.Money {
+	amounts := make([]models.Money, len(splits))
+	for i, split := range splits {
+		amounts[i] = split.Amount
+	}
+	return amounts
+}
+
+func TestBuildSplits(t *testing.T) {
+	lines := []SplitLine{{CategoryID: 3, Amount: 1250}, {CategoryID: 4, Amount: 750}}
+	splits, err := buildSplits(lines, 2000)
+	if err != nil {
+		t.Fatalf("buildSplits returned error: %v", err)
+	}
+	if total := splitsTotal(splits); total != 2000 {
+		t.Errorf("splits add up to %d, want 2000", total)
+	}
+
+	invalid := []struct {
+		name    string
+		lines   []SplitLine
+		entered models.Money
+	}{
+		{"one line", []SplitLine{{CategoryID: 3, Amount: 2000}}, 2000},
+		{"sum too small", []SplitLine{{CategoryID: 3, Amount: 1000}, {CategoryID: 4, Amount: 999}}, 2000},
+		{"sum too large", []SplitLine{{CategoryID: 3, Amount: 1000}, {CategoryID: 4, Amount: 1001}}, 2000},
+		{"missing category", []SplitLine{{CategoryID: 0, Amount: 1000}, {CategoryID: 4, Amount: 1000}}, 2000},
+		{"repeated category", []SplitLine{{CategoryID: 3, Amount: 1000}, {CategoryID: 3, Amount: 1000}}, 2000},
+		{"zero line", []SplitLine{{CategoryID: 3, Amount: 2000}, {CategoryID: 4, Amount: 0}}, 2000},
+		{"negative line", []SplitLine{{CategoryID: 3, Amount: 2500}, {CategoryID: 4, Amount: -500}}, 2000},
+	}
+	for _, tt := range invalid {
+		if _, err := buildSplits(tt.lines, tt.entered); err == nil {
+			t.Errorf("%s: buildSplits returned no error", tt.name)
+		}
+	}
+}
+
+func TestScaleSplits(t *testing.T) {
+	tests := []struct {
+		amounts []models.Money
+		total   models.Money
+		want    []models.Money
+	}{
+		{[]models.Money{1000, 1000}, 2000, []models.Money{1000, 1000}}, // Unchanged total
+		{[]models.Money{1000, 1000}, 3000, []models.Money{1500, 1500}},
+		{[]models.Money{1000, 1000}, 1001, []models.Money{500, 501}},      // 500.5 each rounds up, the first line gives a cent back
+		{[]models.Money{100, 100, 100}, 100, []models.Money{34, 33, 33}},  // 33.33 each, the first largest line takes the rest
+		{[]models.Money{200, 100, 100}, 333, []models.Money{167, 83, 83}}, // 166.5, 83.25 and 83.25
+		{[]models.Money{1, 1, 1, 97}, 50, []models.Money{1, 1, 1, 47}},    // 0.5 rounds up to 1 on the small lines
+		{[]models.Money{9999, 1}, 10001, []models.Money{10000, 1}},        // 1.0001 rounds down to 1
+		{[]models.Money{333, 333, 334}, 1, nil},                           // Too small to split
+		{[]models.Money{5000, 5000}, 1, nil},
+	}
+	for _, tt := range tests {
+		splits := splitsOf(tt.amounts...)
+		scaled, err := scaleSplits(splits, tt.total)
+		if tt.want == nil {
+			if err == nil {
+				t.Errorf("scaleSplits(%v, %d) = %v, want an error", tt.amounts, tt.total, splitAmounts(scaled))
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("scaleSplits(%v, %d) returned error: %v", tt.amounts, tt.total, err)
+			continue
+		}
+		got := splitAmounts(scaled)
+		for i := range tt.want {
+			if got[i] != tt.want[i] {
+				t.Errorf("scaleSplits(%v, %d) = %v, want %v", tt.amounts, tt.total, got, tt.want)
+				break
+			}
+		}
+		for i := range scaled {
+			if scaled[i].CategoryID != splits[i].CategoryID {
+				t.Errorf("scaleSplits(%v, %d) moved line %d to category %d", tt.amounts, tt.total, i, scaled[i].CategoryID)
+			}
+		}
+		if splitAmounts(splits)[0] != tt.amounts[0] {
+			t.Errorf("scaleSplits(%v, %d) changed its input", tt.amounts, tt.total)
+		}
+	}
+}
+
+// Whatever the new total, scaled lines add up to it exactly and every line stays positive
+func TestScaleSplitsAlwaysAddsUp(t *testing.T) {
+	lineSets := [][]models.Money{
+		{1, 1},
+		{1, 2},
+		{100, 100, 100},
+		{1, 1, 1, 97},
+		{3333, 3333, 3334},
+		{1999, 1, 1, 1, 1, 1},
+		{12345, 678, 9, 10001},
+	}
+	for _, amounts := range lineSets {
+		for total := models.Money(1); total <= 5000; total++ {
+			scaled, err := scaleSplits(splitsOf(amounts...), total)
+			if err != nil {
+				// Only shrinking can leave a line with nothing
+				if total >= splitsTotal(splitsOf(amounts...)) {
+					t.Errorf("scaleSplits(%v, %d) returned error: %v", amounts, total, err)
+				}
+				continue
+			}
+			if sum := splitsTotal(scaled); sum != total {
+				t.Fatalf("scaleSplits(%v, %d) adds up to %d", amounts, total, sum)
+			}
+			for _, split := range scaled {
+				if split.Amount <= 0 {
+					t.Fatalf("scaleSplits(%v, %d) = %v, which has a line that is not positive", amounts, total, splitAmounts(scaled))
+				}
+			}
+		}
+	}
+}
+
+func TestValidateSplits(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if err := db.AutoMigrate(&models.Wallet{}, &models.Category{}); err != nil {
+		t.Fatalf("AutoMigrate returned error: %v", err)
+	}
+
+	categories := []models.Category{
+		{CategoryID: 1, Name: "Groceries", RootID: 1, WalletID: 1, Kind: models.CategoryKindExpense},
+		{CategoryID: 2, Name: "Household", RootID: 2, WalletID: 1, Kind: models.CategoryKindExpense},
+		{CategoryID: 3, Name: "Salary", RootID: 3, WalletID: 1, Kind: models.CategoryKindIncome},
+		{CategoryID: 4, Name: "Groceries", RootID: 4, WalletID: 2, Kind: models.CategoryKindExpense},
+	}
+	for _, wallet := range []models.Wallet{{WalletID: 1, Name: "Main"}, {WalletID: 2, Name: "Other"}} {
+		if err := db.Create(&wallet).Error; err != nil {
+			t.Fatalf("failed to create wallet: %v", err)
+		}
+	}
+	for _, category := range categories {
+		if err := db.Create(&category).Error; err != nil {
+			t.Fatalf("failed to create category: %v", err)
+		}
+	}
+
+	expense := &models.Transaction{WalletID: 1, CategoryID: 1, Amount: 2000, Category: categories[0]}
+	tests := []struct {
+		name    string
+		t       *models.Transaction
+		lines   []uint
+		wantErr bool
+	}{
+		{"same kind and wallet", expense, []uint{1, 2}, false},
+		{"mixed kinds", expense, []uint{1, 3}, true},
+		{"other wallet", expense, []uint{1, 4}, true},
+		{"unknown category", expense, []uint{1, 99}, true},
+		{"signed kind", &models.Transaction{WalletID: 1, Amount: 2000,
+			Category: models.Category{Kind: models.CategoryKindTransfer}}, []uint{1, 2}, true},
+	}
+	for _, tt := range tests {
+		splits := make([]models.TransactionSplit, len(tt.lines))
+		for i, categoryID := range tt.lines {
+			splits[i] = models.TransactionSplit{CategoryID: categoryID, Amount: 1000}
+		}
+		err := validateSplits(db, tt.t, splits)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: validateSplits returned %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
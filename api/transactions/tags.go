@@ -0,0 +1,142 @@
+package transactions
+
+import (
+	"fmt"
+	"log"
+	"moneyplanner/api/tags"
+	"moneyplanner/database"
+	"moneyplanner/models"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// saveTags replaces the tags of a transaction with the named ones, creating missing tags;
+// no names takes every tag off
+func saveTags(tx *gorm.DB, transactionID uint, names []string) error {
+	found, err := tags.FindOrCreateTagsByName(tx, names)
+	if err != nil {
+		return err
+	}
+	if err := tx.Exec("DELETE FROM transaction_tags WHERE transaction_transaction_id = ?", transactionID).Error; err != nil {
+		return fmt.Errorf("failed to untag transaction: %w", err)
+	}
+	for _, tag := range found {
+		if err := tx.Exec("INSERT INTO transaction_tags (transaction_transaction_id, tag_tag_id) VALUES (?, ?)",
+			transactionID, tag.TagID).Error; err != nil {
+			return fmt.Errorf("failed to tag transaction: %w", err)
+		}
+	}
+	return nil
+}
+
+// tagFilter keeps the transactions carrying any of the tags and none of the excluded ones,
+// by name regardless of case
+func tagFilter(query *gorm.DB, tagNames, excludeTagNames []string) *gorm.DB {
+	tagged := func(names []string) *gorm.DB {
+		lowered := make([]string, len(names))
+		for i, name := range names {
+			lowered[i] = strings.ToLower(strings.TrimSpace(name))
+		}
+		return database.DB.Table("transaction_tags").Select("transaction_tags.transaction_transaction_id").
+			Joins("JOIN tags ON tags.tag_id = transaction_tags.tag_tag_id").
+			Where("LOWER(tags.name) IN ?", lowered)
+	}
+	if len(tagNames) > 0 {
+		query = query.Where("transaction_id IN (?)", tagged(tagNames))
+	}
+	if len(excludeTagNames) > 0 {
+		query = query.Where("transaction_id NOT IN (?)", tagged(excludeTagNames))
+	}
+	return query
+}
+
+// SetTransactionTags replaces the tags of a transaction. Tags are labels that do not touch
+// the balance, so unlike other changes this is allowed on transfer legs and on the postings
+// of debts and shared expenses. A non-nil expectedVersion must match the stored one.
+func SetTransactionTags(transactionID uint, names []string, expectedVersion *uint) (*models.Transaction, error) {
+	var transaction *models.Transaction
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		transaction, err = loadTransaction(tx, transactionID)
+		if err != nil {
+			return err
+		}
+		if err := models.CheckVersion(transaction.Version, expectedVersion); err != nil {
+			return err
+		}
+
+		result := tx.Model(&models.Transaction{}).
+			Where("transaction_id = ? AND version = ?", transactionID, transaction.Version).
+			Updates(map[string]interface{}{"last_modified_time": time.Now(), "version": gorm.Expr("version + 1")})
+		if result.Error != nil {
+			return fmt.Errorf("failed to update transaction: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return models.ErrVersionConflict
+		}
+		if err := saveTags(tx, transactionID, names); err != nil {
+			return err
+		}
+
+		transaction, err = loadTransaction(tx, transactionID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("✓ Transaction (ID: %d) tagged with %d tag(s)", transactionID, len(transaction.Tags))
+	return transaction, nil
+}
+
+// TotalsByTag totals the transactions matching the filter per tag, kind and currency. A
+// transaction counts towards each of its tags; with CategoryIDs only the lines of split
+// transactions in those categories count.
+func TotalsByTag(filter *TransactionFilter) ([]TagTotal, error) {
+	lines, err := matchingLines(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	type key struct {
+		tagID    uint
+		kind     models.CategoryKind
+		currency string
+	}
+	byKey := map[key]*TagTotal{}
+	counted := map[key]map[uint]bool{}
+	for _, line := range lines {
+		for _, tag := range line.transaction.Tags {
+			k := key{tag.TagID, line.category.Kind, line.transaction.Wallet.Currency}
+			total := byKey[k]
+			if total == nil {
+				total = &TagTotal{TagID: tag.TagID, TagName: tag.Name, Kind: k.kind, Currency: k.currency}
+				byKey[k] = total
+				counted[k] = map[uint]bool{}
+			}
+			total.Total += line.amount
+			if !counted[k][line.transaction.TransactionID] {
+				counted[k][line.transaction.TransactionID] = true
+				total.Count++
+			}
+		}
+	}
+
+	totals := make([]TagTotal, 0, len(byKey))
+	for _, total := range byKey {
+		totals = append(totals, *total)
+	}
+	sort.Slice(totals, func(i, j int) bool {
+		if totals[i].TagID != totals[j].TagID {
+			return strings.ToLower(totals[i].TagName) < strings.ToLower(totals[j].TagName)
+		}
+		if totals[i].Kind != totals[j].Kind {
+			return totals[i].Kind < totals[j].Kind
+		}
+		return totals[i].Currency < totals[j].Currency
+	})
+	return totals, nil
+}
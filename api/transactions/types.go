@@ -1,42 +1,96 @@
 package transactions
 
 import (
+	"moneyplanner/models"
 	"time"
 )
 
 type TransactionCreationRequest struct {
-	WalletID        uint       `json:"wallet_id"`
-	CategoryID      uint       `json:"category_id"`
-	Amount          float64    `json:"amount"`
-	PersonID        *uint      `json:"person_id,omitempty"`
-	PersonName      *string    `json:"person_name,omitempty"` // To create person if not exists
-	Note            *string    `json:"note,omitempty"`
-	TransactionTime *time.Time `json:"transaction_time,omitempty"`
-	UserID          uint       `json:"user_id"`
+	WalletID        uint         `json:"wallet_id"`
+	CategoryID      uint         `json:"category_id"`
+	Amount          models.Money `json:"amount"`
+	Currency        *string      `json:"currency,omitempty"` // Currency of amount; defaults to the wallet's
+	PersonID        *uint        `json:"person_id,omitempty"`
+	PersonName      *string      `json:"person_name,omitempty"` // To create person if not exists
+	Note            *string      `json:"note,omitempty"`
+	TransactionTime *time.Time   `json:"transaction_time,omitempty"`
+	UserID          uint         `json:"-"` // Set from the caller
+
+	// Set by the recurring transaction scheduler
+	RecurringTransactionID *uint      `json:"-"`
+	OccurrenceTime         *time.Time `json:"-"`
+
+	// Two or more lines splitting amount across categories; category_id is then the first line's
+	Splits []SplitLine `json:"splits,omitempty"`
+
+	Tags []string `json:"tags,omitempty"` // Tag names; missing tags are created
+
+	// Set when paying a bill
+	BillID *uint `json:"-"`
+
+	// Set when posting a shared expense
+	SharedExpenseID *uint `json:"-"`
+}
+
+// SplitLine is one category line of a split transaction, in the currency of its amount
+type SplitLine struct {
+	CategoryID uint         `json:"category_id"`
+	Amount     models.Money `json:"amount"`
+	Note       *string      `json:"note,omitempty"`
 }
 
 type TransactionUpdateRequest struct {
-	WalletID        *uint      `json:"wallet_id,omitempty"`
-	CategoryID      *uint      `json:"category_id,omitempty"`
-	Amount          *float64   `json:"amount,omitempty"`
-	PersonID        *uint      `json:"person_id,omitempty"`
-	PersonName      *string    `json:"person_name,omitempty"`
-	Note            *string    `json:"note,omitempty"`
-	TransactionTime *time.Time `json:"transaction_time,omitempty"`
+	WalletID        *uint         `json:"wallet_id,omitempty"`
+	CategoryID      *uint         `json:"category_id,omitempty"`
+	Amount          *models.Money `json:"amount,omitempty"`
+	Currency        *string       `json:"currency,omitempty"` // Currency of amount; defaults to the wallet's
+	PersonID        *uint         `json:"person_id,omitempty"`
+	PersonName      *string       `json:"person_name,omitempty"`
+	Note            *string       `json:"note,omitempty"`
+	TransactionTime *time.Time    `json:"transaction_time,omitempty"`
+	Splits          *[]SplitLine  `json:"splits,omitempty"` // Replaces the lines; [] makes it an unsplit transaction again
+	Tags            *[]string     `json:"tags,omitempty"`   // Replaces the tags; [] takes them all off
+	ExpectedVersion *uint         `json:"-"`                // From If-Match
 }
 
 type TransactionFilter struct {
-	StartTransactionTime  *time.Time `json:"start_transaction_time,omitempty"`
-	EndTransactionTime    *time.Time `json:"end_transaction_time,omitempty"`
-	StartEntryTime        *time.Time `json:"start_entry_time,omitempty"`
-	EndEntryTime          *time.Time `json:"end_entry_time,omitempty"`
-	StartLastModifiedTime *time.Time `json:"start_last_modified_time,omitempty"`
-	EndLastModifiedTime   *time.Time `json:"end_last_modified_time,omitempty"`
-	UserID                *uint      `json:"user_id,omitempty"`
-	CategoryIDs           []uint     `json:"category_ids,omitempty"`
-	WalletID              *uint      `json:"wallet_id,omitempty"`
-	PersonID              *uint      `json:"person_id,omitempty"`
-	FuzzyNote             *string    `json:"fuzzy_note,omitempty"`
-	AmountOp              *string    `json:"amount_op,omitempty"` // eq, lt, le, gt, ge
-	AmountValue           *float64   `json:"amount_value,omitempty"`
+	StartTransactionTime  *time.Time           `json:"start_transaction_time,omitempty"`
+	EndTransactionTime    *time.Time           `json:"end_transaction_time,omitempty"`
+	StartEntryTime        *time.Time           `json:"start_entry_time,omitempty"`
+	EndEntryTime          *time.Time           `json:"end_entry_time,omitempty"`
+	StartLastModifiedTime *time.Time           `json:"start_last_modified_time,omitempty"`
+	EndLastModifiedTime   *time.Time           `json:"end_last_modified_time,omitempty"`
+	UserID                *uint                `json:"user_id,omitempty"`
+	CategoryIDs           []uint               `json:"category_ids,omitempty"`
+	Kind                  *models.CategoryKind `json:"kind,omitempty"` // Category kind: income, expense, transfer, adjustment, debt
+	WalletID              *uint                `json:"wallet_id,omitempty"`
+	WalletIDs             []uint               `json:"wallet_ids,omitempty"` // Restricts results to these wallets when non-nil
+	PersonID              *uint                `json:"person_id,omitempty"`
+	FuzzyNote             *string              `json:"fuzzy_note,omitempty"`
+	AmountOp              *string              `json:"amount_op,omitempty"` // eq, lt, le, gt, ge
+	AmountValue           *models.Money        `json:"amount_value,omitempty"`
+	Tags                  []string             `json:"tags,omitempty"`         // Any of these tag names
+	ExcludeTags           []string             `json:"exclude_tags,omitempty"` // None of these tag names
+}
+
+// CategoryTotal is what transactions add up to in one category, with the lines of split
+// transactions counted in their own categories
+type CategoryTotal struct {
+	CategoryID   uint                `json:"category_id"`
+	CategoryName string              `json:"category_name"`
+	Kind         models.CategoryKind `json:"kind"`
+	WalletID     uint                `json:"wallet_id"`
+	Currency     string              `json:"currency"`
+	Total        models.Money        `json:"total"`
+	Count        int                 `json:"count"` // Transactions or split lines counted
+}
+
+// TagTotal is what the transactions carrying a tag add up to in one kind and currency
+type TagTotal struct {
+	TagID    uint                `json:"tag_id"`
+	TagName  string              `json:"tag_name"`
+	Kind     models.CategoryKind `json:"kind"`
+	Currency string              `json:"currency"`
+	Total    models.Money        `json:"total"`
+	Count    int                 `json:"count"` // Transactions counted
 }
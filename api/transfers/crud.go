@@ -0,0 +1,441 @@
+package transfers
+
+import (
+	"fmt"
+	"log"
+	"moneyplanner/api/categories"
+	"moneyplanner/api/exchangerates"
+	"moneyplanner/api/transactions"
+	"moneyplanner/database"
+	"moneyplanner/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// loadLegs attaches the transfer's transactions to it
+func loadLegs(tx *gorm.DB, transfer *models.Transfer) error {
+	transfer.Legs = []models.Transaction{}
+	if err := tx.Preload("Category.Wallet").Preload("Person").Preload("Wallet").Preload("User").
+		Where("transfer_id = ?", transfer.TransferID).
+		Order("transaction_id").Find(&transfer.Legs).Error; err != nil {
+		return fmt.Errorf("failed to load transfer legs: %w", err)
+	}
+	return nil
+}
+
+// loadTransfer loads a transfer with its legs on the given handle
+func loadTransfer(tx *gorm.DB, transferID uint) (*models.Transfer, error) {
+	var transfer models.Transfer
+	if err := tx.First(&transfer, transferID).Error; err != nil {
+		return nil, fmt.Errorf("transfer not found: %w", err)
+	}
+	if err := loadLegs(tx, &transfer); err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+// GetTransferByID retrieves a transfer with its legs
+func GetTransferByID(transferID uint) (*models.Transfer, error) {
+	return loadTransfer(database.DB, transferID)
+}
+
+// ListTransfers lists transfers, newest first
+func ListTransfers(filter *TransferFilter) ([]models.Transfer, error) {
+	transfers := []models.Transfer{}
+	query := database.DB.Order("transfer_time DESC")
+
+	if filter != nil {
+		if filter.WalletIDs != nil {
+			query = query.Where("from_wallet_id IN ? OR to_wallet_id IN ?", filter.WalletIDs, filter.WalletIDs)
+		}
+		if filter.WalletID != nil {
+			query = query.Where("from_wallet_id = ? OR to_wallet_id = ?", *filter.WalletID, *filter.WalletID)
+		}
+	}
+
+	if err := query.Find(&transfers).Error; err != nil {
+		return nil, fmt.Errorf("failed to list transfers: %w", err)
+	}
+	for i := range transfers {
+		if err := loadLegs(database.DB, &transfers[i]); err != nil {
+			return nil, err
+		}
+	}
+	return transfers, nil
+}
+
+// resolveToAmount returns the amount credited to the destination wallet: the given one,
+// or the source amount converted at the transfer time when the currencies differ
+func resolveToAmount(tx *gorm.DB, transfer *models.Transfer, toAmount *models.Money) error {
+	var from, to models.Wallet
+	if err := tx.First(&from, transfer.FromWalletID).Error; err != nil {
+		return fmt.Errorf("source wallet not found: %w", err)
+	}
+	if err := tx.First(&to, transfer.ToWalletID).Error; err != nil {
+		return fmt.Errorf("destination wallet not found: %w", err)
+	}
+
+	if toAmount != nil {
+		transfer.ToAmount = *toAmount
+		return nil
+	}
+	converted, err := exchangerates.Convert(transfer.Amount, from.Currency, to.Currency, transfer.TransferTime)
+	if err != nil {
+		return err
+	}
+	transfer.ToAmount = converted
+	return nil
+}
+
+// validateTransfer checks the amounts and wallets of a transfer
+func validateTransfer(transfer *models.Transfer) error {
+	if transfer.FromWalletID == 0 || transfer.ToWalletID == 0 {
+		return fmt.Errorf("from_wallet_id and to_wallet_id are required")
+	}
+	if transfer.FromWalletID == transfer.ToWalletID {
+		return fmt.Errorf("from_wallet_id and to_wallet_id must differ")
+	}
+	if transfer.Amount <= 0 {
+		return fmt.Errorf("amount must be positive")
+	}
+	if transfer.ToAmount <= 0 {
+		return fmt.Errorf("to_amount must be positive")
+	}
+	if transfer.Fee < 0 {
+		return fmt.Errorf("fee cannot be negative")
+	}
+	return nil
+}
+
+// postLegs creates and applies the transfer's transactions
+func postLegs(tx *gorm.DB, transfer *models.Transfer) error {
+	fromCategory, err := categories.EnsureKindRootCategory(tx, transfer.FromWalletID, models.CategoryKindTransfer, "Transfer", "🔁")
+	if err != nil {
+		return err
+	}
+	toCategory, err := categories.EnsureKindRootCategory(tx, transfer.ToWalletID, models.CategoryKindTransfer, "Transfer", "🔁")
+	if err != nil {
+		return err
+	}
+
+	newLeg := func(walletID, categoryID uint, amount models.Money) *models.Transaction {
+		return &models.Transaction{
+			WalletID:         walletID,
+			CategoryID:       categoryID,
+			Amount:           amount,
+			Note:             transfer.Note,
+			TransferID:       &transfer.TransferID,
+			TransactionTime:  transfer.TransferTime,
+			EntryTime:        transfer.LastModifiedTime,
+			LastModifiedTime: transfer.LastModifiedTime,
+			UserID:           transfer.UserID,
+		}
+	}
+
+	debit := newLeg(transfer.FromWalletID, fromCategory.CategoryID, -transfer.Amount)
+	if _, err := transactions.PostTransaction(tx, debit); err != nil {
+		return err
+	}
+
+	credit := newLeg(transfer.ToWalletID, toCategory.CategoryID, transfer.ToAmount)
+	if transfer.ToAmount != transfer.Amount {
+		// Keep what was sent alongside what arrived
+		var from models.Wallet
+		if err := tx.First(&from, transfer.FromWalletID).Error; err != nil {
+			return fmt.Errorf("source wallet not found: %w", err)
+		}
+		credit.OriginalAmount = &transfer.Amount
+		credit.OriginalCurrency = &from.Currency
+	}
+	if _, err := transactions.PostTransaction(tx, credit); err != nil {
+		return err
+	}
+
+	if transfer.Fee > 0 {
+		feeCategoryID := uint(0)
+		if transfer.FeeCategoryID != nil {
+			feeCategoryID = *transfer.FeeCategoryID
+		} else {
+			expense, err := categories.EnsureKindRootCategory(tx, transfer.FromWalletID, models.CategoryKindExpense, "Expense", "💸")
+			if err != nil {
+				return err
+			}
+			feeCategoryID = expense.CategoryID
+		}
+		fee := newLeg(transfer.FromWalletID, feeCategoryID, transfer.Fee)
+		posted, err := transactions.PostTransaction(tx, fee)
+		if err != nil {
+			return err
+		}
+		if posted.Category.Kind != models.CategoryKindExpense {
+			return fmt.Errorf("fee_category_id must be an expense category")
+		}
+	}
+	return nil
+}
+
+// trashLegs reverses all of the transfer's transactions and moves them to the trash
+func trashLegs(tx *gorm.DB, transfer *models.Transfer, deletedAt time.Time, deletedBy uint) error {
+	var legs []models.Transaction
+	if err := tx.Preload("Category").Where("transfer_id = ?", transfer.TransferID).Find(&legs).Error; err != nil {
+		return fmt.Errorf("failed to load transfer legs: %w", err)
+	}
+	for i := range legs {
+		if err := transactions.TrashTransaction(tx, &legs[i], deletedAt, deletedBy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unpostLegs reverses and permanently deletes all of the transfer's transactions
+func unpostLegs(tx *gorm.DB, transfer *models.Transfer) error {
+	var legs []models.Transaction
+	if err := tx.Preload("Category").Where("transfer_id = ?", transfer.TransferID).Find(&legs).Error; err != nil {
+		return fmt.Errorf("failed to load transfer legs: %w", err)
+	}
+	for i := range legs {
+		if err := transactions.UnpostTransaction(tx, &legs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// claimVersion bumps the stored version if it still matches the loaded one, so two
+// concurrent updates cannot both re-post the legs
+func claimVersion(tx *gorm.DB, transfer *models.Transfer) error {
+	result := tx.Model(&models.Transfer{}).
+		Where("transfer_id = ? AND version = ?", transfer.TransferID, transfer.Version).
+		Update("version", gorm.Expr("version + 1"))
+	if result.Error != nil {
+		return fmt.Errorf("failed to update transfer: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return models.ErrVersionConflict
+	}
+	transfer.Version++
+	return nil
+}
+
+// CreateTransfer creates a transfer and its legs in one DB transaction
+func CreateTransfer(req *TransferCreationRequest) (*models.Transfer, error) {
+	if req.UserID == 0 {
+		return nil, fmt.Errorf("user_id is required")
+	}
+
+	now := time.Now()
+	transfer := &models.Transfer{
+		FromWalletID:     req.FromWalletID,
+		ToWalletID:       req.ToWalletID,
+		Amount:           req.Amount,
+		FeeCategoryID:    req.FeeCategoryID,
+		Note:             req.Note,
+		TransferTime:     now,
+		EntryTime:        now,
+		LastModifiedTime: now,
+		UserID:           req.UserID,
+	}
+	if req.TransferTime != nil {
+		transfer.TransferTime = *req.TransferTime
+	}
+	if req.Fee != nil {
+		transfer.Fee = *req.Fee
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := resolveToAmount(tx, transfer, req.ToAmount); err != nil {
+			return err
+		}
+		if err := validateTransfer(transfer); err != nil {
+			return err
+		}
+		if err := tx.Create(transfer).Error; err != nil {
+			return fmt.Errorf("failed to create transfer: %w", err)
+		}
+		if err := postLegs(tx, transfer); err != nil {
+			return err
+		}
+		return loadLegs(tx, transfer)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("✓ Transfer created (ID: %d) from wallet %d to wallet %d", transfer.TransferID, transfer.FromWalletID, transfer.ToWalletID)
+	return transfer, nil
+}
+
+// UpdateTransfer changes a transfer and re-posts its legs in one DB transaction
+func UpdateTransfer(transferID uint, req *TransferUpdateRequest) (*models.Transfer, error) {
+	var transfer *models.Transfer
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		transfer, err = loadTransfer(tx, transferID)
+		if err != nil {
+			return err
+		}
+		if err := models.CheckVersion(transfer.Version, req.ExpectedVersion); err != nil {
+			return err
+		}
+
+		if req.FromWalletID != nil {
+			transfer.FromWalletID = *req.FromWalletID
+		}
+		if req.ToWalletID != nil {
+			transfer.ToWalletID = *req.ToWalletID
+		}
+		if req.Amount != nil {
+			transfer.Amount = *req.Amount
+		}
+		if req.Fee != nil {
+			transfer.Fee = *req.Fee
+		}
+		if req.FeeCategoryID != nil {
+			transfer.FeeCategoryID = req.FeeCategoryID
+			if *req.FeeCategoryID == 0 {
+				transfer.FeeCategoryID = nil
+			}
+		}
+		if req.Note != nil {
+			transfer.Note = req.Note
+		}
+		if req.TransferTime != nil {
+			transfer.TransferTime = *req.TransferTime
+		}
+		transfer.LastModifiedTime = time.Now()
+
+		// The received amount follows the sent one unless given explicitly
+		toAmount := req.ToAmount
+		if toAmount == nil && req.Amount == nil && req.FromWalletID == nil && req.ToWalletID == nil && req.TransferTime == nil {
+			toAmount = &transfer.ToAmount
+		}
+		if err := resolveToAmount(tx, transfer, toAmount); err != nil {
+			return err
+		}
+		if err := validateTransfer(transfer); err != nil {
+			return err
+		}
+
+		if err := claimVersion(tx, transfer); err != nil {
+			return err
+		}
+		if err := unpostLegs(tx, transfer); err != nil {
+			return err
+		}
+		if err := tx.Save(transfer).Error; err != nil {
+			return fmt.Errorf("failed to update transfer: %w", err)
+		}
+		if err := postLegs(tx, transfer); err != nil {
+			return err
+		}
+		return loadLegs(tx, transfer)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("✓ Transfer (ID: %d) updated", transferID)
+	return transfer, nil
+}
+
+// TrashTransfer moves a loaded transfer and its legs to the trash, reversing the legs.
+// Items trashed together share deletedAt so they can be restored together.
+func TrashTransfer(tx *gorm.DB, transfer *models.Transfer, deletedAt time.Time, deletedBy uint) error {
+	if err := trashLegs(tx, transfer, deletedAt, deletedBy); err != nil {
+		return err
+	}
+	result := tx.Model(&models.Transfer{}).
+		Where("transfer_id = ? AND version = ?", transfer.TransferID, transfer.Version).
+		Updates(map[string]interface{}{
+			"deleted_at": deletedAt,
+			"deleted_by": deletedBy,
+			"version":    gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete transfer: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return models.ErrVersionConflict
+	}
+	return nil
+}
+
+// DeleteTransfer moves a transfer to the trash together with its legs. A non-nil
+// expectedVersion must match the stored one.
+func DeleteTransfer(transferID, deletedBy uint, expectedVersion *uint) error {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		transfer, err := loadTransfer(tx, transferID)
+		if err != nil {
+			return err
+		}
+		if err := models.CheckVersion(transfer.Version, expectedVersion); err != nil {
+			return err
+		}
+		return TrashTransfer(tx, transfer, time.Now(), deletedBy)
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("✓ Transfer (ID: %d) moved to trash", transferID)
+	return nil
+}
+
+// GetTrashedTransfer retrieves a transfer that is in the trash
+func GetTrashedTransfer(transferID uint) (*models.Transfer, error) {
+	var transfer models.Transfer
+	if err := database.DB.Unscoped().Where("deleted_at IS NOT NULL").First(&transfer, transferID).Error; err != nil {
+		return nil, fmt.Errorf("transfer %d is not in the trash", transferID)
+	}
+	return &transfer, nil
+}
+
+// UntrashTransfer takes a transfer and its legs out of the trash and re-applies the legs
+func UntrashTransfer(tx *gorm.DB, transferID uint) (*models.Transfer, error) {
+	var legIDs []uint
+	if err := tx.Unscoped().Model(&models.Transaction{}).
+		Where("transfer_id = ? AND deleted_at IS NOT NULL", transferID).
+		Pluck("transaction_id", &legIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to load transfer legs: %w", err)
+	}
+
+	result := tx.Unscoped().Model(&models.Transfer{}).
+		Where("transfer_id = ? AND deleted_at IS NOT NULL", transferID).
+		Updates(map[string]interface{}{
+			"deleted_at": nil,
+			"deleted_by": nil,
+			"version":    gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to restore transfer: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("transfer %d is not in the trash", transferID)
+	}
+
+	for _, legID := range legIDs {
+		if _, err := transactions.UntrashTransaction(tx, legID); err != nil {
+			return nil, err
+		}
+	}
+	return loadTransfer(tx, transferID)
+}
+
+// RestoreTransfer takes a transfer and its legs out of the trash and re-applies the legs
+func RestoreTransfer(transferID uint) (*models.Transfer, error) {
+	var transfer *models.Transfer
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		transfer, err = UntrashTransfer(tx, transferID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("✓ Transfer (ID: %d) restored", transferID)
+	return transfer, nil
+}
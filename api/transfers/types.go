@@ -0,0 +1,36 @@
+package transfers
+
+import (
+	"moneyplanner/models"
+	"time"
+)
+
+type TransferCreationRequest struct {
+	FromWalletID  uint          `json:"from_wallet_id"`
+	ToWalletID    uint          `json:"to_wallet_id"`
+	Amount        models.Money  `json:"amount"`                    // In the source wallet's currency
+	ToAmount      *models.Money `json:"to_amount,omitempty"`       // Received amount; converted from amount when omitted
+	Fee           *models.Money `json:"fee,omitempty"`             // Charged to the source wallet
+	FeeCategoryID *uint         `json:"fee_category_id,omitempty"` // Defaults to the source wallet's expense root
+	Note          *string       `json:"note,omitempty"`
+	TransferTime  *time.Time    `json:"transfer_time,omitempty"`
+	UserID        uint          `json:"-"` // Set from the caller
+}
+
+type TransferUpdateRequest struct {
+	FromWalletID  *uint         `json:"from_wallet_id,omitempty"`
+	ToWalletID    *uint         `json:"to_wallet_id,omitempty"`
+	Amount        *models.Money `json:"amount,omitempty"`
+	ToAmount      *models.Money `json:"to_amount,omitempty"` // Reconverted from amount when the amount or wallets change without it
+	Fee           *models.Money `json:"fee,omitempty"`
+	FeeCategoryID *uint         `json:"fee_category_id,omitempty"`
+	Note          *string       `json:"note,omitempty"`
+	TransferTime  *time.Time    `json:"transfer_time,omitempty"`
+
+	ExpectedVersion *uint `json:"-"` // From If-Match
+}
+
+type TransferFilter struct {
+	WalletIDs []uint // Transfers touching any of these wallets, when non-nil
+	WalletID  *uint
+}
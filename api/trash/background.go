@@ -0,0 +1,63 @@
+package trash
+
+import (
+	"log"
+	"moneyplanner/database"
+	"moneyplanner/models"
+	"os"
+	"time"
+)
+
+// envRetention sets how long deleted items stay in the trash, as a Go duration ("0" keeps them)
+const envRetention = "MONEYPLANNER_TRASH_RETENTION"
+
+const (
+	defaultRetention = 30 * 24 * time.Hour
+	purgeInterval    = time.Hour
+)
+
+// Retention returns how long deleted items stay in the trash; 0 means until restored
+func Retention() time.Duration {
+	value := os.Getenv(envRetention)
+	if value == "" {
+		return defaultRetention
+	}
+	retention, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Warning: Invalid %s '%s', using %s", envRetention, value, defaultRetention)
+		return defaultRetention
+	}
+	if retention < 0 {
+		return 0
+	}
+	return retention
+}
+
+// purgeExpired removes everything that has been in the trash longer than the retention period
+func purgeExpired(retention time.Duration) {
+	if database.DB == nil || !database.DB.Migrator().HasColumn(&models.Transaction{}, "deleted_at") {
+		return
+	}
+	if _, err := Purge(time.Now().Add(-retention)); err != nil {
+		log.Printf("Warning: Trash purge failed: %v", err)
+	}
+}
+
+// StartPurgeJob purges expired trash now and then every hour, keeping deleted items for
+// MONEYPLANNER_TRASH_RETENTION (default 30 days)
+func StartPurgeJob() {
+	retention := Retention()
+	if retention == 0 {
+		log.Println("Trash purge disabled")
+		return
+	}
+
+	go func() {
+		purgeExpired(retention)
+		ticker := time.NewTicker(purgeInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			purgeExpired(retention)
+		}
+	}()
+}
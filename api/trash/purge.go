@@ -0,0 +1,237 @@
+package trash
+
+import (
+	"fmt"
+	"log"
+	"moneyplanner/api/attachments"
+	"moneyplanner/database"
+	"moneyplanner/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// trashedBefore returns the IDs of model rows that went to the trash before cutoff
+func trashedBefore(tx *gorm.DB, model interface{}, idColumn string, cutoff time.Time) ([]uint, error) {
+	ids := []uint{}
+	err := tx.Unscoped().Model(model).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+		Pluck(idColumn, &ids).Error
+	return ids, err
+}
+
+// purgeWallets permanently removes trashed wallets with everything that belongs to them.
+// Transfers to other wallets are kept so the other side's history stays intact.
+func purgeWallets(tx *gorm.DB, walletIDs []uint) error {
+	if len(walletIDs) == 0 {
+		return nil
+	}
+	if err := tx.Unscoped().Where("wallet_id IN ?", walletIDs).Delete(&models.Transaction{}).Error; err != nil {
+		return fmt.Errorf("failed to purge wallet transactions: %w", err)
+	}
+	if err := tx.Unscoped().Where("wallet_id IN ?", walletIDs).Delete(&models.Category{}).Error; err != nil {
+		return fmt.Errorf("failed to purge wallet categories: %w", err)
+	}
+	if err := tx.Exec(`DELETE FROM recurring_skips WHERE recurring_transaction_id IN
+		(SELECT recurring_transaction_id FROM recurring_transactions WHERE wallet_id IN ?)`, walletIDs).Error; err != nil {
+		return fmt.Errorf("failed to purge skipped occurrences: %w", err)
+	}
+	if err := tx.Where("wallet_id IN ?", walletIDs).Delete(&models.RecurringTransaction{}).Error; err != nil {
+		return fmt.Errorf("failed to purge recurring transactions: %w", err)
+	}
+	if err := tx.Where("wallet_id IN ?", walletIDs).Delete(&models.Bill{}).Error; err != nil {
+		return fmt.Errorf("failed to purge bills: %w", err)
+	}
+	if err := tx.Where("wallet_id IN ?", walletIDs).Delete(&models.GoalContribution{}).Error; err != nil {
+		return fmt.Errorf("failed to purge goal contributions: %w", err)
+	}
+	if err := tx.Exec(`DELETE FROM goal_contributions WHERE goal_id IN
+		(SELECT goal_id FROM goals WHERE wallet_id IN ?)`, walletIDs).Error; err != nil {
+		return fmt.Errorf("failed to purge goal contributions: %w", err)
+	}
+	if err := tx.Where("wallet_id IN ?", walletIDs).Delete(&models.Goal{}).Error; err != nil {
+		return fmt.Errorf("failed to purge goals: %w", err)
+	}
+	if err := tx.Exec(`DELETE FROM debt_repayments WHERE debt_id IN
+		(SELECT debt_id FROM debts WHERE wallet_id IN ?)`, walletIDs).Error; err != nil {
+		return fmt.Errorf("failed to purge debt repayments: %w", err)
+	}
+	if err := tx.Where("wallet_id IN ?", walletIDs).Delete(&models.Debt{}).Error; err != nil {
+		return fmt.Errorf("failed to purge debts: %w", err)
+	}
+	if err := tx.Exec(`DELETE FROM shared_expense_shares WHERE shared_expense_id IN
+		(SELECT shared_expense_id FROM shared_expenses WHERE wallet_id IN ?)`, walletIDs).Error; err != nil {
+		return fmt.Errorf("failed to purge shared expense shares: %w", err)
+	}
+	if err := tx.Where("wallet_id IN ?", walletIDs).Delete(&models.SharedExpense{}).Error; err != nil {
+		return fmt.Errorf("failed to purge shared expenses: %w", err)
+	}
+	if err := tx.Where("wallet_id IN ?", walletIDs).Delete(&models.SharedSettlement{}).Error; err != nil {
+		return fmt.Errorf("failed to purge shared settlements: %w", err)
+	}
+	if err := tx.Where("wallet_wallet_id IN ?", walletIDs).Delete(&models.UserWallet{}).Error; err != nil {
+		return fmt.Errorf("failed to purge wallet members: %w", err)
+	}
+	if err := tx.Exec("DELETE FROM wallet_wallet_groups WHERE wallet_wallet_id IN ?", walletIDs).Error; err != nil {
+		return fmt.Errorf("failed to purge wallet groups: %w", err)
+	}
+	if err := tx.Model(&models.User{}).Where("default_wallet_id IN ?", walletIDs).
+		Update("default_wallet_id", nil).Error; err != nil {
+		return fmt.Errorf("failed to clear default wallets: %w", err)
+	}
+	if err := tx.Unscoped().Delete(&models.Wallet{}, walletIDs).Error; err != nil {
+		return fmt.Errorf("failed to purge wallets: %w", err)
+	}
+	return nil
+}
+
+// purgeCategories permanently removes trashed categories that nothing refers to any more.
+// Parents go once their children are gone, so it repeats until nothing changes.
+func purgeCategories(tx *gorm.DB, cutoff time.Time) (int64, error) {
+	var total int64
+	for {
+		result := tx.Exec(`DELETE FROM categories
+			WHERE deleted_at IS NOT NULL AND deleted_at < ?
+			AND category_id NOT IN (SELECT category_id FROM transactions WHERE category_id IS NOT NULL)
+			AND category_id NOT IN (SELECT category_id FROM transaction_splits)
+			AND category_id NOT IN (SELECT parent_id FROM categories WHERE parent_id IS NOT NULL)`, cutoff)
+		if result.Error != nil {
+			return total, fmt.Errorf("failed to purge categories: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return total, nil
+		}
+		total += result.RowsAffected
+	}
+}
+
+// withoutSharedParticipants leaves out the persons that still take part in a shared ledger,
+// whose balances would not add up without them
+func withoutSharedParticipants(tx *gorm.DB, personIDs []uint) ([]uint, error) {
+	if len(personIDs) == 0 {
+		return personIDs, nil
+	}
+	remaining := []uint{}
+	err := tx.Unscoped().Model(&models.Person{}).Where("person_id IN ?", personIDs).
+		Where("person_id NOT IN (SELECT person_id FROM shared_expense_shares WHERE person_id IS NOT NULL)").
+		Where("person_id NOT IN (SELECT paid_by_person_id FROM shared_expenses WHERE paid_by_person_id IS NOT NULL)").
+		Where("person_id NOT IN (SELECT from_person_id FROM shared_settlements WHERE from_person_id IS NOT NULL)").
+		Where("person_id NOT IN (SELECT to_person_id FROM shared_settlements WHERE to_person_id IS NOT NULL)").
+		Pluck("person_id", &remaining).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up shared expense participants: %w", err)
+	}
+	return remaining, nil
+}
+
+// Purge permanently removes everything that went to the trash before cutoff
+func Purge(cutoff time.Time) (*PurgeResult, error) {
+	result := &PurgeResult{}
+	var orphanedFiles []string
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// Transfers go with all of their legs
+		transferIDs, err := trashedBefore(tx, &models.Transfer{}, "transfer_id", cutoff)
+		if err != nil {
+			return fmt.Errorf("failed to find trashed transfers: %w", err)
+		}
+		if len(transferIDs) > 0 {
+			legs := tx.Unscoped().Where("transfer_id IN ?", transferIDs).Delete(&models.Transaction{})
+			if legs.Error != nil {
+				return fmt.Errorf("failed to purge transfer legs: %w", legs.Error)
+			}
+			result.Transactions += legs.RowsAffected
+			if err := tx.Unscoped().Delete(&models.Transfer{}, transferIDs).Error; err != nil {
+				return fmt.Errorf("failed to purge transfers: %w", err)
+			}
+			result.Transfers = int64(len(transferIDs))
+		}
+
+		transactions := tx.Unscoped().
+			Where("deleted_at IS NOT NULL AND deleted_at < ? AND transfer_id IS NULL", cutoff).
+			Delete(&models.Transaction{})
+		if transactions.Error != nil {
+			return fmt.Errorf("failed to purge transactions: %w", transactions.Error)
+		}
+		result.Transactions += transactions.RowsAffected
+
+		walletIDs, err := trashedBefore(tx, &models.Wallet{}, "wallet_id", cutoff)
+		if err != nil {
+			return fmt.Errorf("failed to find trashed wallets: %w", err)
+		}
+		if err := purgeWallets(tx, walletIDs); err != nil {
+			return err
+		}
+		result.Wallets = int64(len(walletIDs))
+
+		if err := tx.Exec("DELETE FROM transaction_splits WHERE transaction_id NOT IN (SELECT transaction_id FROM transactions)").Error; err != nil {
+			return fmt.Errorf("failed to purge transaction splits: %w", err)
+		}
+		if err := tx.Exec("DELETE FROM transaction_tags WHERE transaction_transaction_id NOT IN (SELECT transaction_id FROM transactions)").Error; err != nil {
+			return fmt.Errorf("failed to purge transaction tags: %w", err)
+		}
+		if orphanedFiles, err = attachments.DeleteOrphans(tx); err != nil {
+			return err
+		}
+		result.Categories, err = purgeCategories(tx, cutoff)
+		if err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM budgets WHERE category_id NOT IN (SELECT category_id FROM categories)").Error; err != nil {
+			return fmt.Errorf("failed to purge budgets: %w", err)
+		}
+		// Repayments go with their purged transactions; a debt goes with its opening
+		if err := tx.Exec("DELETE FROM debt_repayments WHERE transaction_id NOT IN (SELECT transaction_id FROM transactions)").Error; err != nil {
+			return fmt.Errorf("failed to purge debt repayments: %w", err)
+		}
+		if err := tx.Exec(`DELETE FROM debt_repayments WHERE debt_id IN
+			(SELECT debt_id FROM debts WHERE transaction_id NOT IN (SELECT transaction_id FROM transactions))`).Error; err != nil {
+			return fmt.Errorf("failed to purge debt repayments: %w", err)
+		}
+		if err := tx.Exec("DELETE FROM debts WHERE transaction_id NOT IN (SELECT transaction_id FROM transactions)").Error; err != nil {
+			return fmt.Errorf("failed to purge debts: %w", err)
+		}
+
+		// Transactions keep their history but lose the link to a purged person
+		personIDs, err := trashedBefore(tx, &models.Person{}, "person_id", cutoff)
+		if err != nil {
+			return fmt.Errorf("failed to find trashed persons: %w", err)
+		}
+		if personIDs, err = withoutSharedParticipants(tx, personIDs); err != nil {
+			return err
+		}
+		if len(personIDs) > 0 {
+			if err := tx.Unscoped().Model(&models.Transaction{}).Where("person_id IN ?", personIDs).
+				Update("person_id", nil).Error; err != nil {
+				return fmt.Errorf("failed to unlink purged persons: %w", err)
+			}
+			if err := tx.Model(&models.Bill{}).Where("person_id IN ?", personIDs).
+				Update("person_id", nil).Error; err != nil {
+				return fmt.Errorf("failed to unlink purged persons from bills: %w", err)
+			}
+			// Their debts go too; the debt transactions stay as ordinary ones
+			if err := tx.Exec(`DELETE FROM debt_repayments WHERE debt_id IN
+				(SELECT debt_id FROM debts WHERE person_id IN ?)`, personIDs).Error; err != nil {
+				return fmt.Errorf("failed to purge debt repayments: %w", err)
+			}
+			if err := tx.Where("person_id IN ?", personIDs).Delete(&models.Debt{}).Error; err != nil {
+				return fmt.Errorf("failed to purge debts: %w", err)
+			}
+			if err := tx.Unscoped().Delete(&models.Person{}, personIDs).Error; err != nil {
+				return fmt.Errorf("failed to purge persons: %w", err)
+			}
+			result.Persons = int64(len(personIDs))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Files go only after the commit, so a rollback cannot leave attachments without them
+	attachments.PruneBlobs(orphanedFiles)
+
+	if *result != (PurgeResult{}) {
+		log.Printf("✓ Trash purged: %d transaction(s), %d transfer(s), %d category(ies), %d wallet(s), %d person(s)",
+			result.Transactions, result.Transfers, result.Categories, result.Wallets, result.Persons)
+	}
+	return result, nil
+}
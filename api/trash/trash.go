@@ -0,0 +1,60 @@
+package trash
+
+import (
+	"fmt"
+	"moneyplanner/database"
+	"moneyplanner/models"
+
+	"gorm.io/gorm"
+)
+
+// unscoped lets a preload see relationships that are in the trash themselves
+func unscoped(db *gorm.DB) *gorm.DB {
+	return db.Unscoped()
+}
+
+// ListWalletTrash retrieves the wallet's trashed transactions, categories and transfers,
+// most recently deleted first
+func ListWalletTrash(walletID uint) (*WalletTrash, error) {
+	trash := &WalletTrash{
+		WalletID:      walletID,
+		Transactions:  []models.Transaction{},
+		Categories:    []models.Category{},
+		Transfers:     []models.Transfer{},
+		RetentionDays: int(Retention().Hours() / 24),
+	}
+
+	if err := database.DB.Unscoped().Preload("Category", unscoped).Preload("Person", unscoped).
+		Where("wallet_id = ? AND deleted_at IS NOT NULL AND transfer_id IS NULL", walletID).
+		Order("deleted_at DESC").Find(&trash.Transactions).Error; err != nil {
+		return nil, fmt.Errorf("failed to list trashed transactions: %w", err)
+	}
+
+	if err := database.DB.Unscoped().
+		Where("wallet_id = ? AND deleted_at IS NOT NULL", walletID).
+		Order("deleted_at DESC").Find(&trash.Categories).Error; err != nil {
+		return nil, fmt.Errorf("failed to list trashed categories: %w", err)
+	}
+
+	if err := database.DB.Unscoped().
+		Where("(from_wallet_id = ? OR to_wallet_id = ?) AND deleted_at IS NOT NULL", walletID, walletID).
+		Order("deleted_at DESC").Find(&trash.Transfers).Error; err != nil {
+		return nil, fmt.Errorf("failed to list trashed transfers: %w", err)
+	}
+
+	return trash, nil
+}
+
+// ListTrashedWallets retrieves the trashed wallets among the given IDs
+func ListTrashedWallets(walletIDs []uint) ([]models.Wallet, error) {
+	wallets := []models.Wallet{}
+	if len(walletIDs) == 0 {
+		return wallets, nil
+	}
+	if err := database.DB.Unscoped().
+		Where("wallet_id IN ? AND deleted_at IS NOT NULL", walletIDs).
+		Order("deleted_at DESC").Find(&wallets).Error; err != nil {
+		return nil, fmt.Errorf("failed to list trashed wallets: %w", err)
+	}
+	return wallets, nil
+}
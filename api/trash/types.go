@@ -0,0 +1,21 @@
+package trash
+
+import "moneyplanner/models"
+
+// WalletTrash lists what a wallet has in the trash
+type WalletTrash struct {
+	WalletID      uint                 `json:"wallet_id"`
+	Transactions  []models.Transaction `json:"transactions"` // Transfer legs are listed with their transfer
+	Categories    []models.Category    `json:"categories"`
+	Transfers     []models.Transfer    `json:"transfers"`
+	RetentionDays int                  `json:"retention_days"` // Purged after this many days; 0 keeps them
+}
+
+// PurgeResult counts what a purge removed for good
+type PurgeResult struct {
+	Transactions int64 `json:"transactions"`
+	Transfers    int64 `json:"transfers"`
+	Categories   int64 `json:"categories"`
+	Wallets      int64 `json:"wallets"`
+	Persons      int64 `json:"persons"`
+}
@@ -5,6 +5,8 @@ import (
 	"log"
 	"moneyplanner/database"
 	"moneyplanner/models"
+
+	"gorm.io/gorm"
 )
 
 // GetUserByID retrieves a user by their ID
@@ -64,8 +66,17 @@ func UpdateUser(userID uint, req *UserUpdateRequest) (*models.User, error) {
 	}
 
 	if req.Password != nil {
-		updates["password"] = *req.Password
-		user.Password = *req.Password
+		if err := ValidatePasswordStrength(user.Username, *req.Password); err != nil {
+			return nil, err
+		}
+		hashedPassword, err := HashPassword(*req.Password)
+		if err != nil {
+			return nil, err
+		}
+		updates["password"] = hashedPassword
+		updates["must_reset_password"] = false
+		user.Password = hashedPassword
+		user.MustResetPassword = false
 	}
 
 	if req.Type != nil {
@@ -78,12 +89,37 @@ func UpdateUser(userID uint, req *UserUpdateRequest) (*models.User, error) {
 		user.DefaultWalletID = req.DefaultWalletID
 	}
 
+	if req.ReportingCurrency != nil {
+		currency := ""
+		if *req.ReportingCurrency != "" {
+			normalized, err := models.NormalizeCurrency(*req.ReportingCurrency)
+			if err != nil {
+				return nil, err
+			}
+			currency = normalized
+		}
+		updates["reporting_currency"] = currency
+		user.ReportingCurrency = currency
+	}
+
 	if len(updates) == 0 {
 		return user, nil // No updates provided
 	}
 
-	if err := database.DB.Model(&models.User{}).Where("user_id = ?", userID).Updates(updates).Error; err != nil {
-		return nil, fmt.Errorf("failed to update user: %w", err)
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("user_id = ?", userID).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to update user: %w", err)
+		}
+		// A new password signs the user out everywhere, including whoever knew the old one
+		if req.Password != nil {
+			if err := tx.Where("user_id = ?", userID).Delete(&models.Session{}).Error; err != nil {
+				return fmt.Errorf("failed to revoke user sessions: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	log.Printf("✓ User '%s' updated", user.Username)
@@ -98,6 +134,14 @@ func DeleteUser(userID uint) error {
 		return err
 	}
 
+	// Revoke any sessions the user still holds
+	if err := database.DB.Where("user_id = ?", userID).Delete(&models.Session{}).Error; err != nil {
+		return fmt.Errorf("failed to revoke user sessions: %w", err)
+	}
+	if err := database.DB.Where("user_id = ?", userID).Delete(&models.APIKey{}).Error; err != nil {
+		return fmt.Errorf("failed to revoke user API keys: %w", err)
+	}
+
 	// Delete the user
 	if err := database.DB.Delete(&models.User{}, userID).Error; err != nil {
 		return fmt.Errorf("failed to delete user: %w", err)
@@ -132,3 +176,81 @@ func capitalizeUsername(username string) string {
 
 	return result
 }
+
+// SetUserAdmin grants or removes administrator rights
+func SetUserAdmin(userID uint, isAdmin bool) error {
+	if err := database.DB.Model(&models.User{}).Where("user_id = ?", userID).Update("is_admin", isAdmin).Error; err != nil {
+		return fmt.Errorf("failed to update admin flag: %w", err)
+	}
+	return nil
+}
+
+// EnsureAdminExists promotes the earliest user to administrator when no admin exists yet,
+// which is the account created by the original database initialization. An account that
+// must reset its password is never promoted; if that leaves no admin, the admin account's
+// password is reset to adminPassword when one is given (see ResetAdminPassword).
+func EnsureAdminExists(adminUsername, adminPassword string) error {
+	var admins int64
+	if err := database.DB.Model(&models.User{}).Where("is_admin = ?", true).Count(&admins).Error; err != nil {
+		return fmt.Errorf("failed to count admins: %w", err)
+	}
+	if admins > 0 {
+		return nil
+	}
+
+	var first models.User
+	result := database.DB.Where("must_reset_password = ? AND type = ?", false, models.UserTypeHuman).
+		Order("user_id").Limit(1).Find(&first)
+	if result.Error != nil {
+		return fmt.Errorf("failed to find first user: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		if adminPassword != "" {
+			return ResetAdminPassword(adminUsername, adminPassword)
+		}
+		log.Printf("Warning: No admin account; run init with admin_password (MONEYPLANNER_ADMIN_PASSWORD) to reset the admin's password")
+		return nil
+	}
+
+	if err := SetUserAdmin(first.UserID, true); err != nil {
+		return err
+	}
+	log.Printf("✓ User '%s' promoted to admin", first.Username)
+	return nil
+}
+
+// ResetAdminPassword sets a new password on an existing account, makes it an administrator
+// and signs it out everywhere. It recovers an install whose only admin had a default password.
+func ResetAdminPassword(username, password string) error {
+	if err := ValidatePasswordStrength(username, password); err != nil {
+		return err
+	}
+	user, err := GetUserByUsername(username)
+	if err != nil {
+		return err
+	}
+	hash, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("user_id = ?", user.UserID).Updates(map[string]interface{}{
+			"password":            hash,
+			"must_reset_password": false,
+			"is_admin":            true,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to reset admin password: %w", err)
+		}
+		if err := tx.Where("user_id = ?", user.UserID).Delete(&models.Session{}).Error; err != nil {
+			return fmt.Errorf("failed to revoke user sessions: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("✓ Password of '%s' reset and account made admin", user.Username)
+	return nil
+}
@@ -0,0 +1,172 @@
+package users
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"moneyplanner/database"
+	"moneyplanner/models"
+	"strings"
+	"unicode"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	minPasswordLength = 8
+	maxPasswordLength = 72 // bcrypt ignores anything past 72 bytes
+)
+
+// commonPasswords are rejected outright regardless of length or character mix
+var commonPasswords = map[string]bool{
+	"password":    true,
+	"password1":   true,
+	"password123": true,
+	"admin":       true,
+	"admin123":    true,
+	"12345678":    true,
+	"123456789":   true,
+	"qwerty123":   true,
+	"letmein1":    true,
+}
+
+// validateNewPassword checks the password of a new account. Humans need one that meets the
+// policy; bots may have none, as they authenticate with API keys.
+func validateNewPassword(username, password string, userType models.UserType) error {
+	if password == "" {
+		if userType == models.UserTypeBot {
+			return nil
+		}
+		return errors.New("password is required")
+	}
+	return ValidatePasswordStrength(username, password)
+}
+
+// ValidatePasswordStrength checks a password against the password policy
+func ValidatePasswordStrength(username, password string) error {
+	if len(password) < minPasswordLength {
+		return fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	}
+	if len(password) > maxPasswordLength {
+		return fmt.Errorf("password must be at most %d bytes", maxPasswordLength)
+	}
+	if commonPasswords[strings.ToLower(password)] {
+		return errors.New("password is too common")
+	}
+	if username != "" && strings.EqualFold(password, username) {
+		return errors.New("password must not match the username")
+	}
+
+	hasLetter, hasDigit := false, false
+	for _, char := range password {
+		if unicode.IsLetter(char) {
+			hasLetter = true
+		} else if unicode.IsDigit(char) {
+			hasDigit = true
+		}
+	}
+	if !hasLetter || !hasDigit {
+		return errors.New("password must contain at least one letter and one digit")
+	}
+	return nil
+}
+
+// HashPassword hashes a plaintext password with bcrypt
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// dummyPasswordHash is a bcrypt hash at the default cost that no password is checked against
+// for real. Comparing with it makes a login for an unknown or passwordless account take as long
+// as one for a real account, so response times do not reveal which accounts exist.
+const dummyPasswordHash = "$2a$10$z64BfXDyX0gHLXzoGuEvyOnzXriUfEywCq.lqFKLRRrKUPNat7IYm"
+
+// RejectPassword spends the time of a password check on a login that has no account to check
+// against, and always fails
+func RejectPassword(password string) bool {
+	bcrypt.CompareHashAndPassword([]byte(dummyPasswordHash), []byte(password))
+	return false
+}
+
+// isPasswordHash reports whether a stored password is already a bcrypt hash
+func isPasswordHash(stored string) bool {
+	_, err := bcrypt.Cost([]byte(stored))
+	return err == nil
+}
+
+// VerifyPassword checks a plaintext password against the user's stored password.
+// Legacy plaintext rows are compared directly and re-hashed on a successful match.
+func VerifyPassword(user *models.User, password string) bool {
+	// Users without a password cannot log in with one
+	if user.Password == "" || password == "" {
+		return RejectPassword(password)
+	}
+
+	if isPasswordHash(user.Password) {
+		return bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) == nil
+	}
+
+	if subtle.ConstantTimeCompare([]byte(user.Password), []byte(password)) != 1 {
+		return false
+	}
+
+	// Transparently upgrade the legacy plaintext password
+	hash, err := HashPassword(password)
+	if err != nil {
+		log.Printf("Warning: Failed to upgrade password for user %d: %v", user.UserID, err)
+		return true
+	}
+	mustReset := ValidatePasswordStrength(user.Username, password) != nil
+	if err := database.DB.Model(&models.User{}).Where("user_id = ?", user.UserID).
+		Updates(map[string]interface{}{"password": hash, "must_reset_password": mustReset}).Error; err != nil {
+		log.Printf("Warning: Failed to upgrade password for user %d: %v", user.UserID, err)
+		return true
+	}
+	user.Password = hash
+	user.MustResetPassword = mustReset
+	log.Printf("✓ Password for user '%s' upgraded to bcrypt", user.Username)
+	return true
+}
+
+// UpgradePlaintextPasswords hashes every password still stored as plain text. Accounts whose
+// password fails the password policy, such as the old admin/admin default, must be reset
+// before they can log in again.
+func UpgradePlaintextPasswords() error {
+	var users []models.User
+	if err := database.DB.Find(&users).Error; err != nil {
+		return fmt.Errorf("failed to list users: %w", err)
+	}
+
+	upgraded, weak := 0, 0
+	for _, user := range users {
+		if user.Password == "" || isPasswordHash(user.Password) {
+			continue
+		}
+		hash, err := HashPassword(user.Password)
+		if err != nil {
+			return err
+		}
+		mustReset := ValidatePasswordStrength(user.Username, user.Password) != nil
+		if err := database.DB.Model(&models.User{}).Where("user_id = ?", user.UserID).
+			Updates(map[string]interface{}{"password": hash, "must_reset_password": mustReset}).Error; err != nil {
+			return fmt.Errorf("failed to upgrade password for user %d: %w", user.UserID, err)
+		}
+		upgraded++
+		if mustReset {
+			weak++
+		}
+	}
+
+	if upgraded > 0 {
+		log.Printf("✓ Upgraded %d plaintext password(s) to bcrypt", upgraded)
+	}
+	if weak > 0 {
+		log.Printf("Warning: %d user(s) had a password that fails the password policy and must have it reset by an admin", weak)
+	}
+	return nil
+}
@@ -31,18 +31,18 @@ func SetupUserWithDefaults(req *UserCreationRequest) (*UserCreationResult, error
 		email = defaultEmail
 	}
 
-	password := req.Password
-	if password == "" {
-		defaultPassword := "password123"
-		password = defaultPassword
-	}
-
 	userType := req.UserType
 	if userType == "" {
 		defaultType := models.UserTypeHuman
 		userType = defaultType
 	}
 
+	// Checked again by CreateUser, but before the wallet and group are created
+	password := req.Password
+	if err := validateNewPassword(req.Username, password, userType); err != nil {
+		return nil, err
+	}
+
 	// Handle wallet assignment or creation
 	walletName := req.WalletName
 	if walletName == "" {
@@ -145,12 +145,14 @@ func CreateWalletGroup(name string) (*models.WalletGroup, error) {
 }
 
 // CreateWallet creates a new wallet with given parameters
-func CreateWallet(name, icon string, initialBalance float64) (*models.Wallet, error) {
+func CreateWallet(name, icon string, initialBalance models.Money) (*models.Wallet, error) {
 	wallet := &models.Wallet{
 		Name:             name,
 		Icon:             icon,
 		IsEnabled:        true,
 		Balance:          initialBalance,
+		OpeningBalance:   initialBalance,
+		Currency:         models.DefaultCurrency,
 		LastModifiedTime: time.Now(),
 	}
 	if err := database.DB.Create(wallet).Error; err != nil {
@@ -228,6 +230,7 @@ func CreateRootCategories(walletID uint) ([]models.Category, error) {
 	incomeCategory := &models.Category{
 		Icon:     "💵",
 		Name:     "Income",
+		Kind:     models.CategoryKindIncome,
 		WalletID: walletID,
 		IsGlobal: true,
 		RootID:   0, // Will be set after creation
@@ -243,6 +246,7 @@ func CreateRootCategories(walletID uint) ([]models.Category, error) {
 	expenseCategory := &models.Category{
 		Icon:     "💸",
 		Name:     "Expense",
+		Kind:     models.CategoryKindExpense,
 		WalletID: walletID,
 		IsGlobal: true,
 		RootID:   0,
@@ -267,6 +271,7 @@ func CreateIncomeSubcategories(walletID uint, parentCategoryID uint) error {
 			Icon:     "📊",
 			ParentID: &parentCategoryID,
 			RootID:   parentCategoryID,
+			Kind:     models.CategoryKindIncome,
 			WalletID: walletID,
 			IsGlobal: false,
 		}
@@ -288,6 +293,7 @@ func CreateExpenseSubcategories(walletID uint, parentCategoryID uint) error {
 			Icon:     "📉",
 			ParentID: &parentCategoryID,
 			RootID:   parentCategoryID,
+			Kind:     models.CategoryKindExpense,
 			WalletID: walletID,
 			IsGlobal: false,
 		}
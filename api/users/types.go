@@ -28,8 +28,10 @@ type UserUpdateRequest struct {
 	Name        *string            `json:"name,omitempty"`
 	Email       *string            `json:"email,omitempty"`
 	Password    *string            `json:"password,omitempty"`
+	CurrentPassword *string        `json:"current_password,omitempty"` // Required to change your own password
 	Type        *models.UserType   `json:"type,omitempty"`
 	DefaultWalletID *uint          `json:"default_wallet_id,omitempty"`
+	ReportingCurrency *string      `json:"reporting_currency,omitempty"` // Empty string clears it
 }
 
 // UserResponse represents a user response
@@ -8,11 +8,25 @@ import (
 
 // CreateUser creates a new user in the database
 func CreateUser(username, name, email, password string, userType models.UserType, defaultWalletID *uint) (*models.User, error) {
+	if err := validateNewPassword(username, password, userType); err != nil {
+		return nil, err
+	}
+
+	// A bot's empty password is stored as-is and disables password login
+	hashedPassword := ""
+	if password != "" {
+		hash, err := HashPassword(password)
+		if err != nil {
+			return nil, err
+		}
+		hashedPassword = hash
+	}
+
 	user := &models.User{
 		Username:        username,
 		Name:            name,
 		Email:           email,
-		Password:        password,
+		Password:        hashedPassword,
 		Type:            userType,
 		DefaultWalletID: defaultWalletID,
 	}
@@ -5,10 +5,39 @@ import (
 	"log"
 	"moneyplanner/database"
 	"moneyplanner/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-// AttachWalletToUser creates a row in user_wallets (idempotent-ish: no error if already attached)
-func AttachWalletToUser(userID, walletID uint) error {
+// resolveRole validates a requested role, falling back to the given default when empty
+func resolveRole(role, fallback models.WalletRole) (models.WalletRole, error) {
+	if role == "" {
+		return fallback, nil
+	}
+	if !role.IsValid() {
+		return "", fmt.Errorf("invalid role '%s' (expected owner, editor or viewer)", role)
+	}
+	return role, nil
+}
+
+// ensureOwnerRemains fails if the wallet has been left without any owner
+func ensureOwnerRemains(tx *gorm.DB, walletID uint) error {
+	var owners int64
+	if err := tx.Model(&models.UserWallet{}).
+		Where("wallet_wallet_id = ? AND role = ?", walletID, models.WalletRoleOwner).
+		Count(&owners).Error; err != nil {
+		return fmt.Errorf("failed to count wallet owners: %w", err)
+	}
+	if owners == 0 {
+		return fmt.Errorf("wallet %d must keep at least one owner", walletID)
+	}
+	return nil
+}
+
+// AttachWalletToUser adds the user to the wallet with the given role, or updates the
+// role if already attached. An empty role defaults to viewer.
+func AttachWalletToUser(userID, walletID uint, role models.WalletRole) error {
 	var user models.User
 	if err := database.DB.First(&user, userID).Error; err != nil {
 		return fmt.Errorf("user not found: %w", err)
@@ -19,12 +48,26 @@ func AttachWalletToUser(userID, walletID uint) error {
 		return fmt.Errorf("wallet not found: %w", err)
 	}
 
-	// Append association
-	if err := database.DB.Model(&user).Association("Wallets").Append(&wallet); err != nil {
-		return fmt.Errorf("failed to attach wallet to user: %w", err)
+	role, err := resolveRole(role, models.WalletRoleViewer)
+	if err != nil {
+		return err
 	}
 
-	log.Printf("✓ Attached wallet %d to user %d", walletID, userID)
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		membership := &models.UserWallet{UserID: userID, WalletID: walletID, Role: role}
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_user_id"}, {Name: "wallet_wallet_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"role"}),
+		}).Create(membership).Error; err != nil {
+			return fmt.Errorf("failed to attach wallet to user: %w", err)
+		}
+		return ensureOwnerRemains(tx, walletID)
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("✓ Attached wallet %d to user %d as %s", walletID, userID, role)
 	return nil
 }
 
@@ -40,40 +83,90 @@ func DetachWalletFromUser(userID, walletID uint) error {
 		return fmt.Errorf("wallet not found: %w", err)
 	}
 
-	if err := database.DB.Model(&user).Association("Wallets").Delete(&wallet); err != nil {
-		return fmt.Errorf("failed to detach wallet from user: %w", err)
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_user_id = ? AND wallet_wallet_id = ?", userID, walletID).
+			Delete(&models.UserWallet{}).Error; err != nil {
+			return fmt.Errorf("failed to detach wallet from user: %w", err)
+		}
+		return ensureOwnerRemains(tx, walletID)
+	})
+	if err != nil {
+		return err
 	}
 
 	log.Printf("✓ Detached wallet %d from user %d", walletID, userID)
 	return nil
 }
 
-// ReplaceUserWallets sets the user's wallets to exactly this list (attach multiple in one go)
-func ReplaceUserWallets(userID uint, walletIDs []uint) error {
+// ReplaceUserWallets sets the user's memberships to exactly this list (attach multiple in one go).
+// Wallets listed without a role keep their current role, or become viewer if newly attached.
+func ReplaceUserWallets(userID uint, memberships []WalletMembership) error {
 	var user models.User
 	if err := database.DB.First(&user, userID).Error; err != nil {
 		return fmt.Errorf("user not found: %w", err)
 	}
 
-	if len(walletIDs) == 0 {
-		// Clear all wallets
-		if err := database.DB.Model(&user).Association("Wallets").Clear(); err != nil {
-			return fmt.Errorf("failed to clear wallets: %w", err)
+	walletIDs := make([]uint, 0, len(memberships))
+	for _, m := range memberships {
+		walletIDs = append(walletIDs, m.WalletID)
+	}
+
+	if len(walletIDs) > 0 {
+		var count int64
+		if err := database.DB.Model(&models.Wallet{}).Where("wallet_id IN ?", walletIDs).Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to load wallets: %w", err)
+		}
+		if int(count) != len(walletIDs) {
+			return fmt.Errorf("one or more wallet IDs not found")
 		}
-		log.Printf("✓ Cleared all wallets for user %d", userID)
-		return nil
 	}
 
-	var wallets []models.Wallet
-	if err := database.DB.Where("wallet_id IN ?", walletIDs).Find(&wallets).Error; err != nil {
-		return fmt.Errorf("failed to load wallets: %w", err)
+	var current []models.UserWallet
+	if err := database.DB.Where("user_user_id = ?", userID).Find(&current).Error; err != nil {
+		return fmt.Errorf("failed to load user wallets: %w", err)
 	}
-	if len(wallets) != len(walletIDs) {
-		return fmt.Errorf("one or more wallet IDs not found")
+	currentRoles := make(map[uint]models.WalletRole, len(current))
+	for _, m := range current {
+		currentRoles[m.WalletID] = m.Role
 	}
 
-	if err := database.DB.Model(&user).Association("Wallets").Replace(wallets); err != nil {
-		return fmt.Errorf("failed to replace user wallets: %w", err)
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// Remove memberships that are not in the new list
+		removeQuery := tx.Where("user_user_id = ?", userID)
+		if len(walletIDs) > 0 {
+			removeQuery = removeQuery.Where("wallet_wallet_id NOT IN ?", walletIDs)
+		}
+		if err := removeQuery.Delete(&models.UserWallet{}).Error; err != nil {
+			return fmt.Errorf("failed to replace user wallets: %w", err)
+		}
+
+		for _, m := range memberships {
+			fallback, ok := currentRoles[m.WalletID]
+			if !ok {
+				fallback = models.WalletRoleViewer
+			}
+			role, err := resolveRole(m.Role, fallback)
+			if err != nil {
+				return err
+			}
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "user_user_id"}, {Name: "wallet_wallet_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"role"}),
+			}).Create(&models.UserWallet{UserID: userID, WalletID: m.WalletID, Role: role}).Error; err != nil {
+				return fmt.Errorf("failed to replace user wallets: %w", err)
+			}
+		}
+
+		// Demoting or removing the user must not leave any wallet without an owner
+		for walletID := range currentRoles {
+			if err := ensureOwnerRemains(tx, walletID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	log.Printf("✓ Replaced wallets for user %d", userID)
@@ -88,3 +181,47 @@ func ListUserWallets(userID uint) ([]models.Wallet, error) {
 	}
 	return user.Wallets, nil
 }
+
+// ListWalletMembers fetches the users attached to a wallet along with their roles
+func ListWalletMembers(walletID uint) ([]WalletMember, error) {
+	members := []WalletMember{}
+	if err := database.DB.Model(&models.UserWallet{}).
+		Select("users.user_id, users.username, users.name, user_wallets.role").
+		Joins("JOIN users ON users.user_id = user_wallets.user_user_id").
+		Where("user_wallets.wallet_wallet_id = ?", walletID).
+		Scan(&members).Error; err != nil {
+		return nil, fmt.Errorf("failed to list wallet members: %w", err)
+	}
+	return members, nil
+}
+
+// GetWalletRole returns the user's role on a wallet, or an empty role if not a member
+func GetWalletRole(userID, walletID uint) (models.WalletRole, error) {
+	var membership models.UserWallet
+	err := database.DB.Where("user_user_id = ? AND wallet_wallet_id = ?", userID, walletID).
+		Limit(1).Find(&membership).Error
+	if err != nil {
+		return "", fmt.Errorf("failed to check wallet membership: %w", err)
+	}
+	return membership.Role, nil
+}
+
+// IsWalletMember reports whether the user is attached to the wallet via user_wallets
+func IsWalletMember(userID, walletID uint) (bool, error) {
+	role, err := GetWalletRole(userID, walletID)
+	if err != nil {
+		return false, err
+	}
+	return role != "", nil
+}
+
+// ListUserWalletIDs returns the IDs of all wallets the user is attached to
+func ListUserWalletIDs(userID uint) ([]uint, error) {
+	walletIDs := []uint{}
+	if err := database.DB.Model(&models.UserWallet{}).
+		Where("user_user_id = ?", userID).
+		Pluck("wallet_wallet_id", &walletIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to list user wallets: %w", err)
+	}
+	return walletIDs, nil
+}
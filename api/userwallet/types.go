@@ -1,5 +1,38 @@
 package userwallet
 
+import "moneyplanner/models"
+
+// WalletMembership pairs a wallet with the role the user should hold on it
+type WalletMembership struct {
+	WalletID uint              `json:"wallet_id"`
+	Role     models.WalletRole `json:"role,omitempty"`
+}
+
+// AttachWalletRequest is the optional body for attaching a wallet to a user
+type AttachWalletRequest struct {
+	Role models.WalletRole `json:"role,omitempty"`
+}
+
+// ReplaceWalletsRequest replaces a user's wallets. Either list plain wallet_ids
+// (roles unchanged, new wallets as viewer) or wallets with explicit roles.
 type ReplaceWalletsRequest struct {
-	WalletIDs []uint `json:"wallet_ids"`
-}
\ No newline at end of file
+	WalletIDs []uint             `json:"wallet_ids"`
+	Wallets   []WalletMembership `json:"wallets,omitempty"`
+}
+
+// Memberships merges WalletIDs and Wallets into a single membership list
+func (r *ReplaceWalletsRequest) Memberships() []WalletMembership {
+	memberships := append([]WalletMembership{}, r.Wallets...)
+	for _, walletID := range r.WalletIDs {
+		memberships = append(memberships, WalletMembership{WalletID: walletID})
+	}
+	return memberships
+}
+
+// WalletMember describes a user attached to a wallet
+type WalletMember struct {
+	UserID   uint              `json:"user_id"`
+	Username string            `json:"username"`
+	Name     string            `json:"name"`
+	Role     models.WalletRole `json:"role"`
+}
@@ -28,6 +28,15 @@ func ListWalletGroupsForUser(userID uint) ([]models.WalletGroup, error) {
 		}
 	}
 
+	// Groups the user created are theirs even before any wallet is added
+	var created []models.WalletGroup
+	if err := database.DB.Where("created_by = ?", userID).Find(&created).Error; err != nil {
+		return nil, fmt.Errorf("failed to list wallet groups: %w", err)
+	}
+	for _, g := range created {
+		seen[g.WalletGroupID] = g
+	}
+
 	groups := make([]models.WalletGroup, 0, len(seen))
 	for _, g := range seen {
 		groups = append(groups, g)
@@ -36,6 +45,28 @@ func ListWalletGroupsForUser(userID uint) ([]models.WalletGroup, error) {
 	return groups, nil
 }
 
+// IsWalletGroupMember reports whether the user can access a wallet group, i.e. created it or
+// is attached to at least one of its wallets
+func IsWalletGroupMember(userID, walletGroupID uint) (bool, error) {
+	var created int64
+	if err := database.DB.Model(&models.WalletGroup{}).
+		Where("wallet_group_id = ? AND created_by = ?", walletGroupID, userID).
+		Count(&created).Error; err != nil {
+		return false, fmt.Errorf("failed to check wallet group membership: %w", err)
+	}
+	if created > 0 {
+		return true, nil
+	}
+
+	var count int64
+	if err := database.DB.Table("wallet_wallet_groups").
+		Joins("JOIN user_wallets ON user_wallets.wallet_wallet_id = wallet_wallet_groups.wallet_wallet_id").
+		Where("wallet_wallet_groups.wallet_group_wallet_group_id = ? AND user_wallets.user_user_id = ?", walletGroupID, userID).
+		Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check wallet group membership: %w", err)
+	}
+	return count > 0, nil
+}
 
 // // ListWalletGroupsForUser returns all wallet groups reachable from user->wallets->wallet_groups
 // func ListWalletGroupsForUser(userID uint) ([]models.WalletGroup, error) {
@@ -3,11 +3,17 @@ package wallet
 import (
 	"fmt"
 	"log"
+	"moneyplanner/api/categories"
+	"moneyplanner/api/transactions"
 	"moneyplanner/database"
 	"moneyplanner/models"
 	"time"
+
+	"gorm.io/gorm"
 )
 
+const balanceAdjustmentNote = "Balance set by hand"
+
 // GetWalletByID retrieves a wallet by its ID
 func GetWalletByID(walletID uint) (*models.Wallet, error) {
 	var wallet models.Wallet
@@ -37,6 +43,7 @@ func CreateWallet(req *WalletCreationRequest) (*models.Wallet, error) {
 		Icon:             req.Icon,
 		IsEnabled:        true,
 		Balance:          0,
+		Currency:         models.DefaultCurrency,
 		LastModifiedTime: time.Now(),
 	}
 
@@ -46,6 +53,14 @@ func CreateWallet(req *WalletCreationRequest) (*models.Wallet, error) {
 	}
 	if req.Balance != nil {
 		w.Balance = *req.Balance
+		w.OpeningBalance = *req.Balance
+	}
+	if req.Currency != nil {
+		currency, err := models.NormalizeCurrency(*req.Currency)
+		if err != nil {
+			return nil, err
+		}
+		w.Currency = currency
 	}
 
 	if err := database.DB.Create(w).Error; err != nil {
@@ -62,6 +77,9 @@ func UpdateWallet(walletID uint, req *WalletUpdateRequest) (*models.Wallet, erro
 	if err != nil {
 		return nil, err
 	}
+	if err := models.CheckVersion(wallet.Version, req.ExpectedVersion); err != nil {
+		return nil, err
+	}
 
 	// Update only provided fields
 	updates := map[string]interface{}{}
@@ -81,41 +99,130 @@ func UpdateWallet(walletID uint, req *WalletUpdateRequest) (*models.Wallet, erro
 		wallet.IsEnabled = *req.IsEnabled
 	}
 
-	if req.Balance != nil {
-		updates["balance"] = *req.Balance
-		wallet.Balance = *req.Balance
+	if req.OpeningBalance != nil {
+		delta := *req.OpeningBalance - wallet.OpeningBalance
+		updates["opening_balance"] = *req.OpeningBalance
+		wallet.OpeningBalance = *req.OpeningBalance
+		updates["balance"] = gorm.Expr("balance + ?", delta)
+		wallet.Balance += delta
+	}
+
+	if req.Currency != nil {
+		currency, err := models.NormalizeCurrency(*req.Currency)
+		if err != nil {
+			return nil, err
+		}
+		updates["currency"] = currency
+		wallet.Currency = currency
 	}
 
 	// Only touch LastModifiedTime if we actually update something
-	if len(updates) == 0 {
+	if len(updates) == 0 && req.Balance == nil {
 		return wallet, nil // No updates provided
 	}
-	updates["last_modified_time"] = time.Now()
-	wallet.LastModifiedTime = updates["last_modified_time"].(time.Time)
 
-	if err := database.DB.Model(&models.Wallet{}).
-		Where("wallet_id = ?", walletID).
-		Updates(updates).Error; err != nil {
-		return nil, fmt.Errorf("failed to update wallet: %w", err)
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if len(updates) > 0 {
+			updates["last_modified_time"] = time.Now()
+			wallet.LastModifiedTime = updates["last_modified_time"].(time.Time)
+			updates["version"] = gorm.Expr("version + 1")
+
+			// Guarded by the version read above so a concurrent edit is not overwritten
+			result := tx.Model(&models.Wallet{}).
+				Where("wallet_id = ? AND version = ?", walletID, wallet.Version).
+				Updates(updates)
+			if result.Error != nil {
+				return fmt.Errorf("failed to update wallet: %w", result.Error)
+			}
+			if result.RowsAffected == 0 {
+				return models.ErrVersionConflict
+			}
+			wallet.Version++
+		} else {
+			// Nothing else to write, so make sure the balance the delta is taken from is still current
+			var current models.Wallet
+			if err := tx.Select("version").First(&current, walletID).Error; err != nil {
+				return fmt.Errorf("failed to load wallet: %w", err)
+			}
+			if current.Version != wallet.Version {
+				return models.ErrVersionConflict
+			}
+		}
+
+		// A new balance is booked as an adjustment, so the transactions still explain it
+		if req.Balance != nil && *req.Balance != wallet.Balance {
+			if err := postBalanceAdjustment(tx, wallet, *req.Balance-wallet.Balance, req.UpdatedBy); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
+	if req.Balance != nil {
+		// Reload for the balance and version the adjustment left behind
+		if wallet, err = GetWalletByID(walletID); err != nil {
+			return nil, err
+		}
+	}
 	log.Printf("✓ Wallet '%s' (ID: %d) updated", wallet.Name, walletID)
 	return wallet, nil
 }
 
-// DeleteWallet deletes a wallet by ID
-func DeleteWallet(walletID uint) error {
-	// Check if wallet exists
-	wallet, err := GetWalletByID(walletID)
+// postBalanceAdjustment posts delta to the wallet's adjustment category, moving its balance
+func postBalanceAdjustment(tx *gorm.DB, wallet *models.Wallet, delta models.Money, userID uint) error {
+	category, err := categories.EnsureKindRootCategory(tx, wallet.WalletID, models.CategoryKindAdjustment, "Adjustment", "⚖️")
 	if err != nil {
 		return err
 	}
+	note := balanceAdjustmentNote
+	if _, err := transactions.CreateTransactionInTx(tx, &transactions.TransactionCreationRequest{
+		WalletID:   wallet.WalletID,
+		CategoryID: category.CategoryID,
+		Amount:     delta,
+		Note:       &note,
+		UserID:     userID,
+	}); err != nil {
+		return fmt.Errorf("failed to post balance adjustment: %w", err)
+	}
+	log.Printf("✓ Wallet %d balance adjusted by %s", wallet.WalletID, delta)
+	return nil
+}
 
-	// Delete the wallet
-	if err := database.DB.Delete(&models.Wallet{}, walletID).Error; err != nil {
-		return fmt.Errorf("failed to delete wallet: %w", err)
+// RestoreWallet takes a wallet out of the trash, along with the categories, transactions and
+// transfers that were trashed with it
+func RestoreWallet(walletID uint) (*models.Wallet, error) {
+	var trashed models.Wallet
+	if err := database.DB.Unscoped().Where("deleted_at IS NOT NULL").First(&trashed, walletID).Error; err != nil {
+		return nil, fmt.Errorf("wallet %d is not in the trash", walletID)
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Model(&models.Wallet{}).
+			Where("wallet_id = ? AND deleted_at IS NOT NULL", walletID).
+			Updates(map[string]interface{}{
+				"deleted_at": nil,
+				"deleted_by": nil,
+				"version":    gorm.Expr("version + 1"),
+			})
+		if result.Error != nil {
+			return fmt.Errorf("failed to restore wallet: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("wallet %d is not in the trash", walletID)
+		}
+		return restoreContents(tx, walletID, trashed.DeletedAt.Time)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	log.Printf("✓ Wallet '%s' (ID: %d) deleted", wallet.Name, walletID)
-	return nil
+	wallet, err := GetWalletByID(walletID)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("✓ Wallet '%s' (ID: %d) restored", wallet.Name, walletID)
+	return wallet, nil
 }
@@ -0,0 +1,414 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"moneyplanner/api/categories"
+	"moneyplanner/api/transactions"
+	"moneyplanner/api/transfers"
+	"moneyplanner/database"
+	"moneyplanner/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// errDryRun rolls back a deletion that was only meant to be previewed
+var errDryRun = errors.New("dry run")
+
+// DeleteWallet moves a wallet to the trash, dealing with its contents as req.Mode says.
+// Everything happens in one DB transaction; a dry run rolls it back and only reports.
+func DeleteWallet(walletID uint, req *WalletDeletionRequest) (*WalletDeletionResult, error) {
+	if req.Mode == "" {
+		req.Mode = DeletionModeRefuse
+	}
+	if !req.Mode.IsValid() {
+		return nil, fmt.Errorf("mode must be one of refuse, cascade or reassign")
+	}
+	if req.Mode == DeletionModeReassign && req.TargetWalletID == nil {
+		return nil, fmt.Errorf("target_wallet_id is required to reassign")
+	}
+	if req.Mode != DeletionModeReassign && req.TargetWalletID != nil {
+		return nil, fmt.Errorf("target_wallet_id is only used to reassign")
+	}
+
+	wallet, err := GetWalletByID(walletID)
+	if err != nil {
+		return nil, err
+	}
+	if err := models.CheckVersion(wallet.Version, req.ExpectedVersion); err != nil {
+		return nil, err
+	}
+
+	result := &WalletDeletionResult{
+		WalletID:          walletID,
+		Mode:              req.Mode,
+		DryRun:            req.DryRun,
+		TargetWalletID:    req.TargetWalletID,
+		AffectedWalletIDs: []uint{},
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		switch req.Mode {
+		case DeletionModeRefuse:
+			if err := ensureEmpty(tx, walletID); err != nil {
+				return err
+			}
+		case DeletionModeCascade:
+			if err := trashContents(tx, walletID, now, req.DeletedBy, result); err != nil {
+				return err
+			}
+		case DeletionModeReassign:
+			if err := reassignContents(tx, wallet, *req.TargetWalletID, result); err != nil {
+				return err
+			}
+		}
+
+		// Whatever categories are left go with the wallet
+		categoriesResult := tx.Model(&models.Category{}).Where("wallet_id = ?", walletID).
+			Updates(map[string]interface{}{
+				"deleted_at": now,
+				"deleted_by": req.DeletedBy,
+				"version":    gorm.Expr("version + 1"),
+			})
+		if categoriesResult.Error != nil {
+			return fmt.Errorf("failed to delete wallet categories: %w", categoriesResult.Error)
+		}
+		result.CategoriesTrashed = categoriesResult.RowsAffected
+
+		// Users pointing at the wallet fall back to the target, or to no default
+		var defaultWallet interface{}
+		if req.Mode == DeletionModeReassign {
+			defaultWallet = *req.TargetWalletID
+		}
+		usersResult := tx.Model(&models.User{}).Where("default_wallet_id = ?", walletID).
+			Update("default_wallet_id", defaultWallet)
+		if usersResult.Error != nil {
+			return fmt.Errorf("failed to update default wallets: %w", usersResult.Error)
+		}
+		result.DefaultWalletUsers = usersResult.RowsAffected
+
+		walletResult := tx.Model(&models.Wallet{}).
+			Where("wallet_id = ? AND version = ?", walletID, wallet.Version).
+			Updates(map[string]interface{}{
+				"deleted_at": now,
+				"deleted_by": req.DeletedBy,
+				"version":    gorm.Expr("version + 1"),
+			})
+		if walletResult.Error != nil {
+			return fmt.Errorf("failed to delete wallet: %w", walletResult.Error)
+		}
+		if walletResult.RowsAffected == 0 {
+			return models.ErrVersionConflict
+		}
+
+		if req.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+
+	if !req.DryRun {
+		log.Printf("✓ Wallet '%s' (ID: %d) moved to trash (%s)", wallet.Name, walletID, req.Mode)
+	}
+	return result, nil
+}
+
+// ensureEmpty fails when the wallet still has transactions
+func ensureEmpty(tx *gorm.DB, walletID uint) error {
+	var count int64
+	if err := tx.Model(&models.Transaction{}).Where("wallet_id = ?", walletID).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to count transactions: %w", err)
+	}
+	if count > 0 {
+		return fmt.Errorf("wallet has %d transaction(s); delete them first or use mode cascade or reassign", count)
+	}
+	return nil
+}
+
+// trashContents trashes the wallet's transfers and transactions. Transfers take their legs in
+// other wallets with them, so those wallets' balances change too.
+func trashContents(tx *gorm.DB, walletID uint, deletedAt time.Time, deletedBy uint, result *WalletDeletionResult) error {
+	var transactionCount int64
+	if err := tx.Model(&models.Transaction{}).Where("wallet_id = ?", walletID).Count(&transactionCount).Error; err != nil {
+		return fmt.Errorf("failed to count transactions: %w", err)
+	}
+	result.TransactionsTrashed = int(transactionCount)
+
+	var walletTransfers []models.Transfer
+	if err := tx.Where("from_wallet_id = ? OR to_wallet_id = ?", walletID, walletID).
+		Find(&walletTransfers).Error; err != nil {
+		return fmt.Errorf("failed to load transfers: %w", err)
+	}
+	affected := map[uint]bool{}
+	for i := range walletTransfers {
+		transfer := &walletTransfers[i]
+		if err := transfers.TrashTransfer(tx, transfer, deletedAt, deletedBy); err != nil {
+			return err
+		}
+		for _, otherID := range []uint{transfer.FromWalletID, transfer.ToWalletID} {
+			if otherID != walletID && !affected[otherID] {
+				affected[otherID] = true
+				result.AffectedWalletIDs = append(result.AffectedWalletIDs, otherID)
+			}
+		}
+	}
+	result.TransfersTrashed = len(walletTransfers)
+
+	var walletTransactions []models.Transaction
+	if err := tx.Preload("Category").Where("wallet_id = ?", walletID).
+		Find(&walletTransactions).Error; err != nil {
+		return fmt.Errorf("failed to load transactions: %w", err)
+	}
+	for i := range walletTransactions {
+		if err := transactions.TrashTransaction(tx, &walletTransactions[i], deletedAt, deletedBy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reassignContents moves the wallet's categories, transactions and transfers to the target.
+// Categories whose name already exists in the target are merged into that category; the
+// rest move over under their mapped parent. Trashed transactions follow their category.
+func reassignContents(tx *gorm.DB, wallet *models.Wallet, targetID uint, result *WalletDeletionResult) error {
+	walletID := wallet.WalletID
+	if targetID == walletID {
+		return fmt.Errorf("target_wallet_id must be a different wallet")
+	}
+	var target models.Wallet
+	if err := tx.First(&target, targetID).Error; err != nil {
+		return fmt.Errorf("target wallet not found: %w", err)
+	}
+	if target.Currency != wallet.Currency {
+		return fmt.Errorf("cannot reassign %s transactions to a %s wallet", wallet.Currency, target.Currency)
+	}
+
+	// A transfer between the two wallets would end up inside one wallet
+	var betweenCount int64
+	if err := tx.Model(&models.Transfer{}).
+		Where("(from_wallet_id = ? AND to_wallet_id = ?) OR (from_wallet_id = ? AND to_wallet_id = ?)",
+			walletID, targetID, targetID, walletID).
+		Count(&betweenCount).Error; err != nil {
+		return fmt.Errorf("failed to check transfers: %w", err)
+	}
+	if betweenCount > 0 {
+		return fmt.Errorf("wallet has %d transfer(s) with wallet %d; delete them first", betweenCount, targetID)
+	}
+
+	categoryMap, err := mapCategories(tx, walletID, targetID, result)
+	if err != nil {
+		return err
+	}
+
+	var balanceDelta models.Money
+	for sourceID, mapped := range categoryMap {
+		var live struct {
+			Count int
+			Total models.Money
+		}
+		if err := tx.Model(&models.Transaction{}).Select("COUNT(*) AS count, COALESCE(SUM(amount), 0) AS total").
+			Where("wallet_id = ? AND category_id = ?", walletID, sourceID).
+			Scan(&live).Error; err != nil {
+			return fmt.Errorf("failed to total transactions: %w", err)
+		}
+
+		// Trashed transfer legs stay behind with their transfer
+		if err := tx.Unscoped().Model(&models.Transaction{}).
+			Where("wallet_id = ? AND category_id = ? AND (deleted_at IS NULL OR transfer_id IS NULL)", walletID, sourceID).
+			Updates(map[string]interface{}{
+				"wallet_id":   targetID,
+				"category_id": mapped.CategoryID,
+				"version":     gorm.Expr("version + 1"),
+			}).Error; err != nil {
+			return fmt.Errorf("failed to move transactions: %w", err)
+		}
+		if err := tx.Model(&models.TransactionSplit{}).Where("category_id = ?", sourceID).
+			Update("category_id", mapped.CategoryID).Error; err != nil {
+			return fmt.Errorf("failed to move split transactions: %w", err)
+		}
+		if err := tx.Model(&models.RecurringTransaction{}).
+			Where("wallet_id = ? AND category_id = ?", walletID, sourceID).
+			Updates(map[string]interface{}{
+				"wallet_id":   targetID,
+				"category_id": mapped.CategoryID,
+			}).Error; err != nil {
+			return fmt.Errorf("failed to move recurring transactions: %w", err)
+		}
+		if err := tx.Model(&models.Bill{}).
+			Where("wallet_id = ? AND category_id = ?", walletID, sourceID).
+			Updates(map[string]interface{}{
+				"wallet_id":   targetID,
+				"category_id": mapped.CategoryID,
+			}).Error; err != nil {
+			return fmt.Errorf("failed to move bills: %w", err)
+		}
+		if err := tx.Model(&models.SharedExpense{}).
+			Where("wallet_id = ? AND category_id = ?", walletID, sourceID).
+			Update("category_id", mapped.CategoryID).Error; err != nil {
+			return fmt.Errorf("failed to move shared expenses: %w", err)
+		}
+		result.TransactionsMoved += live.Count
+		balanceDelta += mapped.Kind.BalanceEffect(live.Total)
+	}
+
+	if balanceDelta != 0 {
+		if err := tx.Model(&models.Wallet{}).Where("wallet_id = ?", walletID).
+			Updates(map[string]interface{}{"balance": gorm.Expr("balance - ?", balanceDelta), "version": gorm.Expr("version + 1")}).Error; err != nil {
+			return fmt.Errorf("failed to update wallet balance: %w", err)
+		}
+		if err := tx.Model(&models.Wallet{}).Where("wallet_id = ?", targetID).
+			Updates(map[string]interface{}{"balance": gorm.Expr("balance + ?", balanceDelta), "version": gorm.Expr("version + 1")}).Error; err != nil {
+			return fmt.Errorf("failed to update wallet balance: %w", err)
+		}
+		result.AffectedWalletIDs = append(result.AffectedWalletIDs, targetID)
+	}
+
+	// Money set aside for goals stays set aside, in the target
+	if err := tx.Model(&models.GoalContribution{}).Where("wallet_id = ?", walletID).
+		Update("wallet_id", targetID).Error; err != nil {
+		return fmt.Errorf("failed to move goal contributions: %w", err)
+	}
+	if err := tx.Model(&models.Goal{}).Where("wallet_id = ?", walletID).
+		Update("wallet_id", targetID).Error; err != nil {
+		return fmt.Errorf("failed to move goals: %w", err)
+	}
+	if err := tx.Model(&models.Debt{}).Where("wallet_id = ?", walletID).
+		Update("wallet_id", targetID).Error; err != nil {
+		return fmt.Errorf("failed to move debts: %w", err)
+	}
+	// The shared ledger joins the target's, which now stands for you
+	if err := tx.Model(&models.SharedExpense{}).Where("wallet_id = ?", walletID).
+		Update("wallet_id", targetID).Error; err != nil {
+		return fmt.Errorf("failed to move shared expenses: %w", err)
+	}
+	if err := tx.Model(&models.SharedSettlement{}).Where("wallet_id = ?", walletID).
+		Update("wallet_id", targetID).Error; err != nil {
+		return fmt.Errorf("failed to move shared settlements: %w", err)
+	}
+
+	for _, column := range []string{"from_wallet_id", "to_wallet_id"} {
+		moved := tx.Model(&models.Transfer{}).Where(column+" = ?", walletID).
+			Updates(map[string]interface{}{
+				column:    targetID,
+				"version": gorm.Expr("version + 1"),
+			})
+		if moved.Error != nil {
+			return fmt.Errorf("failed to move transfers: %w", moved.Error)
+		}
+		result.TransfersMoved += int(moved.RowsAffected)
+	}
+	return nil
+}
+
+// mapCategories finds or makes a target category for every live category of the wallet,
+// parents before children. Roots map to the target's root of the same kind.
+func mapCategories(tx *gorm.DB, walletID, targetID uint, result *WalletDeletionResult) (map[uint]*models.Category, error) {
+	var walletCategories []models.Category
+	if err := tx.Where("wallet_id = ?", walletID).Order("category_id").Find(&walletCategories).Error; err != nil {
+		return nil, fmt.Errorf("failed to load categories: %w", err)
+	}
+	children := map[uint][]*models.Category{}
+	var queue []*models.Category
+	for i := range walletCategories {
+		category := &walletCategories[i]
+		if category.ParentID == nil {
+			queue = append(queue, category)
+		} else {
+			children[*category.ParentID] = append(children[*category.ParentID], category)
+		}
+	}
+
+	categoryMap := map[uint]*models.Category{}
+	for len(queue) > 0 {
+		category := queue[0]
+		queue = queue[1:]
+		queue = append(queue, children[category.CategoryID]...)
+
+		if category.ParentID == nil {
+			root, err := categories.EnsureKindRootCategory(tx, targetID, category.Kind, category.Name, category.Icon)
+			if err != nil {
+				return nil, err
+			}
+			categoryMap[category.CategoryID] = root
+			result.CategoriesMerged++
+			continue
+		}
+
+		parent := categoryMap[*category.ParentID]
+		var existing models.Category
+		if err := tx.Unscoped().Where("wallet_id = ? AND name = ?", targetID, category.Name).
+			Limit(1).Find(&existing).Error; err != nil {
+			return nil, fmt.Errorf("failed to look up category '%s': %w", category.Name, err)
+		}
+		if existing.CategoryID != 0 {
+			if existing.DeletedAt.Valid || existing.Kind != category.Kind {
+				return nil, fmt.Errorf("category '%s' clashes with category %d of wallet %d; rename one of them first",
+					category.Name, existing.CategoryID, targetID)
+			}
+			categoryMap[category.CategoryID] = &existing
+			result.CategoriesMerged++
+			continue
+		}
+
+		if err := tx.Model(&models.Category{}).Where("category_id = ?", category.CategoryID).
+			Updates(map[string]interface{}{
+				"wallet_id": targetID,
+				"parent_id": parent.CategoryID,
+				"root_id":   parent.RootID,
+				"version":   gorm.Expr("version + 1"),
+			}).Error; err != nil {
+			return nil, fmt.Errorf("failed to move category '%s': %w", category.Name, err)
+		}
+		category.WalletID = targetID
+		category.ParentID = &parent.CategoryID
+		category.RootID = parent.RootID
+		categoryMap[category.CategoryID] = category
+		result.CategoriesMoved++
+	}
+	return categoryMap, nil
+}
+
+// restoreContents takes the categories, transactions and transfers that were trashed together
+// with the wallet back out of the trash
+func restoreContents(tx *gorm.DB, walletID uint, deletedAt time.Time) error {
+	if err := tx.Unscoped().Model(&models.Category{}).
+		Where("wallet_id = ? AND deleted_at = ?", walletID, deletedAt).
+		Updates(map[string]interface{}{
+			"deleted_at": nil,
+			"deleted_by": nil,
+			"version":    gorm.Expr("version + 1"),
+		}).Error; err != nil {
+		return fmt.Errorf("failed to restore wallet categories: %w", err)
+	}
+
+	var transactionIDs []uint
+	if err := tx.Unscoped().Model(&models.Transaction{}).
+		Where("wallet_id = ? AND deleted_at = ? AND transfer_id IS NULL", walletID, deletedAt).
+		Pluck("transaction_id", &transactionIDs).Error; err != nil {
+		return fmt.Errorf("failed to load wallet transactions: %w", err)
+	}
+	for _, transactionID := range transactionIDs {
+		if _, err := transactions.UntrashTransaction(tx, transactionID); err != nil {
+			return err
+		}
+	}
+
+	var transferIDs []uint
+	if err := tx.Unscoped().Model(&models.Transfer{}).
+		Where("(from_wallet_id = ? OR to_wallet_id = ?) AND deleted_at = ?", walletID, walletID, deletedAt).
+		Pluck("transfer_id", &transferIDs).Error; err != nil {
+		return fmt.Errorf("failed to load wallet transfers: %w", err)
+	}
+	for _, transferID := range transferIDs {
+		if _, err := transfers.UntrashTransfer(tx, transferID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,15 +1,67 @@
 package wallet
 
+import "moneyplanner/models"
+
 type WalletCreationRequest struct {
-	Name      string   `json:"name"`
-	Icon      string   `json:"icon"`
-	IsEnabled *bool    `json:"is_enabled,omitempty"`
-	Balance   *float64 `json:"balance,omitempty"`
+	Name      string        `json:"name"`
+	Icon      string        `json:"icon"`
+	IsEnabled *bool         `json:"is_enabled,omitempty"`
+	Balance   *models.Money `json:"balance,omitempty"`
+	Currency  *string       `json:"currency,omitempty"` // Defaults to models.DefaultCurrency
 }
 
 type WalletUpdateRequest struct {
-	Name      *string  `json:"name,omitempty"`
-	Icon      *string  `json:"icon,omitempty"`
-	IsEnabled *bool    `json:"is_enabled,omitempty"`
-	Balance   *float64 `json:"balance,omitempty"`
+	Name      *string       `json:"name,omitempty"`
+	Icon      *string       `json:"icon,omitempty"`
+	IsEnabled *bool         `json:"is_enabled,omitempty"`
+	Balance   *models.Money `json:"balance,omitempty"`  // Booked as an adjustment transaction for the difference
+	Currency  *string       `json:"currency,omitempty"` // Relabels the wallet; balances are not converted
+
+	// Corrects the opening balance; the stored balance moves by the same difference
+	OpeningBalance *models.Money `json:"opening_balance,omitempty"`
+
+	// Version the client last read (from If-Match); the update fails if it has moved on
+	ExpectedVersion *uint `json:"-"`
+	UpdatedBy       uint  `json:"-"` // Set from the caller; the user of a balance adjustment
+}
+
+// DeletionMode decides what happens to a wallet's contents when it is deleted
+type DeletionMode string
+
+const (
+	DeletionModeRefuse   DeletionMode = "refuse"   // Only delete a wallet without transactions
+	DeletionModeCascade  DeletionMode = "cascade"  // Trash its transactions, transfers and categories with it
+	DeletionModeReassign DeletionMode = "reassign" // Move its transactions and categories to another wallet first
+)
+
+// IsValid reports whether the mode is one of the known deletion modes
+func (m DeletionMode) IsValid() bool {
+	return m == DeletionModeRefuse || m == DeletionModeCascade || m == DeletionModeReassign
+}
+
+type WalletDeletionRequest struct {
+	Mode           DeletionMode // Defaults to DeletionModeRefuse
+	TargetWalletID *uint        // Required for DeletionModeReassign
+	DryRun         bool         // Report what would happen without changing anything
+	DeletedBy      uint
+
+	// Version the client last read (from If-Match); the delete fails if it has moved on
+	ExpectedVersion *uint
+}
+
+// WalletDeletionResult counts what a wallet deletion changed, or would change on a dry run
+type WalletDeletionResult struct {
+	WalletID            uint         `json:"wallet_id"`
+	Mode                DeletionMode `json:"mode"`
+	DryRun              bool         `json:"dry_run"`
+	TargetWalletID      *uint        `json:"target_wallet_id,omitempty"`
+	TransactionsTrashed int          `json:"transactions_trashed"`
+	TransactionsMoved   int          `json:"transactions_moved"`
+	TransfersTrashed    int          `json:"transfers_trashed"`
+	TransfersMoved      int          `json:"transfers_moved"`
+	CategoriesTrashed   int64        `json:"categories_trashed"`
+	CategoriesMoved     int          `json:"categories_moved"`
+	CategoriesMerged    int          `json:"categories_merged"`    // Matched by name to a category of the target
+	DefaultWalletUsers  int64        `json:"default_wallet_users"` // Users whose default wallet was changed
+	AffectedWalletIDs   []uint       `json:"affected_wallet_ids"`  // Other wallets whose balance changed
 }
@@ -5,6 +5,8 @@ import (
 	"log"
 	"moneyplanner/database"
 	"moneyplanner/models"
+
+	"gorm.io/gorm"
 )
 
 // GetWalletGroupByID retrieves a wallet group by ID
@@ -26,13 +28,14 @@ func ListAllWalletGroups() ([]models.WalletGroup, error) {
 }
 
 // CreateWalletGroup creates a wallet group
-func CreateWalletGroup(req *WalletGroupCreationRequest) (*models.WalletGroup, error) {
+func CreateWalletGroup(req *WalletGroupCreationRequest, createdBy uint) (*models.WalletGroup, error) {
 	if req.WalletGroupName == "" {
 		return nil, fmt.Errorf("wallet_group_name is required")
 	}
 
 	wg := &models.WalletGroup{
 		WalletGroupName: req.WalletGroupName,
+		CreatedBy:       &createdBy,
 	}
 
 	if err := database.DB.Create(wg).Error; err != nil {
@@ -78,8 +81,17 @@ func DeleteWalletGroup(walletGroupID uint) error {
 		return err
 	}
 
-	if err := database.DB.Delete(&models.WalletGroup{}, walletGroupID).Error; err != nil {
-		return fmt.Errorf("failed to delete wallet group: %w", err)
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("wallet_group_id = ?", walletGroupID).Delete(&models.Budget{}).Error; err != nil {
+			return fmt.Errorf("failed to delete wallet group budgets: %w", err)
+		}
+		if err := tx.Delete(&models.WalletGroup{}, walletGroupID).Error; err != nil {
+			return fmt.Errorf("failed to delete wallet group: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	log.Printf("✓ WalletGroup '%s' (ID: %d) deleted", wg.WalletGroupName, walletGroupID)
@@ -5,6 +5,7 @@ import (
 	"log"
 	"moneyplanner/models"
 	"os"
+	"strings"
 
 	"github.com/glebarez/sqlite"
 	"gorm.io/gorm"
@@ -18,11 +19,15 @@ type InitOptions struct {
 	DBPath       string
 }
 
+// busyTimeout makes a write wait for a concurrent one (background jobs, requests) instead of
+// failing with "database is locked"
+const busyTimeout = "?_pragma=busy_timeout(5000)"
+
 // InitDB initializes the database connection (does NOT run migrations)
 func InitDB(dbPath string) error {
 	var err error
 
-	DB, err = gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
+	DB, err = gorm.Open(sqlite.Open(dbPath+busyTimeout), &gorm.Config{})
 	if err != nil {
 		return fmt.Errorf("failed to connect to database: %w", err)
 	}
@@ -44,7 +49,7 @@ func IsNewDatabase(dbPath string) bool {
 		return !DB.Migrator().HasTable("wallet_groups")
 	}
 	
-	DB, err = gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
+	DB, err = gorm.Open(sqlite.Open(dbPath+busyTimeout), &gorm.Config{})
 	if err != nil {
 		return true
 	}
@@ -64,6 +69,25 @@ func IsMigrationNeeded() (needed bool, missingItems []string) {
 		&models.WalletGroup{},
 		&models.Category{},
 		&models.Transaction{},
+		&models.UserWallet{},
+		&models.Session{},
+		&models.APIKey{},
+		&models.ExchangeRate{},
+		&models.Transfer{},
+		&models.Budget{},
+		&models.RecurringTransaction{},
+		&models.RecurringSkip{},
+		&models.Bill{},
+		&models.Goal{},
+		&models.GoalContribution{},
+		&models.Debt{},
+		&models.DebtRepayment{},
+		&models.TransactionSplit{},
+		&models.SharedExpense{},
+		&models.SharedExpenseShare{},
+		&models.SharedSettlement{},
+		&models.Tag{},
+		&models.Attachment{},
 	}
 
 	for _, model := range modelsToCheck {
@@ -101,19 +125,297 @@ func IsMigrationNeeded() (needed bool, missingItems []string) {
 		}
 	}
 
+	for _, column := range pendingMoneyColumns() {
+		missingItems = append(missingItems, "money column: "+column.table+"."+column.column)
+		needed = true
+		log.Printf("Migration needed: %s.%s still stores floating point amounts", column.table, column.column)
+	}
+
+	if countUnclassifiedCategories() > 0 {
+		missingItems = append(missingItems, "data: categories.kind")
+		needed = true
+		log.Printf("Migration needed: categories without a kind")
+	}
+
 	return needed, missingItems
 }
 
+// countUnclassifiedCategories counts categories that have no kind yet
+func countUnclassifiedCategories() int64 {
+	if !DB.Migrator().HasColumn(&models.Category{}, "kind") {
+		return 0
+	}
+	var count int64
+	DB.Unscoped().Model(&models.Category{}).Where("kind = '' OR kind IS NULL").Count(&count)
+	return count
+}
+
+// legacyRootKind guesses the kind of a root category created before kinds existed:
+// by name first, then by the IDs the old balance logic hard-coded (1 income, 2 expense)
+func legacyRootKind(root models.Category) models.CategoryKind {
+	name := strings.ToLower(root.Name)
+	switch {
+	case strings.Contains(name, "income"):
+		return models.CategoryKindIncome
+	case strings.Contains(name, "expense"):
+		return models.CategoryKindExpense
+	case strings.Contains(name, "transfer"):
+		return models.CategoryKindTransfer
+	case strings.Contains(name, "adjust"):
+		return models.CategoryKindAdjustment
+	case root.CategoryID == 1:
+		return models.CategoryKindIncome
+	}
+	return models.CategoryKindExpense
+}
+
+// classifyCategoryKinds gives every category without a kind its root's kind
+func classifyCategoryKinds() error {
+	if countUnclassifiedCategories() == 0 {
+		return nil
+	}
+
+	return DB.Transaction(func(tx *gorm.DB) error {
+		var unclassified []uint
+		if err := tx.Unscoped().Model(&models.Category{}).Where("kind = '' OR kind IS NULL").
+			Pluck("category_id", &unclassified).Error; err != nil {
+			return fmt.Errorf("failed to load unclassified categories: %w", err)
+		}
+
+		var roots []models.Category
+		if err := tx.Unscoped().Where("parent_id IS NULL AND (kind = '' OR kind IS NULL)").Find(&roots).Error; err != nil {
+			return fmt.Errorf("failed to load root categories: %w", err)
+		}
+		for _, root := range roots {
+			kind := legacyRootKind(root)
+			if err := tx.Unscoped().Model(&models.Category{}).Where("category_id = ?", root.CategoryID).
+				Update("kind", kind).Error; err != nil {
+				return fmt.Errorf("failed to classify category %d: %w", root.CategoryID, err)
+			}
+			log.Printf("✓ Root category '%s' (ID: %d) classified as %s", root.Name, root.CategoryID, kind)
+		}
+
+		// Descendants inherit their root's kind
+		if err := tx.Exec(`UPDATE categories SET kind = (
+			SELECT root.kind FROM categories AS root WHERE root.category_id = categories.root_id
+		) WHERE (kind = '' OR kind IS NULL) AND parent_id IS NOT NULL`).Error; err != nil {
+			return fmt.Errorf("failed to classify subcategories: %w", err)
+		}
+
+		// Anything still unclassified has a broken root_id; fall back to expense
+		if err := tx.Unscoped().Model(&models.Category{}).Where("kind = '' OR kind IS NULL").
+			Update("kind", models.CategoryKindExpense).Error; err != nil {
+			return fmt.Errorf("failed to classify categories: %w", err)
+		}
+
+		return rebalanceClassifiedWallets(tx, unclassified)
+	})
+}
+
+// legacyBalanceEffect is how the old balance logic applied an amount: added under root
+// category 1, subtracted under root category 2 and ignored everywhere else
+func legacyBalanceEffect(rootID uint, amount models.Money) models.Money {
+	switch rootID {
+	case 1:
+		return amount
+	case 2:
+		return -amount
+	}
+	return 0
+}
+
+// rebalanceClassifiedWallets replaces the legacy effect of the transactions in the newly
+// classified categories with the effect of their kind. Only the difference is applied, so
+// a balance set by hand before the upgrade is kept.
+func rebalanceClassifiedWallets(tx *gorm.DB, categoryIDs []uint) error {
+	if len(categoryIDs) == 0 {
+		return nil
+	}
+
+	var rows []struct {
+		WalletID uint
+		RootID   uint
+		Kind     models.CategoryKind
+		Total    models.Money
+	}
+	if err := tx.Raw(`SELECT t.wallet_id, c.root_id, c.kind, SUM(t.amount) AS total
+		FROM transactions AS t JOIN categories AS c ON c.category_id = t.category_id
+		WHERE t.category_id IN ? AND t.deleted_at IS NULL
+		GROUP BY t.wallet_id, c.root_id, c.kind`, categoryIDs).Scan(&rows).Error; err != nil {
+		return fmt.Errorf("failed to total transactions: %w", err)
+	}
+
+	deltas := make(map[uint]models.Money)
+	for _, row := range rows {
+		deltas[row.WalletID] += row.Kind.BalanceEffect(row.Total) - legacyBalanceEffect(row.RootID, row.Total)
+	}
+	for walletID, delta := range deltas {
+		if delta == 0 {
+			continue
+		}
+		if err := tx.Model(&models.Wallet{}).Where("wallet_id = ?", walletID).
+			Update("balance", gorm.Expr("balance + ?", delta)).Error; err != nil {
+			return fmt.Errorf("failed to rebalance wallet %d: %w", walletID, err)
+		}
+		log.Printf("✓ Wallet %d balance recomputed with category kinds (%s)", walletID, delta)
+	}
+	return nil
+}
+
+// backfillOpeningBalances sets each wallet's opening balance to what its stored balance
+// does not owe to its live transactions, so existing wallets reconcile without an adjustment
+func backfillOpeningBalances() error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		var totals []struct {
+			WalletID uint
+			Kind     models.CategoryKind
+			Total    models.Money
+		}
+		if err := tx.Model(&models.Transaction{}).
+			Select("transactions.wallet_id AS wallet_id, categories.kind AS kind, SUM(transactions.amount) AS total").
+			Joins("JOIN categories ON categories.category_id = transactions.category_id").
+			Group("transactions.wallet_id, categories.kind").
+			Scan(&totals).Error; err != nil {
+			return fmt.Errorf("failed to total transactions: %w", err)
+		}
+
+		effects := make(map[uint]models.Money)
+		for _, total := range totals {
+			effects[total.WalletID] += total.Kind.BalanceEffect(total.Total)
+		}
+
+		var wallets []models.Wallet
+		if err := tx.Unscoped().Select("wallet_id", "balance").Find(&wallets).Error; err != nil {
+			return fmt.Errorf("failed to load wallets: %w", err)
+		}
+		for _, wallet := range wallets {
+			opening := wallet.Balance - effects[wallet.WalletID]
+			if opening == 0 {
+				continue
+			}
+			if err := tx.Unscoped().Model(&models.Wallet{}).Where("wallet_id = ?", wallet.WalletID).
+				Update("opening_balance", opening).Error; err != nil {
+				return fmt.Errorf("failed to set opening balance of wallet %d: %w", wallet.WalletID, err)
+			}
+		}
+		log.Printf("✓ Opening balances backfilled for %d wallet(s)", len(wallets))
+		return nil
+	})
+}
+
+// moneyColumn identifies a column holding a models.Money amount
+type moneyColumn struct {
+	model  interface{}
+	field  string
+	table  string
+	column string
+}
+
+// moneyColumns were REAL (float64) columns before amounts moved to integer minor units
+var moneyColumns = []moneyColumn{
+	{model: &models.Transaction{}, field: "Amount", table: "transactions", column: "amount"},
+	{model: &models.Wallet{}, field: "Balance", table: "wallets", column: "balance"},
+}
+
+// pendingMoneyColumns returns the money columns that still have a floating point type
+func pendingMoneyColumns() []moneyColumn {
+	var pending []moneyColumn
+	for _, column := range moneyColumns {
+		if !DB.Migrator().HasTable(column.table) {
+			continue
+		}
+		columnTypes, err := DB.Migrator().ColumnTypes(column.table)
+		if err != nil {
+			continue
+		}
+		for _, col := range columnTypes {
+			if col.Name() != column.column {
+				continue
+			}
+			switch strings.ToLower(col.DatabaseTypeName()) {
+			case "real", "float", "double", "numeric", "decimal":
+				pending = append(pending, column)
+			}
+		}
+	}
+	return pending
+}
+
+// convertMoneyColumns rewrites floating point amounts as integer minor units, rounding
+// half away from zero like models.Money. The column type is changed in the same
+// transaction so a column is never converted twice.
+func convertMoneyColumns() error {
+	pending := pendingMoneyColumns()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	return DB.Transaction(func(tx *gorm.DB) error {
+		for _, column := range pending {
+			query := fmt.Sprintf("UPDATE %s SET %s = CAST(ROUND(%s * %d) AS INTEGER) WHERE %s IS NOT NULL",
+				column.table, column.column, column.column, models.MoneyFactor, column.column)
+			if err := tx.Exec(query).Error; err != nil {
+				return fmt.Errorf("failed to convert %s.%s to minor units: %w", column.table, column.column, err)
+			}
+			if err := tx.Migrator().AlterColumn(column.model, column.field); err != nil {
+				return fmt.Errorf("failed to change type of %s.%s: %w", column.table, column.column, err)
+			}
+			log.Printf("✓ Converted %s.%s to integer minor units", column.table, column.column)
+		}
+		return nil
+	})
+}
+
 // MigrateDB runs all migrations
 func MigrateDB() error {
-	return DB.AutoMigrate(
+	// Must run before AutoMigrate changes the column types to integer
+	if err := convertMoneyColumns(); err != nil {
+		return err
+	}
+
+	// Wallets from before opening balances need one worked out once the column exists
+	backfillOpening := DB.Migrator().HasTable(&models.Wallet{}) &&
+		!DB.Migrator().HasColumn(&models.Wallet{}, "OpeningBalance")
+
+	if err := DB.AutoMigrate(
 		&models.User{},
 		&models.Person{},
 		&models.Wallet{},
 		&models.WalletGroup{},
 		&models.Category{},
 		&models.Transaction{},
-	)
+		&models.UserWallet{},
+		&models.Session{},
+		&models.APIKey{},
+		&models.ExchangeRate{},
+		&models.Transfer{},
+		&models.Budget{},
+		&models.RecurringTransaction{},
+		&models.RecurringSkip{},
+		&models.Bill{},
+		&models.Goal{},
+		&models.GoalContribution{},
+		&models.Debt{},
+		&models.DebtRepayment{},
+		&models.TransactionSplit{},
+		&models.SharedExpense{},
+		&models.SharedExpenseShare{},
+		&models.SharedSettlement{},
+		&models.Tag{},
+		&models.Attachment{},
+	); err != nil {
+		return err
+	}
+
+	if err := classifyCategoryKinds(); err != nil {
+		return err
+	}
+
+	// After the kinds are known, as the backfill goes by them
+	if backfillOpening {
+		return backfillOpeningBalances()
+	}
+	return nil
 }
 
 // CloseDB closes the database connection
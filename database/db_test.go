@@ -0,0 +1,242 @@
+package database
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"moneyplanner/models"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB points DB at an empty database file for the duration of a test
+func openTestDB(t *testing.T) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	previous := DB
+	DB = db
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+		DB = previous
+	})
+}
+
+func mustExec(t *testing.T, sql string, values ...interface{}) {
+	t.Helper()
+	if err := DB.Exec(sql, values...).Error; err != nil {
+		t.Fatalf("%s: %v", sql, err)
+	}
+}
+
+func columnType(t *testing.T, table, column string) string {
+	t.Helper()
+	columnTypes, err := DB.Migrator().ColumnTypes(table)
+	if err != nil {
+		t.Fatalf("failed to read columns of %s: %v", table, err)
+	}
+	for _, col := range columnTypes {
+		if col.Name() == column {
+			return strings.ToLower(col.DatabaseTypeName())
+		}
+	}
+	t.Fatalf("%s has no column %s", table, column)
+	return ""
+}
+
+func TestConvertMoneyColumns(t *testing.T) {
+	openTestDB(t)
+	// Quoted the way GORM created the tables before the conversion
+	mustExec(t, "CREATE TABLE `transactions` (`transaction_id` integer,`amount` real,`note` text,PRIMARY KEY (`transaction_id`))")
+	mustExec(t, "CREATE TABLE `wallets` (`wallet_id` integer,`name` text,`balance` real,PRIMARY KEY (`wallet_id`))")
+
+	amounts := []float64{0, 12.34, -12.34, 0.125, -0.125, 19.99, 1234567.89, 0.1 + 0.2}
+	for i, amount := range amounts {
+		mustExec(t, "INSERT INTO transactions (transaction_id, amount, note) VALUES (?, ?, ?)", i+1, amount, "n")
+	}
+	mustExec(t, "INSERT INTO transactions (transaction_id, amount) VALUES (?, NULL)", len(amounts)+1)
+	mustExec(t, "INSERT INTO wallets (wallet_id, name, balance) VALUES (1, 'Main', ?)", 1050.5)
+
+	if err := convertMoneyColumns(); err != nil {
+		t.Fatalf("convertMoneyColumns returned error: %v", err)
+	}
+
+	for i, amount := range amounts {
+		var got int64
+		if err := DB.Raw("SELECT amount FROM transactions WHERE transaction_id = ?", i+1).Scan(&got).Error; err != nil {
+			t.Fatalf("failed to read amount: %v", err)
+		}
+		// The SQL conversion must round exactly like models.MoneyFromFloat
+		if want := int64(models.MoneyFromFloat(amount)); got != want {
+			t.Errorf("amount %v converted to %d, want %d", amount, got, want)
+		}
+	}
+	var null *int64
+	DB.Raw("SELECT amount FROM transactions WHERE transaction_id = ?", len(amounts)+1).Scan(&null)
+	if null != nil {
+		t.Errorf("NULL amount converted to %d, want NULL", *null)
+	}
+	var note string
+	DB.Raw("SELECT note FROM transactions WHERE transaction_id = 1").Scan(&note)
+	if note != "n" {
+		t.Errorf("other columns were not kept: note = %q", note)
+	}
+
+	var balance int64
+	DB.Raw("SELECT balance FROM wallets WHERE wallet_id = 1").Scan(&balance)
+	if balance != 105050 {
+		t.Errorf("balance converted to %d, want 105050", balance)
+	}
+
+	for _, c := range [][2]string{{"transactions", "amount"}, {"wallets", "balance"}} {
+		if typ := columnType(t, c[0], c[1]); typ == "real" {
+			t.Errorf("%s.%s is still %s", c[0], c[1], typ)
+		}
+	}
+
+	// A second run finds nothing left to convert and must not scale the amounts again
+	if pending := pendingMoneyColumns(); len(pending) != 0 {
+		t.Errorf("pendingMoneyColumns after conversion = %d column(s), want none", len(pending))
+	}
+	if err := convertMoneyColumns(); err != nil {
+		t.Fatalf("second convertMoneyColumns returned error: %v", err)
+	}
+	DB.Raw("SELECT balance FROM wallets WHERE wallet_id = 1").Scan(&balance)
+	if balance != 105050 {
+		t.Errorf("balance after second run = %d, want 105050", balance)
+	}
+}
+
+func TestConvertMoneyColumnsWithoutTables(t *testing.T) {
+	openTestDB(t)
+	if err := convertMoneyColumns(); err != nil {
+		t.Fatalf("convertMoneyColumns on an empty database returned error: %v", err)
+	}
+}
+
+func TestClassifyCategoryKindsRebalancesWallets(t *testing.T) {
+	openTestDB(t)
+	if err := DB.AutoMigrate(&models.Wallet{}, &models.Category{}, &models.Transaction{}); err != nil {
+		t.Fatalf("AutoMigrate returned error: %v", err)
+	}
+
+	// Two wallets upgraded from before kinds. The old logic only knew root categories 1 and 2,
+	// so wallet 2's income and expense never moved its balance; both started with 100.00 set by hand.
+	mustExec(t, "INSERT INTO wallets (wallet_id, name, balance) VALUES (1, 'First', ?), (2, 'Second', ?)", 10000+5000-2000, 10000)
+	for _, c := range []struct {
+		id, parent, root, wallet uint
+		name                     string
+	}{
+		{1, 0, 1, 1, "Income"},
+		{2, 0, 2, 1, "Expenses"},
+		{3, 2, 2, 1, "Food"},
+		{4, 0, 4, 2, "Income"},
+		{5, 0, 5, 2, "Expenses"},
+		{6, 5, 5, 2, "Rent"},
+	} {
+		var parent interface{}
+		if c.parent != 0 {
+			parent = c.parent
+		}
+		mustExec(t, "INSERT INTO categories (category_id, name, parent_id, root_id, wallet_id, kind) VALUES (?, ?, ?, ?, ?, '')",
+			c.id, c.name, parent, c.root, c.wallet)
+	}
+	for i, tx := range []struct {
+		category, wallet uint
+		amount           int64
+	}{
+		{1, 1, 5000},
+		{3, 1, 2000},
+		{4, 2, 3000},
+		{6, 2, 1200},
+		{5, 2, 300},
+	} {
+		mustExec(t, "INSERT INTO transactions (transaction_id, category_id, wallet_id, amount) VALUES (?, ?, ?, ?)",
+			i+1, tx.category, tx.wallet, tx.amount)
+	}
+	// Trashed transactions are not part of the balance
+	mustExec(t, "INSERT INTO transactions (transaction_id, category_id, wallet_id, amount, deleted_at) VALUES (99, 4, 2, 100000, CURRENT_TIMESTAMP)")
+
+	if err := classifyCategoryKinds(); err != nil {
+		t.Fatalf("classifyCategoryKinds returned error: %v", err)
+	}
+
+	for _, tt := range []struct {
+		wallet uint
+		want   int64
+	}{
+		{1, 10000 + 5000 - 2000},
+		{2, 10000 + 3000 - 1200 - 300},
+	} {
+		var balance int64
+		DB.Raw("SELECT balance FROM wallets WHERE wallet_id = ?", tt.wallet).Scan(&balance)
+		if balance != tt.want {
+			t.Errorf("wallet %d balance = %d, want %d", tt.wallet, balance, tt.want)
+		}
+	}
+
+	// Nothing is left to classify, so a second run leaves the balances alone
+	if err := classifyCategoryKinds(); err != nil {
+		t.Fatalf("second classifyCategoryKinds returned error: %v", err)
+	}
+	var balance int64
+	DB.Raw("SELECT balance FROM wallets WHERE wallet_id = 2").Scan(&balance)
+	if balance != 10000+3000-1200-300 {
+		t.Errorf("wallet 2 balance after second run = %d, want %d", balance, 10000+3000-1200-300)
+	}
+}
+
+func TestMigrateDBBackfillsOpeningBalance(t *testing.T) {
+	openTestDB(t)
+	if err := DB.AutoMigrate(&models.Wallet{}, &models.Category{}, &models.Transaction{}); err != nil {
+		t.Fatalf("AutoMigrate returned error: %v", err)
+	}
+	// As a wallet table looked before opening balances
+	if err := DB.Migrator().DropColumn(&models.Wallet{}, "OpeningBalance"); err != nil {
+		t.Fatalf("DropColumn returned error: %v", err)
+	}
+
+	mustExec(t, "INSERT INTO wallets (wallet_id, name, balance) VALUES (1, 'Main', ?), (2, 'Empty', ?)", 10000+5000-2000, 2500)
+	mustExec(t, "INSERT INTO categories (category_id, name, root_id, wallet_id, kind) VALUES (1, 'Salary', 1, 1, 'income'), (2, 'Food', 2, 1, 'expense')")
+	mustExec(t, "INSERT INTO transactions (transaction_id, category_id, wallet_id, amount) VALUES (1, 1, 1, 5000), (2, 2, 1, 2000)")
+	// Trashed transactions are not part of the balance
+	mustExec(t, "INSERT INTO transactions (transaction_id, category_id, wallet_id, amount, deleted_at) VALUES (3, 2, 1, 700, CURRENT_TIMESTAMP)")
+
+	if err := MigrateDB(); err != nil {
+		t.Fatalf("MigrateDB returned error: %v", err)
+	}
+
+	for _, tt := range []struct {
+		wallet uint
+		want   int64
+	}{
+		{1, 10000},
+		{2, 2500},
+	} {
+		var opening int64
+		DB.Raw("SELECT opening_balance FROM wallets WHERE wallet_id = ?", tt.wallet).Scan(&opening)
+		if opening != tt.want {
+			t.Errorf("wallet %d opening_balance = %d, want %d", tt.wallet, opening, tt.want)
+		}
+	}
+
+	// Once the column exists later runs leave a corrected opening balance alone
+	mustExec(t, "UPDATE wallets SET opening_balance = 0 WHERE wallet_id = 2")
+	if err := MigrateDB(); err != nil {
+		t.Fatalf("second MigrateDB returned error: %v", err)
+	}
+	var opening int64
+	DB.Raw("SELECT opening_balance FROM wallets WHERE wallet_id = 2").Scan(&opening)
+	if opening != 0 {
+		t.Errorf("wallet 2 opening_balance after second run = %d, want 0", opening)
+	}
+}
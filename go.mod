@@ -4,6 +4,7 @@ go 1.21
 
 require (
 	github.com/glebarez/sqlite v1.9.0
+	golang.org/x/crypto v0.14.0
 	gorm.io/gorm v1.25.5
 )
 
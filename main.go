@@ -6,6 +6,12 @@ import (
 	"moneyplanner/database"
 
 	"moneyplanner/api"
+	"moneyplanner/api/auth"
+	exchangeRatesAPI "moneyplanner/api/exchangerates"
+	initAPI "moneyplanner/api/init"
+	reconcileAPI "moneyplanner/api/reconcile"
+	recurringAPI "moneyplanner/api/recurring"
+	trashAPI "moneyplanner/api/trash"
 )
 
 func main() {
@@ -25,8 +31,30 @@ func main() {
 		log.Println("  Body: {\"force_migrate\": true, \"default_wallet_name\": \"My Wallet\"}")
 	}
 
+	// One-time bootstrap from MONEYPLANNER_* environment variables, if enabled
+	if resp := initAPI.BootstrapFromEnv("moneyplanner.db"); resp != nil {
+		if !resp.Success {
+			log.Fatalf("Bootstrap failed: %s: %s", resp.Message, resp.Error)
+		}
+		log.Printf("✓ Bootstrap: %s", resp.Message)
+	}
+
+	// Exchange rates from MONEYPLANNER_EXCHANGE_RATES_CSV, if set
+	if _, err := exchangeRatesAPI.ImportCSVFromEnv(); err != nil {
+		log.Printf("Warning: Failed to load exchange rates: %v", err)
+	}
+
+	// Periodic wallet balance drift check
+	reconcileAPI.StartBackgroundCheck()
+
+	// Permanent removal of items that have been in the trash too long
+	trashAPI.StartPurgeJob()
+
+	// Posting of recurring transactions as they fall due
+	recurringAPI.StartScheduler()
+
 
-	if err := http.ListenAndServe(":8080", mux); err != nil {
+	if err := http.ListenAndServe(":8080", auth.Middleware(mux)); err != nil {
 		log.Fatal(err)
 	}
 }
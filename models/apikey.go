@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// APIKey is a long-lived credential for a user (typically a bot). Only a SHA-256 hash
+// of the key is stored; Prefix is kept so users can tell their keys apart.
+type APIKey struct {
+	APIKeyID     uint       `gorm:"primaryKey" json:"api_key_id"`
+	UserID       uint       `gorm:"index;not null" json:"user_id"`
+	Name         string     `json:"name"`
+	Prefix       string     `json:"prefix"`
+	KeyHash      string     `gorm:"uniqueIndex;not null" json:"-"`
+	Scopes       string     `json:"-"` // Comma-separated, e.g. "transactions:write@3,categories:read"
+	CreatedTime  time.Time  `json:"created_time"`
+	LastUsedTime *time.Time `json:"last_used_time"` // Nullable
+	ExpiresAt    *time.Time `json:"expires_at"`     // Nullable
+	RevokedTime  *time.Time `json:"revoked_time"`   // Nullable
+}
+
+func (APIKey) TableName() string {
+	return "api_keys"
+}
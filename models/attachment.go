@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Attachment is a receipt, invoice or other document uploaded to a transaction. The file
+// itself lives in the attachment store under its SHA-256, so identical uploads share it.
+type Attachment struct {
+	AttachmentID  uint      `gorm:"primaryKey" json:"attachment_id"`
+	TransactionID *uint     `gorm:"index" json:"transaction_id"` // Nullable; cleared when the transaction is deleted for good
+	FileName      string    `gorm:"not null" json:"file_name"`
+	ContentType   string    `gorm:"size:100;not null" json:"content_type"`
+	Size          int64     `gorm:"not null" json:"size"`                               // In bytes
+	SHA256        string    `gorm:"column:sha256;size:64;not null;index" json:"sha256"` // Hex; the key of the file in the store
+	HasThumbnail  bool      `json:"has_thumbnail"`
+	UserID        uint      `json:"user_id"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func (Attachment) TableName() string {
+	return "attachments"
+}
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+type BillStatus string
+
+const (
+	BillStatusUpcoming BillStatus = "upcoming" // Not paid and not due yet
+	BillStatusDue      BillStatus = "due"      // Not paid, within RemindDaysBefore of the due date
+	BillStatusOverdue  BillStatus = "overdue"  // Not paid, past the due date
+	BillStatusPaid     BillStatus = "paid"     // Payments cover the amount
+)
+
+// IsValid reports whether the status is one of the known bill statuses
+func (s BillStatus) IsValid() bool {
+	switch s {
+	case BillStatusUpcoming, BillStatusDue, BillStatusOverdue, BillStatusPaid:
+		return true
+	}
+	return false
+}
+
+// Bill is an amount owed by a due date. It is paid, possibly late or in parts, by expense
+// transactions linked to it through Transaction.BillID; what is paid is derived from those.
+type Bill struct {
+	BillID           uint      `gorm:"primaryKey" json:"bill_id"`
+	Name             string    `gorm:"not null" json:"name"`
+	WalletID         uint      `gorm:"not null;index" json:"wallet_id"`
+	CategoryID       uint      `gorm:"not null" json:"category_id"`    // Expense category of the payments
+	PersonID         *uint     `json:"person_id"`                      // Nullable; the payee
+	Amount           Money     `gorm:"not null" json:"amount"`         // In the wallet's currency
+	DueDate          time.Time `gorm:"not null;index" json:"due_date"` // Midnight UTC; overdue from the day after
+	RemindDaysBefore int       `gorm:"not null;default:3" json:"remind_days_before"`
+	Note             *string   `json:"note"`
+	UserID           uint      `json:"user_id"`
+	EntryTime        time.Time `json:"entry_time"`
+	LastModifiedTime time.Time `json:"last_modified_time"`
+
+	// Derived from the live payments when loaded; not stored
+	PaidAmount Money      `gorm:"-" json:"paid_amount"`
+	Remaining  Money      `gorm:"-" json:"remaining"`
+	Status     BillStatus `gorm:"-" json:"status"`
+	PaidTime   *time.Time `gorm:"-" json:"paid_time"` // Time of the last payment once fully paid
+	PaidLate   bool       `gorm:"-" json:"paid_late"`
+
+	// Loaded separately; no relationship so no reverse constraints are generated
+	Payments []Transaction `gorm:"-" json:"payments,omitempty"`
+}
+
+func (Bill) TableName() string {
+	return "bills"
+}
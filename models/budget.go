@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+type BudgetPeriod string
+
+const (
+	BudgetPeriodWeekly  BudgetPeriod = "weekly"  // Calendar weeks starting on Monday
+	BudgetPeriodMonthly BudgetPeriod = "monthly" // Calendar months
+	BudgetPeriodYearly  BudgetPeriod = "yearly"  // Calendar years
+	BudgetPeriodCustom  BudgetPeriod = "custom"  // A single period from StartDate to EndDate
+)
+
+// IsValid reports whether the period is one of the known budget periods
+func (p BudgetPeriod) IsValid() bool {
+	switch p {
+	case BudgetPeriodWeekly, BudgetPeriodMonthly, BudgetPeriodYearly, BudgetPeriodCustom:
+		return true
+	}
+	return false
+}
+
+// Budget caps the spending in a category and all of its subcategories per period, either in
+// one wallet or across a wallet group. Exactly one of WalletID and WalletGroupID is set.
+type Budget struct {
+	BudgetID         uint         `gorm:"primaryKey" json:"budget_id"`
+	Name             string       `gorm:"not null" json:"name"`
+	CategoryID       uint         `gorm:"not null;index" json:"category_id"` // An expense category; in a group, matched by name in each wallet
+	WalletID         *uint        `gorm:"index" json:"wallet_id"`
+	WalletGroupID    *uint        `gorm:"index" json:"wallet_group_id"`
+	Period           BudgetPeriod `gorm:"size:10;not null" json:"period"`
+	StartDate        time.Time    `gorm:"not null" json:"start_date"` // Midnight UTC; nothing before it counts
+	EndDate          *time.Time   `json:"end_date"`                   // Last day, inclusive; required for custom budgets
+	Amount           Money        `gorm:"not null" json:"amount"`     // The limit per period, in Currency
+	Currency         string       `gorm:"size:3;not null" json:"currency"`
+	UserID           uint         `json:"user_id"`
+	EntryTime        time.Time    `json:"entry_time"`
+	LastModifiedTime time.Time    `json:"last_modified_time"`
+
+	// Relationships
+	Category Category `gorm:"foreignKey:CategoryID;references:CategoryID" json:"category,omitempty"`
+}
+
+func (Budget) TableName() string {
+	return "budgets"
+}
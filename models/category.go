@@ -1,13 +1,55 @@
 package models
 
+import "gorm.io/gorm"
+
+type CategoryKind string
+
+const (
+	CategoryKindIncome     CategoryKind = "income"
+	CategoryKindExpense    CategoryKind = "expense"
+	CategoryKindTransfer   CategoryKind = "transfer"
+	CategoryKindAdjustment CategoryKind = "adjustment"
+	CategoryKindDebt       CategoryKind = "debt" // Lending and borrowing, and their repayments
+)
+
+// categoryKindSigns is the direction each kind moves the wallet balance. Income and
+// expense amounts are positive; transfer, adjustment and debt amounts carry their own sign.
+var categoryKindSigns = map[CategoryKind]Money{
+	CategoryKindIncome:     1,
+	CategoryKindExpense:    -1,
+	CategoryKindTransfer:   1,
+	CategoryKindAdjustment: 1,
+	CategoryKindDebt:       1,
+}
+
+// IsValid reports whether the kind is one of the known category kinds
+func (k CategoryKind) IsValid() bool {
+	_, ok := categoryKindSigns[k]
+	return ok
+}
+
+// IsSigned reports whether amounts of this kind may be negative
+func (k CategoryKind) IsSigned() bool {
+	return k == CategoryKindTransfer || k == CategoryKindAdjustment || k == CategoryKindDebt
+}
+
+// BalanceEffect returns how much a transaction of this kind moves its wallet's balance
+func (k CategoryKind) BalanceEffect(amount Money) Money {
+	return categoryKindSigns[k] * amount
+}
+
 type Category struct {
-	CategoryID uint   `gorm:"primaryKey" json:"category_id"`
-	Icon       string `json:"icon"`
-	Name       string `gorm:"uniqueIndex:idx_category_wallet_name;not null" json:"name"`
-	ParentID   *uint  `json:"parent_id"` // Nullable foreign key to Category
-	RootID     uint   `json:"root_id"`
-	WalletID   uint   `gorm:"uniqueIndex:idx_category_wallet_name;not null" json:"wallet_id"`
-	IsGlobal   bool   `json:"is_global"`
+	CategoryID uint           `gorm:"primaryKey" json:"category_id"`
+	Icon       string         `json:"icon"`
+	Name       string         `gorm:"uniqueIndex:idx_category_wallet_name;not null" json:"name"`
+	ParentID   *uint          `json:"parent_id"` // Nullable foreign key to Category
+	RootID     uint           `json:"root_id"`
+	Kind       CategoryKind   `gorm:"size:20;not null;default:''" json:"kind"` // Always the root category's kind
+	WalletID   uint           `gorm:"uniqueIndex:idx_category_wallet_name;not null" json:"wallet_id"`
+	IsGlobal   bool           `json:"is_global"`
+	Version    uint           `gorm:"not null;default:1" json:"version"`
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"deleted_at"`
+	DeletedBy  *uint          `json:"deleted_by"`
 
 	// Relationships
 	Parent       *Category     `gorm:"foreignKey:ParentID;references:CategoryID" json:"parent,omitempty"`
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultCurrency is used for wallets created without a currency and for
+// wallets that existed before currencies were introduced
+const DefaultCurrency = "USD"
+
+// NormalizeCurrency upper-cases and validates an ISO 4217 style currency code
+func NormalizeCurrency(code string) (string, error) {
+	normalized := strings.ToUpper(strings.TrimSpace(code))
+	if len(normalized) != 3 {
+		return "", fmt.Errorf("invalid currency '%s' (expected a 3-letter code such as EUR)", code)
+	}
+	for _, c := range normalized {
+		if c < 'A' || c > 'Z' {
+			return "", fmt.Errorf("invalid currency '%s' (expected a 3-letter code such as EUR)", code)
+		}
+	}
+	return normalized, nil
+}
@@ -0,0 +1,84 @@
+package models
+
+import "time"
+
+type DebtDirection string
+
+const (
+	DebtDirectionLent     DebtDirection = "lent"     // Money given to the person, who owes it back
+	DebtDirectionBorrowed DebtDirection = "borrowed" // Money received from the person, owed back to them
+)
+
+// IsValid reports whether the direction is one of the known debt directions
+func (d DebtDirection) IsValid() bool {
+	return d == DebtDirectionLent || d == DebtDirectionBorrowed
+}
+
+// Sign is the sign of the wallet posting that opens a debt in this direction; repayments
+// post the opposite sign
+func (d DebtDirection) Sign() Money {
+	if d == DebtDirectionLent {
+		return -1
+	}
+	return 1
+}
+
+type DebtStatus string
+
+const (
+	DebtStatusOpen    DebtStatus = "open"
+	DebtStatusSettled DebtStatus = "settled"
+)
+
+// IsValid reports whether the status is one of the known debt statuses
+func (s DebtStatus) IsValid() bool {
+	return s == DebtStatusOpen || s == DebtStatusSettled
+}
+
+// Debt is money lent to or borrowed from a person. It is opened by a debt-kind transaction in
+// its wallet (TransactionID) and paid off by repayments, each linked to the transaction that
+// moved the money. What is repaid is derived from the repayments whose transaction is live.
+type Debt struct {
+	DebtID           uint          `gorm:"primaryKey" json:"debt_id"`
+	PersonID         uint          `gorm:"not null;index" json:"person_id"`
+	WalletID         uint          `gorm:"not null;index" json:"wallet_id"`
+	Direction        DebtDirection `gorm:"size:10;not null" json:"direction"`
+	Amount           Money         `gorm:"not null" json:"amount"` // Positive, in Currency
+	Currency         string        `gorm:"size:3;not null" json:"currency"`
+	TransactionID    uint          `gorm:"not null;uniqueIndex" json:"transaction_id"`
+	DueDate          *time.Time    `json:"due_date"` // Nullable; midnight UTC
+	Note             *string       `json:"note"`
+	DebtTime         time.Time     `json:"debt_time"`
+	UserID           uint          `json:"user_id"`
+	EntryTime        time.Time     `json:"entry_time"`
+	LastModifiedTime time.Time     `json:"last_modified_time"`
+
+	// Derived from the live repayments when loaded; not stored
+	Repaid      Money      `gorm:"-" json:"repaid"`
+	Outstanding Money      `gorm:"-" json:"outstanding"`
+	Status      DebtStatus `gorm:"-" json:"status"`
+
+	// Loaded separately; no relationships so no reverse constraints are generated
+	Person     *Person         `gorm:"-" json:"person,omitempty"`
+	Repayments []DebtRepayment `gorm:"-" json:"repayments,omitempty"`
+}
+
+func (Debt) TableName() string {
+	return "debts"
+}
+
+// DebtRepayment is the part of a transaction that pays off a debt. A settle-up transaction
+// can repay several debts with the same person at once.
+type DebtRepayment struct {
+	DebtRepaymentID uint  `gorm:"primaryKey" json:"debt_repayment_id"`
+	DebtID          uint  `gorm:"not null;index" json:"debt_id"`
+	TransactionID   uint  `gorm:"not null;index" json:"transaction_id"`
+	Amount          Money `gorm:"not null" json:"amount"` // Positive, in the debt's currency
+
+	// Loaded separately
+	Transaction *Transaction `gorm:"-" json:"transaction,omitempty"`
+}
+
+func (DebtRepayment) TableName() string {
+	return "debt_repayments"
+}
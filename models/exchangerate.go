@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// ExchangeRate records that one unit of BaseCurrency was worth Rate units of
+// QuoteCurrency from EffectiveDate until the next rate for the same pair
+type ExchangeRate struct {
+	ExchangeRateID   uint      `gorm:"primaryKey" json:"exchange_rate_id"`
+	BaseCurrency     string    `gorm:"size:3;not null;uniqueIndex:idx_exchange_rates_pair_date" json:"base_currency"`
+	QuoteCurrency    string    `gorm:"size:3;not null;uniqueIndex:idx_exchange_rates_pair_date" json:"quote_currency"`
+	EffectiveDate    time.Time `gorm:"not null;uniqueIndex:idx_exchange_rates_pair_date" json:"effective_date"`
+	Rate             string    `gorm:"not null" json:"rate"` // Exact decimal, e.g. "89.1234"
+	Source           string    `json:"source"`               // "manual" or "csv"
+	LastModifiedTime time.Time `json:"last_modified_time"`
+}
+
+func (ExchangeRate) TableName() string {
+	return "exchange_rates"
+}
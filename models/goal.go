@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+type GoalStatus string
+
+const (
+	GoalStatusInProgress GoalStatus = "in_progress" // No deadline and not reached yet
+	GoalStatusOnTrack    GoalStatus = "on_track"    // Saved at least as much as a steady pace to the deadline
+	GoalStatusBehind     GoalStatus = "behind"      // Saved less than a steady pace to the deadline
+	GoalStatusOverdue    GoalStatus = "overdue"     // The deadline has passed without reaching the target
+	GoalStatusAchieved   GoalStatus = "achieved"    // Saved reaches the target
+)
+
+// Goal is a savings target. Money is set aside for it from wallets by contributions, which
+// earmark part of a wallet's balance without moving it. WalletID is the goal's home wallet,
+// which decides who may see and change it.
+type Goal struct {
+	GoalID           uint       `gorm:"primaryKey" json:"goal_id"`
+	Name             string     `gorm:"not null" json:"name"`
+	Icon             string     `json:"icon"`
+	WalletID         uint       `gorm:"not null;index" json:"wallet_id"`
+	TargetAmount     Money      `gorm:"not null" json:"target_amount"` // In Currency
+	Currency         string     `gorm:"size:3;not null" json:"currency"`
+	StartDate        time.Time  `gorm:"not null" json:"start_date"` // Midnight UTC; the pace to the deadline is measured from here
+	Deadline         *time.Time `json:"deadline"`                   // Nullable; last day, midnight UTC
+	Note             *string    `json:"note"`
+	UserID           uint       `json:"user_id"`
+	EntryTime        time.Time  `json:"entry_time"`
+	LastModifiedTime time.Time  `json:"last_modified_time"`
+
+	// Derived from the contributions when loaded; not stored
+	Saved           Money      `gorm:"-" json:"saved"`
+	Remaining       Money      `gorm:"-" json:"remaining"`
+	PercentComplete float64    `gorm:"-" json:"percent_complete"`
+	MonthsLeft      *int       `gorm:"-" json:"months_left"`      // Null without a deadline
+	RequiredMonthly *Money     `gorm:"-" json:"required_monthly"` // To reach the target by the deadline; null without one
+	Status          GoalStatus `gorm:"-" json:"status"`
+}
+
+func (Goal) TableName() string {
+	return "goals"
+}
+
+// GoalContribution sets money aside for a goal from a wallet, or takes it back when negative
+type GoalContribution struct {
+	GoalContributionID uint      `gorm:"primaryKey" json:"goal_contribution_id"`
+	GoalID             uint      `gorm:"not null;index" json:"goal_id"`
+	WalletID           uint      `gorm:"not null;index" json:"wallet_id"`
+	Amount             Money     `gorm:"not null" json:"amount"`      // In the wallet's currency; negative for a withdrawal
+	GoalAmount         Money     `gorm:"not null" json:"goal_amount"` // The same in the goal's currency
+	Note               *string   `json:"note"`
+	ContributionTime   time.Time `json:"contribution_time"`
+	UserID             uint      `json:"user_id"`
+	EntryTime          time.Time `json:"entry_time"`
+}
+
+func (GoalContribution) TableName() string {
+	return "goal_contributions"
+}
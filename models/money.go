@@ -0,0 +1,157 @@
+package models
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// MoneyScale is the number of decimal places kept for every amount
+const MoneyScale = 2
+
+// MoneyFactor is 10^MoneyScale, the number of minor units in one major unit
+const MoneyFactor = 100
+
+// ErrMoneyOutOfRange is returned when a computed amount does not fit in Money
+var ErrMoneyOutOfRange = errors.New("amount is out of range")
+
+// Money is an exact amount stored as an integer number of minor units (cents).
+//
+// In JSON it is written as a plain decimal number ("12.34") and read from either a
+// number or a string without ever passing through float64. Inputs with more than
+// MoneyScale decimal places are rounded half away from zero (12.345 -> 12.35,
+// -12.345 -> -12.35); the same policy applies wherever a computation produces a
+// fraction of a minor unit.
+type Money int64
+
+// ParseMoney parses a decimal string such as "12.34", "-0.5" or "+7" into Money
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("invalid amount ''")
+	}
+
+	text := s
+	negative := false
+	switch text[0] {
+	case '-':
+		negative = true
+		text = text[1:]
+	case '+':
+		text = text[1:]
+	}
+
+	whole, frac, _ := strings.Cut(text, ".")
+	if whole == "" && frac == "" {
+		return 0, fmt.Errorf("invalid amount '%s'", s)
+	}
+	if !isDigits(whole) || !isDigits(frac) {
+		return 0, fmt.Errorf("invalid amount '%s'", s)
+	}
+
+	var units int64
+	if whole != "" {
+		w, err := strconv.ParseInt(whole, 10, 64)
+		if err != nil || w > math.MaxInt64/MoneyFactor-1 {
+			return 0, fmt.Errorf("amount '%s' is out of range", s)
+		}
+		units = w * MoneyFactor
+	}
+
+	// Keep MoneyScale digits and round on the first dropped one
+	kept := frac
+	if len(kept) > MoneyScale {
+		kept = kept[:MoneyScale]
+	}
+	kept += strings.Repeat("0", MoneyScale-len(kept))
+	minor, _ := strconv.ParseInt(kept, 10, 64)
+	units += minor
+	if len(frac) > MoneyScale && frac[MoneyScale] >= '5' {
+		units++
+	}
+
+	if negative {
+		units = -units
+	}
+	return Money(units), nil
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// MoneyFromFloat converts a float amount to Money, rounding half away from zero.
+// Only meant for legacy values; new code should parse amounts with ParseMoney.
+func MoneyFromFloat(f float64) Money {
+	return Money(math.Round(f * MoneyFactor))
+}
+
+// MulRat returns m multiplied by an exact ratio (e.g. an exchange rate), rounded half away
+// from zero. It fails with ErrMoneyOutOfRange rather than wrap when the result does not fit.
+func (m Money) MulRat(ratio *big.Rat) (Money, error) {
+	product := new(big.Rat).Mul(new(big.Rat).SetInt64(int64(m)), ratio)
+
+	// Round by adding half the sign before truncating towards zero
+	half := big.NewRat(1, 2)
+	if product.Sign() < 0 {
+		product.Sub(product, half)
+	} else {
+		product.Add(product, half)
+	}
+	rounded := new(big.Int).Quo(product.Num(), product.Denom())
+	if !rounded.IsInt64() {
+		return 0, ErrMoneyOutOfRange
+	}
+	return Money(rounded.Int64()), nil
+}
+
+// String formats the amount as a decimal with MoneyScale places, e.g. "-12.30"
+func (m Money) String() string {
+	// Through uint64 so that the most negative value does not overflow when negated
+	units := uint64(m)
+	sign := ""
+	if m < 0 {
+		sign = "-"
+		units = -units
+	}
+	return fmt.Sprintf("%s%d.%0*d", sign, units/MoneyFactor, MoneyScale, units%MoneyFactor)
+}
+
+// MarshalJSON writes the amount as a JSON number
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON reads the amount from a JSON number or numeric string
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if string(data) == "null" {
+		return nil
+	}
+	text := string(data)
+	if len(data) >= 2 && data[0] == '"' && data[len(data)-1] == '"' {
+		unquoted, err := strconv.Unquote(text)
+		if err != nil {
+			return fmt.Errorf("invalid amount %s", text)
+		}
+		text = unquoted
+	} else if strings.ContainsAny(text, "eE") {
+		return fmt.Errorf("invalid amount %s: exponent notation is not supported", text)
+	}
+
+	parsed, err := ParseMoney(text)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
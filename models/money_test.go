@@ -0,0 +1,214 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Money
+	}{
+		{"0", 0},
+		{"12", 1200},
+		{"12.3", 1230},
+		{"12.34", 1234},
+		{"+7", 700},
+		{".5", 50},
+		{"5.", 500},
+		{"  1.25  ", 125},
+		{"-0.5", -50},
+		{"-12.34", -1234},
+		{"0.001", 0},
+		{"0.004", 0},
+		{"0.005", 1},
+		{"1.005", 101},
+		{"12.345", 1235},
+		{"12.3449", 1234},
+		{"-12.345", -1235},
+		{"-0.005", -1},
+		{"-0.004", 0},
+		{"99.995", 10000},
+	}
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.in)
+		if err != nil {
+			t.Errorf("ParseMoney(%q) returned error: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseMoney(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseMoneyInvalid(t *testing.T) {
+	for _, in := range []string{"", " ", "-", "+", ".", "abc", "1,5", "1.2.3", "--1", "1e3", "0x10", "1 000", "99999999999999999999"} {
+		if got, err := ParseMoney(in); err == nil {
+			t.Errorf("ParseMoney(%q) = %d, want an error", in, got)
+		}
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	tests := []struct {
+		in   Money
+		want string
+	}{
+		{0, "0.00"},
+		{5, "0.05"},
+		{-5, "-0.05"},
+		{1230, "12.30"},
+		{-1234, "-12.34"},
+		{100000, "1000.00"},
+		{math.MaxInt64, "92233720368547758.07"},
+		{math.MinInt64, "-92233720368547758.08"},
+	}
+	for _, tt := range tests {
+		if got := tt.in.String(); got != tt.want {
+			t.Errorf("Money(%d).String() = %q, want %q", int64(tt.in), got, tt.want)
+		}
+	}
+}
+
+func TestMoneyFromFloat(t *testing.T) {
+	tests := []struct {
+		in   float64
+		want Money
+	}{
+		{0, 0},
+		{12.34, 1234},
+		{-12.34, -1234},
+		{0.125, 13},
+		{-0.125, -13},
+		{19.99, 1999},
+	}
+	for _, tt := range tests {
+		if got := MoneyFromFloat(tt.in); got != tt.want {
+			t.Errorf("MoneyFromFloat(%v) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyMulRat(t *testing.T) {
+	tests := []struct {
+		amount Money
+		ratio  string
+		want   Money
+	}{
+		{1000, "1", 1000},
+		{1000, "0.5", 500},
+		{1, "0.5", 1},   // 0.5 cent rounds up
+		{-1, "0.5", -1}, // and away from zero when negative
+		{3, "1/3", 1},
+		{-3, "1/3", -1},
+		{10000, "89.9535", 899535},
+		{12345, "1.10005", 13580}, // 13580.11725
+		{-12345, "1.10005", -13580},
+		{250, "0.01", 3}, // 2.5 -> 3
+		{-250, "0.01", -3},
+	}
+	for _, tt := range tests {
+		ratio, ok := new(big.Rat).SetString(tt.ratio)
+		if !ok {
+			t.Fatalf("invalid ratio %q", tt.ratio)
+		}
+		got, err := tt.amount.MulRat(ratio)
+		if err != nil {
+			t.Errorf("Money(%d).MulRat(%s) returned error: %v", int64(tt.amount), tt.ratio, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Money(%d).MulRat(%s) = %d, want %d", int64(tt.amount), tt.ratio, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyMulRatOutOfRange(t *testing.T) {
+	tests := []struct {
+		amount Money
+		ratio  *big.Rat
+	}{
+		{math.MaxInt64, big.NewRat(2, 1)},
+		{math.MinInt64, big.NewRat(2, 1)},
+		{math.MinInt64, big.NewRat(-1, 1)}, // One more than the largest Money
+		{math.MaxInt64 / 3, big.NewRat(1_000_000_000_000_000, 1)},
+	}
+	for _, tt := range tests {
+		if got, err := tt.amount.MulRat(tt.ratio); !errors.Is(err, ErrMoneyOutOfRange) {
+			t.Errorf("Money(%d).MulRat(%s) = %d, %v, want ErrMoneyOutOfRange", int64(tt.amount), tt.ratio, got, err)
+		}
+	}
+
+	// The extremes themselves still fit
+	for _, amount := range []Money{math.MaxInt64, math.MinInt64} {
+		if got, err := amount.MulRat(big.NewRat(1, 1)); err != nil || got != amount {
+			t.Errorf("Money(%d).MulRat(1) = %d, %v, want %d", int64(amount), got, err, int64(amount))
+		}
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Money
+		out  string
+	}{
+		{`12.34`, 1234, `12.34`},
+		{`"12.34"`, 1234, `12.34`},
+		{`-0.5`, -50, `-0.50`},
+		{`"-0.5"`, -50, `-0.50`},
+		{`1.005`, 101, `1.01`},
+		{`0`, 0, `0.00`},
+		{`100`, 10000, `100.00`},
+	}
+	for _, tt := range tests {
+		var got Money
+		if err := json.Unmarshal([]byte(tt.in), &got); err != nil {
+			t.Errorf("Unmarshal(%s) returned error: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Unmarshal(%s) = %d, want %d", tt.in, got, tt.want)
+		}
+		out, err := json.Marshal(got)
+		if err != nil {
+			t.Errorf("Marshal(%d) returned error: %v", got, err)
+			continue
+		}
+		if string(out) != tt.out {
+			t.Errorf("Marshal(%d) = %s, want %s", got, out, tt.out)
+		}
+
+		// What is written reads back as the same amount
+		var again Money
+		if err := json.Unmarshal(out, &again); err != nil || again != got {
+			t.Errorf("round trip of %d gave %d (%v)", got, again, err)
+		}
+	}
+}
+
+func TestMoneyJSONInvalid(t *testing.T) {
+	for _, in := range []string{`"abc"`, `1e3`, `"1e3"`, `true`, `"12,5"`} {
+		var got Money
+		if err := json.Unmarshal([]byte(in), &got); err == nil {
+			t.Errorf("Unmarshal(%s) = %d, want an error", in, got)
+		}
+	}
+}
+
+func TestMoneyJSONNullKeepsValue(t *testing.T) {
+	var holder struct {
+		Amount *Money `json:"amount"`
+	}
+	if err := json.Unmarshal([]byte(`{"amount": null}`), &holder); err != nil {
+		t.Fatalf("Unmarshal returned error: %v", err)
+	}
+	if holder.Amount != nil {
+		t.Errorf("null amount = %d, want nil", *holder.Amount)
+	}
+}
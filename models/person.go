@@ -1,9 +1,13 @@
 package models
 
+import "gorm.io/gorm"
+
 type Person struct {
-	PersonID   uint   `gorm:"primaryKey" json:"person_id"`
-	PersonName string `json:"person_name"`
-	Alias      string `json:"alias"`
+	PersonID   uint           `gorm:"primaryKey" json:"person_id"`
+	PersonName string         `json:"person_name"`
+	Alias      string         `json:"alias"`
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"deleted_at"`
+	DeletedBy  *uint          `json:"deleted_by"`
 
 	// Relationships
 	Transactions []Transaction `gorm:"foreignKey:PersonID" json:"transactions,omitempty"`
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// RecurringTransaction is a template that posts a transaction on every occurrence of its
+// recurrence rule. NextOccurrence is the first occurrence not yet posted or skipped.
+type RecurringTransaction struct {
+	RecurringTransactionID uint       `gorm:"primaryKey" json:"recurring_transaction_id"`
+	WalletID               uint       `gorm:"not null;index" json:"wallet_id"`
+	CategoryID             uint       `gorm:"not null" json:"category_id"`
+	Amount                 Money      `gorm:"not null" json:"amount"`
+	Currency               *string    `json:"currency"` // Nullable; converted at each occurrence when not the wallet's
+	PersonID               *uint      `json:"person_id"`
+	Note                   *string    `json:"note"`
+	RRule                  string     `gorm:"column:rrule;not null" json:"rrule"` // RFC 5545 RRULE, e.g. FREQ=MONTHLY;BYMONTHDAY=1
+	StartTime              time.Time  `gorm:"not null" json:"start_time"`         // First possible occurrence; sets the time of day
+	NextOccurrence         *time.Time `gorm:"index" json:"next_occurrence"`       // Null once the series has ended
+	IsEnabled              bool       `json:"is_enabled"`
+	UserID                 uint       `json:"user_id"`
+	EntryTime              time.Time  `json:"entry_time"`
+	LastModifiedTime       time.Time  `json:"last_modified_time"`
+}
+
+func (RecurringTransaction) TableName() string {
+	return "recurring_transactions"
+}
+
+// RecurringSkip marks one occurrence of a recurring transaction that must not be posted
+type RecurringSkip struct {
+	RecurringSkipID        uint      `gorm:"primaryKey" json:"recurring_skip_id"`
+	RecurringTransactionID uint      `gorm:"not null;uniqueIndex:idx_recurring_skips_occurrence" json:"recurring_transaction_id"`
+	OccurrenceTime         time.Time `gorm:"not null;uniqueIndex:idx_recurring_skips_occurrence" json:"occurrence_time"`
+}
+
+func (RecurringSkip) TableName() string {
+	return "recurring_skips"
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// Session is an issued bearer token pair. Only SHA-256 hashes of the tokens are stored.
+type Session struct {
+	SessionID        uint      `gorm:"primaryKey" json:"session_id"`
+	UserID           uint      `gorm:"index;not null" json:"user_id"`
+	TokenHash        string    `gorm:"uniqueIndex;not null" json:"-"`
+	RefreshTokenHash string    `gorm:"uniqueIndex;not null" json:"-"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+	CreatedTime      time.Time `json:"created_time"`
+	LastUsedTime     time.Time `json:"last_used_time"`
+}
+
+func (Session) TableName() string {
+	return "sessions"
+}
@@ -0,0 +1,77 @@
+package models
+
+import "time"
+
+type SplitMethod string
+
+const (
+	SplitMethodEqual      SplitMethod = "equal"      // Everyone pays the same
+	SplitMethodExact      SplitMethod = "exact"      // Each share is given as an amount
+	SplitMethodPercentage SplitMethod = "percentage" // Each share is given as a percentage of the amount
+	SplitMethodWeighted   SplitMethod = "weighted"   // Each share is given as a weight, e.g. 2 for a couple
+)
+
+// IsValid reports whether the method is one of the known split methods
+func (m SplitMethod) IsValid() bool {
+	switch m {
+	case SplitMethodEqual, SplitMethodExact, SplitMethodPercentage, SplitMethodWeighted:
+		return true
+	}
+	return false
+}
+
+// SharedExpense is an expense paid by one person on behalf of several, kept in a wallet's
+// shared ledger. A nil person stands for the wallet itself ("you"): when you paid, the money
+// left the wallet, and your own share is posted to CategoryID as an expense.
+type SharedExpense struct {
+	SharedExpenseID  uint        `gorm:"primaryKey" json:"shared_expense_id"`
+	WalletID         uint        `gorm:"not null;index" json:"wallet_id"`
+	Description      string      `gorm:"not null" json:"description"`
+	Amount           Money       `gorm:"not null" json:"amount"`         // Positive, in the wallet's currency
+	PaidByPersonID   *uint       `gorm:"index" json:"paid_by_person_id"` // Nullable; nil when you paid
+	SplitMethod      SplitMethod `gorm:"size:10;not null" json:"split_method"`
+	CategoryID       *uint       `json:"category_id"` // Nullable; the expense category of your share
+	ExpenseTime      time.Time   `json:"expense_time"`
+	UserID           uint        `json:"user_id"`
+	EntryTime        time.Time   `json:"entry_time"`
+	LastModifiedTime time.Time   `json:"last_modified_time"`
+
+	// Loaded separately; no relationship so no reverse constraints are generated
+	Shares []SharedExpenseShare `gorm:"-" json:"shares,omitempty"`
+}
+
+func (SharedExpense) TableName() string {
+	return "shared_expenses"
+}
+
+// SharedExpenseShare is one participant's part of a shared expense
+type SharedExpenseShare struct {
+	SharedExpenseShareID uint  `gorm:"primaryKey" json:"shared_expense_share_id"`
+	SharedExpenseID      uint  `gorm:"not null;index" json:"shared_expense_id"`
+	PersonID             *uint `gorm:"index" json:"person_id"` // Nullable; nil for you
+	Value                Money `json:"value"`                  // As entered: the amount, percentage or weight; 0 for equal splits
+	Amount               Money `gorm:"not null" json:"amount"` // The share worked out from Value
+}
+
+func (SharedExpenseShare) TableName() string {
+	return "shared_expense_shares"
+}
+
+// SharedSettlement is a payment between two participants of a wallet's shared ledger that
+// pays off what one owes the other. When you are one of them it is posted to the wallet.
+type SharedSettlement struct {
+	SharedSettlementID uint      `gorm:"primaryKey" json:"shared_settlement_id"`
+	WalletID           uint      `gorm:"not null;index" json:"wallet_id"`
+	FromPersonID       *uint     `json:"from_person_id"` // Nullable; nil when you paid
+	ToPersonID         *uint     `json:"to_person_id"`   // Nullable; nil when you were paid
+	Amount             Money     `gorm:"not null" json:"amount"`
+	TransactionID      *uint     `gorm:"index" json:"transaction_id"` // Nullable; set when you are one of the two
+	Note               *string   `json:"note"`
+	SettlementTime     time.Time `json:"settlement_time"`
+	UserID             uint      `json:"user_id"`
+	EntryTime          time.Time `json:"entry_time"`
+}
+
+func (SharedSettlement) TableName() string {
+	return "shared_settlements"
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Tag is a label such as "vacation-2026" or "tax-deductible" that can be put on any number
+// of transactions, across wallets and independent of their categories
+type Tag struct {
+	TagID     uint      `gorm:"primaryKey" json:"tag_id"`
+	Name      string    `gorm:"not null;uniqueIndex" json:"name"` // Unique regardless of case
+	Color     *string   `json:"color"`                            // Nullable; e.g. "#ff8800"
+	CreatedAt time.Time `json:"created_at"`
+
+	TransactionCount *int64 `gorm:"-" json:"transaction_count,omitempty"` // Live transactions carrying the tag, on lists
+}
+
+func (Tag) TableName() string {
+	return "tags"
+}
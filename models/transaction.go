@@ -1,26 +1,63 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Transaction struct {
-	TransactionID    uint      `gorm:"primaryKey" json:"transaction_id"`
-	CategoryID       uint      `json:"category_id"`
-	Amount           float64   `json:"amount"`
-	Note             *string   `json:"note"`      // Nullable
-	PersonID         *uint     `json:"person_id"` // Nullable foreign key
-	WalletID         uint      `json:"wallet_id"`
-	TransactionTime  time.Time `json:"transaction_time"`
-	EntryTime        time.Time `json:"entry_time"`
-	LastModifiedTime time.Time `json:"last_modified_time"`
-	UserID           uint      `json:"user_id"`
+	TransactionID    uint           `gorm:"primaryKey" json:"transaction_id"`
+	CategoryID       uint           `json:"category_id"`
+	Amount           Money          `json:"amount"`            // Minor units, see Money; always in the wallet's currency
+	OriginalAmount   *Money         `json:"original_amount"`   // Nullable; the amount as entered when it was in another currency
+	OriginalCurrency *string        `json:"original_currency"` // Nullable
+	Note             *string        `json:"note"`              // Nullable
+	PersonID         *uint          `json:"person_id"`         // Nullable foreign key
+	WalletID         uint           `json:"wallet_id"`
+	TransferID       *uint          `gorm:"index" json:"transfer_id"` // Nullable; set on the legs of a transfer
+	TransactionTime  time.Time      `json:"transaction_time"`
+	EntryTime        time.Time      `json:"entry_time"`
+	LastModifiedTime time.Time      `json:"last_modified_time"`
+	Version          uint           `gorm:"not null;default:1" json:"version"` // Used as the ETag for If-Match
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"deleted_at"`           // Set while the transaction is in the trash
+	DeletedBy        *uint          `json:"deleted_by"`
+	UserID           uint           `json:"user_id"`
+
+	// Set when posted by a recurring transaction; unique so an occurrence is never posted twice
+	RecurringTransactionID *uint      `gorm:"uniqueIndex:idx_transactions_occurrence" json:"recurring_transaction_id"`
+	OccurrenceTime         *time.Time `gorm:"uniqueIndex:idx_transactions_occurrence" json:"occurrence_time"`
+
+	BillID          *uint `gorm:"index" json:"bill_id"`           // Nullable; set on payments of a bill
+	SharedExpenseID *uint `gorm:"index" json:"shared_expense_id"` // Nullable; set on the postings of a shared expense
+
+	// Loaded separately; no relationship so no reverse constraints are generated
+	Splits []TransactionSplit `gorm:"-" json:"splits,omitempty"`
 
 	// Relationships
 	Category Category `gorm:"foreignKey:CategoryID;references:CategoryID" json:"category,omitempty"`
 	Person   *Person  `gorm:"foreignKey:PersonID;references:PersonID" json:"person,omitempty"`
 	Wallet   Wallet   `gorm:"foreignKey:WalletID;references:WalletID" json:"wallet,omitempty"`
 	User     User     `gorm:"foreignKey:UserID;references:UserID" json:"user,omitempty"`
+	Tags     []Tag    `gorm:"many2many:transaction_tags;" json:"tags,omitempty"`
 }
 
 func (Transaction) TableName() string {
 	return "transactions"
 }
+
+// TransactionSplit is one category line of a split transaction. The lines of a transaction
+// add up to its amount, and its CategoryID is the first line's; unsplit transactions have none.
+type TransactionSplit struct {
+	TransactionSplitID uint    `gorm:"primaryKey" json:"transaction_split_id"`
+	TransactionID      uint    `gorm:"not null;index" json:"transaction_id"`
+	CategoryID         uint    `gorm:"not null;index" json:"category_id"`
+	Amount             Money   `gorm:"not null" json:"amount"` // Positive, in the wallet's currency like the transaction
+	Note               *string `json:"note"`
+
+	Category Category `gorm:"foreignKey:CategoryID;references:CategoryID" json:"category,omitempty"`
+}
+
+func (TransactionSplit) TableName() string {
+	return "transaction_splits"
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Transfer moves money between two wallets. It owns the linked transactions (legs)
+// that actually move the balances: a debit in FromWallet, a credit in ToWallet and,
+// when there is a fee, an expense in FromWallet.
+type Transfer struct {
+	TransferID       uint           `gorm:"primaryKey" json:"transfer_id"`
+	FromWalletID     uint           `gorm:"not null;index" json:"from_wallet_id"`
+	ToWalletID       uint           `gorm:"not null;index" json:"to_wallet_id"`
+	Amount           Money          `gorm:"not null" json:"amount"`    // Debited from FromWallet, in its currency
+	ToAmount         Money          `gorm:"not null" json:"to_amount"` // Credited to ToWallet, in its currency
+	Fee              Money          `gorm:"not null;default:0" json:"fee"`
+	FeeCategoryID    *uint          `json:"fee_category_id"` // Nullable; expense category of the fee leg
+	Note             *string        `json:"note"`
+	TransferTime     time.Time      `json:"transfer_time"`
+	EntryTime        time.Time      `json:"entry_time"`
+	LastModifiedTime time.Time      `json:"last_modified_time"`
+	Version          uint           `gorm:"not null;default:1" json:"version"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"deleted_at"` // Set together with its legs
+	DeletedBy        *uint          `json:"deleted_by"`
+	UserID           uint           `json:"user_id"`
+
+	// Loaded separately; no relationship so no reverse constraints are generated
+	Legs []Transaction `gorm:"-" json:"legs,omitempty"`
+}
+
+func (Transfer) TableName() string {
+	return "transfers"
+}
@@ -12,8 +12,11 @@ type User struct {
 	Username         string    `gorm:"uniqueIndex" json:"username"`
 	Name             string    `json:"name"`
 	Email            string    `gorm:"uniqueIndex" json:"email"`
-	Password         string    `json:"password"`
+	Password         string    `json:"-"`
 	Type             UserType  `json:"type"`
+	IsAdmin          bool      `gorm:"not null;default:false" json:"is_admin"`
+	MustResetPassword bool     `gorm:"not null;default:false" json:"must_reset_password"` // Login is refused until an admin sets a new password
+	ReportingCurrency string   `gorm:"size:3" json:"reporting_currency"` // Empty falls back to the default wallet currency
 	DefaultWalletID  *uint     `json:"default_wallet_id"` // Nullable
 	
 	// Relationships
@@ -0,0 +1,39 @@
+package models
+
+type WalletRole string
+
+const (
+	WalletRoleOwner  WalletRole = "owner"
+	WalletRoleEditor WalletRole = "editor"
+	WalletRoleViewer WalletRole = "viewer"
+)
+
+// walletRoleRanks orders roles so that a higher rank includes every lower permission
+var walletRoleRanks = map[WalletRole]int{
+	WalletRoleViewer: 1,
+	WalletRoleEditor: 2,
+	WalletRoleOwner:  3,
+}
+
+// IsValid reports whether the role is one of the known wallet roles
+func (r WalletRole) IsValid() bool {
+	_, ok := walletRoleRanks[r]
+	return ok
+}
+
+// Includes reports whether this role grants at least the permissions of other
+func (r WalletRole) Includes(other WalletRole) bool {
+	return walletRoleRanks[r] >= walletRoleRanks[other]
+}
+
+// UserWallet is the user_wallets join row behind User.Wallets, carrying the member's role.
+// Column names match the ones GORM generated for the original many2many table.
+type UserWallet struct {
+	UserID   uint       `gorm:"primaryKey;column:user_user_id" json:"user_id"`
+	WalletID uint       `gorm:"primaryKey;column:wallet_wallet_id" json:"wallet_id"`
+	Role     WalletRole `gorm:"not null;default:owner" json:"role"`
+}
+
+func (UserWallet) TableName() string {
+	return "user_wallets"
+}
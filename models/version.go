@@ -0,0 +1,14 @@
+package models
+
+import "errors"
+
+// ErrVersionConflict is returned when a write was based on a version that is no longer current
+var ErrVersionConflict = errors.New("the resource was modified by someone else; reload it and try again")
+
+// CheckVersion fails with ErrVersionConflict if an expected version is given and differs from current
+func CheckVersion(current uint, expected *uint) error {
+	if expected != nil && *expected != current {
+		return ErrVersionConflict
+	}
+	return nil
+}
@@ -1,14 +1,23 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Wallet struct {
-	WalletID         uint      `gorm:"primaryKey" json:"wallet_id"`
-	Name             string    `json:"name"`
-	Icon             string    `json:"icon"`
-	IsEnabled        bool      `json:"is_enabled"`
-	Balance          float64   `json:"balance"`
-	LastModifiedTime time.Time `json:"last_modified_time"`
+	WalletID         uint           `gorm:"primaryKey" json:"wallet_id"`
+	Name             string         `json:"name"`
+	Icon             string         `json:"icon"`
+	IsEnabled        bool           `json:"is_enabled"`
+	Balance          Money          `json:"balance"`                                   // Minor units, see Money
+	OpeningBalance   Money          `gorm:"not null;default:0" json:"opening_balance"` // Balance before the first transaction
+	Currency         string         `gorm:"size:3;not null;default:'USD'" json:"currency"`
+	LastModifiedTime time.Time      `json:"last_modified_time"`
+	Version          uint           `gorm:"not null;default:1" json:"version"` // Bumped on edits and whenever the balance moves
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"deleted_at"`
+	DeletedBy        *uint          `json:"deleted_by"`
 
 	// Relationships
 	Categories   []Category    `gorm:"foreignKey:WalletID;references:WalletID" json:"categories,omitempty"`
@@ -3,6 +3,7 @@ package models
 type WalletGroup struct {
 	WalletGroupID   uint     `gorm:"primaryKey" json:"wallet_group_id"`
 	WalletGroupName string   `json:"wallet_group_name"`
+	CreatedBy       *uint    `gorm:"index" json:"created_by"` // Nullable; unset on groups created before it was recorded
 
 	// Relationships
 	Wallets []Wallet `gorm:"many2many:wallet_wallet_groups;" json:"wallets,omitempty"`